@@ -0,0 +1,299 @@
+// Package analytics provides zone and line crossing analytics on the results
+// of object tracking, such as counting the people and vehicles entering an
+// area or crossing a line and how long they dwell there.
+package analytics
+
+import (
+	"github.com/swdee/go-rknnlite/tracker"
+	"sync"
+	"time"
+)
+
+// Point is an x, y image coordinate
+type Point struct {
+	X, Y float32
+}
+
+// Anchor defines the point of a tracked object's bounding box used to
+// determine its position
+type Anchor int
+
+const (
+	// BottomCenter is the bottom center of the box, being the feet of a
+	// person or wheels of a vehicle
+	BottomCenter Anchor = 1
+	// Center is the center of the box
+	Center Anchor = 2
+)
+
+// EventType defines the type of analytics event
+type EventType int
+
+const (
+	// ZoneEnter is when a track enters a zone
+	ZoneEnter EventType = 1
+	// ZoneExit is when a track exits a zone or is no longer tracked whilst
+	// inside it
+	ZoneExit EventType = 2
+	// LineCross is when a track crosses a line
+	LineCross EventType = 3
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case ZoneEnter:
+		return "enter"
+	case ZoneExit:
+		return "exit"
+	case LineCross:
+		return "cross"
+	default:
+		return "unknown"
+	}
+}
+
+// Direction defines the direction a line is crossed in
+type Direction int
+
+const (
+	// In is crossing from the left to the right side of the line when
+	// looking from its start to end point in image coordinates
+	In Direction = 1
+	// Out is crossing from the right to the left side of the line
+	Out Direction = 2
+)
+
+// String returns the name of the direction
+func (d Direction) String() string {
+	switch d {
+	case In:
+		return "in"
+	case Out:
+		return "out"
+	default:
+		return "none"
+	}
+}
+
+// Event describes a track entering or exiting a zone or crossing a line
+type Event struct {
+	// Type of event
+	Type EventType
+	// Name of the zone or line
+	Name string
+	// TrackID is the ID of the track
+	TrackID int
+	// Label is the object label/class of the track
+	Label int
+	// Direction the line was crossed in, only set for LineCross events
+	Direction Direction
+	// Dwell is the time spent in the zone, only set for ZoneExit events
+	Dwell time.Duration
+	// Point is the anchor point of the track
+	Point Point
+	// Time of the event
+	Time time.Time
+}
+
+// trackState holds the last known position of a track
+type trackState struct {
+	// point is the last anchor point
+	point Point
+	// lastSeen is the time the track was last updated
+	lastSeen time.Time
+}
+
+// Analyzer consumes the tracks each frame and emits the events of tracks
+// entering and exiting its zones and crossing its lines
+type Analyzer struct {
+	// zones to monitor
+	zones []*Zone
+	// lines to monitor
+	lines []*Line
+	// anchor is the point of the track box used as its position
+	anchor Anchor
+	// frameInterval is the time between frames used by Update
+	frameInterval time.Duration
+	// timeout is how long a track can be missing before it is considered gone
+	timeout time.Duration
+	// now is the time of the current frame
+	now time.Time
+	// tracks are the last known positions of the tracks
+	tracks map[int]*trackState
+	sync.Mutex
+}
+
+// NewAnalyzer returns an Analyzer for a video of the given frame rate.  Tracks
+// are positioned by their bottom center and considered gone after being
+// missing for one second.
+func NewAnalyzer(frameRate int) *Analyzer {
+
+	interval := time.Second
+
+	if frameRate > 0 {
+		interval = time.Second / time.Duration(frameRate)
+	}
+
+	return &Analyzer{
+		anchor:        BottomCenter,
+		frameInterval: interval,
+		timeout:       time.Second,
+		now:           time.Now(),
+		tracks:        make(map[int]*trackState),
+	}
+}
+
+// SetAnchor sets the point of the track box used as its position
+func (a *Analyzer) SetAnchor(anchor Anchor) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.anchor = anchor
+}
+
+// SetTrackTimeout sets how long a track can be missing from the updates
+// before it is considered gone and exits any zones it is in.  This prevents
+// short occlusions from being counted as an exit and reentry.
+func (a *Analyzer) SetTrackTimeout(timeout time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.timeout = timeout
+}
+
+// AddZone adds a polygon zone to monitor and returns it
+func (a *Analyzer) AddZone(name string, polygon []Point) *Zone {
+	a.Lock()
+	defer a.Unlock()
+
+	z := newZone(name, polygon)
+	a.zones = append(a.zones, z)
+
+	return z
+}
+
+// AddLine adds a directed line from start to end to monitor and returns it
+func (a *Analyzer) AddLine(name string, start, end Point) *Line {
+	a.Lock()
+	defer a.Unlock()
+
+	l := newLine(name, start, end)
+	a.lines = append(a.lines, l)
+
+	return l
+}
+
+// Zones returns the zones monitored
+func (a *Analyzer) Zones() []*Zone {
+	a.Lock()
+	defer a.Unlock()
+
+	return append([]*Zone(nil), a.zones...)
+}
+
+// Lines returns the lines monitored
+func (a *Analyzer) Lines() []*Line {
+	a.Lock()
+	defer a.Unlock()
+
+	return append([]*Line(nil), a.lines...)
+}
+
+// Update processes the tracks of the next frame, advancing the time by the
+// frame interval, and returns the events that occurred
+func (a *Analyzer) Update(tracks []tracker.Track) []Event {
+	return a.UpdateAt(tracks, a.now.Add(a.frameInterval))
+}
+
+// UpdateAt processes the tracks of the frame captured at the given time and
+// returns the events that occurred
+func (a *Analyzer) UpdateAt(tracks []tracker.Track, now time.Time) []Event {
+	a.Lock()
+	defer a.Unlock()
+
+	a.now = now
+	var events []Event
+
+	for _, t := range tracks {
+
+		id := t.GetTrackID()
+		label := t.GetLabel()
+		p := a.anchorPoint(t.GetRect())
+
+		st, exists := a.tracks[id]
+
+		if !exists {
+			st = &trackState{}
+			a.tracks[id] = st
+		}
+
+		for _, l := range a.lines {
+			if e, ok := l.update(id, label, p, now); ok {
+				events = append(events, e)
+			}
+		}
+
+		for _, z := range a.zones {
+			if e, ok := z.update(id, label, p, now); ok {
+				events = append(events, e)
+			}
+		}
+
+		st.point = p
+		st.lastSeen = now
+	}
+
+	// remove tracks that have been missing for longer than the timeout
+	for id, st := range a.tracks {
+
+		if now.Sub(st.lastSeen) <= a.timeout {
+			continue
+		}
+
+		for _, z := range a.zones {
+			if e, ok := z.leave(id, st.point, st.lastSeen); ok {
+				events = append(events, e)
+			}
+		}
+
+		for _, l := range a.lines {
+			l.forget(id)
+		}
+
+		delete(a.tracks, id)
+	}
+
+	return events
+}
+
+// Reset clears the tracks, counts and dwell times
+func (a *Analyzer) Reset() {
+	a.Lock()
+	defer a.Unlock()
+
+	a.tracks = make(map[int]*trackState)
+
+	for _, z := range a.zones {
+		z.Reset()
+	}
+
+	for _, l := range a.lines {
+		l.Reset()
+	}
+}
+
+// anchorPoint returns the anchor point of the track box
+func (a *Analyzer) anchorPoint(rect *tracker.Rect) Point {
+
+	p := Point{
+		X: rect.X() + rect.Width()/2,
+		Y: rect.Y() + rect.Height()/2,
+	}
+
+	if a.anchor == BottomCenter {
+		p.Y = rect.BRY()
+	}
+
+	return p
+}
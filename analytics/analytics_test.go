@@ -0,0 +1,213 @@
+package analytics
+
+import (
+	"github.com/swdee/go-rknnlite/tracker"
+	"testing"
+	"time"
+)
+
+// track returns a track of the given ID and label whose box has its bottom
+// center at x, y
+func track(id, label int, x, y float32) tracker.Track {
+
+	s := tracker.NewSTrack(tracker.NewRect(x-10, y-40, 20, 40), 0.9, int64(id), label)
+	s.Activate(1, id)
+
+	return s
+}
+
+// square returns a square zone polygon
+func square(x, y, size float32) []Point {
+	return []Point{
+		{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size},
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+
+	poly := []Point{{0, 0}, {100, 0}, {100, 100}, {50, 50}, {0, 100}}
+
+	tests := []struct {
+		p    Point
+		want bool
+	}{
+		{Point{10, 10}, true},
+		{Point{90, 90}, true},
+		{Point{50, 80}, false},
+		{Point{150, 50}, false},
+		{Point{-1, 50}, false},
+	}
+
+	for _, tc := range tests {
+		if got := pointInPolygon(tc.p, poly); got != tc.want {
+			t.Errorf("point %v: expected %v, got %v", tc.p, tc.want, got)
+		}
+	}
+}
+
+func TestZoneEnterExit(t *testing.T) {
+
+	a := NewAnalyzer(10)
+	zone := a.AddZone("door", square(100, 100, 100))
+	start := time.Unix(0, 0)
+
+	// track 1 walks through the zone and track 2 stays outside
+	xs := []float32{50, 120, 150, 180, 250}
+	var events []Event
+
+	for i, x := range xs {
+		now := start.Add(time.Duration(i) * time.Second)
+		tracks := []tracker.Track{track(1, 0, x, 150), track(2, 2, 500, 150)}
+		events = append(events, a.UpdateAt(tracks, now)...)
+
+		if i == 2 {
+			occ := zone.Occupants()
+
+			if len(occ) != 1 || occ[0].TrackID != 1 || occ[0].Dwell != time.Second {
+				t.Errorf("unexpected occupants %+v", occ)
+			}
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+
+	if events[0].Type != ZoneEnter || events[0].TrackID != 1 ||
+		!events[0].Time.Equal(start.Add(time.Second)) {
+		t.Errorf("unexpected enter event %+v", events[0])
+	}
+
+	if events[1].Type != ZoneExit || events[1].Dwell != 3*time.Second ||
+		events[1].Name != "door" {
+		t.Errorf("unexpected exit event %+v", events[1])
+	}
+
+	if got := zone.Counts()[0]; got != 1 {
+		t.Errorf("expected 1 entry, got %d", got)
+	}
+
+	if got := zone.TotalDwell()[0]; got != 3*time.Second {
+		t.Errorf("expected total dwell of 3s, got %v", got)
+	}
+
+	if got := len(zone.Occupancy()); got != 0 {
+		t.Errorf("expected empty zone, got %d classes", got)
+	}
+}
+
+func TestZoneTimeout(t *testing.T) {
+
+	a := NewAnalyzer(10)
+	a.SetTrackTimeout(500 * time.Millisecond)
+	zone := a.AddZone("area", square(0, 0, 100))
+
+	// track is in the zone for 3 frames then disappears
+	var events []Event
+
+	for i := 0; i < 10; i++ {
+
+		var tracks []tracker.Track
+
+		if i < 3 {
+			tracks = append(tracks, track(7, 1, 50, 50))
+		}
+
+		events = append(events, a.Update(tracks)...)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+
+	if events[1].Type != ZoneExit || events[1].Dwell != 200*time.Millisecond {
+		t.Errorf("unexpected exit event %+v", events[1])
+	}
+
+	if got := zone.AverageDwell(1); got != 200*time.Millisecond {
+		t.Errorf("expected average dwell of 200ms, got %v", got)
+	}
+}
+
+func TestLineCrossing(t *testing.T) {
+
+	a := NewAnalyzer(30)
+	// vertical line pointing down so the right side is to the left in the
+	// image and moving right to left crosses in
+	line := a.AddLine("gate", Point{100, 0}, Point{100, 200})
+
+	paths := map[int][]float32{
+		1: {150, 120, 90, 60},  // crosses in
+		2: {50, 80, 130, 160},  // crosses out
+		3: {50, 80, 130, 160},  // crosses out beyond the end of the line
+		4: {150, 140, 130, 90}, // crosses in
+	}
+
+	labels := map[int]int{1: 0, 2: 0, 3: 0, 4: 2}
+	ys := map[int]float32{1: 50, 2: 100, 3: 300, 4: 150}
+	var events []Event
+
+	for i := 0; i < 4; i++ {
+
+		var tracks []tracker.Track
+
+		for id := 1; id <= 4; id++ {
+			tracks = append(tracks, track(id, labels[id], paths[id][i], ys[id]))
+		}
+
+		events = append(events, a.Update(tracks)...)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d: %+v", len(events), events)
+	}
+
+	want := map[int]Direction{1: In, 2: Out, 4: In}
+
+	for _, e := range events {
+		if e.Type != LineCross || want[e.TrackID] != e.Direction {
+			t.Errorf("unexpected crossing event %+v", e)
+		}
+	}
+
+	if got := line.Counts(In); got[0] != 1 || got[2] != 1 {
+		t.Errorf("unexpected in counts %v", got)
+	}
+
+	if got := line.Total(Out); got != 1 {
+		t.Errorf("expected 1 out crossing, got %d", got)
+	}
+
+	a.Reset()
+
+	if got := line.Total(In); got != 0 {
+		t.Errorf("expected counts cleared, got %d", got)
+	}
+}
+
+func TestLineNoDoubleCount(t *testing.T) {
+
+	a := NewAnalyzer(30)
+	line := a.AddLine("gate", Point{0, 100}, Point{200, 100})
+
+	// track jitters on the line then moves across and back
+	ys := []float32{90, 100, 90, 110, 105, 95}
+	var events []Event
+
+	for _, y := range ys {
+		events = append(events, a.Update([]tracker.Track{track(1, 0, 50, y)})...)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+
+	// line points right so its right side is below in the image
+	if events[0].Direction != In || events[1].Direction != Out {
+		t.Errorf("unexpected directions %s, %s", events[0].Direction, events[1].Direction)
+	}
+
+	if line.Total(In) != 1 || line.Total(Out) != 1 {
+		t.Errorf("unexpected totals in %d, out %d", line.Total(In), line.Total(Out))
+	}
+}
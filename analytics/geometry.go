@@ -0,0 +1,60 @@
+package analytics
+
+// crossProduct returns the z component of the cross product of the line a to
+// b and a to p.  In image coordinates it is positive when p is on the right
+// side of the line looking from a to b.
+func crossProduct(a, b, p Point) float32 {
+	return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+}
+
+// crossesSegment returns true if the movement from p to q, whose end points
+// are on opposite sides of the line through a and b, crosses it between a
+// and b
+func crossesSegment(a, b, p, q Point) bool {
+
+	cp := crossProduct(a, b, p)
+	cq := crossProduct(a, b, q)
+
+	if cp == cq {
+		return false
+	}
+
+	// point the movement crosses the line
+	t := cp / (cp - cq)
+	x := Point{
+		X: p.X + (q.X-p.X)*t,
+		Y: p.Y + (q.Y-p.Y)*t,
+	}
+
+	// position of the crossing point along the line
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lenSq := dx*dx + dy*dy
+
+	if lenSq == 0 {
+		return false
+	}
+
+	u := ((x.X-a.X)*dx + (x.Y-a.Y)*dy) / lenSq
+
+	return u >= 0 && u <= 1
+}
+
+// pointInPolygon returns true if the point is inside the polygon using ray
+// casting
+func pointInPolygon(p Point, polygon []Point) bool {
+
+	inside := false
+	n := len(polygon)
+
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+
+		a, b := polygon[i], polygon[j]
+
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,131 @@
+package analytics
+
+import (
+	"sync"
+	"time"
+)
+
+// lineSide is the last side of the line a track was observed on
+type lineSide struct {
+	// side is the sign of the cross product, -1 left or 1 right
+	side int
+	// point is the last point observed on that side
+	point Point
+}
+
+// Line is a directed line segment that counts the tracks crossing it in each
+// direction
+type Line struct {
+	// Name of the line
+	Name string
+	// Start point of the line
+	Start Point
+	// End point of the line
+	End Point
+	// sides are the last side of the line each track was on
+	sides map[int]lineSide
+	// counts are the number of crossings per direction and class
+	counts map[Direction]map[int]int
+	sync.Mutex
+}
+
+// newLine returns a directed line from start to end
+func newLine(name string, start, end Point) *Line {
+	return &Line{
+		Name:  name,
+		Start: start,
+		End:   end,
+		sides: make(map[int]lineSide),
+		counts: map[Direction]map[int]int{
+			In:  make(map[int]int),
+			Out: make(map[int]int),
+		},
+	}
+}
+
+// Counts returns the number of crossings in the direction per class
+func (l *Line) Counts(dir Direction) map[int]int {
+	l.Lock()
+	defer l.Unlock()
+
+	return copyCounts(l.counts[dir])
+}
+
+// Total returns the total number of crossings in the direction
+func (l *Line) Total(dir Direction) int {
+	l.Lock()
+	defer l.Unlock()
+
+	return sumCounts(l.counts[dir])
+}
+
+// Reset clears the crossing counts
+func (l *Line) Reset() {
+	l.Lock()
+	defer l.Unlock()
+
+	l.sides = make(map[int]lineSide)
+	l.counts = map[Direction]map[int]int{
+		In:  make(map[int]int),
+		Out: make(map[int]int),
+	}
+}
+
+// update updates the line with the track's position returning a crossing
+// event if the track crossed the line since it was last on the other side
+func (l *Line) update(id, label int, p Point, now time.Time) (Event, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	cross := crossProduct(l.Start, l.End, p)
+
+	if cross == 0 {
+		// on the line so wait until it is on a side
+		return Event{}, false
+	}
+
+	side := 1
+
+	if cross < 0 {
+		side = -1
+	}
+
+	last, seen := l.sides[id]
+	l.sides[id] = lineSide{side: side, point: p}
+
+	if !seen || last.side == side {
+		return Event{}, false
+	}
+
+	// check the movement crossed within the line segment and not its
+	// extension
+	if !crossesSegment(l.Start, l.End, last.point, p) {
+		return Event{}, false
+	}
+
+	dir := In
+
+	if side < 0 {
+		dir = Out
+	}
+
+	l.counts[dir][label]++
+
+	return Event{
+		Type:      LineCross,
+		Name:      l.Name,
+		TrackID:   id,
+		Label:     label,
+		Direction: dir,
+		Point:     p,
+		Time:      now,
+	}, true
+}
+
+// forget removes the track's last side
+func (l *Line) forget(id int) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.sides, id)
+}
@@ -0,0 +1,241 @@
+package analytics
+
+import (
+	"sync"
+	"time"
+)
+
+// Occupant is a track currently inside a zone
+type Occupant struct {
+	// TrackID is the ID of the track
+	TrackID int
+	// Label is the object label/class of the track
+	Label int
+	// Entered is the time the track entered the zone
+	Entered time.Time
+	// Dwell is the time spent in the zone so far
+	Dwell time.Duration
+}
+
+// Zone is a polygon area that counts the tracks entering it and the time they
+// dwell inside
+type Zone struct {
+	// Name of the zone
+	Name string
+	// Polygon are the vertices of the zone
+	Polygon []Point
+	// occupants are the tracks inside the zone keyed by track ID
+	occupants map[int]*Occupant
+	// entries are the number of tracks that entered the zone per class
+	entries map[int]int
+	// exits are the number of tracks that exited the zone per class
+	exits map[int]int
+	// dwell is the total dwell time of tracks that exited per class
+	dwell map[int]time.Duration
+	// now is the time of the last update
+	now time.Time
+	sync.Mutex
+}
+
+// newZone returns a zone of the polygon
+func newZone(name string, polygon []Point) *Zone {
+	return &Zone{
+		Name:      name,
+		Polygon:   append([]Point(nil), polygon...),
+		occupants: make(map[int]*Occupant),
+		entries:   make(map[int]int),
+		exits:     make(map[int]int),
+		dwell:     make(map[int]time.Duration),
+	}
+}
+
+// Contains returns true if the point is inside the zone polygon
+func (z *Zone) Contains(p Point) bool {
+	return pointInPolygon(p, z.Polygon)
+}
+
+// Counts returns the number of tracks that entered the zone per class
+func (z *Zone) Counts() map[int]int {
+	z.Lock()
+	defer z.Unlock()
+
+	return copyCounts(z.entries)
+}
+
+// ExitCounts returns the number of tracks that exited the zone per class
+func (z *Zone) ExitCounts() map[int]int {
+	z.Lock()
+	defer z.Unlock()
+
+	return copyCounts(z.exits)
+}
+
+// Total returns the total number of tracks that entered the zone
+func (z *Zone) Total() int {
+	z.Lock()
+	defer z.Unlock()
+
+	return sumCounts(z.entries)
+}
+
+// Occupancy returns the number of tracks currently in the zone per class
+func (z *Zone) Occupancy() map[int]int {
+	z.Lock()
+	defer z.Unlock()
+
+	res := make(map[int]int)
+
+	for _, o := range z.occupants {
+		res[o.Label]++
+	}
+
+	return res
+}
+
+// Occupants returns the tracks currently in the zone with their dwell time
+func (z *Zone) Occupants() []Occupant {
+	z.Lock()
+	defer z.Unlock()
+
+	res := make([]Occupant, 0, len(z.occupants))
+
+	for _, o := range z.occupants {
+		occ := *o
+		occ.Dwell = z.now.Sub(o.Entered)
+		res = append(res, occ)
+	}
+
+	return res
+}
+
+// TotalDwell returns the total dwell time per class of the tracks that have
+// exited the zone
+func (z *Zone) TotalDwell() map[int]time.Duration {
+	z.Lock()
+	defer z.Unlock()
+
+	res := make(map[int]time.Duration, len(z.dwell))
+
+	for k, v := range z.dwell {
+		res[k] = v
+	}
+
+	return res
+}
+
+// AverageDwell returns the average dwell time of the tracks of the class that
+// have exited the zone
+func (z *Zone) AverageDwell(label int) time.Duration {
+	z.Lock()
+	defer z.Unlock()
+
+	if z.exits[label] == 0 {
+		return 0
+	}
+
+	return z.dwell[label] / time.Duration(z.exits[label])
+}
+
+// Reset clears the occupants, counts and dwell times
+func (z *Zone) Reset() {
+	z.Lock()
+	defer z.Unlock()
+
+	z.occupants = make(map[int]*Occupant)
+	z.entries = make(map[int]int)
+	z.exits = make(map[int]int)
+	z.dwell = make(map[int]time.Duration)
+}
+
+// update updates the zone with the track's position returning an enter or
+// exit event if one occurred
+func (z *Zone) update(id, label int, p Point, now time.Time) (Event, bool) {
+	z.Lock()
+	defer z.Unlock()
+
+	z.now = now
+	inside := pointInPolygon(p, z.Polygon)
+	_, occupied := z.occupants[id]
+
+	switch {
+	case inside && !occupied:
+		z.occupants[id] = &Occupant{
+			TrackID: id,
+			Label:   label,
+			Entered: now,
+		}
+
+		z.entries[label]++
+
+		return Event{
+			Type:    ZoneEnter,
+			Name:    z.Name,
+			TrackID: id,
+			Label:   label,
+			Point:   p,
+			Time:    now,
+		}, true
+
+	case !inside && occupied:
+		return z.exit(id, p, now), true
+	}
+
+	return Event{}, false
+}
+
+// leave exits a track that is no longer tracked at the time it was last seen
+func (z *Zone) leave(id int, p Point, lastSeen time.Time) (Event, bool) {
+	z.Lock()
+	defer z.Unlock()
+
+	if _, occupied := z.occupants[id]; !occupied {
+		return Event{}, false
+	}
+
+	return z.exit(id, p, lastSeen), true
+}
+
+// exit removes the occupant recording its dwell time
+func (z *Zone) exit(id int, p Point, at time.Time) Event {
+
+	o := z.occupants[id]
+	dwell := at.Sub(o.Entered)
+
+	z.exits[o.Label]++
+	z.dwell[o.Label] += dwell
+	delete(z.occupants, id)
+
+	return Event{
+		Type:    ZoneExit,
+		Name:    z.Name,
+		TrackID: id,
+		Label:   o.Label,
+		Dwell:   dwell,
+		Point:   p,
+		Time:    at,
+	}
+}
+
+// copyCounts returns a copy of the counts
+func copyCounts(counts map[int]int) map[int]int {
+
+	res := make(map[int]int, len(counts))
+
+	for k, v := range counts {
+		res[k] = v
+	}
+
+	return res
+}
+
+// sumCounts returns the sum of all counts
+func sumCounts(counts map[int]int) int {
+
+	total := 0
+
+	for _, v := range counts {
+		total += v
+	}
+
+	return total
+}
@@ -48,6 +48,13 @@ type ResultFrame struct {
 	Err error
 }
 
+// FrameTracker defines an interface for object trackers that use the image
+// frame for ReID inference and camera motion compensation
+type FrameTracker interface {
+	tracker.Tracker
+	UpdateWithFrame(objects []tracker.Object, frame gocv.Mat) ([]tracker.Track, error)
+}
+
 // YOLOProcessor defines an interface for different versions of YOLO
 // models used for object detection
 type YOLOProcessor interface {
@@ -133,13 +140,20 @@ type Demo struct {
 	reid bool
 	// reidPool of rknnlite runtimes to perform inference in parallel
 	reidPool *rknnlite.Pool
+	// trackerType is the object tracking algorithm to use, bytetrack, ocsort
+	// or botsort
+	trackerType string
+	// gmcMethod is the camera motion compensation method to use, zero if not
+	// being used
+	gmcMethod tracker.GMCMethod
 }
 
 // NewDemo returns and instance of Demo, a streaming HTTP server showing
 // video with object detection
 func NewDemo(vidSrc *VideoSource, modelFile, labelFile string, poolSize int,
 	modelType string, renderFormat string, rkPlatform string,
-	reidModelFile string, useReid bool) (*Demo, error) {
+	reidModelFile string, useReid bool, trackerType string,
+	gmcMethod string) (*Demo, error) {
 
 	var err error
 
@@ -148,6 +162,28 @@ func NewDemo(vidSrc *VideoSource, modelFile, labelFile string, poolSize int,
 		limitObjs:     make([]string, 0),
 		reidModelFile: reidModelFile,
 		reid:          useReid,
+		trackerType:   trackerType,
+	}
+
+	switch trackerType {
+	case "bytetrack", "botsort":
+	case "ocsort":
+		if useReid {
+			log.Println("***WARNING*** ReID is not supported by OC-SORT and will be ignored")
+			d.reid = false
+		}
+	default:
+		return nil, fmt.Errorf("Unknown tracker type: %s", trackerType)
+	}
+
+	switch gmcMethod {
+	case "":
+	case "sparseflow":
+		d.gmcMethod = tracker.GMCSparseOptFlow
+	case "orb":
+		d.gmcMethod = tracker.GMCORB
+	default:
+		return nil, fmt.Errorf("Unknown camera motion compensation method: %s", gmcMethod)
 	}
 
 	if vidSrc.Format == VideoFile {
@@ -183,8 +219,27 @@ func NewDemo(vidSrc *VideoSource, modelFile, labelFile string, poolSize int,
 
 	d.pool.Return(rt)
 
+	// load in Model class names
+	d.labels, err = rknnlite.LoadLabels(labelFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error loading model labels: %w", err)
+	}
+
 	// create YOLOv5 post processor
 	switch modelType {
+	case "auto":
+		rt = d.pool.Get()
+		auto, err := postprocess.NewYOLOAuto(rt, d.labels)
+		d.pool.Return(rt)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error detecting YOLO model type: %w", err)
+		}
+
+		log.Printf("Detected YOLO model type: %s\n", auto.Layout.Family)
+		d.process = auto
+
 	case "v8":
 		d.process = postprocess.NewYOLOv8(postprocess.YOLOv8COCOParams())
 	case "v5":
@@ -216,7 +271,7 @@ func NewDemo(vidSrc *VideoSource, modelFile, labelFile string, poolSize int,
 		d.process = postprocess.NewYOLOv8obb(postprocess.YOLOv8obbDOTAv1Params())
 
 	default:
-		log.Fatal("Unknown model type, use 'v5', 'v8', 'v10', 'v11', 'x', 'v5seg', 'v8seg', 'v8pose', or 'v8obb'")
+		log.Fatal("Unknown model type, use 'auto', 'v5', 'v8', 'v10', 'v11', 'x', 'v5seg', 'v8seg', 'v8pose', or 'v8obb'")
 	}
 
 	d.modelType = modelType
@@ -238,13 +293,6 @@ func NewDemo(vidSrc *VideoSource, modelFile, labelFile string, poolSize int,
 		log.Println("***WARNING*** ReID is experimental and requires alot of NPU, downgraded to 4 FPS")
 	}
 
-	// load in Model class names
-	d.labels, err = rknnlite.LoadLabels(labelFile)
-
-	if err != nil {
-		return nil, fmt.Errorf("Error loading model labels: %w", err)
-	}
-
 	// create pool for ReID
 	if d.reid {
 		d.reidPool, err = rknnlite.NewPool(poolSize, reidModelFile,
@@ -386,14 +434,10 @@ func (d *Demo) Stream(w http.ResponseWriter, r *http.Request) {
 	// pointer to position in video buffer
 	frameNum := -1
 
-	// create a bytetracker for tracking detected objects
-	// you must create a new instance of byteTrack per stream as it keeps a
+	// create a tracker for tracking detected objects
+	// you must create a new instance of the tracker per stream as it keeps a
 	// record of past object detections for tracking
-	byteTrack := tracker.NewBYTETracker(FPS, FPS*10, 0.5, 0.6, 0.8)
-
-	if d.reid {
-		byteTrack.UseReID(d.reidPool, tracker.Euclidean, 0.51)
-	}
+	objTracker := d.NewTracker()
 
 	// create a trails history
 	trail := tracker.NewTrail(90)
@@ -434,7 +478,7 @@ loop:
 			frameNum++
 
 			go d.ProcessFrame(frame, recvFrame, fps, frameNum,
-				byteTrack, trail, true)
+				objTracker, trail, true)
 
 		// simulate reading 30FPS web camera
 		case <-ticker.C:
@@ -449,13 +493,13 @@ loop:
 				// last frame reached so loop back to start of video
 				frameNum = 0
 				// clear tracker data
-				byteTrack.Reset()
+				objTracker.Reset()
 				// clear trail data
 				trail.Reset()
 			}
 
 			go d.ProcessFrame(d.vidBuffer[frameNum], recvFrame, fps, frameNum,
-				byteTrack, trail, false)
+				objTracker, trail, false)
 
 		case buf := <-recvFrame:
 
@@ -491,11 +535,46 @@ loop:
 	}
 }
 
+// NewTracker returns a new instance of the selected object tracker
+func (d *Demo) NewTracker() tracker.Tracker {
+
+	switch d.trackerType {
+	case "ocsort":
+		return tracker.NewOCSORT(FPS, FPS, 0.6, 0.3)
+
+	case "botsort":
+		botSort := tracker.NewBoTSORT(FPS, FPS*10, 0.5, 0.6, 0.8)
+
+		if d.reid {
+			botSort.UseReID(d.reidPool, tracker.Cosine, 0.25)
+		}
+
+		if d.gmcMethod != 0 {
+			botSort.UseGMC(d.gmcMethod)
+		}
+
+		return botSort
+
+	default:
+		byteTrack := tracker.NewBYTETracker(FPS, FPS*10, 0.5, 0.6, 0.8)
+
+		if d.reid {
+			byteTrack.UseReID(d.reidPool, tracker.Euclidean, 0.51)
+		}
+
+		if d.gmcMethod != 0 {
+			byteTrack.UseGMC(d.gmcMethod)
+		}
+
+		return byteTrack
+	}
+}
+
 // ProcessFrame takes an image from the video and runs inference/object
 // detection on it, annotates the image and returns the result encoded
 // as a JPG file
 func (d *Demo) ProcessFrame(img gocv.Mat, retChan chan<- ResultFrame,
-	fps float64, frameNum int, byteTrack *tracker.BYTETracker,
+	fps float64, frameNum int, objTracker tracker.Tracker,
 	trail *tracker.Trail, closeImg bool) {
 
 	timing := &Timing{
@@ -526,15 +605,17 @@ func (d *Demo) ProcessFrame(img gocv.Mat, retChan chan<- ResultFrame,
 	// track detected objects
 	timing.TrackerStart = time.Now()
 
-	var trackObjs []*tracker.STrack
+	var trackObjs []tracker.Track
 
-	if d.reid {
-		trackObjs, err = byteTrack.UpdateWithFrame(
+	// pass the frame to trackers using ReID or camera motion compensation
+	if ft, ok := objTracker.(FrameTracker); ok &&
+		(d.reid || d.gmcMethod != 0 || d.trackerType == "botsort") {
+		trackObjs, err = ft.UpdateWithFrame(
 			postprocess.DetectionsToObjects(detectResults),
 			resImg,
 		)
 	} else {
-		trackObjs, err = byteTrack.Update(
+		trackObjs, err = objTracker.Update(
 			postprocess.DetectionsToObjects(detectResults),
 		)
 	}
@@ -588,7 +669,7 @@ func (d *Demo) ProcessFrame(img gocv.Mat, retChan chan<- ResultFrame,
 
 // LimitResults takes the tracked results and strips out any results that
 // we don't want to track
-func (d *Demo) LimitResults(trackResults []*tracker.STrack) []*tracker.STrack {
+func (d *Demo) LimitResults(trackResults []tracker.Track) []tracker.Track {
 
 	if len(d.limitObjs) == 0 {
 		return trackResults
@@ -596,7 +677,7 @@ func (d *Demo) LimitResults(trackResults []*tracker.STrack) []*tracker.STrack {
 	}
 
 	// strip out and detected objects we don't want to track
-	var newTrackResults []*tracker.STrack
+	var newTrackResults []tracker.Track
 
 	for _, tResult := range trackResults {
 
@@ -616,7 +697,7 @@ func (d *Demo) LimitResults(trackResults []*tracker.STrack) []*tracker.STrack {
 // AnnotateImg draws the detection boxes and processing statistics on the given
 // image Mat
 func (d *Demo) AnnotateImg(img gocv.Mat, detectResults []result.DetectResult,
-	trackResults []*tracker.STrack,
+	trackResults []tracker.Track,
 	segMask postprocess.SegMask, keyPoints [][]result.KeyPoint,
 	trail *tracker.Trail, fps float64,
 	frameNum int, timing *Timing) {
@@ -748,7 +829,7 @@ func main() {
 
 	// read in cli flags
 	modelFile := flag.String("m", "../data/models/rk3588/yolov5s-rk3588.rknn", "RKNN compiled YOLO model file")
-	modelType := flag.String("t", "v5", "Version of YOLO model [v5|v8|v10|v11|x|v5seg|v8seg|v8pose]")
+	modelType := flag.String("t", "v5", "Version of YOLO model [auto|v5|v8|v10|v11|x|v5seg|v8seg|v8pose]")
 	vidFile := flag.String("v", "../data/palace.mp4", "Video file to run object detection and tracking on or device of web camera when used with -c flag")
 	labelFile := flag.String("l", "../data/coco_80_labels_list.txt", "Text file containing model labels")
 	httpAddr := flag.String("a", "localhost:8080", "HTTP Address to run server on, format address:port")
@@ -759,6 +840,8 @@ func main() {
 	rkPlatform := flag.String("p", "rk3588", "Rockchip CPU Model number [rk3562|rk3566|rk3568|rk3576|rk3582|rk3582|rk3588]")
 	reidModelFile := flag.String("rm", "../data/models/rk3588/osnet-market1501-batch8-rk3588.rknn", "RKNN compiled OSNet/Re-Identification model file")
 	useReid := flag.Bool("reid", false, "Enable Re-Identification enhanced tracking")
+	trackerType := flag.String("tracker", "bytetrack", "Object tracker to use [bytetrack|ocsort|botsort]")
+	gmcMethod := flag.String("gmc", "", "Camera motion compensation method for bytetrack and botsort trackers [sparseflow|orb]")
 
 	// Initialize the custom camera resolution flag with a default value
 	cameraRes := &cameraResFlag{value: "1280x720@30"}
@@ -811,7 +894,8 @@ func main() {
 	}
 
 	demo, err := NewDemo(vidSrc, *modelFile, *labelFile, *poolSize,
-		*modelType, *renderFormat, *rkPlatform, *reidModelFile, *useReid)
+		*modelType, *renderFormat, *rkPlatform, *reidModelFile, *useReid,
+		*trackerType, *gmcMethod)
 
 	if err != nil {
 		log.Fatalf("Error creating demo: %v", err)
@@ -298,6 +298,8 @@ type OutputAttribute struct {
 	ZPs        []int32
 	DimHeights []uint32
 	DimWidths  []uint32
+	Fmts       []TensorFormat
+	Dims       [][]uint32
 	IONumber   uint32
 }
 
@@ -310,6 +312,8 @@ func (o *Outputs) OutputAttributes() OutputAttribute {
 		ZPs:        make([]int32, 0),
 		DimHeights: make([]uint32, 0),
 		DimWidths:  make([]uint32, 0),
+		Fmts:       make([]TensorFormat, 0),
+		Dims:       make([][]uint32, 0),
 		IONumber:   o.rt.ioNum.NumberOutput,
 	}
 
@@ -318,6 +322,10 @@ func (o *Outputs) OutputAttributes() OutputAttribute {
 		data.ZPs = append(data.ZPs, o.rt.outputAttrs[i].ZP)
 		data.DimHeights = append(data.DimHeights, o.rt.outputAttrs[i].Dims[2])
 		data.DimWidths = append(data.DimWidths, o.rt.outputAttrs[i].Dims[3])
+		data.Fmts = append(data.Fmts, o.rt.outputAttrs[i].Fmt)
+
+		dims := o.rt.outputAttrs[i].Dims
+		data.Dims = append(data.Dims, dims[:o.rt.outputAttrs[i].NDims])
 	}
 
 	return data
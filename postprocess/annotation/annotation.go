@@ -0,0 +1,279 @@
+/*
+Package annotation provides encoders and decoders for serialising detection
+results to JSON and the common dataset annotation formats being COCO results,
+YOLO txt labels, Pascal VOC XML and LabelMe.  These can be used to store
+detections for auditing or to create labelled datasets for retraining a Model.
+*/
+package annotation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/swdee/go-rknnlite/postprocess"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+// Frame holds the results of a single image to be serialised
+type Frame struct {
+	// FileName is the file name or path of the source image
+	FileName string
+	// ImageID is the image ID used in COCO results
+	ImageID int
+	// Width of the source image in pixels
+	Width int
+	// Height of the source image in pixels
+	Height int
+	// Detections are the object detection results
+	Detections []result.DetectResult
+	// KeyPoints are the pose or landmark keypoints for each of the Detections
+	// in the same order, nil if the Model does not produce keypoints
+	KeyPoints [][]result.KeyPoint
+	// Mask is the segment mask of the source image where each pixel is the
+	// index+1 of the detection in Detections, or zero for background.  This
+	// is SegMask.Mask as returned by the segmentation post processors
+	Mask []uint8
+	// Text are the text regions detected by the PPOCR detection Model
+	Text []postprocess.PPOCRBox
+}
+
+// Codec defines the struct for encoding and decoding Frames to the supported
+// serialisation formats
+type Codec struct {
+	// Labels are the class labels the Model was trained on as returned by
+	// rknnlite.LoadLabels()
+	Labels []string
+	// Width of the source image in pixels
+	Width int
+	// Height of the source image in pixels
+	Height int
+	// CategoryIDs optionally maps the class index to the category_id used
+	// in COCO results, such as COCO80ToCOCO91.  If nil the class index is used
+	CategoryIDs []int
+	// KeyPointThreshold is the minimum keypoint score for it to be marked as
+	// visible in YOLO labels.  If zero a threshold of 0.5 is used
+	KeyPointThreshold float32
+}
+
+// NewCodec returns an instance of the Codec using the given labels and the
+// source image size of the resizer
+func NewCodec(labels []string, resizer *preprocess.Resizer) *Codec {
+	return &Codec{
+		Labels: labels,
+		Width:  resizer.SrcWidth(),
+		Height: resizer.SrcHeight(),
+	}
+}
+
+// NewFrame returns a Frame for the given detection results sized to the
+// Codec's source image
+func (c *Codec) NewFrame(fileName string, dets []result.DetectResult) Frame {
+	return Frame{
+		FileName:   fileName,
+		Width:      c.Width,
+		Height:     c.Height,
+		Detections: dets,
+	}
+}
+
+// COCO80ToCOCO91 maps the 80 class indexes of Models trained on the COCO
+// dataset to the original 91 COCO category ID's used in COCO results
+var COCO80ToCOCO91 = []int{
+	1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25, 27, 28, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42,
+	43, 44, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64, 65, 67, 70, 72, 73, 74, 75, 76, 77, 78, 79, 80, 81, 82, 84,
+	85, 86, 87, 88, 89, 90,
+}
+
+// label returns the label name of the class
+func (c *Codec) label(class int) string {
+	if class >= 0 && class < len(c.Labels) {
+		return c.Labels[class]
+	}
+
+	return fmt.Sprintf("%d", class)
+}
+
+// class returns the class index of the label name
+func (c *Codec) class(label string) (int, error) {
+
+	for i, l := range c.Labels {
+		if l == label {
+			return i, nil
+		}
+	}
+
+	var class int
+
+	if _, err := fmt.Sscanf(label, "%d", &class); err == nil {
+		return class, nil
+	}
+
+	return 0, fmt.Errorf("unknown label: %s", label)
+}
+
+// frameSize returns the frame dimensions defaulting to the Codec's source
+// image size
+func (c *Codec) frameSize(f Frame) (int, int) {
+
+	w, h := f.Width, f.Height
+
+	if w == 0 || h == 0 {
+		w, h = c.Width, c.Height
+	}
+
+	return w, h
+}
+
+// corners returns the four corner points of the detection's bounding box.
+// Oriented bounding boxes are rotated around their center.
+func corners(b result.BoxRectF) [4][2]float32 {
+
+	if b.Mode != result.ModeXYWH {
+		return [4][2]float32{
+			{b.Left, b.Top},
+			{b.Right, b.Top},
+			{b.Right, b.Bottom},
+			{b.Left, b.Bottom},
+		}
+	}
+
+	cx := float64(b.X + b.Width/2)
+	cy := float64(b.Y + b.Height/2)
+	aCos := math.Cos(float64(b.Angle))
+	aSin := math.Sin(float64(b.Angle))
+	xD := float64(b.Width) / 2
+	yD := float64(b.Height) / 2
+
+	offsets := [4][2]float64{{-xD, -yD}, {xD, -yD}, {xD, yD}, {-xD, yD}}
+
+	var pts [4][2]float32
+
+	for i, o := range offsets {
+		pts[i][0] = float32(aCos*o[0] - aSin*o[1] + cx)
+		pts[i][1] = float32(aSin*o[0] + aCos*o[1] + cy)
+	}
+
+	return pts
+}
+
+// fromCorners returns the bounding box of the four corner points.  If the
+// points are rotated an oriented bounding box is returned.
+func fromCorners(pts [4][2]float32) result.BoxRectF {
+
+	dx := float64(pts[1][0] - pts[0][0])
+	dy := float64(pts[1][1] - pts[0][1])
+	angle := math.Atan2(dy, dx)
+
+	if math.Abs(angle) < 1e-6 && pts[0][0] <= pts[2][0] && pts[0][1] <= pts[2][1] {
+		return result.BoxRectF{
+			Left:   pts[0][0],
+			Top:    pts[0][1],
+			Right:  pts[2][0],
+			Bottom: pts[2][1],
+		}
+	}
+
+	w := math.Hypot(dx, dy)
+	h := math.Hypot(float64(pts[2][0]-pts[1][0]), float64(pts[2][1]-pts[1][1]))
+	cx := float64(pts[0][0]+pts[2][0]) / 2
+	cy := float64(pts[0][1]+pts[2][1]) / 2
+
+	return result.BoxRectF{
+		X:      float32(cx - w/2),
+		Y:      float32(cy - h/2),
+		Width:  float32(w),
+		Height: float32(h),
+		Angle:  float32(angle),
+		Mode:   result.ModeXYWH,
+	}
+}
+
+// bounds returns the axis aligned x, y, width and height of the bounding box
+func bounds(b result.BoxRectF) (float32, float32, float32, float32) {
+
+	if b.Mode != result.ModeXYWH {
+		return b.Left, b.Top, b.Right - b.Left, b.Bottom - b.Top
+	}
+
+	pts := corners(b)
+	x1, y1 := pts[0][0], pts[0][1]
+	x2, y2 := x1, y1
+
+	for _, p := range pts[1:] {
+		x1 = min32(x1, p[0])
+		y1 = min32(y1, p[1])
+		x2 = max32(x2, p[0])
+		y2 = max32(y2, p[1])
+	}
+
+	return x1, y1, x2 - x1, y2 - y1
+}
+
+// textCorners returns the corner points of a PPOCR text box
+func textCorners(b postprocess.PPOCRBox) [4][2]float32 {
+	return [4][2]float32{
+		{float32(b.LeftTop.X), float32(b.LeftTop.Y)},
+		{float32(b.RightTop.X), float32(b.RightTop.Y)},
+		{float32(b.RightBottom.X), float32(b.RightBottom.Y)},
+		{float32(b.LeftBottom.X), float32(b.LeftBottom.Y)},
+	}
+}
+
+// textBox returns the PPOCR text box of the corner points
+func textBox(pts [4][2]float32, score float32) postprocess.PPOCRBox {
+
+	pt := func(p [2]float32) postprocess.PPOCRPoint {
+		return postprocess.PPOCRPoint{
+			X: int(math.Round(float64(p[0]))),
+			Y: int(math.Round(float64(p[1]))),
+		}
+	}
+
+	return postprocess.PPOCRBox{
+		LeftTop:     pt(pts[0]),
+		RightTop:    pt(pts[1]),
+		RightBottom: pt(pts[2]),
+		LeftBottom:  pt(pts[3]),
+		Score:       score,
+	}
+}
+
+// newDetectResult returns a detection result for the decoded box
+func newDetectResult(id int64, class int, prob float32, boxF result.BoxRectF) result.DetectResult {
+	return result.DetectResult{
+		ID:          id,
+		Class:       class,
+		Probability: prob,
+		Box:         boxF.Rect(),
+		BoxF:        boxF,
+	}
+}
+
+// maskFromRLE sets the pixels of the RLE in the frame mask to the given value
+func maskFromRLE(mask []uint8, rle result.RLE, value uint8) {
+
+	decoded := rle.Decode(value)
+
+	for i, v := range decoded {
+		if v != 0 && i < len(mask) {
+			mask[i] = v
+		}
+	}
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,242 @@
+package annotation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/postprocess"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// testCodec returns a Codec for a 100x50 source image
+func testCodec() *Codec {
+	return &Codec{
+		Labels: []string{"person", "car", "dog"},
+		Width:  100,
+		Height: 50,
+	}
+}
+
+// testFrame returns a Frame with a box detection with keypoints and a mask
+// and a second box detection
+func testFrame() Frame {
+
+	mask := make([]uint8, 100*50)
+
+	// first detection covers a 4x2 region
+	for y := 10; y < 12; y++ {
+		for x := 20; x < 24; x++ {
+			mask[y*100+x] = 1
+		}
+	}
+
+	return Frame{
+		FileName: "images/test.jpg",
+		ImageID:  7,
+		Width:    100,
+		Height:   50,
+		Detections: []result.DetectResult{
+			newDetectResult(1, 0, 0.9, result.BoxRectF{Left: 10.5, Top: 5.25, Right: 30.5, Bottom: 45}),
+			newDetectResult(2, 2, 0.6, result.BoxRectF{Left: 60, Top: 20, Right: 80, Bottom: 30}),
+		},
+		KeyPoints: [][]result.KeyPoint{
+			{{X: 15, Y: 10, Score: 0.8}, {X: 25, Y: 30, Score: 0.1}},
+			nil,
+		},
+		Mask: mask,
+	}
+}
+
+func closeTo(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 0.01
+}
+
+func boxEqual(a, b result.BoxRectF) bool {
+	return a.Mode == b.Mode &&
+		closeTo(a.Left, b.Left) && closeTo(a.Top, b.Top) &&
+		closeTo(a.Right, b.Right) && closeTo(a.Bottom, b.Bottom) &&
+		closeTo(a.X, b.X) && closeTo(a.Y, b.Y) &&
+		closeTo(a.Width, b.Width) && closeTo(a.Height, b.Height) &&
+		closeTo(a.Angle, b.Angle)
+}
+
+// checkDetections compares the decoded detections class and boxes
+func checkDetections(t *testing.T, format string, want, got []result.DetectResult) {
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d detections, got %d", format, len(want), len(got))
+	}
+
+	for i := range want {
+		if got[i].Class != want[i].Class {
+			t.Errorf("%s: detection %d expected class %d, got %d", format, i,
+				want[i].Class, got[i].Class)
+		}
+
+		if !boxEqual(got[i].BoxF, want[i].BoxF) {
+			t.Errorf("%s: detection %d expected box %+v, got %+v", format, i,
+				want[i].BoxF, got[i].BoxF)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+
+	c := testCodec()
+	f := testFrame()
+	f.Text = []postprocess.PPOCRBox{
+		{
+			LeftTop:     postprocess.PPOCRPoint{X: 1, Y: 2},
+			RightTop:    postprocess.PPOCRPoint{X: 11, Y: 2},
+			RightBottom: postprocess.PPOCRPoint{X: 11, Y: 8},
+			LeftBottom:  postprocess.PPOCRPoint{X: 1, Y: 8},
+			Score:       0.7,
+		},
+	}
+
+	data, err := c.EncodeJSON(f)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := c.DecodeJSON(data)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkDetections(t, "json", f.Detections, got.Detections)
+
+	if got.Detections[0].Probability != 0.9 || got.Detections[0].ID != 1 {
+		t.Errorf("expected probability and ID to be kept, got %+v", got.Detections[0])
+	}
+
+	if len(got.KeyPoints) != 2 || len(got.KeyPoints[0]) != 2 || got.KeyPoints[0][1] != f.KeyPoints[0][1] {
+		t.Errorf("expected keypoints %v, got %v", f.KeyPoints, got.KeyPoints)
+	}
+
+	for i := range f.Mask {
+		if got.Mask[i] != f.Mask[i] {
+			t.Fatalf("mask mismatch at %d", i)
+		}
+	}
+
+	if len(got.Text) != 1 || got.Text[0] != f.Text[0] {
+		t.Errorf("expected text %+v, got %+v", f.Text, got.Text)
+	}
+}
+
+func TestCOCO(t *testing.T) {
+
+	c := testCodec()
+	c.CategoryIDs = []int{1, 3, 18}
+	f := testFrame()
+
+	data, err := c.EncodeCOCO(f)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	frames, err := c.DecodeCOCO(data)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(frames) != 1 || frames[0].ImageID != 7 {
+		t.Fatalf("expected a single frame with image ID 7, got %+v", frames)
+	}
+
+	checkDetections(t, "coco", f.Detections, frames[0].Detections)
+
+	if len(frames[0].KeyPoints) != 1 || len(frames[0].KeyPoints[0]) != 2 {
+		t.Errorf("expected keypoints for first detection, got %v", frames[0].KeyPoints)
+	}
+
+	area := 0
+
+	for _, v := range frames[0].Mask {
+		if v == 1 {
+			area++
+		}
+	}
+
+	if area != 8 {
+		t.Errorf("expected decoded mask area 8, got %d", area)
+	}
+}
+
+func TestYOLO(t *testing.T) {
+
+	c := testCodec()
+	f := testFrame()
+
+	obb := result.BoxRectF{X: 40, Y: 20, Width: 20, Height: 10, Angle: 0.5, Mode: result.ModeXYWH}
+	f.Detections = append(f.Detections, newDetectResult(3, 1, 0.5, obb))
+
+	got, err := c.DecodeYOLO(c.EncodeYOLO(f))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkDetections(t, "yolo", f.Detections, got.Detections)
+
+	// keypoint under the visibility threshold is written as not visible
+	if len(got.KeyPoints) != 1 || got.KeyPoints[0][0].Score != 1 || got.KeyPoints[0][1].Score != 0 {
+		t.Errorf("unexpected keypoints %v", got.KeyPoints)
+	}
+}
+
+func TestVOC(t *testing.T) {
+
+	c := testCodec()
+	f := testFrame()
+
+	data, err := c.EncodeVOC(f)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := c.DecodeVOC(data)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkDetections(t, "voc", f.Detections, got.Detections)
+
+	if got.FileName != "test.jpg" || got.Width != 100 || got.Height != 50 {
+		t.Errorf("unexpected image attributes %s %dx%d", got.FileName, got.Width, got.Height)
+	}
+}
+
+func TestLabelMe(t *testing.T) {
+
+	c := testCodec()
+	f := testFrame()
+
+	obb := result.BoxRectF{X: 40, Y: 20, Width: 20, Height: 10, Angle: -0.3, Mode: result.ModeXYWH}
+	f.Detections = append(f.Detections, newDetectResult(3, 1, 0.5, obb))
+
+	data, err := c.EncodeLabelMe(f)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := c.DecodeLabelMe(data)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkDetections(t, "labelme", f.Detections, got.Detections)
+
+	if len(got.KeyPoints) != 3 || len(got.KeyPoints[0]) != 2 || got.KeyPoints[0][0] != f.KeyPoints[0][0] {
+		t.Errorf("expected keypoints %v, got %v", f.KeyPoints, got.KeyPoints)
+	}
+}
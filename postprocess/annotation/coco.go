@@ -0,0 +1,164 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// cocoResult is a single detection in the COCO results format as used by
+// the COCO evaluation tools
+type cocoResult struct {
+	ImageID      int         `json:"image_id"`
+	CategoryID   int         `json:"category_id"`
+	BBox         [4]float32  `json:"bbox"`
+	Score        float32     `json:"score"`
+	Keypoints    []float32   `json:"keypoints,omitempty"`
+	Segmentation *result.RLE `json:"segmentation,omitempty"`
+}
+
+// EncodeCOCO serialises the Frames to the COCO results JSON format.  Boxes
+// are written as the axis aligned [x, y, width, height] bounds, keypoints as
+// [x, y, score] triplets and segment masks as compressed RLE.  Each Frame's
+// ImageID is used as the image_id.
+func (c *Codec) EncodeCOCO(frames ...Frame) ([]byte, error) {
+
+	res := make([]cocoResult, 0)
+
+	for _, f := range frames {
+		w, h := c.frameSize(f)
+
+		for i, det := range f.Detections {
+			x, y, bw, bh := bounds(det.FloatBox())
+
+			cr := cocoResult{
+				ImageID:    f.ImageID,
+				CategoryID: c.categoryID(det.Class),
+				BBox:       [4]float32{x, y, bw, bh},
+				Score:      det.Probability,
+			}
+
+			if i < len(f.KeyPoints) {
+				for _, kp := range f.KeyPoints[i] {
+					cr.Keypoints = append(cr.Keypoints,
+						float32(kp.X), float32(kp.Y), kp.Score)
+				}
+			}
+
+			if len(f.Mask) == w*h && len(f.Mask) > 0 {
+				rle := result.EncodeRLE(f.Mask, w, h, uint8(i+1))
+				cr.Segmentation = &rle
+			}
+
+			res = append(res, cr)
+		}
+	}
+
+	return json.Marshal(res)
+}
+
+// DecodeCOCO deserialises the COCO results JSON format returning a Frame for
+// each image_id in the order first seen.  Oriented bounding boxes are decoded
+// as their axis aligned bounds.
+func (c *Codec) DecodeCOCO(data []byte) ([]Frame, error) {
+
+	var res []cocoResult
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	frames := make([]Frame, 0)
+	index := make(map[int]int)
+
+	for n, cr := range res {
+		class, err := c.classID(cr.CategoryID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fi, ok := index[cr.ImageID]
+
+		if !ok {
+			fi = len(frames)
+			index[cr.ImageID] = fi
+			frames = append(frames, Frame{
+				ImageID: cr.ImageID,
+				Width:   c.Width,
+				Height:  c.Height,
+			})
+		}
+
+		f := &frames[fi]
+
+		boxF := result.BoxRectF{
+			Left:   cr.BBox[0],
+			Top:    cr.BBox[1],
+			Right:  cr.BBox[0] + cr.BBox[2],
+			Bottom: cr.BBox[1] + cr.BBox[3],
+		}
+
+		f.Detections = append(f.Detections,
+			newDetectResult(int64(n+1), class, cr.Score, boxF))
+
+		if len(cr.Keypoints) > 0 {
+			// pad keypoints of earlier detections without any
+			for len(f.KeyPoints) < len(f.Detections)-1 {
+				f.KeyPoints = append(f.KeyPoints, nil)
+			}
+
+			kps := make([]result.KeyPoint, 0, len(cr.Keypoints)/3)
+
+			for k := 0; k+2 < len(cr.Keypoints); k += 3 {
+				kps = append(kps, result.KeyPoint{
+					X:     int(math.Round(float64(cr.Keypoints[k]))),
+					Y:     int(math.Round(float64(cr.Keypoints[k+1]))),
+					Score: cr.Keypoints[k+2],
+				})
+			}
+
+			f.KeyPoints = append(f.KeyPoints, kps)
+		}
+
+		if cr.Segmentation != nil {
+			if f.Mask == nil {
+				f.Width = cr.Segmentation.Width
+				f.Height = cr.Segmentation.Height
+				f.Mask = make([]uint8, f.Width*f.Height)
+			}
+
+			maskFromRLE(f.Mask, *cr.Segmentation, uint8(len(f.Detections)))
+		}
+	}
+
+	return frames, nil
+}
+
+// categoryID returns the COCO category_id of the class index
+func (c *Codec) categoryID(class int) int {
+
+	if c.CategoryIDs != nil && class >= 0 && class < len(c.CategoryIDs) {
+		return c.CategoryIDs[class]
+	}
+
+	return class
+}
+
+// classID returns the class index of the COCO category_id
+func (c *Codec) classID(categoryID int) (int, error) {
+
+	if c.CategoryIDs == nil {
+		return categoryID, nil
+	}
+
+	for i, id := range c.CategoryIDs {
+		if id == categoryID {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown category_id: %d", categoryID)
+}
@@ -0,0 +1,205 @@
+package annotation
+
+import (
+	"encoding/json"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// jsonFrame is the JSON representation of a Frame
+type jsonFrame struct {
+	FileName   string          `json:"file_name,omitempty"`
+	ImageID    int             `json:"image_id,omitempty"`
+	Width      int             `json:"width"`
+	Height     int             `json:"height"`
+	Detections []jsonDetection `json:"detections"`
+	Text       []jsonText      `json:"text,omitempty"`
+}
+
+// jsonDetection is the JSON representation of a detection result
+type jsonDetection struct {
+	ID        int64          `json:"id"`
+	Class     int            `json:"class"`
+	Label     string         `json:"label"`
+	Score     float32        `json:"score"`
+	Box       jsonBox        `json:"box"`
+	KeyPoints []jsonKeyPoint `json:"keypoints,omitempty"`
+	Mask      *result.RLE    `json:"mask,omitempty"`
+}
+
+// jsonBox is the JSON representation of the sub-pixel bounding box
+type jsonBox struct {
+	Mode   string  `json:"mode"`
+	Left   float32 `json:"left,omitempty"`
+	Top    float32 `json:"top,omitempty"`
+	Right  float32 `json:"right,omitempty"`
+	Bottom float32 `json:"bottom,omitempty"`
+	X      float32 `json:"x,omitempty"`
+	Y      float32 `json:"y,omitempty"`
+	Width  float32 `json:"width,omitempty"`
+	Height float32 `json:"height,omitempty"`
+	Angle  float32 `json:"angle,omitempty"`
+}
+
+// jsonKeyPoint is the JSON representation of a keypoint
+type jsonKeyPoint struct {
+	X     int     `json:"x"`
+	Y     int     `json:"y"`
+	Score float32 `json:"score"`
+}
+
+// jsonText is the JSON representation of a PPOCR text box
+type jsonText struct {
+	Points [4][2]float32 `json:"points"`
+	Score  float32       `json:"score"`
+}
+
+const (
+	modeLTRB = "ltrb"
+	modeXYWH = "xywh"
+)
+
+// EncodeJSON serialises the Frame to JSON including the keypoints, segment
+// masks as COCO RLE and PPOCR text boxes
+func (c *Codec) EncodeJSON(f Frame) ([]byte, error) {
+
+	w, h := c.frameSize(f)
+
+	jf := jsonFrame{
+		FileName:   f.FileName,
+		ImageID:    f.ImageID,
+		Width:      w,
+		Height:     h,
+		Detections: make([]jsonDetection, len(f.Detections)),
+	}
+
+	for i, det := range f.Detections {
+		boxF := det.FloatBox()
+
+		jd := jsonDetection{
+			ID:    det.ID,
+			Class: det.Class,
+			Label: c.label(det.Class),
+			Score: det.Probability,
+		}
+
+		if boxF.Mode == result.ModeXYWH {
+			jd.Box = jsonBox{
+				Mode:   modeXYWH,
+				X:      boxF.X,
+				Y:      boxF.Y,
+				Width:  boxF.Width,
+				Height: boxF.Height,
+				Angle:  boxF.Angle,
+			}
+		} else {
+			jd.Box = jsonBox{
+				Mode:   modeLTRB,
+				Left:   boxF.Left,
+				Top:    boxF.Top,
+				Right:  boxF.Right,
+				Bottom: boxF.Bottom,
+			}
+		}
+
+		if i < len(f.KeyPoints) {
+			for _, kp := range f.KeyPoints[i] {
+				jd.KeyPoints = append(jd.KeyPoints, jsonKeyPoint(kp))
+			}
+		}
+
+		if len(f.Mask) == w*h && len(f.Mask) > 0 {
+			rle := result.EncodeRLE(f.Mask, w, h, uint8(i+1))
+			jd.Mask = &rle
+		}
+
+		jf.Detections[i] = jd
+	}
+
+	for _, t := range f.Text {
+		jf.Text = append(jf.Text, jsonText{
+			Points: textCorners(t),
+			Score:  t.Score,
+		})
+	}
+
+	return json.Marshal(jf)
+}
+
+// DecodeJSON deserialises a Frame from JSON created by EncodeJSON()
+func (c *Codec) DecodeJSON(data []byte) (Frame, error) {
+
+	var jf jsonFrame
+
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return Frame{}, err
+	}
+
+	f := Frame{
+		FileName:   jf.FileName,
+		ImageID:    jf.ImageID,
+		Width:      jf.Width,
+		Height:     jf.Height,
+		Detections: make([]result.DetectResult, len(jf.Detections)),
+	}
+
+	hasKeyPoints := false
+	hasMask := false
+
+	for _, jd := range jf.Detections {
+		hasKeyPoints = hasKeyPoints || len(jd.KeyPoints) > 0
+		hasMask = hasMask || jd.Mask != nil
+	}
+
+	if hasKeyPoints {
+		f.KeyPoints = make([][]result.KeyPoint, len(jf.Detections))
+	}
+
+	if hasMask {
+		f.Mask = make([]uint8, f.Width*f.Height)
+	}
+
+	for i, jd := range jf.Detections {
+		var boxF result.BoxRectF
+
+		if jd.Box.Mode == modeXYWH {
+			boxF = result.BoxRectF{
+				X:      jd.Box.X,
+				Y:      jd.Box.Y,
+				Width:  jd.Box.Width,
+				Height: jd.Box.Height,
+				Angle:  jd.Box.Angle,
+				Mode:   result.ModeXYWH,
+			}
+		} else {
+			boxF = result.BoxRectF{
+				Left:   jd.Box.Left,
+				Top:    jd.Box.Top,
+				Right:  jd.Box.Right,
+				Bottom: jd.Box.Bottom,
+			}
+		}
+
+		f.Detections[i] = newDetectResult(jd.ID, jd.Class, jd.Score, boxF)
+
+		if hasKeyPoints {
+			kps := make([]result.KeyPoint, len(jd.KeyPoints))
+
+			for k, kp := range jd.KeyPoints {
+				kps[k] = result.KeyPoint(kp)
+			}
+
+			f.KeyPoints[i] = kps
+		}
+
+		if jd.Mask != nil {
+			maskFromRLE(f.Mask, *jd.Mask, uint8(i+1))
+		}
+	}
+
+	for _, t := range jf.Text {
+		f.Text = append(f.Text, textBox(t.Points, t.Score))
+	}
+
+	return f, nil
+}
@@ -0,0 +1,233 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// labelMeVersion is the LabelMe file format version written
+const labelMeVersion = "5.2.1"
+
+// labelMeTextLabel is the label used for PPOCR text boxes
+const labelMeTextLabel = "text"
+
+// labelMeFile is a LabelMe annotation file
+type labelMeFile struct {
+	Version     string          `json:"version"`
+	Flags       map[string]bool `json:"flags"`
+	Shapes      []labelMeShape  `json:"shapes"`
+	ImagePath   string          `json:"imagePath"`
+	ImageData   *string         `json:"imageData"`
+	ImageHeight int             `json:"imageHeight"`
+	ImageWidth  int             `json:"imageWidth"`
+}
+
+// labelMeShape is a LabelMe annotated shape
+type labelMeShape struct {
+	Label       string          `json:"label"`
+	Points      [][2]float32    `json:"points"`
+	GroupID     *int            `json:"group_id"`
+	Description string          `json:"description"`
+	ShapeType   string          `json:"shape_type"`
+	Flags       map[string]bool `json:"flags"`
+}
+
+// EncodeLabelMe serialises the Frame to the LabelMe JSON format.
+//   - boxes are written as rectangle shapes and oriented bounding boxes as
+//     polygon shapes
+//   - keypoints are written as point shapes sharing the group_id of their
+//     detection
+//   - PPOCR text boxes are written as polygon shapes labelled "text" without
+//     a group_id
+//
+// Scores are written to each shape's description.  Segment masks are not
+// written.
+func (c *Codec) EncodeLabelMe(f Frame) ([]byte, error) {
+
+	w, h := c.frameSize(f)
+
+	lm := labelMeFile{
+		Version:     labelMeVersion,
+		Flags:       map[string]bool{},
+		Shapes:      make([]labelMeShape, 0),
+		ImagePath:   filepath.Base(f.FileName),
+		ImageHeight: h,
+		ImageWidth:  w,
+	}
+
+	if f.FileName == "" {
+		lm.ImagePath = ""
+	}
+
+	for i, det := range f.Detections {
+		groupID := i + 1
+		boxF := det.FloatBox()
+
+		shape := labelMeShape{
+			Label:       c.label(det.Class),
+			GroupID:     &groupID,
+			Description: formatScore(det.Probability),
+			Flags:       map[string]bool{},
+		}
+
+		if boxF.Mode == result.ModeXYWH {
+			pts := corners(boxF)
+			shape.ShapeType = "polygon"
+			shape.Points = pts[:]
+
+		} else {
+			shape.ShapeType = "rectangle"
+			shape.Points = [][2]float32{
+				{boxF.Left, boxF.Top},
+				{boxF.Right, boxF.Bottom},
+			}
+		}
+
+		lm.Shapes = append(lm.Shapes, shape)
+
+		if i < len(f.KeyPoints) {
+			for _, kp := range f.KeyPoints[i] {
+				lm.Shapes = append(lm.Shapes, labelMeShape{
+					Label:       shape.Label,
+					Points:      [][2]float32{{float32(kp.X), float32(kp.Y)}},
+					GroupID:     &groupID,
+					Description: formatScore(kp.Score),
+					ShapeType:   "point",
+					Flags:       map[string]bool{},
+				})
+			}
+		}
+	}
+
+	for _, t := range f.Text {
+		pts := textCorners(t)
+
+		lm.Shapes = append(lm.Shapes, labelMeShape{
+			Label:       labelMeTextLabel,
+			Points:      pts[:],
+			Description: formatScore(t.Score),
+			ShapeType:   "polygon",
+			Flags:       map[string]bool{},
+		})
+	}
+
+	return json.Marshal(lm)
+}
+
+// DecodeLabelMe deserialises the LabelMe JSON format.  Rectangle and four
+// point polygon shapes are decoded as detections, point shapes as keypoints of
+// the detection with the same group_id, and polygon shapes without a group_id
+// labelled "text" as PPOCR text boxes.  Shapes without a score in their
+// description are given a score of 1.
+func (c *Codec) DecodeLabelMe(data []byte) (Frame, error) {
+
+	var lm labelMeFile
+
+	if err := json.Unmarshal(data, &lm); err != nil {
+		return Frame{}, err
+	}
+
+	f := Frame{
+		FileName: lm.ImagePath,
+		Width:    lm.ImageWidth,
+		Height:   lm.ImageHeight,
+	}
+
+	// groups maps the group_id to the detection index
+	groups := make(map[int]int)
+	keyPoints := make(map[int][]result.KeyPoint)
+	hasKeyPoints := false
+
+	for _, shape := range lm.Shapes {
+		score := parseScore(shape.Description)
+
+		switch shape.ShapeType {
+		case "rectangle", "polygon":
+			var boxF result.BoxRectF
+
+			if shape.ShapeType == "rectangle" && len(shape.Points) == 2 {
+				boxF = result.BoxRectF{
+					Left:   min32(shape.Points[0][0], shape.Points[1][0]),
+					Top:    min32(shape.Points[0][1], shape.Points[1][1]),
+					Right:  max32(shape.Points[0][0], shape.Points[1][0]),
+					Bottom: max32(shape.Points[0][1], shape.Points[1][1]),
+				}
+
+			} else if len(shape.Points) == 4 {
+				var pts [4][2]float32
+				copy(pts[:], shape.Points)
+
+				if shape.GroupID == nil && shape.Label == labelMeTextLabel {
+					f.Text = append(f.Text, textBox(pts, score))
+					continue
+				}
+
+				boxF = fromCorners(pts)
+
+			} else {
+				return Frame{}, fmt.Errorf("unsupported %s shape with %d points",
+					shape.ShapeType, len(shape.Points))
+			}
+
+			class, err := c.class(shape.Label)
+
+			if err != nil {
+				return Frame{}, err
+			}
+
+			idx := len(f.Detections)
+			f.Detections = append(f.Detections,
+				newDetectResult(int64(idx+1), class, score, boxF))
+
+			if shape.GroupID != nil {
+				groups[*shape.GroupID] = idx
+			}
+
+		case "point":
+			if shape.GroupID == nil || len(shape.Points) != 1 {
+				continue
+			}
+
+			hasKeyPoints = true
+			keyPoints[*shape.GroupID] = append(keyPoints[*shape.GroupID], result.KeyPoint{
+				X:     int(math.Round(float64(shape.Points[0][0]))),
+				Y:     int(math.Round(float64(shape.Points[0][1]))),
+				Score: score,
+			})
+		}
+	}
+
+	if hasKeyPoints {
+		f.KeyPoints = make([][]result.KeyPoint, len(f.Detections))
+
+		for groupID, kps := range keyPoints {
+			if idx, ok := groups[groupID]; ok {
+				f.KeyPoints[idx] = kps
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// formatScore returns the score as a string
+func formatScore(score float32) string {
+	return strconv.FormatFloat(float64(score), 'f', -1, 32)
+}
+
+// parseScore returns the score from the string defaulting to 1
+func parseScore(s string) float32 {
+
+	v, err := strconv.ParseFloat(s, 32)
+
+	if err != nil {
+		return 1
+	}
+
+	return float32(v)
+}
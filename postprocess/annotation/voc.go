@@ -0,0 +1,131 @@
+package annotation
+
+import (
+	"encoding/xml"
+	"path/filepath"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// vocAnnotation is the Pascal VOC XML annotation of an image
+type vocAnnotation struct {
+	XMLName  xml.Name    `xml:"annotation"`
+	Filename string      `xml:"filename"`
+	Size     vocSize     `xml:"size"`
+	Objects  []vocObject `xml:"object"`
+}
+
+// vocSize is the Pascal VOC image size
+type vocSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+// vocObject is a Pascal VOC annotated object
+type vocObject struct {
+	Name       string    `xml:"name"`
+	Pose       string    `xml:"pose"`
+	Truncated  int       `xml:"truncated"`
+	Difficult  int       `xml:"difficult"`
+	Confidence *float32  `xml:"confidence,omitempty"`
+	BndBox     vocBndBox `xml:"bndbox"`
+}
+
+// vocBndBox is a Pascal VOC bounding box
+type vocBndBox struct {
+	XMin float32 `xml:"xmin"`
+	YMin float32 `xml:"ymin"`
+	XMax float32 `xml:"xmax"`
+	YMax float32 `xml:"ymax"`
+}
+
+// EncodeVOC serialises the Frame to Pascal VOC XML.  Oriented bounding boxes
+// are written as their axis aligned bounds and the detection Probability is
+// written to the non standard confidence element.
+func (c *Codec) EncodeVOC(f Frame) ([]byte, error) {
+
+	w, h := c.frameSize(f)
+
+	ann := vocAnnotation{
+		Filename: filepath.Base(f.FileName),
+		Size: vocSize{
+			Width:  w,
+			Height: h,
+			Depth:  3,
+		},
+		Objects: make([]vocObject, len(f.Detections)),
+	}
+
+	if f.FileName == "" {
+		ann.Filename = ""
+	}
+
+	for i, det := range f.Detections {
+		x, y, bw, bh := bounds(det.FloatBox())
+		prob := det.Probability
+
+		ann.Objects[i] = vocObject{
+			Name:       c.label(det.Class),
+			Pose:       "Unspecified",
+			Confidence: &prob,
+			BndBox: vocBndBox{
+				XMin: x,
+				YMin: y,
+				XMax: x + bw,
+				YMax: y + bh,
+			},
+		}
+	}
+
+	out, err := xml.MarshalIndent(ann, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// DecodeVOC deserialises a Pascal VOC XML annotation.  Objects without a
+// confidence element are given a Probability of 1.
+func (c *Codec) DecodeVOC(data []byte) (Frame, error) {
+
+	var ann vocAnnotation
+
+	if err := xml.Unmarshal(data, &ann); err != nil {
+		return Frame{}, err
+	}
+
+	f := Frame{
+		FileName:   ann.Filename,
+		Width:      ann.Size.Width,
+		Height:     ann.Size.Height,
+		Detections: make([]result.DetectResult, len(ann.Objects)),
+	}
+
+	for i, obj := range ann.Objects {
+		class, err := c.class(obj.Name)
+
+		if err != nil {
+			return Frame{}, err
+		}
+
+		prob := float32(1)
+
+		if obj.Confidence != nil {
+			prob = *obj.Confidence
+		}
+
+		boxF := result.BoxRectF{
+			Left:   obj.BndBox.XMin,
+			Top:    obj.BndBox.YMin,
+			Right:  obj.BndBox.XMax,
+			Bottom: obj.BndBox.YMax,
+		}
+
+		f.Detections[i] = newDetectResult(int64(i+1), class, prob, boxF)
+	}
+
+	return f, nil
+}
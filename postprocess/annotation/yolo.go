@@ -0,0 +1,180 @@
+package annotation
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// EncodeYOLO serialises the Frame to the YOLO txt label format with one line
+// per detection and coordinates normalised to the source image size.
+//   - boxes are written as "class cx cy w h"
+//   - oriented bounding boxes are written as "class x1 y1 x2 y2 x3 y3 x4 y4"
+//   - keypoints are appended to the box as "x y visibility" triplets where a
+//     keypoint is visible (2) if its score is at least KeyPointThreshold,
+//     otherwise it is written as "0 0 0"
+func (c *Codec) EncodeYOLO(f Frame) []byte {
+
+	w, h := c.frameSize(f)
+	fw, fh := float32(w), float32(h)
+
+	var buf bytes.Buffer
+
+	for i, det := range f.Detections {
+		boxF := det.FloatBox()
+		fields := []string{strconv.Itoa(det.Class)}
+
+		if boxF.Mode == result.ModeXYWH {
+			for _, p := range corners(boxF) {
+				fields = append(fields, norm(p[0], fw), norm(p[1], fh))
+			}
+
+		} else {
+			x, y, bw, bh := bounds(boxF)
+			fields = append(fields,
+				norm(x+bw/2, fw), norm(y+bh/2, fh), norm(bw, fw), norm(bh, fh))
+
+			if i < len(f.KeyPoints) {
+				for _, kp := range f.KeyPoints[i] {
+					if kp.Score < c.keyPointThreshold() {
+						fields = append(fields, "0", "0", "0")
+						continue
+					}
+
+					fields = append(fields,
+						norm(float32(kp.X), fw), norm(float32(kp.Y), fh), "2")
+				}
+			}
+		}
+
+		buf.WriteString(strings.Join(fields, " "))
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// DecodeYOLO deserialises the YOLO txt label format scaling the normalised
+// coordinates to the Codec's source image size.  As the format has no
+// confidence scores detections are given a Probability of 1.
+func (c *Codec) DecodeYOLO(data []byte) (Frame, error) {
+
+	f := Frame{
+		Width:  c.Width,
+		Height: c.Height,
+	}
+
+	fw, fh := float32(c.Width), float32(c.Height)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		class, err := strconv.Atoi(fields[0])
+
+		if err != nil {
+			return Frame{}, fmt.Errorf("line %d: invalid class: %w", line, err)
+		}
+
+		vals := make([]float32, len(fields)-1)
+
+		for i, s := range fields[1:] {
+			v, err := strconv.ParseFloat(s, 32)
+
+			if err != nil {
+				return Frame{}, fmt.Errorf("line %d: invalid value: %w", line, err)
+			}
+
+			vals[i] = float32(v)
+		}
+
+		id := int64(len(f.Detections) + 1)
+
+		switch {
+		case len(vals) == 8:
+			var pts [4][2]float32
+
+			for i := range pts {
+				pts[i] = [2]float32{vals[i*2] * fw, vals[i*2+1] * fh}
+			}
+
+			f.Detections = append(f.Detections, newDetectResult(id, class, 1, fromCorners(pts)))
+
+		case len(vals) >= 4 && (len(vals)-4)%3 == 0:
+			cx, cy := vals[0]*fw, vals[1]*fh
+			bw, bh := vals[2]*fw, vals[3]*fh
+
+			boxF := result.BoxRectF{
+				Left:   cx - bw/2,
+				Top:    cy - bh/2,
+				Right:  cx + bw/2,
+				Bottom: cy + bh/2,
+			}
+
+			f.Detections = append(f.Detections, newDetectResult(id, class, 1, boxF))
+
+			if len(vals) > 4 {
+				for len(f.KeyPoints) < len(f.Detections)-1 {
+					f.KeyPoints = append(f.KeyPoints, nil)
+				}
+
+				kps := make([]result.KeyPoint, 0, (len(vals)-4)/3)
+
+				for k := 4; k+2 < len(vals); k += 3 {
+					kp := result.KeyPoint{
+						X: int(math.Round(float64(vals[k] * fw))),
+						Y: int(math.Round(float64(vals[k+1] * fh))),
+					}
+
+					if vals[k+2] > 0 {
+						kp.Score = 1
+					}
+
+					kps = append(kps, kp)
+				}
+
+				f.KeyPoints = append(f.KeyPoints, kps)
+			}
+
+		default:
+			return Frame{}, fmt.Errorf("line %d: unexpected number of values %d", line, len(vals))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Frame{}, err
+	}
+
+	return f, nil
+}
+
+// keyPointThreshold returns the minimum keypoint score for it to be visible
+func (c *Codec) keyPointThreshold() float32 {
+
+	if c.KeyPointThreshold > 0 {
+		return c.KeyPointThreshold
+	}
+
+	return 0.5
+}
+
+// norm returns the coordinate normalised to the given size as a string
+func norm(v float32, size float32) string {
+
+	if size <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatFloat(float64(v/size), 'f', 6, 32)
+}
@@ -0,0 +1,79 @@
+package postprocess
+
+import (
+	"fmt"
+
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/reid"
+)
+
+// ArcFace defines the struct for ArcFace and MobileFaceNet face recognition
+// model inference post processing.  The models take a 112x112 face aligned
+// with preprocess.FaceAligner and output an embedding of the face identity.
+type ArcFace struct{}
+
+// NewArcFace returns an instance of the ArcFace post processor
+func NewArcFace() *ArcFace {
+	return &ArcFace{}
+}
+
+// Embedding takes the RKNN outputs of a single face and returns its L2
+// normalised embedding
+func (a *ArcFace) Embedding(outputs *rknnlite.Outputs) []float32 {
+
+	attrs := outputs.OutputAttributes()
+	size := int(attrs.DimForDFL)
+
+	if isFloatOutput(outputs, 0) {
+		return normalizeEmbedding(outputs.Output[0].BufFloat[:size])
+	}
+
+	return reid.DequantizeAndL2Normalize(outputs.Output[0].BufInt[:size],
+		attrs.Scales[0], attrs.ZPs[0])
+}
+
+// BatchEmbeddings takes the RKNN outputs of the batch and returns the L2
+// normalised embeddings of the first count faces in it
+func (a *ArcFace) BatchEmbeddings(outputs *rknnlite.Outputs,
+	batch *rknnlite.Batch, count int) ([][]float32, error) {
+
+	attrs := outputs.OutputAttributes()
+	size := int(attrs.DimForDFL)
+	embeddings := make([][]float32, count)
+
+	for idx := 0; idx < count; idx++ {
+
+		if isFloatOutput(outputs, 0) {
+
+			output, err := batch.GetOutputF32(idx, outputs.Output[0], size)
+
+			if err != nil {
+				return nil, fmt.Errorf("error getting output %d: %w", idx, err)
+			}
+
+			embeddings[idx] = normalizeEmbedding(output)
+			continue
+		}
+
+		output, err := batch.GetOutputInt(idx, outputs.Output[0], size)
+
+		if err != nil {
+			return nil, fmt.Errorf("error getting output %d: %w", idx, err)
+		}
+
+		embeddings[idx] = reid.DequantizeAndL2Normalize(output, attrs.Scales[0],
+			attrs.ZPs[0])
+	}
+
+	return embeddings, nil
+}
+
+// normalizeEmbedding returns an L2 normalised copy of the float output so the
+// embedding remains valid after the outputs are freed
+func normalizeEmbedding(output []float32) []float32 {
+
+	v := make([]float32, len(output))
+	copy(v, output)
+
+	return reid.NormalizeVec(v)
+}
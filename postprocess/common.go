@@ -4,15 +4,73 @@ import (
 	"image"
 	"math"
 
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
 	"gocv.io/x/gocv"
 )
 
+// isFloatOutput returns true if the output tensor at the given index holds
+// float32 data rather than quantized int8 data.  This is the case when the
+// Runtime has SetWantFloat(true) or the Model was compiled with fp16 outputs
+func isFloatOutput(outputs *rknnlite.Outputs, idx int) bool {
+	return len(outputs.Output[idx].BufFloat) > 0
+}
+
 // deqntAffineToF32 converts a quantized int8 value back to a float32 using
 // the provided zero point and scale
 func deqntAffineToF32(qnt int8, zp int32, scale float32) float32 {
 	return (float32(qnt) - float32(zp)) * scale
 }
 
+// outputTensor reads the values of an output tensor which can be float32 or
+// quantized int8
+type outputTensor struct {
+	// buf is the float32 output data, nil for int8 outputs
+	buf []float32
+	// qnt is the quantized int8 output data
+	qnt []int8
+	// zp is the zero point of the quantized output
+	zp int32
+	// scale is the scale of the quantized output
+	scale float32
+}
+
+// newOutputTensor returns the output tensor at the index
+func newOutputTensor(outputs *rknnlite.Outputs, attrs rknnlite.OutputAttribute,
+	idx int) outputTensor {
+
+	if isFloatOutput(outputs, idx) {
+		return outputTensor{buf: outputs.Output[idx].BufFloat}
+	}
+
+	return outputTensor{
+		qnt:   outputs.Output[idx].BufInt,
+		zp:    attrs.ZPs[idx],
+		scale: attrs.Scales[idx],
+	}
+}
+
+// at returns the dequantized value at the index
+func (o outputTensor) at(i int) float32 {
+
+	if o.buf != nil {
+		return o.buf[i]
+	}
+
+	return deqntAffineToF32(o.qnt[i], o.zp, o.scale)
+}
+
+// len returns the number of values in the output tensor
+func (o outputTensor) len() int {
+
+	if o.buf != nil {
+		return len(o.buf)
+	}
+
+	return len(o.qnt)
+}
+
 // qntF32ToAffine converts a float32 value to an int8 using quantization
 // parameters: zero point and scale
 func qntF32ToAffine(f32 float32, zp int32, scale float32) int8 {
@@ -127,66 +185,116 @@ func quickSortIndiceInverse(input []float32, left int, right int, indices []int)
 	return low
 }
 
-// nms implements a Non-Maximum Suppression (NMS) algorithm
-func nms(validCount int, outputLocations []float32, classIds, order []int,
-	filterId int, threshold float32, pos int) {
+// greedyNMS returns the Suppressor used when none has been set, which is per
+// class greedy NMS with the given IoU threshold
+func greedyNMS(threshold float32) nms.Suppressor {
+	return nms.New(nms.Params{
+		Method:       nms.Greedy,
+		IoUThreshold: threshold,
+	})
+}
+
+// applyNMS runs Non-Maximum Suppression over the stride data candidates.  If
+// no Suppressor has been set per class greedy NMS is used with the given
+// threshold.  indexArray and objProbs must have been sorted by
+// quickSortIndiceInverse beforehand.
+func applyNMS(s nms.Suppressor, validCount int, data *strideData,
+	indexArray []int, threshold float32, pos int) {
+
+	if s == nil {
+		s = greedyNMS(threshold)
+	}
+
+	suppressCandidates(s, validCount, data, indexArray, pos, false)
+}
+
+// suppressCandidates runs the Suppressor over the stride data candidates.
+// The boxes are passed in Model input coordinates with the candidate index
+// as the detection ID.  On return the kept candidates are ranked at the start
+// of indexArray with objProbs holding their scores, which may have been
+// decayed by the Suppressor, and the remaining entries are set to -1.  Boxes
+// fused by the Suppressor are written back to filterBoxes.
+func suppressCandidates(s nms.Suppressor, validCount int, data *strideData,
+	indexArray []int, pos int, rotated bool) {
+
+	dets := make([]result.DetectResult, 0, validCount)
+	orig := make(map[int]result.BoxRectF, validCount)
 
 	for i := 0; i < validCount; i++ {
+		n := indexArray[i]
 
-		if order[i] == -1 || classIds[i] != filterId {
+		if n == -1 {
 			continue
 		}
 
-		n := order[i]
-
-		for j := i + 1; j < validCount; j++ {
-			m := order[j]
-
-			if m == -1 || classIds[i] != filterId {
-				continue
+		x := data.filterBoxes[n*pos+0]
+		y := data.filterBoxes[n*pos+1]
+		w := data.filterBoxes[n*pos+2]
+		h := data.filterBoxes[n*pos+3]
+
+		var boxF result.BoxRectF
+
+		if rotated {
+			boxF = result.BoxRectF{
+				X:      x,
+				Y:      y,
+				Width:  w,
+				Height: h,
+				Angle:  data.filterBoxes[n*pos+4],
+				Mode:   result.ModeXYWH,
 			}
+		} else {
+			boxF = result.BoxRectF{
+				Left:   x,
+				Top:    y,
+				Right:  x + w,
+				Bottom: y + h,
+			}
+		}
 
-			xmin0 := outputLocations[n*pos+0]
-			ymin0 := outputLocations[n*pos+1]
-			xmax0 := xmin0 + outputLocations[n*pos+2]
-			ymax0 := ymin0 + outputLocations[n*pos+3]
+		orig[n] = boxF
 
-			xmin1 := outputLocations[m*pos+0]
-			ymin1 := outputLocations[m*pos+1]
-			xmax1 := xmin1 + outputLocations[m*pos+2]
-			ymax1 := ymin1 + outputLocations[m*pos+3]
+		dets = append(dets, result.DetectResult{
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: data.objProbs[i],
+			Class:       data.classID[n],
+			ID:          int64(n),
+		})
+	}
 
-			iou := calculateOverlap(xmin0, ymin0, xmax0, ymax0, xmin1, ymin1, xmax1, ymax1)
+	kept := s.Suppress(dets)
 
-			if iou > threshold {
-				order[j] = -1
-			}
-		}
+	for i := range indexArray {
+		indexArray[i] = -1
 	}
-}
 
-// calculateOverlap works out the Intersection of Union (IoU) value of two
-// boxes dimensions
-func calculateOverlap(xmin0, ymin0, xmax0, ymax0, xmin1, ymin1,
-	xmax1, ymax1 float32) float32 {
+	for i, det := range kept {
+		n := int(det.ID)
 
-	w := math.Max(0.0, math.Min(float64(xmax0), float64(xmax1))-math.Max(float64(xmin0), float64(xmin1))+1.0)
-	h := math.Max(0.0, math.Min(float64(ymax0), float64(ymax1))-math.Max(float64(ymin0), float64(ymin1))+1.0)
-	intersection := w * h
+		indexArray[i] = n
+		data.objProbs[i] = det.Probability
 
-	// Calculate the area of both rectangles with added 1.0 for inclusive pixel calculation
-	area0 := (xmax0 - xmin0 + 1) * (ymax0 - ymin0 + 1)
-	area1 := (xmax1 - xmin1 + 1) * (ymax1 - ymin1 + 1)
+		boxF := det.FloatBox()
 
-	// Calculate union
-	union := area0 + area1 - float32(intersection)
+		if boxF == orig[n] {
+			continue
+		}
 
-	if union <= 0 {
-		return 0.0
+		// box has been fused so update the candidate coordinates
+		if rotated {
+			data.filterBoxes[n*pos+0] = boxF.X
+			data.filterBoxes[n*pos+1] = boxF.Y
+			data.filterBoxes[n*pos+2] = boxF.Width
+			data.filterBoxes[n*pos+3] = boxF.Height
+			data.filterBoxes[n*pos+4] = boxF.Angle
+		} else {
+			data.filterBoxes[n*pos+0] = boxF.Left
+			data.filterBoxes[n*pos+1] = boxF.Top
+			data.filterBoxes[n*pos+2] = boxF.Right - boxF.Left
+			data.filterBoxes[n*pos+3] = boxF.Bottom - boxF.Top
+		}
 	}
-
-	// Return Intersection of Union (IoU)
-	return float32(intersection) / union
 }
 
 // computeDFL calculates the Distribution Focal Loss (DFL)
@@ -261,6 +369,12 @@ func boxReverse(pos int, pad int, scale float32) int {
 	return int(float32(pos-pad) / scale)
 }
 
+// boxReverseF scales the sub-pixel box coordinate back to the original image
+// dimensions
+func boxReverseF(pos float32, pad int, scale float32) float32 {
+	return (pos - float32(pad)) / scale
+}
+
 // segReverse scales the segment mask back to the size of the original image dimensions
 func segReverse(segMask, croppedSeg, segMaskReal []uint8,
 	modelInHeight, modelInWidth, croppedHeight, croppedWidth,
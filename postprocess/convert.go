@@ -14,17 +14,20 @@ func DetectionsToObjects(dets []result.DetectResult) []tracker.Object {
 
 	for _, det := range dets {
 
-		if det.Box.Mode == result.ModeXYWH {
-			x = float32(det.Box.X)
-			y = float32(det.Box.Y)
-			width = float32(det.Box.Width)
-			height = float32(det.Box.Height)
+		// use the sub-pixel box so precision is not lost for small objects
+		box := det.FloatBox()
+
+		if box.Mode == result.ModeXYWH {
+			x = box.X
+			y = box.Y
+			width = box.Width
+			height = box.Height
 
 		} else {
-			x = float32(det.Box.Left)
-			y = float32(det.Box.Top)
-			width = float32(det.Box.Right - det.Box.Left)
-			height = float32(det.Box.Bottom - det.Box.Top)
+			x = box.Left
+			y = box.Top
+			width = box.Right - box.Left
+			height = box.Bottom - box.Top
 		}
 
 		objs = append(objs, tracker.Object{
@@ -32,6 +35,9 @@ func DetectionsToObjects(dets []result.DetectResult) []tracker.Object {
 			Label: det.Class,
 			Prob:  det.Probability,
 			ID:    det.ID,
+			// oriented bounding boxes are tracked with their rotation
+			Oriented: box.Mode == result.ModeXYWH,
+			Angle:    box.Angle,
 		})
 	}
 
@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"sort"
+)
+
+// ConfusionMatrix defines the struct holding the counts of detections matched
+// to ground truth by class.  The last row and column is the background class
+// which counts missed ground truth and false positive detections.
+type ConfusionMatrix struct {
+	// NumClasses is the number of classes excluding background
+	NumClasses int
+	// Matrix is indexed by [ground truth class][detected class] with index
+	// NumClasses being background
+	Matrix [][]int
+	// ScoreThreshold is the minimum detection score counted
+	ScoreThreshold float64
+	// IoUThreshold is the minimum box IoU for a detection to match
+	IoUThreshold float64
+}
+
+// ConfusionMatrix returns the confusion matrix of the detections for the
+// class indexes 0 to numClasses-1.  Detections below the score threshold are
+// discarded and the remainder are matched one to one with the ground truth of
+// any class by the highest box IoU above the IoU threshold.  Crowd ground
+// truth is not counted.
+func (e *Evaluator) ConfusionMatrix(numClasses int, scoreThresh, iouThresh float64) *ConfusionMatrix {
+
+	cm := &ConfusionMatrix{
+		NumClasses:     numClasses,
+		Matrix:         make([][]int, numClasses+1),
+		ScoreThreshold: scoreThresh,
+		IoUThreshold:   iouThresh,
+	}
+
+	for i := range cm.Matrix {
+		cm.Matrix[i] = make([]int, numClasses+1)
+	}
+
+	type classed struct {
+		obj   *object
+		class int
+	}
+
+	type pair struct {
+		gt, dt int
+		iou    float64
+	}
+
+	// collect the objects of each image across all classes
+	gts := make(map[int][]classed)
+	dts := make(map[int][]classed)
+
+	for k, objs := range e.gts {
+		for _, o := range objs {
+			if !o.crowd && k.class >= 0 && k.class < numClasses {
+				gts[k.imageID] = append(gts[k.imageID], classed{o, k.class})
+			}
+		}
+	}
+
+	for k, objs := range e.dts {
+		for _, o := range objs {
+			if o.score >= scoreThresh && k.class >= 0 && k.class < numClasses {
+				dts[k.imageID] = append(dts[k.imageID], classed{o, k.class})
+			}
+		}
+	}
+
+	// order objects so matching of equal IoU's is deterministic
+	for _, m := range []map[int][]classed{gts, dts} {
+		for _, objs := range m {
+			sort.Slice(objs, func(a, b int) bool {
+				if objs[a].class != objs[b].class {
+					return objs[a].class < objs[b].class
+				}
+				return objs[a].obj.id < objs[b].obj.id
+			})
+		}
+	}
+
+	bg := numClasses
+
+	for _, imageID := range sortedKeys(e.images) {
+		g := gts[imageID]
+		d := dts[imageID]
+
+		pairs := make([]pair, 0)
+
+		for i := range g {
+			for j := range d {
+				if iou := boxIoU(d[j].obj, g[i].obj); iou > iouThresh {
+					pairs = append(pairs, pair{i, j, iou})
+				}
+			}
+		}
+
+		sort.SliceStable(pairs, func(a, b int) bool {
+			return pairs[a].iou > pairs[b].iou
+		})
+
+		gtMatched := make([]bool, len(g))
+		dtMatched := make([]bool, len(d))
+
+		for _, p := range pairs {
+			if gtMatched[p.gt] || dtMatched[p.dt] {
+				continue
+			}
+
+			gtMatched[p.gt] = true
+			dtMatched[p.dt] = true
+			cm.Matrix[g[p.gt].class][d[p.dt].class]++
+		}
+
+		for i, matched := range gtMatched {
+			if !matched {
+				cm.Matrix[g[i].class][bg]++
+			}
+		}
+
+		for j, matched := range dtMatched {
+			if !matched {
+				cm.Matrix[bg][d[j].class]++
+			}
+		}
+	}
+
+	return cm
+}
+
+// Precision returns the fraction of detections of the class that matched
+// ground truth of the same class
+func (cm *ConfusionMatrix) Precision(class int) float64 {
+
+	total := 0
+
+	for i := range cm.Matrix {
+		total += cm.Matrix[i][class]
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(cm.Matrix[class][class]) / float64(total)
+}
+
+// Recall returns the fraction of ground truth of the class that was detected
+// as the same class
+func (cm *ConfusionMatrix) Recall(class int) float64 {
+
+	total := 0
+
+	for _, n := range cm.Matrix[class] {
+		total += n
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(cm.Matrix[class][class]) / float64(total)
+}
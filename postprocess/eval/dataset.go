@@ -0,0 +1,343 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// Image is an image of a ground truth dataset
+type Image struct {
+	ID       int
+	FileName string
+	Width    int
+	Height   int
+}
+
+// Category is an object category of a ground truth dataset
+type Category struct {
+	ID   int
+	Name string
+}
+
+// Dataset defines the struct holding a ground truth dataset
+type Dataset struct {
+	// Images are the images of the dataset
+	Images []Image
+	// Categories are the object categories of the dataset
+	Categories []Category
+	// GroundTruth are the annotated objects keyed by image ID
+	GroundTruth map[int][]GroundTruth
+}
+
+// AddTo adds all images and their ground truth of the dataset to the
+// Evaluator
+func (d *Dataset) AddTo(e *Evaluator) {
+	for _, img := range d.Images {
+		e.AddGroundTruth(img.ID, d.GroundTruth[img.ID]...)
+	}
+}
+
+// ImageID returns the ID of the image with the given file name
+func (d *Dataset) ImageID(fileName string) (int, bool) {
+
+	for _, img := range d.Images {
+		if img.FileName == fileName {
+			return img.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// cocoDataset is the COCO instances annotation file format
+type cocoDataset struct {
+	Images []struct {
+		ID       int    `json:"id"`
+		FileName string `json:"file_name"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+	} `json:"images"`
+	Annotations []struct {
+		ID           int             `json:"id"`
+		ImageID      int             `json:"image_id"`
+		CategoryID   int             `json:"category_id"`
+		BBox         [4]float64      `json:"bbox"`
+		Area         float64         `json:"area"`
+		IsCrowd      int             `json:"iscrowd"`
+		Segmentation json.RawMessage `json:"segmentation"`
+		KeyPoints    []float64       `json:"keypoints"`
+	} `json:"annotations"`
+	Categories []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"categories"`
+}
+
+// LoadCOCO loads a COCO instances or person keypoints annotation file as
+// the ground truth dataset.  See ParseCOCO.
+func LoadCOCO(path string, labels []string) (*Dataset, error) {
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading COCO annotations: %w", err)
+	}
+
+	return ParseCOCO(data, labels)
+}
+
+// ParseCOCO parses a COCO instances or person keypoints annotation file.  If
+// labels are given, as returned by rknnlite.LoadLabels(), ground truth Class
+// is set to the index of the category name in labels so it can be evaluated
+// against the class indexes of the post processors.  Otherwise the COCO
+// category_id is used as the Class.  Polygon segmentations are converted to
+// RLE masks.
+func ParseCOCO(data []byte, labels []string) (*Dataset, error) {
+
+	var coco cocoDataset
+
+	if err := json.Unmarshal(data, &coco); err != nil {
+		return nil, fmt.Errorf("error parsing COCO annotations: %w", err)
+	}
+
+	ds := &Dataset{
+		Images:      make([]Image, len(coco.Images)),
+		Categories:  make([]Category, len(coco.Categories)),
+		GroundTruth: make(map[int][]GroundTruth),
+	}
+
+	sizes := make(map[int][2]int)
+
+	for i, img := range coco.Images {
+		ds.Images[i] = Image{
+			ID:       img.ID,
+			FileName: img.FileName,
+			Width:    img.Width,
+			Height:   img.Height,
+		}
+		sizes[img.ID] = [2]int{img.Width, img.Height}
+	}
+
+	// classes maps the category_id to the ground truth Class
+	classes := make(map[int]int)
+
+	for i, cat := range coco.Categories {
+		ds.Categories[i] = Category{
+			ID:   cat.ID,
+			Name: cat.Name,
+		}
+
+		if labels == nil {
+			classes[cat.ID] = cat.ID
+			continue
+		}
+
+		classes[cat.ID] = -1
+
+		for l, label := range labels {
+			if label == cat.Name {
+				classes[cat.ID] = l
+				break
+			}
+		}
+
+		if classes[cat.ID] == -1 {
+			return nil, fmt.Errorf("category %q not found in labels", cat.Name)
+		}
+	}
+
+	for _, ann := range coco.Annotations {
+		class, ok := classes[ann.CategoryID]
+
+		if !ok {
+			return nil, fmt.Errorf("annotation %d has unknown category_id %d",
+				ann.ID, ann.CategoryID)
+		}
+
+		x, y := float32(ann.BBox[0]), float32(ann.BBox[1])
+		w, h := float32(ann.BBox[2]), float32(ann.BBox[3])
+
+		gt := GroundTruth{
+			ID:      ann.ID,
+			Class:   class,
+			Box:     result.BoxRectF{Left: x, Top: y, Right: x + w, Bottom: y + h},
+			Area:    ann.Area,
+			IsCrowd: ann.IsCrowd != 0,
+		}
+
+		size := sizes[ann.ImageID]
+		mask, err := parseSegmentation(ann.Segmentation, size[0], size[1])
+
+		if err != nil {
+			return nil, fmt.Errorf("annotation %d: %w", ann.ID, err)
+		}
+
+		gt.Mask = mask
+
+		for k := 0; k+2 < len(ann.KeyPoints); k += 3 {
+			gt.KeyPoints = append(gt.KeyPoints, result.KeyPoint{
+				X:     int(math.Round(ann.KeyPoints[k])),
+				Y:     int(math.Round(ann.KeyPoints[k+1])),
+				Score: float32(ann.KeyPoints[k+2]),
+			})
+		}
+
+		ds.GroundTruth[ann.ImageID] = append(ds.GroundTruth[ann.ImageID], gt)
+	}
+
+	return ds, nil
+}
+
+// parseSegmentation returns the RLE mask of a COCO segmentation given as
+// either polygons or an RLE
+func parseSegmentation(data json.RawMessage, width, height int) (*result.RLE, error) {
+
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	if data[0] == '[' {
+		var polys [][]float64
+
+		if err := json.Unmarshal(data, &polys); err != nil {
+			return nil, fmt.Errorf("invalid polygon segmentation: %w", err)
+		}
+
+		if len(polys) == 0 || width <= 0 || height <= 0 {
+			return nil, nil
+		}
+
+		rle := rleFromPolygons(polys, width, height)
+
+		return &rle, nil
+	}
+
+	var rle result.RLE
+
+	if err := json.Unmarshal(data, &rle); err != nil {
+		return nil, fmt.Errorf("invalid RLE segmentation: %w", err)
+	}
+
+	return &rle, nil
+}
+
+// LoadYOLO loads a YOLO txt label file as the ground truth of an image of
+// the given size.  See ParseYOLO.
+func LoadYOLO(path string, width, height int) ([]GroundTruth, error) {
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading YOLO labels: %w", err)
+	}
+
+	return ParseYOLO(data, width, height)
+}
+
+// ParseYOLO parses YOLO txt labels scaling the normalised coordinates to the
+// given image size.  Lines may be boxes "class cx cy w h" optionally followed
+// by keypoint "x y visibility" triplets, or oriented bounding boxes as
+// "class x1 y1 x2 y2 x3 y3 x4 y4".
+func ParseYOLO(data []byte, width, height int) ([]GroundTruth, error) {
+
+	gts := make([]GroundTruth, 0)
+	fw, fh := float64(width), float64(height)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		class, err := strconv.Atoi(fields[0])
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid class: %w", line, err)
+		}
+
+		vals := make([]float64, len(fields)-1)
+
+		for i, s := range fields[1:] {
+			if vals[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid value: %w", line, err)
+			}
+		}
+
+		gt := GroundTruth{
+			ID:    len(gts) + 1,
+			Class: class,
+		}
+
+		switch {
+		case len(vals) == 8:
+			gt.Box = obbFromCorners(vals, fw, fh)
+
+		case len(vals) >= 4 && (len(vals)-4)%3 == 0:
+			cx, cy := vals[0]*fw, vals[1]*fh
+			bw, bh := vals[2]*fw, vals[3]*fh
+
+			gt.Box = result.BoxRectF{
+				Left:   float32(cx - bw/2),
+				Top:    float32(cy - bh/2),
+				Right:  float32(cx + bw/2),
+				Bottom: float32(cy + bh/2),
+			}
+
+			for k := 4; k+2 < len(vals); k += 3 {
+				gt.KeyPoints = append(gt.KeyPoints, result.KeyPoint{
+					X:     int(math.Round(vals[k] * fw)),
+					Y:     int(math.Round(vals[k+1] * fh)),
+					Score: float32(vals[k+2]),
+				})
+			}
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected number of values %d", line, len(vals))
+		}
+
+		gts = append(gts, gt)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return gts, nil
+}
+
+// obbFromCorners returns the oriented bounding box of the four normalised
+// corner points scaled to the image size
+func obbFromCorners(vals []float64, fw, fh float64) result.BoxRectF {
+
+	var pts [4][2]float64
+
+	for i := range pts {
+		pts[i] = [2]float64{vals[i*2] * fw, vals[i*2+1] * fh}
+	}
+
+	w := math.Hypot(pts[1][0]-pts[0][0], pts[1][1]-pts[0][1])
+	h := math.Hypot(pts[2][0]-pts[1][0], pts[2][1]-pts[1][1])
+	cx := (pts[0][0] + pts[2][0]) / 2
+	cy := (pts[0][1] + pts[2][1]) / 2
+
+	return result.BoxRectF{
+		X:      float32(cx - w/2),
+		Y:      float32(cy - h/2),
+		Width:  float32(w),
+		Height: float32(h),
+		Angle:  float32(math.Atan2(pts[1][1]-pts[0][1], pts[1][0]-pts[0][0])),
+		Mode:   result.ModeXYWH,
+	}
+}
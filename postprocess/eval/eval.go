@@ -0,0 +1,636 @@
+/*
+Package eval provides evaluation of object detection, instance segmentation
+and pose estimation results against a ground truth dataset.  Metrics are
+calculated using the same algorithm as pycocotools COCOeval so the mAP@0.5,
+mAP@0.5:0.95, per class AP and recall values are directly comparable with
+those published for the Models.
+*/
+package eval
+
+import (
+	"math"
+	"sort"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// IoUType is the overlap measure used to match detections to ground truth
+type IoUType int
+
+const (
+	// BBox matches detections by the IoU of their bounding boxes
+	BBox IoUType = 0
+	// Segm matches detections by the IoU of their segment masks
+	Segm IoUType = 1
+	// KeyPoints matches detections by the Object Keypoint Similarity (OKS)
+	// of their pose keypoints
+	KeyPoints IoUType = 2
+)
+
+// AreaRange is a named range of object areas in pixels that metrics are
+// calculated over
+type AreaRange struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// COCOKeyPointSigmas are the per keypoint standard deviations of the 17 COCO
+// person keypoints used in calculating OKS
+var COCOKeyPointSigmas = []float64{
+	0.026, 0.025, 0.025, 0.035, 0.035, 0.079, 0.079, 0.072, 0.072, 0.062,
+	0.062, 0.107, 0.107, 0.087, 0.087, 0.089, 0.089,
+}
+
+// Params defines the struct containing the evaluation parameters
+type Params struct {
+	// IoUType is the overlap measure used to match detections
+	IoUType IoUType
+	// IoUThresholds are the IoU (or OKS) thresholds AP is calculated at
+	IoUThresholds []float64
+	// RecallThresholds are the recall values precision is interpolated at
+	RecallThresholds []float64
+	// MaxDets are the maximum number of detections per image per class
+	// evaluated, in ascending order
+	MaxDets []int
+	// AreaRanges are the object area ranges metrics are calculated over, the
+	// first range should cover all objects
+	AreaRanges []AreaRange
+	// KeyPointSigmas are the per keypoint standard deviations used by OKS
+	KeyPointSigmas []float64
+}
+
+// DefaultParams returns an instance of Params matching the pycocotools
+// defaults for the given IoUType
+func DefaultParams(t IoUType) Params {
+
+	p := Params{
+		IoUType:          t,
+		IoUThresholds:    linspace(0.5, 0.95, 10),
+		RecallThresholds: linspace(0, 1, 101),
+		MaxDets:          []int{1, 10, 100},
+		AreaRanges: []AreaRange{
+			{Name: "all", Min: 0, Max: 1e10},
+			{Name: "small", Min: 0, Max: 32 * 32},
+			{Name: "medium", Min: 32 * 32, Max: 96 * 96},
+			{Name: "large", Min: 96 * 96, Max: 1e10},
+		},
+		KeyPointSigmas: COCOKeyPointSigmas,
+	}
+
+	if t == KeyPoints {
+		p.MaxDets = []int{20}
+		p.AreaRanges = []AreaRange{
+			{Name: "all", Min: 0, Max: 1e10},
+			{Name: "medium", Min: 32 * 32, Max: 96 * 96},
+			{Name: "large", Min: 96 * 96, Max: 1e10},
+		}
+	}
+
+	return p
+}
+
+// GroundTruth is an annotated object of an image
+type GroundTruth struct {
+	// ID is the annotation ID
+	ID int
+	// Class is the class index or category ID of the object
+	Class int
+	// Box is the bounding box of the object
+	Box result.BoxRectF
+	// Area of the object in pixels used for the area ranges.  If zero the
+	// area of the Mask or Box is used
+	Area float64
+	// IsCrowd marks the annotation as a crowd region which detections can
+	// match without being counted as true or false positives
+	IsCrowd bool
+	// Mask is the segment mask of the object, required for Segm evaluation
+	Mask *result.RLE
+	// KeyPoints are the pose keypoints of the object, required for KeyPoints
+	// evaluation.  The Score of each keypoint is the COCO visibility flag
+	// where zero is not labelled
+	KeyPoints []result.KeyPoint
+}
+
+// Detection is a detected object of an image
+type Detection struct {
+	// Class is the class index or category ID of the object
+	Class int
+	// Score is the confidence score of the detection
+	Score float32
+	// Box is the bounding box of the detection
+	Box result.BoxRectF
+	// Mask is the segment mask of the detection, required for Segm evaluation
+	Mask *result.RLE
+	// KeyPoints are the pose keypoints of the detection, required for
+	// KeyPoints evaluation
+	KeyPoints []result.KeyPoint
+}
+
+// object is a ground truth or detection prepared for evaluation
+type object struct {
+	id        int
+	box       result.BoxRectF
+	x, y      float64
+	w, h      float64
+	area      float64
+	score     float64
+	crowd     bool
+	ignore    bool
+	mask      *result.RLE
+	keyPoints []result.KeyPoint
+}
+
+// key is the image and class an object belongs to
+type key struct {
+	imageID int
+	class   int
+}
+
+// Evaluator defines the struct for accumulating ground truth and detections
+// of a dataset to be evaluated
+type Evaluator struct {
+	// Params are the evaluation parameters
+	Params Params
+	// gts are the ground truth objects by image and class
+	gts map[key][]*object
+	// dts are the detections by image and class
+	dts map[key][]*object
+	// images are the image ID's of the dataset
+	images map[int]bool
+	// classes are the classes of the ground truth
+	classes map[int]bool
+	// nextGT is the ID assigned to ground truth without one
+	nextGT int
+	// nextDT is the ID assigned to the next detection
+	nextDT int
+}
+
+// New returns an instance of the Evaluator
+func New(p Params) *Evaluator {
+	return &Evaluator{
+		Params:  p,
+		gts:     make(map[key][]*object),
+		dts:     make(map[key][]*object),
+		images:  make(map[int]bool),
+		classes: make(map[int]bool),
+	}
+}
+
+// AddGroundTruth adds the image to the dataset with its annotated objects.
+// Images must be added even if they have no objects so detections on them are
+// counted as false positives.
+func (e *Evaluator) AddGroundTruth(imageID int, gts ...GroundTruth) {
+
+	e.images[imageID] = true
+
+	for _, gt := range gts {
+		e.nextGT++
+
+		obj := &object{
+			id:        gt.ID,
+			box:       gt.Box,
+			area:      gt.Area,
+			crowd:     gt.IsCrowd,
+			ignore:    gt.IsCrowd,
+			mask:      gt.Mask,
+			keyPoints: gt.KeyPoints,
+		}
+
+		if obj.id == 0 {
+			obj.id = e.nextGT
+		}
+
+		obj.x, obj.y, obj.w, obj.h = bounds(gt.Box)
+
+		if obj.area == 0 {
+			if gt.Mask != nil {
+				obj.area = float64(gt.Mask.Area())
+			} else {
+				obj.area = obj.w * obj.h
+			}
+		}
+
+		if e.Params.IoUType == KeyPoints && visibleKeyPoints(gt.KeyPoints) == 0 {
+			obj.ignore = true
+		}
+
+		k := key{imageID, gt.Class}
+		e.gts[k] = append(e.gts[k], obj)
+		e.classes[gt.Class] = true
+	}
+}
+
+// AddDetections adds the detected objects of the image
+func (e *Evaluator) AddDetections(imageID int, dts ...Detection) {
+
+	for _, dt := range dts {
+		e.nextDT++
+
+		obj := &object{
+			id:        e.nextDT,
+			box:       dt.Box,
+			score:     float64(dt.Score),
+			mask:      dt.Mask,
+			keyPoints: dt.KeyPoints,
+		}
+
+		obj.x, obj.y, obj.w, obj.h = bounds(dt.Box)
+		obj.area = obj.w * obj.h
+
+		switch e.Params.IoUType {
+		case Segm:
+			if dt.Mask != nil {
+				obj.area = float64(dt.Mask.Area())
+			}
+
+		case KeyPoints:
+			// as with pycocotools the area of keypoint detections is that of
+			// the box enclosing the keypoints
+			if len(dt.KeyPoints) > 0 {
+				x1, y1 := float64(dt.KeyPoints[0].X), float64(dt.KeyPoints[0].Y)
+				x2, y2 := x1, y1
+
+				for _, kp := range dt.KeyPoints[1:] {
+					x1 = math.Min(x1, float64(kp.X))
+					y1 = math.Min(y1, float64(kp.Y))
+					x2 = math.Max(x2, float64(kp.X))
+					y2 = math.Max(y2, float64(kp.Y))
+				}
+
+				obj.area = (x2 - x1) * (y2 - y1)
+			}
+		}
+
+		k := key{imageID, dt.Class}
+		e.dts[k] = append(e.dts[k], obj)
+	}
+}
+
+// AddResults adds the detection results of a post processor for the image
+func (e *Evaluator) AddResults(imageID int, dets []result.DetectResult) {
+	e.AddDetections(imageID, newDetections(dets)...)
+}
+
+// AddPoseResults adds the detection results and their keypoints of a pose
+// post processor for the image
+func (e *Evaluator) AddPoseResults(imageID int, dets []result.DetectResult,
+	keyPoints [][]result.KeyPoint) {
+
+	dts := newDetections(dets)
+
+	for i := range dts {
+		if i < len(keyPoints) {
+			dts[i].KeyPoints = keyPoints[i]
+		}
+	}
+
+	e.AddDetections(imageID, dts...)
+}
+
+// AddSegResults adds the detection results of a segmentation post processor
+// for the image.  The mask is SegMask.Mask where each pixel is the index+1 of
+// the detection or zero for background.
+func (e *Evaluator) AddSegResults(imageID int, dets []result.DetectResult,
+	mask []uint8, width, height int) {
+
+	dts := newDetections(dets)
+
+	for i := range dts {
+		rle := result.EncodeRLE(mask, width, height, uint8(i+1))
+		dts[i].Mask = &rle
+	}
+
+	e.AddDetections(imageID, dts...)
+}
+
+// newDetections converts the detection results to Detections
+func newDetections(dets []result.DetectResult) []Detection {
+
+	dts := make([]Detection, len(dets))
+
+	for i, det := range dets {
+		dts[i] = Detection{
+			Class: det.Class,
+			Score: det.Probability,
+			Box:   det.FloatBox(),
+		}
+	}
+
+	return dts
+}
+
+// imageEval are the matching results of an image and class for an area range
+type imageEval struct {
+	// dtScores are the scores of the detections in descending order
+	dtScores []float64
+	// dtMatched is whether each detection matched per IoU threshold
+	dtMatched [][]bool
+	// dtIgnore is whether each detection is ignored per IoU threshold
+	dtIgnore [][]bool
+	// gtIgnore is whether each ground truth is ignored
+	gtIgnore []bool
+}
+
+// Evaluate matches the detections to the ground truth and returns the
+// accumulated metrics
+func (e *Evaluator) Evaluate() *Results {
+
+	p := e.Params
+
+	images := sortedKeys(e.images)
+	classes := sortedKeys(e.classes)
+	maxDet := p.MaxDets[len(p.MaxDets)-1]
+
+	T := len(p.IoUThresholds)
+	R := len(p.RecallThresholds)
+	K := len(classes)
+	A := len(p.AreaRanges)
+	M := len(p.MaxDets)
+
+	res := &Results{
+		Params:    p,
+		Classes:   classes,
+		precision: filled(T*R*K*A*M, -1),
+		recall:    filled(T*K*A*M, -1),
+	}
+
+	for k, class := range classes {
+		// match each image once per area range
+		evals := make([][]*imageEval, A)
+
+		for _, imageID := range images {
+			gts := e.gts[key{imageID, class}]
+			dts := sortDetections(e.dts[key{imageID, class}], maxDet)
+			ious := e.computeIoU(gts, dts)
+
+			for a, rng := range p.AreaRanges {
+				if ev := e.evaluateImage(gts, dts, ious, rng); ev != nil {
+					evals[a] = append(evals[a], ev)
+				}
+			}
+		}
+
+		for a := range p.AreaRanges {
+			for m, md := range p.MaxDets {
+				e.accumulate(res, evals[a], k, a, m, md)
+			}
+		}
+	}
+
+	res.summarize()
+
+	return res
+}
+
+// sortDetections returns the detections sorted by descending score keeping
+// the order of equal scores and truncated to maxDet
+func sortDetections(dts []*object, maxDet int) []*object {
+
+	sorted := make([]*object, len(dts))
+	copy(sorted, dts)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].score > sorted[j].score
+	})
+
+	if len(sorted) > maxDet {
+		sorted = sorted[:maxDet]
+	}
+
+	return sorted
+}
+
+// evaluateImage greedily matches the detections of an image and class to the
+// ground truth for each IoU threshold
+func (e *Evaluator) evaluateImage(gts, dts []*object, ious [][]float64,
+	rng AreaRange) *imageEval {
+
+	if len(gts) == 0 && len(dts) == 0 {
+		return nil
+	}
+
+	// order ground truth with those not ignored first
+	gtIgnore := make([]bool, len(gts))
+
+	for i, g := range gts {
+		gtIgnore[i] = g.ignore || g.area < rng.Min || g.area > rng.Max
+	}
+
+	order := make([]int, len(gts))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return !gtIgnore[order[i]] && gtIgnore[order[j]]
+	})
+
+	T := len(e.Params.IoUThresholds)
+
+	ev := &imageEval{
+		dtScores:  make([]float64, len(dts)),
+		dtMatched: make([][]bool, T),
+		dtIgnore:  make([][]bool, T),
+		gtIgnore:  make([]bool, len(gts)),
+	}
+
+	for i, g := range order {
+		ev.gtIgnore[i] = gtIgnore[g]
+	}
+
+	for d, dt := range dts {
+		ev.dtScores[d] = dt.score
+	}
+
+	for t, thresh := range e.Params.IoUThresholds {
+		gtMatched := make([]bool, len(gts))
+		ev.dtMatched[t] = make([]bool, len(dts))
+		ev.dtIgnore[t] = make([]bool, len(dts))
+
+		for d := range dts {
+			best := math.Min(thresh, 1-1e-10)
+			m := -1
+
+			for i, g := range order {
+				// skip ground truth already matched unless a crowd
+				if gtMatched[i] && !gts[g].crowd {
+					continue
+				}
+
+				// stop once past the ground truth not ignored if a match has
+				// already been found
+				if m > -1 && !ev.gtIgnore[m] && ev.gtIgnore[i] {
+					break
+				}
+
+				if ious[d][g] < best {
+					continue
+				}
+
+				best = ious[d][g]
+				m = i
+			}
+
+			if m == -1 {
+				continue
+			}
+
+			ev.dtIgnore[t][d] = ev.gtIgnore[m]
+			ev.dtMatched[t][d] = true
+			gtMatched[m] = true
+		}
+
+		// unmatched detections outside the area range are ignored
+		for d, dt := range dts {
+			if !ev.dtMatched[t][d] && (dt.area < rng.Min || dt.area > rng.Max) {
+				ev.dtIgnore[t][d] = true
+			}
+		}
+	}
+
+	return ev
+}
+
+// accumulate calculates the interpolated precision and recall of a class over
+// all images for an area range and maximum number of detections
+func (e *Evaluator) accumulate(res *Results, evals []*imageEval, k, a, m, maxDet int) {
+
+	p := e.Params
+
+	type scored struct {
+		score float64
+		img   int
+		det   int
+	}
+
+	dets := make([]scored, 0)
+	npig := 0
+
+	for i, ev := range evals {
+		for d := 0; d < len(ev.dtScores) && d < maxDet; d++ {
+			dets = append(dets, scored{ev.dtScores[d], i, d})
+		}
+
+		for _, ig := range ev.gtIgnore {
+			if !ig {
+				npig++
+			}
+		}
+	}
+
+	if npig == 0 {
+		return
+	}
+
+	sort.SliceStable(dets, func(i, j int) bool {
+		return dets[i].score > dets[j].score
+	})
+
+	nd := len(dets)
+
+	for t := range p.IoUThresholds {
+		rc := make([]float64, 0, nd)
+		pr := make([]float64, 0, nd)
+		tp, fp := 0.0, 0.0
+
+		for _, d := range dets {
+			ev := evals[d.img]
+
+			if ev.dtIgnore[t][d.det] {
+				// ignored detections are neither true nor false positives
+			} else if ev.dtMatched[t][d.det] {
+				tp++
+			} else {
+				fp++
+			}
+
+			rc = append(rc, tp/float64(npig))
+			pr = append(pr, tp/(fp+tp+epsilon))
+		}
+
+		if nd > 0 {
+			res.recall[res.recallIndex(t, k, a, m)] = rc[nd-1]
+		} else {
+			res.recall[res.recallIndex(t, k, a, m)] = 0
+		}
+
+		// make precision monotonically decreasing
+		for i := nd - 1; i > 0; i-- {
+			if pr[i] > pr[i-1] {
+				pr[i-1] = pr[i]
+			}
+		}
+
+		for r, thresh := range p.RecallThresholds {
+			q := 0.0
+			pi := sort.SearchFloat64s(rc, thresh)
+
+			if pi < nd {
+				q = pr[pi]
+			}
+
+			res.precision[res.precisionIndex(t, r, k, a, m)] = q
+		}
+	}
+}
+
+// epsilon is the spacing of floats at 1 as used by numpy's np.spacing(1)
+const epsilon = 2.220446049250313e-16
+
+// linspace returns n evenly spaced values from start to stop inclusive
+// calculated as numpy's np.linspace
+func linspace(start, stop float64, n int) []float64 {
+
+	step := (stop - start) / float64(n-1)
+	vals := make([]float64, n)
+
+	for i := range vals {
+		vals[i] = float64(i)*step + start
+	}
+
+	vals[n-1] = stop
+
+	return vals
+}
+
+// filled returns a slice of the given length with all values set to v
+func filled(n int, v float64) []float64 {
+
+	s := make([]float64, n)
+
+	for i := range s {
+		s[i] = v
+	}
+
+	return s
+}
+
+// sortedKeys returns the keys of the map in ascending order
+func sortedKeys(m map[int]bool) []int {
+
+	keys := make([]int, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Ints(keys)
+
+	return keys
+}
+
+// visibleKeyPoints returns the number of labelled keypoints
+func visibleKeyPoints(kps []result.KeyPoint) int {
+
+	n := 0
+
+	for _, kp := range kps {
+		if kp.Score > 0 {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,351 @@
+package eval
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+var testLabels = []string{"person", "bicycle", "car"}
+
+// box returns the box of the COCO x, y, width and height
+func box(x, y, w, h float32) result.BoxRectF {
+	return result.BoxRectF{Left: x, Top: y, Right: x + w, Bottom: y + h}
+}
+
+// det returns a detection result for the COCO box
+func det(class int, prob float32, x, y, w, h float32) result.DetectResult {
+	b := box(x, y, w, h)
+
+	return result.DetectResult{
+		Class:       class,
+		Probability: prob,
+		Box:         b.Rect(),
+		BoxF:        b,
+	}
+}
+
+// loadFixture returns an Evaluator with the fixture ground truth
+func loadFixture(t *testing.T, iouType IoUType) (*Evaluator, *Dataset) {
+
+	ds, err := LoadCOCO("testdata/instances.json", testLabels)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := New(DefaultParams(iouType))
+	ds.AddTo(e)
+
+	return e, ds
+}
+
+func TestLoadCOCO(t *testing.T) {
+
+	_, ds := loadFixture(t, BBox)
+
+	if len(ds.Images) != 3 || len(ds.Categories) != 2 {
+		t.Fatalf("expected 3 images and 2 categories, got %d and %d",
+			len(ds.Images), len(ds.Categories))
+	}
+
+	if id, ok := ds.ImageID("two.jpg"); !ok || id != 2 {
+		t.Errorf("expected image ID 2, got %d", id)
+	}
+
+	gts := ds.GroundTruth[1]
+
+	if len(gts) != 3 || gts[0].Class != 0 || gts[2].Class != 2 {
+		t.Fatalf("unexpected ground truth %+v", gts)
+	}
+
+	if gts[0].Mask == nil || gts[0].Mask.Area() != 400 {
+		t.Errorf("expected polygon mask area 400, got %+v", gts[0].Mask)
+	}
+
+	crowd := ds.GroundTruth[2][1]
+
+	if !crowd.IsCrowd || crowd.Mask == nil || crowd.Mask.Area() != 900 {
+		t.Errorf("expected crowd RLE mask area 900, got %+v", crowd)
+	}
+
+	if _, err := ParseCOCO([]byte(`{"categories":[{"id":1,"name":"cat"}]}`), testLabels); err == nil {
+		t.Errorf("expected error for category not in labels")
+	}
+}
+
+func TestEvaluateBBox(t *testing.T) {
+
+	e, _ := loadFixture(t, BBox)
+
+	e.AddResults(1, []result.DetectResult{
+		det(0, 0.9, 10, 10, 20, 20), // exact match
+		det(0, 0.8, 50, 50, 30, 28), // IoU 0.933
+		det(0, 0.3, 70, 0, 10, 10),  // false positive
+		det(2, 0.6, 0, 0, 10, 8.2),  // IoU 0.82
+		det(1, 0.4, 40, 40, 10, 10), // class without ground truth
+	})
+
+	e.AddResults(2, []result.DetectResult{
+		det(0, 0.7, 20, 20, 40, 31), // IoU 0.775
+		det(0, 0.5, 62, 62, 20, 20), // inside crowd region so ignored
+	})
+
+	res := e.Evaluate()
+
+	// expected values calculated by hand following pycocotools COCOeval
+	want := []float64{
+		1548.0 / 2020, // AP
+		1,             // AP50
+		1,             // AP75
+		1667.0 / 2020, // AP small
+		0.6,           // AP medium
+		-1,            // AP large
+		(16.0/30 + 0.7) / 2,
+		(25.0/30 + 0.7) / 2,
+		(25.0/30 + 0.7) / 2,
+		0.825,
+		0.6,
+		-1,
+	}
+
+	if len(res.Stats) != len(want) {
+		t.Fatalf("expected %d stats, got %d", len(want), len(res.Stats))
+	}
+
+	for i := range want {
+		if math.Abs(res.Stats[i]-want[i]) > 1e-9 {
+			t.Errorf("stat %d expected %.6f, got %.6f", i, want[i], res.Stats[i])
+		}
+	}
+
+	if res.MAP() != res.Stats[0] || res.MAP50() != res.Stats[1] {
+		t.Errorf("expected MAP and MAP50 to match stats")
+	}
+
+	perClass := res.PerClass()
+
+	if len(perClass) != 2 || perClass[0].Class != 0 || perClass[1].Class != 2 {
+		t.Fatalf("unexpected per class results %+v", perClass)
+	}
+
+	if math.Abs(perClass[0].AP-841.0/1010) > 1e-9 || math.Abs(perClass[1].AP-0.7) > 1e-9 {
+		t.Errorf("unexpected per class AP %+v", perClass)
+	}
+
+	recall, precision := res.PRCurve(0, 0.8)
+
+	if len(recall) != 101 || precision[0] != 1 || precision[66] != 1 || precision[67] != 0 {
+		t.Errorf("unexpected precision/recall curve %v", precision)
+	}
+
+	if !strings.Contains(res.String(), "Average Precision  (AP) @[ IoU=0.50:0.95 | area=   all | maxDets=100 ] = 0.766") {
+		t.Errorf("unexpected summary\n%s", res.String())
+	}
+}
+
+func TestEvaluateMatchesPycocotools(t *testing.T) {
+
+	// expected stats of the pycocotools COCOeval reference implementation
+	// for the testdata, regenerate with testdata/pycocotools_eval.py
+	expected := []float64{
+		0.616832, 1.000000, 0.500000, 0.625248, 0.700000, -1.000000,
+		0.500000, 0.616667, 0.616667, 0.625000, 0.700000, -1.000000,
+	}
+
+	// use COCO category_id as the Class the same as pycocotools
+	ds, err := LoadCOCO("testdata/instances.json", nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile("testdata/detections.json")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var dets []struct {
+		ImageID    int        `json:"image_id"`
+		CategoryID int        `json:"category_id"`
+		BBox       [4]float32 `json:"bbox"`
+		Score      float32    `json:"score"`
+	}
+
+	if err := json.Unmarshal(data, &dets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := New(DefaultParams(BBox))
+	ds.AddTo(e)
+
+	for _, d := range dets {
+		e.AddResults(d.ImageID, []result.DetectResult{
+			det(d.CategoryID, d.Score, d.BBox[0], d.BBox[1], d.BBox[2], d.BBox[3]),
+		})
+	}
+
+	res := e.Evaluate()
+
+	if len(res.Stats) != len(expected) {
+		t.Fatalf("expected %d stats, got %d", len(expected), len(res.Stats))
+	}
+
+	for i := range expected {
+		if math.Abs(res.Stats[i]-expected[i]) > 1e-6 {
+			t.Errorf("stat %d expected %.6f, got %.6f", i, expected[i], res.Stats[i])
+		}
+	}
+}
+
+func TestEvaluateSegm(t *testing.T) {
+
+	e, ds := loadFixture(t, Segm)
+
+	// detection masks are the ground truth masks of the first image
+	gts := ds.GroundTruth[1]
+	mask := make([]uint8, 100*100)
+
+	for i, gt := range gts {
+		for p, v := range gt.Mask.Decode(1) {
+			if v != 0 {
+				mask[p] = uint8(i + 1)
+			}
+		}
+	}
+
+	e.AddSegResults(1, []result.DetectResult{
+		det(0, 0.9, 10, 10, 20, 20),
+		det(0, 0.8, 50, 50, 30, 30),
+		det(2, 0.7, 0, 0, 10, 10),
+	}, mask, 100, 100)
+
+	res := e.Evaluate()
+
+	// all of image 1 detected perfectly, the medium person of image 2 missed
+	perClass := res.PerClass()
+
+	if math.Abs(perClass[0].AP-67.0/101) > 1e-9 || math.Abs(perClass[1].AP-1) > 1e-9 {
+		t.Errorf("unexpected per class AP %+v", perClass)
+	}
+
+	if math.Abs(res.AP(-1, "small", 100)-1) > 1e-9 {
+		t.Errorf("expected small AP of 1, got %f", res.AP(-1, "small", 100))
+	}
+}
+
+func TestEvaluateKeyPoints(t *testing.T) {
+
+	p := DefaultParams(KeyPoints)
+	p.KeyPointSigmas = COCOKeyPointSigmas[:3]
+	e := New(p)
+
+	kps := []result.KeyPoint{{X: 20, Y: 20, Score: 2}, {X: 30, Y: 25, Score: 2}, {X: 40, Y: 50, Score: 0}}
+
+	e.AddGroundTruth(1, GroundTruth{Class: 0, Box: box(10, 10, 40, 50), KeyPoints: kps})
+	e.AddGroundTruth(2, GroundTruth{Class: 0, Box: box(10, 10, 40, 50), KeyPoints: kps})
+
+	far := []result.KeyPoint{{X: 90, Y: 90}, {X: 95, Y: 95}, {X: 99, Y: 99}}
+
+	e.AddPoseResults(1, []result.DetectResult{det(0, 0.9, 10, 10, 40, 50)}, [][]result.KeyPoint{kps})
+	e.AddPoseResults(2, []result.DetectResult{det(0, 0.8, 10, 10, 40, 50)}, [][]result.KeyPoint{far})
+
+	res := e.Evaluate()
+
+	if math.Abs(res.MAP()-51.0/101) > 1e-9 || math.Abs(res.Stats[5]-0.5) > 1e-9 {
+		t.Errorf("expected AP of 51/101 and AR of 0.5, got %v", res.Stats)
+	}
+
+	if len(res.Stats) != 10 {
+		t.Errorf("expected 10 keypoint stats, got %d", len(res.Stats))
+	}
+}
+
+func TestRLEFromPolygon(t *testing.T) {
+
+	rle := rleFromPolygon([]float64{2, 1, 6, 1, 6, 4, 2, 4}, 8, 6)
+
+	if rle.Area() != 12 {
+		t.Errorf("expected area 12, got %d", rle.Area())
+	}
+
+	a := result.EncodeRLE(make([]uint8, 48), 8, 6, 0)
+
+	if rleIntersection(&rle, &a) != 12 || maskIoU(&rle, &rle, false) != 1 {
+		t.Errorf("unexpected mask intersection")
+	}
+}
+
+func TestParseYOLO(t *testing.T) {
+
+	data := []byte("0 0.5 0.5 0.2 0.4\n" +
+		"1 0.1 0.1 0.3 0.1 0.3 0.3 0.1 0.3\n" +
+		"2 0.5 0.5 0.2 0.2 0.5 0.5 2 0 0 0\n")
+
+	gts, err := ParseYOLO(data, 100, 50)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(gts) != 3 {
+		t.Fatalf("expected 3 ground truth, got %d", len(gts))
+	}
+
+	if gts[0].Box != box(40, 15, 20, 20) {
+		t.Errorf("unexpected box %+v", gts[0].Box)
+	}
+
+	x, y, w, h := bounds(gts[1].Box)
+
+	if math.Abs(x-10) > 1e-4 || math.Abs(y-5) > 1e-4 || math.Abs(w-20) > 1e-4 || math.Abs(h-10) > 1e-4 {
+		t.Errorf("unexpected oriented box bounds %f %f %f %f", x, y, w, h)
+	}
+
+	if len(gts[2].KeyPoints) != 2 || gts[2].KeyPoints[0] != (result.KeyPoint{X: 50, Y: 25, Score: 2}) {
+		t.Errorf("unexpected keypoints %+v", gts[2].KeyPoints)
+	}
+
+	if _, err := ParseYOLO([]byte("0 0.5 0.5"), 100, 50); err == nil {
+		t.Errorf("expected error for invalid line")
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+
+	e, _ := loadFixture(t, BBox)
+
+	e.AddResults(1, []result.DetectResult{
+		det(0, 0.9, 10, 10, 20, 20),  // person as person
+		det(2, 0.8, 50, 50, 30, 30),  // person as car
+		det(0, 0.1, 0, 0, 10, 10),    // below score threshold
+		det(1, 0.6, 70, 70, 100, 10), // background as bicycle
+	})
+
+	cm := e.ConfusionMatrix(3, 0.25, 0.45)
+
+	want := [][]int{
+		{1, 0, 1, 1}, // person, missed on image 2
+		{0, 0, 0, 0},
+		{0, 0, 0, 1}, // car missed
+		{0, 1, 0, 0},
+	}
+
+	for i := range want {
+		for j := range want[i] {
+			if cm.Matrix[i][j] != want[i][j] {
+				t.Errorf("expected matrix %v, got %v", want, cm.Matrix)
+				return
+			}
+		}
+	}
+
+	if cm.Precision(0) != 1 || math.Abs(cm.Recall(0)-1.0/3) > 1e-9 {
+		t.Errorf("unexpected precision %f and recall %f", cm.Precision(0), cm.Recall(0))
+	}
+}
@@ -0,0 +1,357 @@
+package eval
+
+import (
+	"math"
+	"sort"
+
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// computeIoU returns the overlap of each detection with each ground truth
+// indexed as [detection][ground truth]
+func (e *Evaluator) computeIoU(gts, dts []*object) [][]float64 {
+
+	ious := make([][]float64, len(dts))
+
+	for d, dt := range dts {
+		ious[d] = make([]float64, len(gts))
+
+		for g, gt := range gts {
+			switch e.Params.IoUType {
+			case Segm:
+				ious[d][g] = maskIoU(dt.mask, gt.mask, gt.crowd)
+			case KeyPoints:
+				ious[d][g] = e.oks(dt, gt)
+			default:
+				ious[d][g] = boxIoU(dt, gt)
+			}
+		}
+	}
+
+	return ious
+}
+
+// bounds returns the axis aligned x, y, width and height of the box
+func bounds(b result.BoxRectF) (float64, float64, float64, float64) {
+
+	if b.Mode != result.ModeXYWH {
+		return float64(b.Left), float64(b.Top),
+			float64(b.Right - b.Left), float64(b.Bottom - b.Top)
+	}
+
+	if b.Angle == 0 {
+		return float64(b.X), float64(b.Y), float64(b.Width), float64(b.Height)
+	}
+
+	// oriented bounding box, X, Y is the top left corner before rotation
+	// around the center
+	cx := float64(b.X) + float64(b.Width)/2
+	cy := float64(b.Y) + float64(b.Height)/2
+	aCos := math.Abs(math.Cos(float64(b.Angle)))
+	aSin := math.Abs(math.Sin(float64(b.Angle)))
+	w := float64(b.Width)*aCos + float64(b.Height)*aSin
+	h := float64(b.Width)*aSin + float64(b.Height)*aCos
+
+	return cx - w/2, cy - h/2, w, h
+}
+
+// boxIoU returns the IoU of the detection and ground truth boxes.  For crowd
+// ground truth the intersection is divided by the detection area only.
+// Oriented bounding boxes are compared by their rotated IoU.
+func boxIoU(dt, gt *object) float64 {
+
+	if dt.box.Angle != 0 || gt.box.Angle != 0 {
+		return float64(nms.IoU(dt.box, gt.box))
+	}
+
+	iw := math.Min(dt.x+dt.w, gt.x+gt.w) - math.Max(dt.x, gt.x)
+	ih := math.Min(dt.y+dt.h, gt.y+gt.h) - math.Max(dt.y, gt.y)
+
+	if iw <= 0 || ih <= 0 {
+		return 0
+	}
+
+	inter := iw * ih
+	union := dt.w * dt.h
+
+	if !gt.crowd {
+		union += gt.w*gt.h - inter
+	}
+
+	if union <= 0 {
+		return 0
+	}
+
+	return inter / union
+}
+
+// maskIoU returns the IoU of the detection and ground truth masks.  For crowd
+// ground truth the intersection is divided by the detection area only.
+func maskIoU(dt, gt *result.RLE, crowd bool) float64 {
+
+	if dt == nil || gt == nil || dt.Width != gt.Width || dt.Height != gt.Height {
+		return 0
+	}
+
+	inter := float64(rleIntersection(dt, gt))
+	union := float64(dt.Area())
+
+	if !crowd {
+		union += float64(gt.Area()) - inter
+	}
+
+	if union <= 0 {
+		return 0
+	}
+
+	return inter / union
+}
+
+// rleIntersection returns the number of foreground pixels shared by two RLEs
+// of the same size
+func rleIntersection(a, b *result.RLE) int {
+
+	ra := foregroundRuns(a)
+	rb := foregroundRuns(b)
+	inter := 0
+
+	for i, j := 0, 0; i < len(ra) && j < len(rb); {
+		start := max(ra[i][0], rb[j][0])
+		end := min(ra[i][1], rb[j][1])
+
+		if end > start {
+			inter += end - start
+		}
+
+		// advance whichever run ends first
+		if ra[i][1] < rb[j][1] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return inter
+}
+
+// foregroundRuns returns the start and end positions of the foreground runs
+// of the RLE
+func foregroundRuns(r *result.RLE) [][2]int {
+
+	runs := make([][2]int, 0, len(r.Counts)/2)
+	pos := 0
+
+	for i, count := range r.Counts {
+		if i%2 == 1 && count > 0 {
+			runs = append(runs, [2]int{pos, pos + count})
+		}
+		pos += count
+	}
+
+	return runs
+}
+
+// oks returns the Object Keypoint Similarity of the detection to the ground
+// truth.  If the ground truth has no labelled keypoints the distance of the
+// detection keypoints outside of the ground truth box is used.
+func (e *Evaluator) oks(dt, gt *object) float64 {
+
+	sigmas := e.Params.KeyPointSigmas
+	n := len(sigmas)
+
+	if len(gt.keyPoints) < n || len(dt.keyPoints) < n {
+		return 0
+	}
+
+	visible := visibleKeyPoints(gt.keyPoints[:n])
+
+	// box twice the size of the ground truth box for unlabelled ground truth
+	x0, x1 := gt.x-gt.w, gt.x+gt.w*2
+	y0, y1 := gt.y-gt.h, gt.y+gt.h*2
+
+	sum := 0.0
+	count := 0
+
+	for i := 0; i < n; i++ {
+		xd, yd := float64(dt.keyPoints[i].X), float64(dt.keyPoints[i].Y)
+		var dx, dy float64
+
+		if visible > 0 {
+			if gt.keyPoints[i].Score <= 0 {
+				continue
+			}
+
+			dx = xd - float64(gt.keyPoints[i].X)
+			dy = yd - float64(gt.keyPoints[i].Y)
+
+		} else {
+			dx = math.Max(0, x0-xd) + math.Max(0, xd-x1)
+			dy = math.Max(0, y0-yd) + math.Max(0, yd-y1)
+		}
+
+		variance := math.Pow(sigmas[i]*2, 2)
+		sum += math.Exp(-((dx*dx + dy*dy) / variance / (gt.area + epsilon) / 2))
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return sum / float64(count)
+}
+
+// rleFromPolygons returns the RLE of the union of the COCO polygons given as
+// flattened x, y coordinates.  Polygons are rasterised with the same algorithm
+// as pycocotools so areas and IoU's match.
+func rleFromPolygons(polys [][]float64, width, height int) result.RLE {
+
+	mask := make([]uint8, width*height)
+
+	for _, poly := range polys {
+		rle := rleFromPolygon(poly, width, height)
+
+		for i, v := range rle.Decode(1) {
+			mask[i] |= v
+		}
+	}
+
+	return result.EncodeRLE(mask, width, height, 1)
+}
+
+// rleFromPolygon rasterises a single polygon to an RLE by upsampling its
+// boundary and encoding the y boundary crossings of each column
+func rleFromPolygon(xy []float64, width, height int) result.RLE {
+
+	const scale = 5.0
+
+	k := len(xy) / 2
+	h, w := height, width
+
+	x := make([]int, k+1)
+	y := make([]int, k+1)
+
+	for j := 0; j < k; j++ {
+		x[j] = int(scale*xy[j*2] + 0.5)
+		y[j] = int(scale*xy[j*2+1] + 0.5)
+	}
+
+	x[k], y[k] = x[0], y[0]
+
+	// get discrete points densely along the entire boundary
+	u := make([]int, 0)
+	v := make([]int, 0)
+
+	for j := 0; j < k; j++ {
+		xs, xe, ys, ye := x[j], x[j+1], y[j], y[j+1]
+		dx := absInt(xe - xs)
+		dy := absInt(ys - ye)
+		flip := (dx >= dy && xs > xe) || (dx < dy && ys > ye)
+
+		if flip {
+			xs, xe = xe, xs
+			ys, ye = ye, ys
+		}
+
+		if dx >= dy {
+			s := 0.0
+			if dx > 0 {
+				s = float64(ye-ys) / float64(dx)
+			}
+
+			for d := 0; d <= dx; d++ {
+				t := d
+				if flip {
+					t = dx - d
+				}
+				u = append(u, t+xs)
+				v = append(v, int(float64(ys)+s*float64(t)+0.5))
+			}
+
+		} else {
+			s := float64(xe-xs) / float64(dy)
+
+			for d := 0; d <= dy; d++ {
+				t := d
+				if flip {
+					t = dy - d
+				}
+				v = append(v, t+ys)
+				u = append(u, int(float64(xs)+s*float64(t)+0.5))
+			}
+		}
+	}
+
+	// get points along the y boundary and downsample
+	a := make([]int, 0)
+
+	for j := 1; j < len(u); j++ {
+		if u[j] == u[j-1] {
+			continue
+		}
+
+		xd := float64(u[j])
+		if u[j] >= u[j-1] {
+			xd = float64(u[j] - 1)
+		}
+		xd = (xd+0.5)/scale - 0.5
+
+		if math.Floor(xd) != xd || xd < 0 || xd > float64(w-1) {
+			continue
+		}
+
+		yd := float64(min(v[j], v[j-1]))
+		yd = (yd+0.5)/scale - 0.5
+
+		if yd < 0 {
+			yd = 0
+		} else if yd > float64(h) {
+			yd = float64(h)
+		}
+
+		yd = math.Ceil(yd)
+		a = append(a, int(xd)*h+int(yd))
+	}
+
+	// compute the rle encoding from the y boundary points
+	a = append(a, h*w)
+	sort.Ints(a)
+
+	p := 0
+
+	for j := range a {
+		t := a[j]
+		a[j] -= p
+		p = t
+	}
+
+	counts := make([]int, 0, len(a))
+	counts = append(counts, a[0])
+
+	for j := 1; j < len(a); {
+		if a[j] > 0 {
+			counts = append(counts, a[j])
+			j++
+		} else {
+			j++
+			if j < len(a) {
+				counts[len(counts)-1] += a[j]
+				j++
+			}
+		}
+	}
+
+	return result.RLE{
+		Height: height,
+		Width:  width,
+		Counts: counts,
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
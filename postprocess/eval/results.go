@@ -0,0 +1,347 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Results defines the struct holding the accumulated evaluation metrics
+type Results struct {
+	// Params are the parameters the metrics were calculated with
+	Params Params
+	// Classes are the evaluated classes in ascending order
+	Classes []int
+	// Stats are the summary metrics in the same order as pycocotools
+	// COCOeval.stats.  For BBox and Segm evaluation these are AP, AP50, AP75,
+	// AP small, AP medium, AP large, AR maxDets 1, AR maxDets 10, AR maxDets
+	// 100, AR small, AR medium and AR large.  For KeyPoints evaluation these
+	// are AP, AP50, AP75, AP medium, AP large, AR, AR50, AR75, AR medium and
+	// AR large.  A value of -1 indicates no ground truth to evaluate
+	Stats []float64
+	// precision is indexed by [IoU threshold][recall threshold][class]
+	// [area range][max dets]
+	precision []float64
+	// recall is indexed by [IoU threshold][class][area range][max dets]
+	recall []float64
+}
+
+// ClassAP are the average precision metrics of a single class
+type ClassAP struct {
+	// Class is the class index or category ID
+	Class int
+	// AP is the average precision over IoU thresholds 0.5:0.95
+	AP float64
+	// AP50 is the average precision at IoU threshold 0.5
+	AP50 float64
+	// AP75 is the average precision at IoU threshold 0.75
+	AP75 float64
+}
+
+// MAP returns the mean average precision over IoU thresholds 0.5:0.95
+func (r *Results) MAP() float64 {
+	return r.AP(-1, "all", r.maxDet())
+}
+
+// MAP50 returns the mean average precision at IoU threshold 0.5
+func (r *Results) MAP50() float64 {
+	return r.AP(0.5, "all", r.maxDet())
+}
+
+// AP returns the mean average precision over all classes at the given IoU
+// threshold, area range name and maximum detections.  An IoU threshold of -1
+// averages over all thresholds.  Returns -1 if there is nothing to evaluate.
+func (r *Results) AP(iouThresh float64, area string, maxDets int) float64 {
+	return r.summary(true, iouThresh, area, maxDets)
+}
+
+// AR returns the mean average recall over all classes at the given IoU
+// threshold, area range name and maximum detections.  An IoU threshold of -1
+// averages over all thresholds.  Returns -1 if there is nothing to evaluate.
+func (r *Results) AR(iouThresh float64, area string, maxDets int) float64 {
+	return r.summary(false, iouThresh, area, maxDets)
+}
+
+// PerClass returns the average precision of each class over all areas at the
+// maximum number of detections
+func (r *Results) PerClass() []ClassAP {
+
+	aps := make([]ClassAP, len(r.Classes))
+
+	for k, class := range r.Classes {
+		aps[k] = ClassAP{
+			Class: class,
+			AP:    r.classAP(k, -1),
+			AP50:  r.classAP(k, 0.5),
+			AP75:  r.classAP(k, 0.75),
+		}
+	}
+
+	return aps
+}
+
+// PRCurve returns the interpolated precision/recall curve of the class at
+// the given IoU threshold over all areas at the maximum number of detections.
+// The recall values are the Params.RecallThresholds.  Returns nil if the class
+// or threshold was not evaluated.
+func (r *Results) PRCurve(class int, iouThresh float64) ([]float64, []float64) {
+
+	k := r.classIndex(class)
+	t := r.iouIndex(iouThresh)
+
+	if k < 0 || t < 0 {
+		return nil, nil
+	}
+
+	m := len(r.Params.MaxDets) - 1
+	recall := make([]float64, len(r.Params.RecallThresholds))
+	precision := make([]float64, len(r.Params.RecallThresholds))
+
+	for i, rt := range r.Params.RecallThresholds {
+		recall[i] = rt
+		precision[i] = math.Max(0, r.precision[r.precisionIndex(t, i, k, 0, m)])
+	}
+
+	return recall, precision
+}
+
+// String returns the summary metrics formatted as printed by pycocotools
+// COCOeval.summarize()
+func (r *Results) String() string {
+
+	var sb strings.Builder
+
+	for _, s := range r.summaryDefs() {
+		title, typ := "Average Precision", "(AP)"
+
+		if !s.ap {
+			title, typ = "Average Recall", "(AR)"
+		}
+
+		iouStr := "0.50:0.95"
+
+		if s.iou >= 0 {
+			iouStr = fmt.Sprintf("%0.2f", s.iou)
+		}
+
+		fmt.Fprintf(&sb, " %-18s %s @[ IoU=%-9s | area=%6s | maxDets=%3d ] = %0.3f\n",
+			title, typ, iouStr, s.area, s.maxDets, r.summary(s.ap, s.iou, s.area, s.maxDets))
+	}
+
+	return sb.String()
+}
+
+// summaryDef defines a single summary metric
+type summaryDef struct {
+	ap      bool
+	iou     float64
+	area    string
+	maxDets int
+}
+
+// summaryDefs returns the summary metrics reported for the IoUType
+func (r *Results) summaryDefs() []summaryDef {
+
+	if r.Params.IoUType == KeyPoints {
+		md := r.maxDet()
+
+		return []summaryDef{
+			{true, -1, "all", md},
+			{true, 0.5, "all", md},
+			{true, 0.75, "all", md},
+			{true, -1, "medium", md},
+			{true, -1, "large", md},
+			{false, -1, "all", md},
+			{false, 0.5, "all", md},
+			{false, 0.75, "all", md},
+			{false, -1, "medium", md},
+			{false, -1, "large", md},
+		}
+	}
+
+	md := r.Params.MaxDets
+	last := r.maxDet()
+	defs := []summaryDef{
+		{true, -1, "all", last},
+		{true, 0.5, "all", last},
+		{true, 0.75, "all", last},
+		{true, -1, "small", last},
+		{true, -1, "medium", last},
+		{true, -1, "large", last},
+	}
+
+	for _, m := range md {
+		defs = append(defs, summaryDef{false, -1, "all", m})
+	}
+
+	return append(defs,
+		summaryDef{false, -1, "small", last},
+		summaryDef{false, -1, "medium", last},
+		summaryDef{false, -1, "large", last},
+	)
+}
+
+// summarize calculates the summary Stats
+func (r *Results) summarize() {
+
+	defs := r.summaryDefs()
+	r.Stats = make([]float64, len(defs))
+
+	for i, s := range defs {
+		r.Stats[i] = r.summary(s.ap, s.iou, s.area, s.maxDets)
+	}
+}
+
+// summary returns the mean precision or recall over all classes and the
+// selected IoU thresholds ignoring classes without ground truth
+func (r *Results) summary(ap bool, iouThresh float64, area string, maxDets int) float64 {
+
+	a := r.areaIndex(area)
+	m := r.maxDetIndex(maxDets)
+
+	if a < 0 || m < 0 {
+		return -1
+	}
+
+	thresholds := r.iouIndexes(iouThresh)
+	sum, n := 0.0, 0
+
+	for _, t := range thresholds {
+		for k := range r.Classes {
+			if ap {
+				for i := range r.Params.RecallThresholds {
+					if v := r.precision[r.precisionIndex(t, i, k, a, m)]; v > -1 {
+						sum += v
+						n++
+					}
+				}
+
+			} else if v := r.recall[r.recallIndex(t, k, a, m)]; v > -1 {
+				sum += v
+				n++
+			}
+		}
+	}
+
+	if n == 0 {
+		return -1
+	}
+
+	return sum / float64(n)
+}
+
+// classAP returns the average precision of the class index over all areas at
+// the maximum number of detections
+func (r *Results) classAP(k int, iouThresh float64) float64 {
+
+	m := len(r.Params.MaxDets) - 1
+	sum, n := 0.0, 0
+
+	for _, t := range r.iouIndexes(iouThresh) {
+		for i := range r.Params.RecallThresholds {
+			if v := r.precision[r.precisionIndex(t, i, k, 0, m)]; v > -1 {
+				sum += v
+				n++
+			}
+		}
+	}
+
+	if n == 0 {
+		return -1
+	}
+
+	return sum / float64(n)
+}
+
+// iouIndexes returns the indexes of the IoU threshold, or all thresholds if
+// iouThresh is -1
+func (r *Results) iouIndexes(iouThresh float64) []int {
+
+	if iouThresh < 0 {
+		idx := make([]int, len(r.Params.IoUThresholds))
+
+		for i := range idx {
+			idx[i] = i
+		}
+
+		return idx
+	}
+
+	if t := r.iouIndex(iouThresh); t >= 0 {
+		return []int{t}
+	}
+
+	return nil
+}
+
+// iouIndex returns the index of the IoU threshold or -1 if not found
+func (r *Results) iouIndex(iouThresh float64) int {
+
+	for i, t := range r.Params.IoUThresholds {
+		if math.Abs(t-iouThresh) < 1e-9 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// areaIndex returns the index of the named area range or -1 if not found
+func (r *Results) areaIndex(name string) int {
+
+	for i, a := range r.Params.AreaRanges {
+		if a.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// maxDetIndex returns the index of the maximum detections or -1 if not found
+func (r *Results) maxDetIndex(maxDets int) int {
+
+	for i, m := range r.Params.MaxDets {
+		if m == maxDets {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// classIndex returns the index of the class or -1 if not evaluated
+func (r *Results) classIndex(class int) int {
+
+	for i, c := range r.Classes {
+		if c == class {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// maxDet returns the largest maximum number of detections
+func (r *Results) maxDet() int {
+	return r.Params.MaxDets[len(r.Params.MaxDets)-1]
+}
+
+// precisionIndex returns the index into the precision slice
+func (r *Results) precisionIndex(t, i, k, a, m int) int {
+	R := len(r.Params.RecallThresholds)
+	K := len(r.Classes)
+	A := len(r.Params.AreaRanges)
+	M := len(r.Params.MaxDets)
+
+	return (((t*R+i)*K+k)*A+a)*M + m
+}
+
+// recallIndex returns the index into the recall slice
+func (r *Results) recallIndex(t, k, a, m int) int {
+	K := len(r.Classes)
+	A := len(r.Params.AreaRanges)
+	M := len(r.Params.MaxDets)
+
+	return ((t*K+k)*A+a)*M + m
+}
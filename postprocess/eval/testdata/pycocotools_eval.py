@@ -0,0 +1,20 @@
+"""
+Prints the expected stats of evaluating detections.json against
+instances.json with the pycocotools COCOeval reference implementation, as
+hard-coded in TestEvaluateMatchesPycocotools.  Run from this directory with:
+
+    pip install pycocotools
+    python3 pycocotools_eval.py
+"""
+from pycocotools.coco import COCO
+from pycocotools.cocoeval import COCOeval
+
+gt = COCO("instances.json")
+dt = gt.loadRes("detections.json")
+
+ev = COCOeval(gt, dt, "bbox")
+ev.evaluate()
+ev.accumulate()
+ev.summarize()
+
+print(", ".join("%.6f" % s for s in ev.stats))
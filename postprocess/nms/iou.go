@@ -0,0 +1,245 @@
+package nms
+
+import (
+	"math"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// box is the float representation of a result.BoxRect used for overlap
+// calculations
+type box struct {
+	// cx, cy are the box center coordinates
+	cx, cy float64
+	// w, h are the box width and height before rotation
+	w, h float64
+	// angle is the rotation of the box in radians
+	angle float64
+}
+
+// toBox converts a BoxRectF to its float64 representation.  Boxes set in
+// ModeXYWH follow the oriented bounding box convention of the YOLOv8obb post
+// processor where X, Y is the top left corner of the box before rotation
+// around its center.
+func toBox(b result.BoxRectF) box {
+
+	if b.Mode == result.ModeXYWH {
+		return box{
+			cx:    float64(b.X) + float64(b.Width)/2,
+			cy:    float64(b.Y) + float64(b.Height)/2,
+			w:     float64(b.Width),
+			h:     float64(b.Height),
+			angle: float64(b.Angle),
+		}
+	}
+
+	return box{
+		cx: float64(b.Left+b.Right) / 2,
+		cy: float64(b.Top+b.Bottom) / 2,
+		w:  float64(b.Right - b.Left),
+		h:  float64(b.Bottom - b.Top),
+	}
+}
+
+// rotated returns true if the box has a rotation angle
+func (b box) rotated() bool {
+	return b.angle != 0
+}
+
+// area returns the area of the box
+func (b box) area() float64 {
+	return math.Max(0, b.w) * math.Max(0, b.h)
+}
+
+// bounds returns the axis aligned bounds of the box as x1, y1, x2, y2
+func (b box) bounds() (float64, float64, float64, float64) {
+
+	if !b.rotated() {
+		return b.cx - b.w/2, b.cy - b.h/2, b.cx + b.w/2, b.cy + b.h/2
+	}
+
+	corners := b.corners()
+	x1, y1 := corners[0][0], corners[0][1]
+	x2, y2 := x1, y1
+
+	for _, c := range corners[1:] {
+		x1 = math.Min(x1, c[0])
+		y1 = math.Min(y1, c[1])
+		x2 = math.Max(x2, c[0])
+		y2 = math.Max(y2, c[1])
+	}
+
+	return x1, y1, x2, y2
+}
+
+// corners returns the four corner points of the box in clockwise order
+func (b box) corners() [][2]float64 {
+
+	aCos := math.Cos(b.angle)
+	aSin := math.Sin(b.angle)
+
+	xD := b.w / 2
+	yD := b.h / 2
+
+	cornersX := [4]float64{-xD, xD, xD, -xD}
+	cornersY := [4]float64{-yD, -yD, yD, yD}
+
+	pts := make([][2]float64, 4)
+
+	for i := 0; i < 4; i++ {
+		pts[i][0] = aCos*cornersX[i] - aSin*cornersY[i] + b.cx
+		pts[i][1] = aSin*cornersX[i] + aCos*cornersY[i] + b.cy
+	}
+
+	return pts
+}
+
+// IoU returns the Intersection over Union of two boxes.  If either box has a
+// rotation angle the intersection is calculated on the rotated boxes.
+func IoU(a, b result.BoxRectF) float32 {
+	return float32(iou(toBox(a), toBox(b)))
+}
+
+// DistanceIoU returns the Distance IoU of two boxes, being the IoU penalised by the
+// squared distance between the box centers normalised by the squared diagonal
+// of the smallest box enclosing both
+func DistanceIoU(a, b result.BoxRectF) float32 {
+	return float32(diou(toBox(a), toBox(b)))
+}
+
+// iou returns the Intersection over Union of two boxes
+func iou(a, b box) float64 {
+
+	inter := intersection(a, b)
+	union := a.area() + b.area() - inter
+
+	if union <= 0 {
+		return 0
+	}
+
+	return inter / union
+}
+
+// diou returns the Distance IoU of two boxes
+func diou(a, b box) float64 {
+
+	ax1, ay1, ax2, ay2 := a.bounds()
+	bx1, by1, bx2, by2 := b.bounds()
+
+	// squared diagonal of the smallest enclosing box
+	cw := math.Max(ax2, bx2) - math.Min(ax1, bx1)
+	ch := math.Max(ay2, by2) - math.Min(ay1, by1)
+	c2 := cw*cw + ch*ch
+
+	if c2 <= 0 {
+		return iou(a, b)
+	}
+
+	dx := a.cx - b.cx
+	dy := a.cy - b.cy
+
+	return iou(a, b) - (dx*dx+dy*dy)/c2
+}
+
+// intersection returns the area of overlap between two boxes
+func intersection(a, b box) float64 {
+
+	if !a.rotated() && !b.rotated() {
+		ax1, ay1, ax2, ay2 := a.bounds()
+		bx1, by1, bx2, by2 := b.bounds()
+
+		w := math.Min(ax2, bx2) - math.Max(ax1, bx1)
+		h := math.Min(ay2, by2) - math.Max(ay1, by1)
+
+		if w <= 0 || h <= 0 {
+			return 0
+		}
+
+		return w * h
+	}
+
+	return polygonArea(clipPolygon(a.corners(), b.corners()))
+}
+
+// clipPolygon returns the intersection of the subject polygon with the convex
+// clip polygon using the Sutherland-Hodgman algorithm.  Both polygons must
+// have their points in the same winding order.
+func clipPolygon(subject, clip [][2]float64) [][2]float64 {
+
+	output := subject
+
+	for i := range clip {
+		if len(output) == 0 {
+			break
+		}
+
+		edgeA := clip[i]
+		edgeB := clip[(i+1)%len(clip)]
+
+		input := output
+		output = make([][2]float64, 0, len(input)+1)
+
+		prev := input[len(input)-1]
+
+		for _, cur := range input {
+			curInside := edgeSide(edgeA, edgeB, cur) >= 0
+			prevInside := edgeSide(edgeA, edgeB, prev) >= 0
+
+			if curInside {
+				if !prevInside {
+					output = append(output, lineIntersect(prev, cur, edgeA, edgeB))
+				}
+				output = append(output, cur)
+
+			} else if prevInside {
+				output = append(output, lineIntersect(prev, cur, edgeA, edgeB))
+			}
+
+			prev = cur
+		}
+	}
+
+	return output
+}
+
+// edgeSide returns the cross product sign of point p relative to the edge a->b,
+// positive values are on the inside for clockwise (image coordinate) polygons
+func edgeSide(a, b, p [2]float64) float64 {
+	return (b[0]-a[0])*(p[1]-a[1]) - (b[1]-a[1])*(p[0]-a[0])
+}
+
+// lineIntersect returns the intersection point of line p1->p2 with the line
+// through a->b
+func lineIntersect(p1, p2, a, b [2]float64) [2]float64 {
+
+	d1 := edgeSide(a, b, p1)
+	d2 := edgeSide(a, b, p2)
+
+	if d1 == d2 {
+		return p2
+	}
+
+	t := d1 / (d1 - d2)
+
+	return [2]float64{
+		p1[0] + t*(p2[0]-p1[0]),
+		p1[1] + t*(p2[1]-p1[1]),
+	}
+}
+
+// polygonArea returns the area of a polygon using the shoelace formula
+func polygonArea(pts [][2]float64) float64 {
+
+	if len(pts) < 3 {
+		return 0
+	}
+
+	area := 0.0
+
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		area += pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1]
+	}
+
+	return math.Abs(area) / 2
+}
@@ -0,0 +1,505 @@
+/*
+Package nms provides Non-Maximum Suppression algorithms for filtering
+overlapping object detection results.  The algorithms operate on
+result.DetectResult so they can be plugged into any of the post processors
+or run over the combined results of multiple detectors.
+*/
+package nms
+
+import (
+	"math"
+	"sort"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// Suppressor defines the interface for algorithms that filter overlapping
+// detection results
+type Suppressor interface {
+	// Suppress takes the detection results and returns those kept sorted by
+	// descending Probability
+	Suppress(dets []result.DetectResult) []result.DetectResult
+}
+
+// Method is the Non-Maximum Suppression algorithm to use
+type Method int
+
+const (
+	// Greedy is standard hard NMS, boxes overlapping a higher scoring box by
+	// more than the IoU threshold are discarded
+	Greedy Method = 0
+	// SoftLinear is Soft-NMS with a linear decay, the score of boxes
+	// overlapping a higher scoring box by more than the IoU threshold is
+	// multiplied by (1 - IoU)
+	SoftLinear Method = 1
+	// SoftGaussian is Soft-NMS with a gaussian decay, the score of every
+	// overlapping box is multiplied by exp(-IoU^2 / SoftSigma)
+	SoftGaussian Method = 2
+	// DIoU is greedy NMS using Distance IoU as the overlap measure so
+	// overlapping boxes with distant centers are kept
+	DIoU Method = 3
+	// Matrix is Matrix NMS which decays the score of all boxes in parallel
+	// based on their overlap with every higher scoring box by
+	// exp(-MatrixSigma * (IoU^2 - compensate^2))
+	Matrix Method = 4
+	// WBF is Weighted Boxes Fusion, overlapping boxes are merged into a single
+	// box with coordinates weighted by their scores
+	WBF Method = 5
+	// Cluster groups boxes overlapping a higher scoring box by more than the
+	// IoU threshold, or with more than SmallBoxOverlap of their area covered
+	// by it, and keeps the largest box of each cluster.  This merges the
+	// partial detections of an object cut by the slice boundary in SAHI
+	Cluster Method = 6
+)
+
+// Params defines the struct containing the parameters for the Non-Maximum
+// Suppression algorithm
+type Params struct {
+	// Method is the suppression algorithm to use
+	Method Method
+	// IoUThreshold is the maximum allowed Intersection Over Union (IoU)
+	// between two bounding boxes for both to be kept.  For WBF it is the
+	// minimum IoU for boxes to be fused together
+	IoUThreshold float32
+	// ClassAgnostic when true suppresses overlapping boxes regardless of
+	// their Class, otherwise boxes are only suppressed by boxes of the
+	// same Class
+	ClassAgnostic bool
+	// SoftSigma is the gaussian decay parameter of SoftGaussian NMS which
+	// divides the squared IoU, larger values decay scores less.  A SoftSigma
+	// of zero uses the default of 0.5
+	SoftSigma float32
+	// MatrixSigma is the gaussian decay parameter of Matrix NMS which
+	// multiplies the squared IoU, larger values decay scores more.  A
+	// MatrixSigma of zero uses a linear decay instead
+	MatrixSigma float32
+	// ScoreThreshold is the minimum score a box must keep after its score has
+	// been decayed by Soft-NMS or Matrix NMS
+	ScoreThreshold float32
+	// MaxDetections is the maximum number of boxes returned, zero for no limit
+	MaxDetections int
+	// SmallBoxOverlap is the fraction of a box's area from 0 to 1 that must be
+	// covered by a higher scoring box for Cluster NMS to cluster them
+	SmallBoxOverlap float32
+}
+
+// DefaultParams returns an instance of Params configured for per class greedy
+// NMS with the same IoU threshold as the YOLO COCO parameters.  The decay
+// parameters are the defaults of the Soft-NMS and SOLOv2 papers.
+func DefaultParams() Params {
+	return Params{
+		Method:         Greedy,
+		IoUThreshold:   0.45,
+		SoftSigma:      0.5,
+		MatrixSigma:    2.0,
+		ScoreThreshold: 0.001,
+	}
+}
+
+// NMS defines the struct for a configurable Non-Maximum Suppression algorithm
+type NMS struct {
+	// Params are the suppression parameters
+	Params Params
+}
+
+// New returns an instance of the NMS suppressor
+func New(p Params) *NMS {
+	return &NMS{
+		Params: p,
+	}
+}
+
+// candidate is a detection result being processed with its float box
+type candidate struct {
+	det   result.DetectResult
+	box   box
+	score float64
+}
+
+// Suppress filters the given detection results and returns those kept sorted
+// by descending Probability.  The given slice is not modified.
+func (n *NMS) Suppress(dets []result.DetectResult) []result.DetectResult {
+
+	if len(dets) == 0 {
+		return []result.DetectResult{}
+	}
+
+	var keep []candidate
+
+	if n.Params.ClassAgnostic {
+		keep = n.run(newCandidates(dets))
+
+	} else {
+		// group detections by class keeping the order classes were first seen
+		// so results are deterministic
+		groups := make(map[int][]result.DetectResult)
+		order := make([]int, 0)
+
+		for _, det := range dets {
+			if _, ok := groups[det.Class]; !ok {
+				order = append(order, det.Class)
+			}
+			groups[det.Class] = append(groups[det.Class], det)
+		}
+
+		for _, class := range order {
+			keep = append(keep, n.run(newCandidates(groups[class]))...)
+		}
+	}
+
+	sortCandidates(keep)
+
+	if n.Params.MaxDetections > 0 && len(keep) > n.Params.MaxDetections {
+		keep = keep[:n.Params.MaxDetections]
+	}
+
+	res := make([]result.DetectResult, len(keep))
+
+	for i, c := range keep {
+		res[i] = c.det
+		res[i].Probability = float32(c.score)
+	}
+
+	return res
+}
+
+// run executes the configured suppression method on the candidates
+func (n *NMS) run(cands []candidate) []candidate {
+
+	switch n.Params.Method {
+	case SoftLinear, SoftGaussian:
+		return n.soft(cands)
+	case DIoU:
+		return n.greedy(cands, diou)
+	case Matrix:
+		return n.matrix(cands)
+	case WBF:
+		return n.fuse(cands)
+	case Cluster:
+		return n.cluster(cands)
+	default:
+		return n.greedy(cands, iou)
+	}
+}
+
+// newCandidates converts detection results to candidates sorted by
+// descending score
+func newCandidates(dets []result.DetectResult) []candidate {
+
+	cands := make([]candidate, len(dets))
+
+	for i, det := range dets {
+		cands[i] = candidate{
+			det:   det,
+			box:   toBox(det.FloatBox()),
+			score: float64(det.Probability),
+		}
+	}
+
+	sortCandidates(cands)
+
+	return cands
+}
+
+// sortCandidates sorts candidates by descending score
+func sortCandidates(cands []candidate) {
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].score > cands[j].score
+	})
+}
+
+// greedy performs hard NMS using the given overlap function
+func (n *NMS) greedy(cands []candidate, overlap func(a, b box) float64) []candidate {
+
+	thresh := float64(n.Params.IoUThreshold)
+	keep := make([]candidate, 0, len(cands))
+	suppressed := make([]bool, len(cands))
+
+	for i := range cands {
+		if suppressed[i] {
+			continue
+		}
+
+		keep = append(keep, cands[i])
+
+		for j := i + 1; j < len(cands); j++ {
+			if suppressed[j] {
+				continue
+			}
+
+			if overlap(cands[i].box, cands[j].box) > thresh {
+				suppressed[j] = true
+			}
+		}
+	}
+
+	return keep
+}
+
+// cluster performs Cluster NMS, keeping the largest box of each cluster of
+// overlapping boxes with ties broken by the higher score
+func (n *NMS) cluster(cands []candidate) []candidate {
+
+	thresh := float64(n.Params.IoUThreshold)
+	smallOverlap := float64(n.Params.SmallBoxOverlap)
+
+	keep := make([]candidate, 0, len(cands))
+	suppressed := make([]bool, len(cands))
+
+	for i := range cands {
+		if suppressed[i] {
+			continue
+		}
+
+		// start a new cluster with the highest scoring remaining box
+		base := cands[i]
+		best := base
+
+		for j := i + 1; j < len(cands); j++ {
+			if suppressed[j] {
+				continue
+			}
+
+			c := cands[j]
+
+			if iou(base.box, c.box) <= thresh {
+				// small box test for boxes mostly covered by the base box
+				area := c.box.area()
+
+				if area <= 0 || intersection(base.box, c.box)/area <= smallOverlap {
+					continue
+				}
+			}
+
+			suppressed[j] = true
+
+			if c.box.area() > best.box.area() ||
+				(c.box.area() == best.box.area() && c.score > best.score) {
+				best = c
+			}
+		}
+
+		keep = append(keep, best)
+	}
+
+	return keep
+}
+
+// soft performs Soft-NMS with a linear or gaussian score decay
+func (n *NMS) soft(cands []candidate) []candidate {
+
+	thresh := float64(n.Params.IoUThreshold)
+	sigma := float64(n.Params.SoftSigma)
+	minScore := float64(n.Params.ScoreThreshold)
+
+	// a sigma of zero would decay no scores so use the default
+	if sigma <= 0 {
+		sigma = float64(DefaultParams().SoftSigma)
+	}
+
+	remaining := make([]candidate, len(cands))
+	copy(remaining, cands)
+
+	keep := make([]candidate, 0, len(cands))
+
+	for len(remaining) > 0 {
+
+		// select highest scoring remaining box
+		best := 0
+
+		for i := 1; i < len(remaining); i++ {
+			if remaining[i].score > remaining[best].score {
+				best = i
+			}
+		}
+
+		top := remaining[best]
+		keep = append(keep, top)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+
+		// decay overlapping boxes and drop those under the score threshold
+		next := remaining[:0]
+
+		for _, c := range remaining {
+			ov := iou(top.box, c.box)
+
+			if n.Params.Method == SoftGaussian {
+				c.score *= math.Exp(-(ov * ov) / sigma)
+			} else if ov > thresh {
+				c.score *= 1 - ov
+			}
+
+			if c.score >= minScore {
+				next = append(next, c)
+			}
+		}
+
+		remaining = next
+	}
+
+	return keep
+}
+
+// matrix performs Matrix NMS as described in SOLOv2, where the score of each
+// box is decayed by its overlap with all higher scoring boxes, compensated by
+// how much those boxes were themselves overlapped
+func (n *NMS) matrix(cands []candidate) []candidate {
+
+	sigma := float64(n.Params.MatrixSigma)
+	minScore := float64(n.Params.ScoreThreshold)
+
+	num := len(cands)
+	ious := make([][]float64, num)
+
+	// compensate is the maximum IoU of each box with any higher scoring box
+	compensate := make([]float64, num)
+
+	for i := 0; i < num; i++ {
+		ious[i] = make([]float64, num)
+
+		for j := 0; j < i; j++ {
+			ious[i][j] = iou(cands[i].box, cands[j].box)
+
+			if ious[i][j] > compensate[i] {
+				compensate[i] = ious[i][j]
+			}
+		}
+	}
+
+	keep := make([]candidate, 0, num)
+
+	for j := 0; j < num; j++ {
+		decay := 1.0
+
+		for i := 0; i < j; i++ {
+			var d float64
+
+			if sigma > 0 {
+				d = math.Exp(-sigma * (ious[j][i]*ious[j][i] - compensate[i]*compensate[i]))
+			} else {
+				d = (1 - ious[j][i]) / math.Max(1-compensate[i], 1e-6)
+			}
+
+			if d < decay {
+				decay = d
+			}
+		}
+
+		c := cands[j]
+		c.score *= decay
+
+		if c.score >= minScore {
+			keep = append(keep, c)
+		}
+	}
+
+	return keep
+}
+
+// fuse performs Weighted Boxes Fusion, clustering boxes that overlap the
+// cluster's fused box by more than the IoU threshold and averaging their
+// coordinates weighted by score.  The fused box keeps the Class and ID of the
+// highest scoring box in the cluster and its score is the cluster's mean score.
+func (n *NMS) fuse(cands []candidate) []candidate {
+
+	thresh := float64(n.Params.IoUThreshold)
+
+	type cluster struct {
+		members []candidate
+		fused   candidate
+	}
+
+	clusters := make([]*cluster, 0)
+
+	for _, c := range cands {
+
+		// find best matching cluster
+		var match *cluster
+		bestIoU := thresh
+
+		for _, cl := range clusters {
+			ov := iou(cl.fused.box, c.box)
+
+			if ov > bestIoU {
+				bestIoU = ov
+				match = cl
+			}
+		}
+
+		if match == nil {
+			clusters = append(clusters, &cluster{
+				members: []candidate{c},
+				fused:   c,
+			})
+			continue
+		}
+
+		match.members = append(match.members, c)
+		match.fused = fuseCluster(match.members)
+	}
+
+	keep := make([]candidate, len(clusters))
+
+	for i, cl := range clusters {
+		keep[i] = cl.fused
+	}
+
+	return keep
+}
+
+// fuseCluster returns the score weighted average box of the cluster members.
+// Members must be sorted by descending score.
+func fuseCluster(members []candidate) candidate {
+
+	var cx, cy, w, h, angle, weight float64
+
+	for _, m := range members {
+		cx += m.box.cx * m.score
+		cy += m.box.cy * m.score
+		w += m.box.w * m.score
+		h += m.box.h * m.score
+		angle += m.box.angle * m.score
+		weight += m.score
+	}
+
+	fused := members[0]
+
+	if weight <= 0 {
+		return fused
+	}
+
+	fused.box = box{
+		cx:    cx / weight,
+		cy:    cy / weight,
+		w:     w / weight,
+		h:     h / weight,
+		angle: angle / weight,
+	}
+	fused.score = weight / float64(len(members))
+	fused.det.BoxF = fromBox(fused.box, fused.det.FloatBox().Mode)
+	fused.det.Box = fused.det.BoxF.Rect()
+
+	return fused
+}
+
+// fromBox converts the float box back to a BoxRectF set in the given Mode
+func fromBox(b box, mode result.BoxRectMode) result.BoxRectF {
+
+	if mode == result.ModeXYWH {
+		return result.BoxRectF{
+			X:      float32(b.cx - b.w/2),
+			Y:      float32(b.cy - b.h/2),
+			Width:  float32(b.w),
+			Height: float32(b.h),
+			Angle:  float32(b.angle),
+			Mode:   result.ModeXYWH,
+		}
+	}
+
+	return result.BoxRectF{
+		Left:   float32(b.cx - b.w/2),
+		Top:    float32(b.cy - b.h/2),
+		Right:  float32(b.cx + b.w/2),
+		Bottom: float32(b.cy + b.h/2),
+	}
+}
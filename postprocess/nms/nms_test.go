@@ -0,0 +1,339 @@
+package nms
+
+import (
+	"math"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+// det is a helper to create a LTRB detection result
+func det(id int64, class int, prob float32, l, t, r, b int) result.DetectResult {
+	return result.DetectResult{
+		ID:          id,
+		Class:       class,
+		Probability: prob,
+		Box: result.BoxRect{
+			Left:   l,
+			Top:    t,
+			Right:  r,
+			Bottom: b,
+		},
+	}
+}
+
+// ids returns the ID's of the detection results
+func ids(dets []result.DetectResult) []int64 {
+	out := make([]int64, len(dets))
+	for i, d := range dets {
+		out[i] = d.ID
+	}
+	return out
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestIoU(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		a, b     result.BoxRectF
+		expected float32
+	}{
+		{"identical", result.BoxRectF{Left: 0, Top: 0, Right: 10, Bottom: 10},
+			result.BoxRectF{Left: 0, Top: 0, Right: 10, Bottom: 10}, 1},
+		{"half overlap", result.BoxRectF{Left: 0, Top: 0, Right: 10, Bottom: 10},
+			result.BoxRectF{Left: 5, Top: 0, Right: 15, Bottom: 10}, 50.0 / 150.0},
+		{"disjoint", result.BoxRectF{Left: 0, Top: 0, Right: 10, Bottom: 10},
+			result.BoxRectF{Left: 20, Top: 20, Right: 30, Bottom: 30}, 0},
+		{"rotated identical", result.BoxRectF{X: 0, Y: 0, Width: 20, Height: 10, Angle: 0.5, Mode: result.ModeXYWH},
+			result.BoxRectF{X: 0, Y: 0, Width: 20, Height: 10, Angle: 0.5, Mode: result.ModeXYWH}, 1},
+		// a square rotated 90 degrees covers the same area
+		{"rotated square", result.BoxRectF{X: 0, Y: 0, Width: 10, Height: 10, Mode: result.ModeXYWH},
+			result.BoxRectF{X: 0, Y: 0, Width: 10, Height: 10, Angle: math.Pi / 2, Mode: result.ModeXYWH}, 1},
+		// a 20x10 box rotated 90 degrees overlaps itself by a 10x10 square
+		{"rotated cross", result.BoxRectF{X: 0, Y: 0, Width: 20, Height: 10, Mode: result.ModeXYWH},
+			result.BoxRectF{X: 0, Y: 0, Width: 20, Height: 10, Angle: math.Pi / 2, Mode: result.ModeXYWH}, 100.0 / 300.0},
+	}
+
+	for _, tc := range tests {
+		got := IoU(tc.a, tc.b)
+
+		if math.Abs(float64(got-tc.expected)) > 1e-4 {
+			t.Errorf("%s: expected IoU %f, got %f", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestDistanceIoU(t *testing.T) {
+
+	a := result.BoxRectF{Left: 0, Top: 0, Right: 10, Bottom: 10}
+	b := result.BoxRectF{Left: 5, Top: 0, Right: 15, Bottom: 10}
+
+	// center distance 5, enclosing box 15x10
+	expected := float32(50.0/150.0 - 25.0/325.0)
+
+	if got := DistanceIoU(a, b); math.Abs(float64(got-expected)) > 1e-4 {
+		t.Errorf("expected DIoU %f, got %f", expected, got)
+	}
+}
+
+func TestGreedy(t *testing.T) {
+
+	dets := []result.DetectResult{
+		det(1, 0, 0.9, 0, 0, 100, 100),
+		det(2, 0, 0.8, 5, 5, 105, 105),
+		det(3, 1, 0.7, 5, 5, 105, 105),
+		det(4, 0, 0.6, 200, 200, 300, 300),
+	}
+
+	p := DefaultParams()
+	res := New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 3, 4}) {
+		t.Errorf("per class: expected ID's [1 3 4], got %v", ids(res))
+	}
+
+	p.ClassAgnostic = true
+	res = New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 4}) {
+		t.Errorf("class agnostic: expected ID's [1 4], got %v", ids(res))
+	}
+
+	p.MaxDetections = 1
+	res = New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1}) {
+		t.Errorf("max detections: expected ID's [1], got %v", ids(res))
+	}
+}
+
+func TestRotatedGreedy(t *testing.T) {
+
+	obb := func(id int64, prob float32, angle float32) result.DetectResult {
+		return result.DetectResult{
+			ID:          id,
+			Probability: prob,
+			Box: result.BoxRect{
+				X: 0, Y: 0, Width: 100, Height: 10, Angle: angle,
+				Mode: result.ModeXYWH,
+			},
+		}
+	}
+
+	// the axis aligned bounds of a box and its 90 degree rotation overlap
+	// heavily, but the rotated boxes only overlap at their centers
+	dets := []result.DetectResult{
+		obb(1, 0.9, 0),
+		obb(2, 0.8, math.Pi/2),
+		obb(3, 0.7, 0.01),
+	}
+
+	res := New(DefaultParams()).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 2}) {
+		t.Errorf("expected ID's [1 2], got %v", ids(res))
+	}
+}
+
+func TestSoftNMS(t *testing.T) {
+
+	dets := []result.DetectResult{
+		det(1, 0, 0.9, 0, 0, 100, 100),
+		det(2, 0, 0.8, 0, 0, 100, 50),
+	}
+
+	// IoU between the boxes is 0.5
+	p := DefaultParams()
+	p.Method = SoftLinear
+	res := New(p).Suppress(dets)
+
+	if len(res) != 2 {
+		t.Fatalf("linear: expected 2 results, got %d", len(res))
+	}
+
+	if math.Abs(float64(res[1].Probability-0.4)) > 1e-4 {
+		t.Errorf("linear: expected decayed score 0.4, got %f", res[1].Probability)
+	}
+
+	p.Method = SoftGaussian
+	res = New(p).Suppress(dets)
+
+	expected := float32(0.8 * math.Exp(-0.25/0.5))
+
+	if len(res) != 2 || math.Abs(float64(res[1].Probability-expected)) > 1e-4 {
+		t.Errorf("gaussian: expected decayed score %f, got %v", expected, res)
+	}
+
+	// unset sigma falls back to the default
+	p.SoftSigma = 0
+	res = New(p).Suppress(dets)
+
+	if len(res) != 2 || math.Abs(float64(res[1].Probability-expected)) > 1e-4 {
+		t.Errorf("gaussian zero sigma: expected decayed score %f, got %v", expected, res)
+	}
+
+	// decayed score falls below threshold
+	p.ScoreThreshold = 0.5
+	res = New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1}) {
+		t.Errorf("gaussian threshold: expected ID's [1], got %v", ids(res))
+	}
+}
+
+func TestDIoUNMS(t *testing.T) {
+
+	// boxes overlap with IoU 0.5 but their centers are apart giving a DIoU
+	// of 0.469
+	dets := []result.DetectResult{
+		det(1, 0, 0.9, 0, 0, 100, 100),
+		det(2, 0, 0.8, 0, 0, 100, 50),
+	}
+
+	p := DefaultParams()
+	p.IoUThreshold = 0.48
+	p.Method = DIoU
+	res := New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 2}) {
+		t.Errorf("expected ID's [1 2], got %v", ids(res))
+	}
+
+	p.Method = Greedy
+	res = New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1}) {
+		t.Errorf("expected ID's [1], got %v", ids(res))
+	}
+}
+
+func TestMatrixNMS(t *testing.T) {
+
+	dets := []result.DetectResult{
+		det(1, 0, 0.9, 0, 0, 100, 100),
+		det(2, 0, 0.8, 0, 0, 100, 100),
+		det(3, 0, 0.7, 200, 200, 300, 300),
+	}
+
+	// default MatrixSigma of 2 is independent of the SoftSigma of 0.5
+	p := DefaultParams()
+	p.Method = Matrix
+	p.ScoreThreshold = 0.2
+	res := New(p).Suppress(dets)
+
+	// duplicate box decays to 0.8*exp(-2) = 0.108, disjoint box is untouched
+	if !equalIDs(ids(res), []int64{1, 3}) {
+		t.Errorf("expected ID's [1 3], got %v", ids(res))
+	}
+
+	if math.Abs(float64(res[1].Probability-0.7)) > 1e-4 {
+		t.Errorf("expected disjoint box score 0.7, got %f", res[1].Probability)
+	}
+
+	p.ScoreThreshold = 0.001
+	res = New(p).Suppress(dets)
+	expected := float32(0.8 * math.Exp(-2))
+
+	if len(res) != 3 || math.Abs(float64(res[2].Probability-expected)) > 1e-4 {
+		t.Errorf("expected duplicate box score %f, got %v", expected, res)
+	}
+}
+
+func TestWBF(t *testing.T) {
+
+	dets := []result.DetectResult{
+		det(1, 0, 0.75, 0, 0, 100, 100),
+		det(2, 0, 0.25, 4, 4, 104, 104),
+		det(3, 0, 0.5, 300, 300, 400, 400),
+	}
+
+	p := DefaultParams()
+	p.Method = WBF
+	p.IoUThreshold = 0.55
+	res := New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 3}) {
+		t.Fatalf("expected ID's [1 3], got %v", ids(res))
+	}
+
+	expected := result.BoxRect{Left: 1, Top: 1, Right: 101, Bottom: 101}
+
+	if res[0].Box != expected {
+		t.Errorf("expected fused box %+v, got %+v", expected, res[0].Box)
+	}
+
+	if res[0].BoxF != expected.Float() {
+		t.Errorf("expected fused float box %+v, got %+v", expected.Float(), res[0].BoxF)
+	}
+
+	if math.Abs(float64(res[0].Probability-0.5)) > 1e-4 {
+		t.Errorf("expected fused score 0.5, got %f", res[0].Probability)
+	}
+}
+
+func TestClusterNMS(t *testing.T) {
+
+	dets := []result.DetectResult{
+		det(1, 0, 0.9, 0, 0, 100, 100),
+		// small box inside box 1 with a low IoU
+		det(2, 0, 0.8, 10, 10, 40, 40),
+		// larger box overlapping box 1 which is kept for the cluster
+		det(3, 0, 0.7, 0, 0, 110, 100),
+		det(4, 0, 0.6, 300, 300, 400, 400),
+	}
+
+	p := DefaultParams()
+	p.Method = Cluster
+	p.SmallBoxOverlap = 0.7
+	res := New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{3, 4}) {
+		t.Errorf("expected ID's [3 4], got %v", ids(res))
+	}
+
+	p.SmallBoxOverlap = 1
+	res = New(p).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{2, 3, 4}) {
+		t.Errorf("without small box overlap: expected ID's [2 3 4], got %v", ids(res))
+	}
+}
+
+func TestSubPixelBoxes(t *testing.T) {
+
+	// the whole pixel boxes are identical but the sub-pixel boxes only
+	// overlap by an IoU of 0.25
+	subPixel := func(id int64, prob float32, left float32) result.DetectResult {
+		boxF := result.BoxRectF{Left: left, Top: 0, Right: left + 1, Bottom: 1}
+
+		return result.DetectResult{
+			ID:          id,
+			Probability: prob,
+			Box:         result.BoxRect{Left: 0, Top: 0, Right: 1, Bottom: 1},
+			BoxF:        boxF,
+		}
+	}
+
+	dets := []result.DetectResult{
+		subPixel(1, 0.9, 0.1),
+		subPixel(2, 0.8, 0.7),
+	}
+
+	res := New(DefaultParams()).Suppress(dets)
+
+	if !equalIDs(ids(res), []int64{1, 2}) {
+		t.Errorf("expected ID's [1 2], got %v", ids(res))
+	}
+}
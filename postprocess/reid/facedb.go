@@ -0,0 +1,192 @@
+package reid
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FaceDBParams defines the struct containing the parameters for the FaceDB
+type FaceDBParams struct {
+	// Dist is the distance method used to compare face embeddings
+	Dist DistanceMethod
+	// Threshold is the maximum distance between a face and an enrolled face
+	// for it to be identified as that person
+	Threshold float32
+}
+
+// DefaultFaceDBParams returns an instance of FaceDBParams suitable for
+// ArcFace embeddings compared by cosine distance
+func DefaultFaceDBParams() FaceDBParams {
+	return FaceDBParams{
+		Dist:      Cosine,
+		Threshold: 0.6,
+	}
+}
+
+// FaceDB stores the face embeddings of known people to identify faces by.
+// Each person can be enrolled with several faces, such as from different
+// angles, and a face is compared against all of them by exhaustive search.
+// For large numbers of identities use a Gallery instead.  A FaceDB is safe
+// for concurrent use.
+type FaceDB struct {
+	// params are the face database parameters
+	params FaceDBParams
+	// dist is the distance function of the distance method
+	dist func(a, b []float32) float32
+	// dim is the embedding dimension, zero until the first face is enrolled
+	dim int
+	// faces are the enrolled face embeddings keyed by name
+	faces map[string][][]float32
+	// mu protects the database state
+	mu sync.RWMutex
+}
+
+// NewFaceDB returns an empty FaceDB
+func NewFaceDB(params FaceDBParams) (*FaceDB, error) {
+
+	dist, err := DistanceFunc(params.Dist)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FaceDB{
+		params: params,
+		dist:   dist,
+		faces:  make(map[string][][]float32),
+	}, nil
+}
+
+// Enrol adds the face embedding to the named person, creating the person if
+// they are not enrolled.  The embedding is L2 normalized when added.
+func (db *FaceDB) Enrol(name string, embedding []float32) error {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(embedding) == 0 {
+		return fmt.Errorf("empty embedding for person %q", name)
+	}
+
+	if db.dim == 0 {
+		db.dim = len(embedding)
+	} else if len(embedding) != db.dim {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDimension, db.dim, len(embedding))
+	}
+
+	vec := NormalizeVec(append([]float32(nil), embedding...))
+	db.faces[name] = append(db.faces[name], vec)
+
+	return nil
+}
+
+// Identify returns the enrolled person closest to the face embedding and
+// true if their distance is within the threshold.  The distance to a person
+// is that of their closest enrolled face.
+func (db *FaceDB) Identify(embedding []float32) (Match, bool, error) {
+
+	matches, err := db.Search(embedding, 1)
+
+	if err != nil || len(matches) == 0 {
+		return Match{}, false, err
+	}
+
+	return matches[0], matches[0].Distance <= db.params.Threshold, nil
+}
+
+// Search returns up to k enrolled people ordered by closest distance to the
+// face embedding, regardless of the threshold
+func (db *FaceDB) Search(embedding []float32, k int) ([]Match, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if len(db.faces) == 0 || k <= 0 {
+		return nil, nil
+	}
+
+	if len(embedding) != db.dim {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrDimension, db.dim, len(embedding))
+	}
+
+	query := NormalizeVec(embedding)
+	matches := make([]Match, 0, len(db.faces))
+
+	for name, faces := range db.faces {
+
+		best := Match{ID: name, Distance: db.dist(query, faces[0])}
+
+		for _, face := range faces[1:] {
+			if d := db.dist(query, face); d < best.Distance {
+				best.Distance = d
+			}
+		}
+
+		matches = append(matches, best)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+
+	return matches, nil
+}
+
+// Remove deletes the named person and all their faces, returning false if
+// they are not enrolled
+func (db *FaceDB) Remove(name string) bool {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.faces[name]; !ok {
+		return false
+	}
+
+	delete(db.faces, name)
+
+	return true
+}
+
+// Names returns the names of the enrolled people in sorted order
+func (db *FaceDB) Names() []string {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	names := make([]string, 0, len(db.faces))
+
+	for name := range db.faces {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Faces returns the number of faces enrolled for the named person
+func (db *FaceDB) Faces(name string) int {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return len(db.faces[name])
+}
+
+// Len returns the number of enrolled people
+func (db *FaceDB) Len() int {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return len(db.faces)
+}
@@ -0,0 +1,105 @@
+package reid
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestFaceDBIdentify(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(3))
+	people := randomVecs(rng, 3, 128)
+	names := []string{"alice", "bob", "carol"}
+
+	db, err := NewFaceDB(DefaultFaceDBParams())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// enrol each person with two faces
+	for i, name := range names {
+		for j := 0; j < 2; j++ {
+			if err := db.Enrol(name, jitter(rng, people[i], 0.05)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	if db.Len() != 3 || db.Faces("bob") != 2 {
+		t.Fatalf("expected 3 people with 2 faces, got %d with %d", db.Len(),
+			db.Faces("bob"))
+	}
+
+	for i, name := range names {
+
+		m, ok, err := db.Identify(jitter(rng, people[i], 0.05))
+
+		if err != nil || !ok || m.ID != name {
+			t.Errorf("expected %s identified, got %+v %v %v", name, m, ok, err)
+		}
+	}
+
+	// a stranger is closest to someone but not within the threshold
+	if m, ok, _ := db.Identify(randomVecs(rng, 1, 128)[0]); ok || m.ID == "" {
+		t.Errorf("expected stranger not identified, got %+v %v", m, ok)
+	}
+
+	matches, err := db.Search(people[1], 5)
+
+	if err != nil || len(matches) != 3 || matches[0].ID != "bob" ||
+		matches[1].Distance < matches[0].Distance {
+		t.Errorf("expected 3 matches ordered by distance, got %+v %v", matches, err)
+	}
+
+	if _, _, err := db.Identify(make([]float32, 64)); !errors.Is(err, ErrDimension) {
+		t.Errorf("expected dimension error, got %v", err)
+	}
+
+	if err := db.Enrol("dave", make([]float32, 64)); !errors.Is(err, ErrDimension) {
+		t.Errorf("expected dimension error, got %v", err)
+	}
+
+	if !db.Remove("bob") || db.Remove("bob") {
+		t.Errorf("expected bob removed once")
+	}
+
+	if got := db.Names(); len(got) != 2 || got[0] != "alice" || got[1] != "carol" {
+		t.Errorf("unexpected names %v", got)
+	}
+}
+
+func TestFaceDBDistance(t *testing.T) {
+
+	if _, err := NewFaceDB(FaceDBParams{Threshold: 0.5}); err == nil {
+		t.Errorf("expected error for unknown distance method")
+	}
+
+	db, err := NewFaceDB(FaceDBParams{Dist: Euclidean, Threshold: 0.5})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := db.Enrol("alice", []float32{3, 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// embeddings are normalized before comparing
+	m, ok, err := db.Identify([]float32{6, 8})
+
+	if err != nil || !ok || m.Distance > 1e-6 {
+		t.Errorf("expected exact match, got %+v %v %v", m, ok, err)
+	}
+
+	if _, ok, _ := db.Identify([]float32{0, 1}); ok {
+		t.Errorf("expected no match beyond the threshold")
+	}
+
+	empty, _ := NewFaceDB(DefaultFaceDBParams())
+
+	if _, ok, err := empty.Identify([]float32{1, 0}); ok || err != nil {
+		t.Errorf("expected no match from empty database, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,643 @@
+package reid
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DistanceMethod defines the distance used to compare embeddings
+type DistanceMethod int
+
+const (
+	// Euclidean is the L2 distance between normalized embeddings
+	Euclidean DistanceMethod = 1
+	// Cosine is the cosine distance between normalized embeddings
+	Cosine DistanceMethod = 2
+)
+
+// galleryMagic identifies a saved Gallery file
+var galleryMagic = [4]byte{'R', 'I', 'D', 'G'}
+
+// galleryVersion is the version of the saved Gallery format
+const galleryVersion = 1
+
+// ErrDimension is returned when an embedding has a different dimension to
+// those already in the Gallery
+var ErrDimension = errors.New("embedding dimension mismatch")
+
+// GalleryParams defines the struct containing the parameters for the Gallery
+type GalleryParams struct {
+	// MaxExemplars is the number of embeddings kept per identity, once
+	// exceeded the oldest is replaced
+	MaxExemplars int
+	// Alpha is the weight of the previous aggregated embedding when adding a
+	// new embedding to the exponential moving average
+	Alpha float32
+	// M is the number of neighbours connected per node in the HNSW index
+	M int
+	// EfConstruction is the size of the candidate list when inserting into
+	// the HNSW index, higher values build a more accurate index slower
+	EfConstruction int
+	// EfSearch is the size of the candidate list when searching the HNSW
+	// index, higher values give better recall slower
+	EfSearch int
+}
+
+// DefaultGalleryParams returns an instance of GalleryParams with defaults
+// suitable for galleries of 100k+ embeddings
+func DefaultGalleryParams() GalleryParams {
+	return GalleryParams{
+		MaxExemplars:   5,
+		Alpha:          0.9,
+		M:              16,
+		EfConstruction: 100,
+		EfSearch:       64,
+	}
+}
+
+// Identity is a person or object stored in the Gallery
+type Identity struct {
+	// ID is the unique identifier of the identity
+	ID string
+	// Embedding is the exponential moving average of all embeddings added
+	Embedding []float32
+	// Exemplars are the most recent embeddings added
+	Exemplars [][]float32
+	// Count is the total number of embeddings added
+	Count int
+}
+
+// Match is the result of a Gallery query
+type Match struct {
+	// ID is the identifier of the matched identity
+	ID string
+	// Distance is the distance between the query and the closest of the
+	// identity's exemplars or aggregated embedding
+	Distance float32
+}
+
+// identity is the stored state of an Identity
+type identity struct {
+	Identity
+	// nodes are the HNSW node IDs of the exemplars
+	nodes []int32
+}
+
+// Gallery stores embeddings for a set of identities and answers nearest
+// neighbour queries.  Each identity keeps several exemplar embeddings along
+// with an exponential moving average of all its embeddings.  Exemplars are
+// indexed in an HNSW graph so queries remain fast for large galleries.
+// Embeddings are L2 normalized when added.  A Gallery is safe for concurrent
+// use.
+type Gallery struct {
+	// params are the gallery parameters
+	params GalleryParams
+	// dim is the embedding dimension, zero until the first embedding is added
+	dim int
+	// identities are the stored identities keyed by ID
+	identities map[string]*identity
+	// index is the nearest neighbour index of exemplars
+	index *hnsw
+	// mu protects the gallery state
+	mu sync.RWMutex
+}
+
+// NewGallery returns an empty Gallery
+func NewGallery(params GalleryParams) *Gallery {
+
+	def := DefaultGalleryParams()
+
+	if params.MaxExemplars <= 0 {
+		params.MaxExemplars = def.MaxExemplars
+	}
+
+	if params.M <= 0 {
+		params.M = def.M
+	}
+
+	if params.EfConstruction <= 0 {
+		params.EfConstruction = def.EfConstruction
+	}
+
+	if params.EfSearch <= 0 {
+		params.EfSearch = def.EfSearch
+	}
+
+	return &Gallery{
+		params:     params,
+		identities: make(map[string]*identity),
+		index:      newHNSW(params.M, params.EfConstruction, params.EfSearch),
+	}
+}
+
+// Add adds the embedding to the identity, creating the identity if it does
+// not exist
+func (g *Gallery) Add(id string, embedding []float32) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(embedding) == 0 {
+		return fmt.Errorf("empty embedding for identity %q", id)
+	}
+
+	if g.dim == 0 {
+		g.dim = len(embedding)
+	} else if len(embedding) != g.dim {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDimension, g.dim, len(embedding))
+	}
+
+	vec := NormalizeVec(append([]float32(nil), embedding...))
+
+	ident, ok := g.identities[id]
+
+	if !ok {
+		ident = &identity{
+			Identity: Identity{
+				ID:        id,
+				Embedding: append([]float32(nil), vec...),
+			},
+		}
+		g.identities[id] = ident
+
+	} else {
+		// update the exponential moving average
+		for i := range ident.Embedding {
+			ident.Embedding[i] = g.params.Alpha*ident.Embedding[i] +
+				(1-g.params.Alpha)*vec[i]
+		}
+
+		ident.Embedding = NormalizeVec(ident.Embedding)
+	}
+
+	ident.Count++
+	ident.Exemplars = append(ident.Exemplars, vec)
+	ident.nodes = append(ident.nodes, g.index.insert(vec, id))
+
+	if len(ident.Exemplars) > g.params.MaxExemplars {
+		g.removeExemplar(ident, 0)
+		g.compact()
+	}
+
+	return nil
+}
+
+// Remove removes the identity and all its embeddings, returning false if the
+// identity does not exist
+func (g *Gallery) Remove(id string) bool {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ident, ok := g.identities[id]
+
+	if !ok {
+		return false
+	}
+
+	for _, n := range ident.nodes {
+		g.index.remove(n)
+	}
+
+	delete(g.identities, id)
+	g.compact()
+
+	return true
+}
+
+// RemoveExemplar removes the exemplar at the index from the identity.  The
+// identity is removed when its last exemplar is removed.
+func (g *Gallery) RemoveExemplar(id string, index int) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ident, ok := g.identities[id]
+
+	if !ok {
+		return fmt.Errorf("identity %q not found", id)
+	}
+
+	if index < 0 || index >= len(ident.Exemplars) {
+		return fmt.Errorf("exemplar index %d out of range for identity %q with %d exemplars",
+			index, id, len(ident.Exemplars))
+	}
+
+	g.removeExemplar(ident, index)
+
+	if len(ident.Exemplars) == 0 {
+		delete(g.identities, id)
+	}
+
+	g.compact()
+
+	return nil
+}
+
+// removeExemplar removes the exemplar at the index from the identity and
+// the index
+func (g *Gallery) removeExemplar(ident *identity, index int) {
+
+	g.index.remove(ident.nodes[index])
+
+	ident.Exemplars = append(ident.Exemplars[:index], ident.Exemplars[index+1:]...)
+	ident.nodes = append(ident.nodes[:index], ident.nodes[index+1:]...)
+}
+
+// compact rebuilds the index once more than half its nodes are deleted, as
+// deleted nodes are kept in the graph
+func (g *Gallery) compact() {
+
+	if g.index.deleted < 1024 || g.index.deleted*2 < len(g.index.nodes) {
+		return
+	}
+
+	g.rebuild()
+}
+
+// rebuild creates a new index of all exemplars in identity order
+func (g *Gallery) rebuild() {
+
+	g.index = newHNSW(g.params.M, g.params.EfConstruction, g.params.EfSearch)
+
+	for _, id := range g.sortedIDs() {
+
+		ident := g.identities[id]
+
+		for i, vec := range ident.Exemplars {
+			ident.nodes[i] = g.index.insert(vec, id)
+		}
+	}
+}
+
+// sortedIDs returns the identity IDs in sorted order
+func (g *Gallery) sortedIDs() []string {
+
+	ids := make([]string, 0, len(g.identities))
+
+	for id := range g.identities {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Get returns a copy of the identity
+func (g *Gallery) Get(id string) (Identity, bool) {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	ident, ok := g.identities[id]
+
+	if !ok {
+		return Identity{}, false
+	}
+
+	res := Identity{
+		ID:        ident.ID,
+		Embedding: append([]float32(nil), ident.Embedding...),
+		Exemplars: make([][]float32, len(ident.Exemplars)),
+		Count:     ident.Count,
+	}
+
+	for i, e := range ident.Exemplars {
+		res.Exemplars[i] = append([]float32(nil), e...)
+	}
+
+	return res, true
+}
+
+// IDs returns the identity IDs in sorted order
+func (g *Gallery) IDs() []string {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.sortedIDs()
+}
+
+// Len returns the number of identities
+func (g *Gallery) Len() int {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return len(g.identities)
+}
+
+// Size returns the number of exemplar embeddings stored
+func (g *Gallery) Size() int {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.index.len()
+}
+
+// Dim returns the embedding dimension, or zero if the Gallery is empty
+func (g *Gallery) Dim() int {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.dim
+}
+
+// Search returns up to k identities closest to the embedding in ascending
+// distance using the distance method.  The distance of an identity is to
+// the closest of its exemplars and aggregated embedding.
+func (g *Gallery) Search(embedding []float32, k int, method DistanceMethod) ([]Match, error) {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if k <= 0 || len(g.identities) == 0 {
+		return nil, nil
+	}
+
+	if len(embedding) != g.dim {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrDimension, g.dim, len(embedding))
+	}
+
+	dist, err := DistanceFunc(method)
+
+	if err != nil {
+		return nil, err
+	}
+
+	q := NormalizeVec(embedding)
+
+	// find the nearest exemplars, fetching more than k as identities can have
+	// several exemplars close to the query
+	cands := g.index.search(q, k*g.params.MaxExemplars)
+
+	seen := make(map[string]bool)
+	matches := make([]Match, 0, k)
+
+	for _, c := range cands {
+
+		id := g.index.nodes[c.id].label
+
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		ident := g.identities[id]
+		best := dist(q, ident.Embedding)
+
+		for _, e := range ident.Exemplars {
+			if d := dist(q, e); d < best {
+				best = d
+			}
+		}
+
+		matches = append(matches, Match{ID: id, Distance: best})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+
+	return matches, nil
+}
+
+// Nearest returns the closest identity to the embedding if its distance is
+// within the threshold
+func (g *Gallery) Nearest(embedding []float32, threshold float32,
+	method DistanceMethod) (Match, bool, error) {
+
+	matches, err := g.Search(embedding, 1, method)
+
+	if err != nil || len(matches) == 0 || matches[0].Distance > threshold {
+		return Match{}, false, err
+	}
+
+	return matches[0], true, nil
+}
+
+// DistanceFunc returns the distance function of the method
+func DistanceFunc(method DistanceMethod) (func(a, b []float32) float32, error) {
+
+	switch method {
+	case Euclidean:
+		return EuclideanDistance, nil
+	case Cosine:
+		return CosineDistance, nil
+	default:
+		return nil, fmt.Errorf("unknown distance method %d", method)
+	}
+}
+
+// Save writes the Gallery in binary format.  The HNSW index is not saved and
+// is rebuilt by LoadGallery().
+func (g *Gallery) Save(w io.Writer) error {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	header := []interface{}{
+		galleryMagic,
+		uint32(galleryVersion),
+		uint32(g.dim),
+		uint32(g.params.MaxExemplars),
+		g.params.Alpha,
+		uint32(g.params.M),
+		uint32(g.params.EfConstruction),
+		uint32(g.params.EfSearch),
+		uint32(len(g.identities)),
+	}
+
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("failed to write gallery header: %w", err)
+		}
+	}
+
+	for _, id := range g.sortedIDs() {
+		if err := writeIdentity(bw, g.identities[id]); err != nil {
+			return fmt.Errorf("failed to write identity %q: %w", id, err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeIdentity writes the identity in binary format
+func writeIdentity(w io.Writer, ident *identity) error {
+
+	fields := []interface{}{
+		uint32(len(ident.ID)),
+		[]byte(ident.ID),
+		uint64(ident.Count),
+		ident.Embedding,
+		uint32(len(ident.Exemplars)),
+	}
+
+	for _, e := range ident.Exemplars {
+		fields = append(fields, e)
+	}
+
+	for _, v := range fields {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveFile writes the Gallery to the file
+func (g *Gallery) SaveFile(path string) error {
+
+	f, err := os.Create(path)
+
+	if err != nil {
+		return fmt.Errorf("failed to create gallery file: %w", err)
+	}
+
+	if err := g.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// LoadGallery reads a Gallery written by Save() and rebuilds its index
+func LoadGallery(r io.Reader) (*Gallery, error) {
+
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Magic          [4]byte
+		Version        uint32
+		Dim            uint32
+		MaxExemplars   uint32
+		Alpha          float32
+		M              uint32
+		EfConstruction uint32
+		EfSearch       uint32
+		Identities     uint32
+	}
+
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read gallery header: %w", err)
+	}
+
+	if header.Magic != galleryMagic {
+		return nil, fmt.Errorf("not a gallery file")
+	}
+
+	if header.Version != galleryVersion {
+		return nil, fmt.Errorf("unsupported gallery version %d", header.Version)
+	}
+
+	g := NewGallery(GalleryParams{
+		MaxExemplars:   int(header.MaxExemplars),
+		Alpha:          header.Alpha,
+		M:              int(header.M),
+		EfConstruction: int(header.EfConstruction),
+		EfSearch:       int(header.EfSearch),
+	})
+
+	g.dim = int(header.Dim)
+
+	for i := uint32(0); i < header.Identities; i++ {
+
+		ident, err := readIdentity(br, g.dim)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity %d: %w", i, err)
+		}
+
+		g.identities[ident.ID] = ident
+	}
+
+	g.rebuild()
+
+	return g, nil
+}
+
+// readIdentity reads an identity in binary format
+func readIdentity(r io.Reader, dim int) (*identity, error) {
+
+	var idLen uint32
+
+	if err := binary.Read(r, binary.LittleEndian, &idLen); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, idLen)
+
+	if _, err := io.ReadFull(r, id); err != nil {
+		return nil, err
+	}
+
+	var count uint64
+
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+
+	ident := &identity{
+		Identity: Identity{
+			ID:        string(id),
+			Embedding: make([]float32, dim),
+			Count:     int(count),
+		},
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, ident.Embedding); err != nil {
+		return nil, err
+	}
+
+	var n uint32
+
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+
+	ident.Exemplars = make([][]float32, n)
+	ident.nodes = make([]int32, n)
+
+	for i := range ident.Exemplars {
+
+		ident.Exemplars[i] = make([]float32, dim)
+
+		if err := binary.Read(r, binary.LittleEndian, ident.Exemplars[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ident, nil
+}
+
+// LoadGalleryFile reads a Gallery from the file written by SaveFile()
+func LoadGalleryFile(path string) (*Gallery, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gallery file: %w", err)
+	}
+
+	defer f.Close()
+
+	return LoadGallery(f)
+}
@@ -0,0 +1,366 @@
+package reid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// randomVecs returns n random normalized vectors of the dimension
+func randomVecs(rng *rand.Rand, n, dim int) [][]float32 {
+
+	vecs := make([][]float32, n)
+
+	for i := range vecs {
+
+		v := make([]float32, dim)
+
+		for j := range v {
+			v[j] = float32(rng.NormFloat64())
+		}
+
+		vecs[i] = NormalizeVec(v)
+	}
+
+	return vecs
+}
+
+// jitter returns the vector with random noise added
+func jitter(rng *rand.Rand, v []float32, scale float32) []float32 {
+
+	res := make([]float32, len(v))
+
+	for i := range v {
+		res[i] = v[i] + scale*float32(rng.NormFloat64())
+	}
+
+	return res
+}
+
+func TestHNSWRecall(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(7))
+	vecs := randomVecs(rng, 3000, 32)
+
+	h := newHNSW(16, 200, 64)
+
+	for i, v := range vecs {
+		h.insert(v, fmt.Sprint(i))
+	}
+
+	const k = 10
+	queries := randomVecs(rng, 50, 32)
+	found := 0
+
+	for _, q := range queries {
+
+		// brute force nearest neighbours
+		exact := make([]candidate, len(vecs))
+
+		for i, v := range vecs {
+			exact[i] = candidate{int32(i), CosineDistance(q, v)}
+		}
+
+		sort.Slice(exact, func(i, j int) bool {
+			return exact[i].dist < exact[j].dist
+		})
+
+		want := make(map[int32]bool)
+
+		for _, c := range exact[:k] {
+			want[c.id] = true
+		}
+
+		for _, c := range h.search(q, k) {
+			if want[c.id] {
+				found++
+			}
+		}
+	}
+
+	if recall := float32(found) / float32(k*len(queries)); recall < 0.95 {
+		t.Errorf("expected recall of at least 0.95, got %f", recall)
+	}
+}
+
+func TestHNSWRemove(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(3))
+	vecs := randomVecs(rng, 200, 16)
+
+	h := newHNSW(8, 100, 32)
+
+	for i, v := range vecs {
+		h.insert(v, fmt.Sprint(i))
+	}
+
+	h.remove(5)
+
+	if res := h.search(vecs[5], 1); len(res) != 1 || res[0].id == 5 {
+		t.Errorf("expected removed node to be excluded, got %v", res)
+	}
+
+	if h.len() != 199 {
+		t.Errorf("expected 199 nodes, got %d", h.len())
+	}
+}
+
+func TestGallerySearch(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+	g := NewGallery(DefaultGalleryParams())
+	people := randomVecs(rng, 100, 64)
+
+	for i, p := range people {
+		for j := 0; j < 3; j++ {
+			if err := g.Add(fmt.Sprintf("person%d", i), jitter(rng, p, 0.05)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	if g.Len() != 100 || g.Size() != 300 {
+		t.Fatalf("expected 100 identities and 300 embeddings, got %d and %d",
+			g.Len(), g.Size())
+	}
+
+	for _, method := range []DistanceMethod{Cosine, Euclidean} {
+
+		matches, err := g.Search(jitter(rng, people[42], 0.05), 3, method)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(matches) != 3 || matches[0].ID != "person42" {
+			t.Fatalf("method %d: expected person42 as closest, got %v", method, matches)
+		}
+
+		if matches[0].Distance > matches[1].Distance ||
+			matches[1].Distance > matches[2].Distance {
+			t.Errorf("method %d: expected ascending distances, got %v", method, matches)
+		}
+	}
+
+	// euclidean and cosine distances of normalized vectors are related by
+	// d_euclidean^2 = 2 * d_cosine
+	q := people[7]
+	cos, _ := g.Search(q, 1, Cosine)
+	euc, _ := g.Search(q, 1, Euclidean)
+
+	if d := euc[0].Distance*euc[0].Distance - 2*cos[0].Distance; d > 1e-4 || d < -1e-4 {
+		t.Errorf("unexpected distances cosine %f euclidean %f", cos[0].Distance, euc[0].Distance)
+	}
+
+	if _, err := g.Search(make([]float32, 8), 1, Cosine); !errors.Is(err, ErrDimension) {
+		t.Errorf("expected dimension error, got %v", err)
+	}
+
+	if err := g.Add("other", make([]float32, 8)); !errors.Is(err, ErrDimension) {
+		t.Errorf("expected dimension error, got %v", err)
+	}
+
+	if _, err := g.Search(q, 1, DistanceMethod(0)); err == nil {
+		t.Errorf("expected error for unknown distance method")
+	}
+}
+
+func TestGalleryExemplars(t *testing.T) {
+
+	g := NewGallery(GalleryParams{MaxExemplars: 2, Alpha: 0.5})
+
+	a := []float32{1, 0, 0}
+	b := []float32{0, 2, 0}
+	c := []float32{0, 0, 3}
+
+	for _, v := range [][]float32{a, b, c} {
+		if err := g.Add("x", v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ident, ok := g.Get("x")
+
+	if !ok {
+		t.Fatalf("expected identity x")
+	}
+
+	// oldest exemplar replaced
+	if ident.Count != 3 || len(ident.Exemplars) != 2 ||
+		ident.Exemplars[0][1] != 1 || ident.Exemplars[1][2] != 1 {
+		t.Errorf("unexpected exemplars %v", ident.Exemplars)
+	}
+
+	if g.Size() != 2 {
+		t.Errorf("expected 2 indexed embeddings, got %d", g.Size())
+	}
+
+	// EMA of normalized embeddings is normalized after each update
+	var norm float32
+
+	for _, v := range ident.Embedding {
+		norm += v * v
+	}
+
+	if norm < 0.999 || norm > 1.001 || ident.Embedding[2] <= ident.Embedding[0] {
+		t.Errorf("unexpected aggregated embedding %v", ident.Embedding)
+	}
+
+	// query matches the aggregate when closer than the exemplars
+	m, ok, err := g.Nearest(a, 0.6, Cosine)
+
+	if err != nil || !ok || m.ID != "x" {
+		t.Errorf("expected match of x, got %v %v %v", m, ok, err)
+	}
+
+	if err := g.RemoveExemplar("x", 5); err == nil {
+		t.Errorf("expected error for exemplar out of range")
+	}
+
+	if err := g.RemoveExemplar("x", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := g.RemoveExemplar("x", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if g.Len() != 0 || g.Size() != 0 {
+		t.Errorf("expected identity removed with its last exemplar")
+	}
+}
+
+func TestGalleryRemove(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(2))
+	g := NewGallery(DefaultGalleryParams())
+	vecs := randomVecs(rng, 50, 16)
+
+	for i, v := range vecs {
+		g.Add(fmt.Sprint(i), v)
+	}
+
+	if !g.Remove("10") || g.Remove("10") {
+		t.Fatalf("expected identity to be removed once")
+	}
+
+	matches, _ := g.Search(vecs[10], 5, Cosine)
+
+	for _, m := range matches {
+		if m.ID == "10" {
+			t.Errorf("expected removed identity not to match")
+		}
+	}
+
+	if g.Len() != 49 || g.Size() != 49 {
+		t.Errorf("expected 49 identities, got %d", g.Len())
+	}
+}
+
+func TestGalleryCompact(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(4))
+	g := NewGallery(GalleryParams{MaxExemplars: 1, M: 8, EfConstruction: 50})
+	vecs := randomVecs(rng, 3000, 8)
+
+	for i, v := range vecs {
+		g.Add(fmt.Sprint(i), v)
+	}
+
+	for i := 0; i < 2000; i++ {
+		g.Remove(fmt.Sprint(i))
+	}
+
+	if g.index.deleted != 0 && g.index.deleted*2 >= len(g.index.nodes) {
+		t.Errorf("expected index to be rebuilt, got %d deleted of %d",
+			g.index.deleted, len(g.index.nodes))
+	}
+
+	for _, i := range []int{2000, 2500, 2999} {
+
+		m, ok, _ := g.Nearest(vecs[i], 0.01, Cosine)
+
+		if !ok || m.ID != fmt.Sprint(i) {
+			t.Errorf("expected %d after rebuild, got %v", i, m)
+		}
+	}
+}
+
+func TestGallerySaveLoad(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(5))
+	g := NewGallery(DefaultGalleryParams())
+	vecs := randomVecs(rng, 20, 32)
+
+	for i, v := range vecs {
+		g.Add(fmt.Sprintf("id%d", i), v)
+		g.Add(fmt.Sprintf("id%d", i), jitter(rng, v, 0.1))
+	}
+
+	path := filepath.Join(t.TempDir(), "gallery.bin")
+
+	if err := g.SaveFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := LoadGalleryFile(path)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if loaded.Len() != 20 || loaded.Size() != 40 || loaded.Dim() != 32 {
+		t.Fatalf("unexpected loaded gallery of %d identities %d embeddings",
+			loaded.Len(), loaded.Size())
+	}
+
+	want, _ := g.Get("id3")
+	got, _ := loaded.Get("id3")
+
+	if got.Count != 2 || len(got.Exemplars) != 2 ||
+		got.Exemplars[1][0] != want.Exemplars[1][0] ||
+		got.Embedding[5] != want.Embedding[5] {
+		t.Errorf("loaded identity differs %v", got)
+	}
+
+	m, ok, _ := loaded.Nearest(vecs[9], 0.1, Euclidean)
+
+	if !ok || m.ID != "id9" {
+		t.Errorf("expected id9, got %v", m)
+	}
+
+	if _, err := LoadGallery(bytes.NewReader([]byte("nope"))); err == nil {
+		t.Errorf("expected error loading invalid data")
+	}
+}
+
+// benchGallery is the gallery of 100k embeddings shared by benchmark runs
+var (
+	benchGallery     *Gallery
+	benchGalleryOnce sync.Once
+)
+
+func BenchmarkGallerySearch(b *testing.B) {
+
+	benchGalleryOnce.Do(func() {
+
+		rng := rand.New(rand.NewSource(6))
+		benchGallery = NewGallery(DefaultGalleryParams())
+
+		for i, v := range randomVecs(rng, 100000, 128) {
+			benchGallery.Add(fmt.Sprint(i), v)
+		}
+	})
+
+	queries := randomVecs(rand.New(rand.NewSource(8)), 100, 128)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		benchGallery.Search(queries[i%len(queries)], 10, Cosine)
+	}
+}
@@ -0,0 +1,388 @@
+package reid
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// hnswNode is an embedding in the HNSW graph
+type hnswNode struct {
+	// vec is the L2 normalized embedding
+	vec []float32
+	// label is the identity the embedding belongs to
+	label string
+	// friends are the indexes of the neighbouring nodes on each level
+	friends [][]int32
+	// deleted is a flag to indicate the node has been removed.  Deleted nodes
+	// are kept in the graph for navigation but excluded from results.
+	deleted bool
+}
+
+// hnsw is a Hierarchical Navigable Small World graph for approximate nearest
+// neighbour search of L2 normalized embeddings by cosine distance, as
+// described in https://arxiv.org/abs/1603.09320
+type hnsw struct {
+	// m is the number of neighbours connected on the upper levels
+	m int
+	// m0 is the maximum number of neighbours on level 0
+	m0 int
+	// efConstruction is the size of the candidate list when inserting
+	efConstruction int
+	// efSearch is the size of the candidate list when searching
+	efSearch int
+	// levelMult is the normalization factor of the random level generation
+	levelMult float64
+	// nodes are all nodes in the graph indexed by their ID
+	nodes []*hnswNode
+	// entry is the ID of the entry point node on the top level, -1 if empty
+	entry int32
+	// maxLevel is the top level of the graph
+	maxLevel int
+	// deleted is the number of deleted nodes
+	deleted int
+	// rng generates the node levels
+	rng *rand.Rand
+}
+
+// candidate is a node and its distance to the query
+type candidate struct {
+	id   int32
+	dist float32
+}
+
+// bitset is a set of node IDs
+type bitset []uint64
+
+// set adds the node ID to the set
+func (b bitset) set(id int32) {
+	b[id>>6] |= 1 << (uint(id) & 63)
+}
+
+// has returns true if the node ID is in the set
+func (b bitset) has(id int32) bool {
+	return b[id>>6]&(1<<(uint(id)&63)) != 0
+}
+
+// minHeap is a heap of candidates with the closest on top
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// maxHeap is a heap of candidates with the furthest on top
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// newHNSW returns an empty graph connecting m neighbours per node
+func newHNSW(m, efConstruction, efSearch int) *hnsw {
+
+	if m < 2 {
+		m = 2
+	}
+
+	return &hnsw{
+		m:              m,
+		m0:             2 * m,
+		efConstruction: efConstruction,
+		efSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(m)),
+		entry:          -1,
+		rng:            rand.New(rand.NewSource(1)),
+	}
+}
+
+// len returns the number of nodes not deleted
+func (h *hnsw) len() int {
+	return len(h.nodes) - h.deleted
+}
+
+// distance returns the cosine distance between normalized vectors.  The dot
+// product is unrolled as it dominates the cost of building and searching.
+func (h *hnsw) distance(a, b []float32) float32 {
+
+	b = b[:len(a)]
+
+	var s0, s1, s2, s3 float32
+	i := 0
+
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+
+	return 1 - (s0 + s1 + s2 + s3)
+}
+
+// randomLevel returns the top level of a new node drawn from an exponentially
+// decaying distribution
+func (h *hnsw) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+// insert adds the normalized vector to the graph and returns its node ID
+func (h *hnsw) insert(vec []float32, label string) int32 {
+
+	id := int32(len(h.nodes))
+	level := h.randomLevel()
+
+	node := &hnswNode{
+		vec:     vec,
+		label:   label,
+		friends: make([][]int32, level+1),
+	}
+
+	h.nodes = append(h.nodes, node)
+
+	if h.entry < 0 {
+		h.entry = id
+		h.maxLevel = level
+		return id
+	}
+
+	ep := candidate{h.entry, h.distance(vec, h.nodes[h.entry].vec)}
+
+	// greedy descent through the levels above the node's top level
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(vec, ep, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+
+		cands := h.searchLayer(vec, ep, h.efConstruction, l)
+		neighbours := h.selectNeighbours(cands, h.m)
+
+		node.friends[l] = make([]int32, len(neighbours))
+
+		for i, n := range neighbours {
+			node.friends[l][i] = n.id
+			h.connect(n.id, id, l)
+		}
+
+		ep = cands[0]
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = id
+	}
+
+	return id
+}
+
+// remove marks the node as deleted
+func (h *hnsw) remove(id int32) {
+
+	if h.nodes[id].deleted {
+		return
+	}
+
+	h.nodes[id].deleted = true
+	h.deleted++
+}
+
+// search returns up to k of the nearest nodes not deleted to the normalized
+// query vector in ascending distance
+func (h *hnsw) search(q []float32, k int) []candidate {
+
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+
+	ep := candidate{h.entry, h.distance(q, h.nodes[h.entry].vec)}
+
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+
+	cands := h.searchLayer(q, ep, max(h.efSearch, k), 0)
+	res := make([]candidate, 0, k)
+
+	for _, c := range cands {
+
+		if h.nodes[c.id].deleted {
+			continue
+		}
+
+		res = append(res, c)
+
+		if len(res) == k {
+			break
+		}
+	}
+
+	return res
+}
+
+// greedy moves from the entry point to the closest neighbour on the level
+// until no neighbour is closer to the query
+func (h *hnsw) greedy(q []float32, ep candidate, level int) candidate {
+
+	for changed := true; changed; {
+
+		changed = false
+
+		for _, f := range h.nodes[ep.id].friends[level] {
+			if d := h.distance(q, h.nodes[f].vec); d < ep.dist {
+				ep = candidate{f, d}
+				changed = true
+			}
+		}
+	}
+
+	return ep
+}
+
+// searchLayer returns the ef nearest nodes to the query found on the level
+// starting from the entry point in ascending distance
+func (h *hnsw) searchLayer(q []float32, ep candidate, ef, level int) []candidate {
+
+	visited := make(bitset, (len(h.nodes)+63)/64)
+	visited.set(ep.id)
+	cands := &minHeap{ep}
+	results := &maxHeap{ep}
+
+	for cands.Len() > 0 {
+
+		c := heap.Pop(cands).(candidate)
+
+		// stop once the closest candidate is further than all results
+		if results.Len() >= ef && c.dist > (*results)[0].dist {
+			break
+		}
+
+		for _, f := range h.nodes[c.id].friends[level] {
+
+			if visited.has(f) {
+				continue
+			}
+
+			visited.set(f)
+			d := h.distance(q, h.nodes[f].vec)
+
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(cands, candidate{f, d})
+				heap.Push(results, candidate{f, d})
+
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	res := make([]candidate, results.Len())
+
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(results).(candidate)
+	}
+
+	return res
+}
+
+// selectNeighbours selects up to m neighbours from the candidates sorted in
+// ascending distance using the heuristic of keeping candidates closer to the
+// base node than to any neighbour already selected, so connections span
+// clusters.  Remaining places are filled with the closest pruned candidates.
+func (h *hnsw) selectNeighbours(cands []candidate, m int) []candidate {
+
+	if len(cands) <= m {
+		return cands
+	}
+
+	selected := make([]candidate, 0, m)
+	var pruned []candidate
+
+	for _, c := range cands {
+
+		if len(selected) == m {
+			break
+		}
+
+		keep := true
+
+		for _, s := range selected {
+			if h.distance(h.nodes[c.id].vec, h.nodes[s.id].vec) < c.dist {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			selected = append(selected, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+
+	for _, c := range pruned {
+
+		if len(selected) == m {
+			break
+		}
+
+		selected = append(selected, c)
+	}
+
+	return selected
+}
+
+// connect adds the new node as a neighbour of the node on the level, pruning
+// its neighbours if it exceeds the maximum connections
+func (h *hnsw) connect(id, newID int32, level int) {
+
+	node := h.nodes[id]
+	node.friends[level] = append(node.friends[level], newID)
+
+	maxConn := h.m
+
+	if level == 0 {
+		maxConn = h.m0
+	}
+
+	if len(node.friends[level]) <= maxConn {
+		return
+	}
+
+	cands := make([]candidate, len(node.friends[level]))
+
+	for i, f := range node.friends[level] {
+		cands[i] = candidate{f, h.distance(node.vec, h.nodes[f].vec)}
+	}
+
+	sort.Slice(cands, func(i, j int) bool {
+		return cands[i].dist < cands[j].dist
+	})
+
+	selected := h.selectNeighbours(cands, maxConn)
+	node.friends[level] = node.friends[level][:0]
+
+	for _, s := range selected {
+		node.friends[level] = append(node.friends[level], s.id)
+	}
+}
@@ -28,19 +28,82 @@ type BoxRect struct {
 	Mode BoxRectMode // Mode indicates how the BoxRect was set
 }
 
+// BoxRectF are the sub-pixel dimensions of the bounding box of a detect object.
+// The fields correspond to those of BoxRect.
+type BoxRectF struct {
+	Left   float32 // Left boundary of the bounding box
+	Right  float32 // Right boundary of the bounding box
+	Top    float32 // Top boundary of the bounding box
+	Bottom float32 // Bottom boundary of the bounding box
+
+	X      float32 // X coordinate of the bounding box center
+	Y      float32 // Y coordinate of the bounding box center
+	Width  float32 // Width of the bounding box
+	Height float32 // Height of the bounding box
+	Angle  float32 // Rotation angle of the bounding box in radians
+
+	Mode BoxRectMode // Mode indicates how the BoxRectF was set
+}
+
+// Rect returns the BoxRect of the float dimensions truncated to whole pixels
+func (b BoxRectF) Rect() BoxRect {
+	return BoxRect{
+		Left:   int(b.Left),
+		Right:  int(b.Right),
+		Top:    int(b.Top),
+		Bottom: int(b.Bottom),
+		X:      int(b.X),
+		Y:      int(b.Y),
+		Width:  int(b.Width),
+		Height: int(b.Height),
+		Angle:  b.Angle,
+		Mode:   b.Mode,
+	}
+}
+
+// Float returns the BoxRectF representation of the BoxRect
+func (b BoxRect) Float() BoxRectF {
+	return BoxRectF{
+		Left:   float32(b.Left),
+		Right:  float32(b.Right),
+		Top:    float32(b.Top),
+		Bottom: float32(b.Bottom),
+		X:      float32(b.X),
+		Y:      float32(b.Y),
+		Width:  float32(b.Width),
+		Height: float32(b.Height),
+		Angle:  b.Angle,
+		Mode:   b.Mode,
+	}
+}
+
 // DetectResult defines the attributes of a single object detected
 type DetectResult struct {
 	// Class is the line number in the labels file the Model was trained on
 	// defining the Class of the detected object
 	Class int
-	// Box are the bounding box dimensions of the object location
+	// Box are the bounding box dimensions of the object location in whole
+	// pixels for rendering
 	Box BoxRect
+	// BoxF are the sub-pixel bounding box dimensions of the object location
+	BoxF BoxRectF
 	// Probability is the confidence score of the object detected
 	Probability float32
 	// ID is a unique ID assigned to the detection result
 	ID int64
 }
 
+// FloatBox returns the sub-pixel bounding box of the object.  If BoxF has not
+// been set, such as for detection results created outside of the post
+// processors, then Box is converted instead.
+func (d DetectResult) FloatBox() BoxRectF {
+	if d.BoxF == (BoxRectF{}) {
+		return d.Box.Float()
+	}
+
+	return d.BoxF
+}
+
 // Keypoint is used for specifying the X, Y coordinates and confidence score
 // of an individual point used in Pose Estimatation
 type KeyPoint struct {
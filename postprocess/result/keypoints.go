@@ -0,0 +1,232 @@
+package result
+
+import (
+	"image/color"
+	"strconv"
+)
+
+// KeypointSchema describes the keypoints a pose or landmark Model is trained
+// on, how they connect to form a skeleton and the colors to render them with
+type KeypointSchema struct {
+	// Name of the schema
+	Name string
+	// KeyPointsNumber is the number of keypoints of each object
+	KeyPointsNumber int
+	// Names are the names of each keypoint, optional
+	Names []string
+	// Skeleton are the pairs of zero based keypoint indexes to draw lines
+	// between
+	Skeleton [][2]int
+	// FlipIndex is the index of the keypoint each keypoint becomes when the
+	// image is mirrored horizontally, such as left eye becoming right eye
+	FlipIndex []int
+	// PointColors are the colors of each keypoint
+	PointColors []color.RGBA
+	// LimbColors are the colors of each line of the Skeleton
+	LimbColors []color.RGBA
+}
+
+// Flip returns the keypoints of an object mirrored horizontally within an
+// image of the given width, with left and right keypoints swapped using the
+// FlipIndex
+func (k KeypointSchema) Flip(keyPoints []KeyPoint, width int) []KeyPoint {
+
+	flipped := make([]KeyPoint, len(keyPoints))
+
+	for i, kp := range keyPoints {
+
+		j := i
+
+		if i < len(k.FlipIndex) && k.FlipIndex[i] < len(keyPoints) {
+			j = k.FlipIndex[i]
+		}
+
+		flipped[j] = KeyPoint{
+			X:     width - 1 - kp.X,
+			Y:     kp.Y,
+			Score: kp.Score,
+		}
+	}
+
+	return flipped
+}
+
+// posePalette are the colors used for the keypoint schema presets
+var posePalette = []color.RGBA{
+	{R: 255, G: 128, B: 0, A: 255},
+	{R: 255, G: 153, B: 51, A: 255},
+	{R: 255, G: 178, B: 102, A: 255},
+	{R: 230, G: 230, B: 0, A: 255},
+	{R: 255, G: 153, B: 255, A: 255},
+	{R: 153, G: 204, B: 255, A: 255},
+	{R: 255, G: 102, B: 255, A: 255},
+	{R: 255, G: 51, B: 255, A: 255},
+	{R: 102, G: 178, B: 255, A: 255},
+	{R: 51, G: 153, B: 255, A: 255},
+	{R: 255, G: 153, B: 153, A: 255},
+	{R: 255, G: 102, B: 102, A: 255},
+	{R: 255, G: 51, B: 51, A: 255},
+	{R: 153, G: 255, B: 153, A: 255},
+	{R: 102, G: 255, B: 102, A: 255},
+	{R: 51, G: 255, B: 51, A: 255},
+	{R: 0, G: 255, B: 0, A: 255},
+	{R: 0, G: 0, B: 255, A: 255},
+	{R: 255, G: 0, B: 0, A: 255},
+	{R: 255, G: 255, B: 255, A: 255},
+}
+
+// COCO17Schema returns the 17 keypoint human body schema of the COCO dataset
+func COCO17Schema() KeypointSchema {
+
+	p := posePalette
+
+	return KeypointSchema{
+		Name:            "coco-17",
+		KeyPointsNumber: 17,
+		Names: []string{
+			"nose", "left_eye", "right_eye", "left_ear", "right_ear",
+			"left_shoulder", "right_shoulder", "left_elbow", "right_elbow",
+			"left_wrist", "right_wrist", "left_hip", "right_hip",
+			"left_knee", "right_knee", "left_ankle", "right_ankle",
+		},
+		Skeleton: [][2]int{
+			{15, 13}, {13, 11}, {16, 14}, {14, 12}, {11, 12}, {5, 11}, {6, 12},
+			{5, 6}, {5, 7}, {6, 8}, {7, 9}, {8, 10}, {1, 2}, {0, 1}, {0, 2},
+			{1, 3}, {2, 4}, {3, 5}, {4, 6},
+		},
+		FlipIndex: []int{0, 2, 1, 4, 3, 6, 5, 8, 7, 10, 9, 12, 11, 14, 13, 16, 15},
+		PointColors: []color.RGBA{
+			p[16], p[16], p[16], p[16], p[16], p[9], p[9], p[9], p[9], p[9],
+			p[9], p[0], p[0], p[0], p[0], p[0], p[0],
+		},
+		LimbColors: []color.RGBA{
+			p[0], p[0], p[0], p[0], p[7], p[7], p[7], p[9], p[9], p[9],
+			p[9], p[9], p[16], p[16], p[16], p[16], p[16], p[16], p[16],
+		},
+	}
+}
+
+// Hand21Schema returns the 21 keypoint hand schema used by MediaPipe and the
+// Ultralytics hand keypoints dataset
+func Hand21Schema() KeypointSchema {
+
+	s := KeypointSchema{
+		Name:            "hand-21",
+		KeyPointsNumber: 21,
+		Names:           []string{"wrist"},
+		FlipIndex:       make([]int, 21),
+		PointColors:     []color.RGBA{posePalette[19]},
+	}
+
+	// a hand has no left and right keypoints to swap
+	for i := range s.FlipIndex {
+		s.FlipIndex[i] = i
+	}
+
+	fingers := []string{"thumb", "index", "middle", "ring", "pinky"}
+	joints := [][]string{
+		{"cmc", "mcp", "ip", "tip"},
+		{"mcp", "pip", "dip", "tip"},
+	}
+	colors := []color.RGBA{
+		posePalette[0], posePalette[3], posePalette[16], posePalette[9],
+		posePalette[7],
+	}
+
+	for f, finger := range fingers {
+
+		base := 1 + f*4
+		names := joints[1]
+
+		if f == 0 {
+			names = joints[0]
+		}
+
+		for _, joint := range names {
+			s.Names = append(s.Names, finger+"_"+joint)
+			s.PointColors = append(s.PointColors, colors[f])
+		}
+
+		// each finger connects to the wrist then joint to joint
+		s.Skeleton = append(s.Skeleton, [2]int{0, base})
+		s.LimbColors = append(s.LimbColors, colors[f])
+
+		for j := base; j < base+3; j++ {
+			s.Skeleton = append(s.Skeleton, [2]int{j, j + 1})
+			s.LimbColors = append(s.LimbColors, colors[f])
+		}
+	}
+
+	// knuckles across the palm
+	for _, edge := range [][2]int{{5, 9}, {9, 13}, {13, 17}} {
+		s.Skeleton = append(s.Skeleton, edge)
+		s.LimbColors = append(s.LimbColors, posePalette[19])
+	}
+
+	return s
+}
+
+// Face68Schema returns the 68 keypoint face landmark schema of the iBUG 300-W
+// dataset
+func Face68Schema() KeypointSchema {
+
+	s := KeypointSchema{
+		Name:            "face-68",
+		KeyPointsNumber: 68,
+		PointColors:     make([]color.RGBA, 68),
+		FlipIndex: []int{
+			// jaw
+			16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+			// eyebrows
+			26, 25, 24, 23, 22, 21, 20, 19, 18, 17,
+			// nose
+			27, 28, 29, 30, 35, 34, 33, 32, 31,
+			// eyes
+			45, 44, 43, 42, 47, 46, 39, 38, 37, 36, 41, 40,
+			// outer lips
+			54, 53, 52, 51, 50, 49, 48, 59, 58, 57, 56, 55,
+			// inner lips
+			64, 63, 62, 61, 60, 67, 66, 65,
+		},
+	}
+
+	// facial features as ranges of keypoints joined in order, closed
+	// features also join the last keypoint to the first
+	features := []struct {
+		name   string
+		first  int
+		last   int
+		closed bool
+		color  color.RGBA
+	}{
+		{"jaw", 0, 16, false, posePalette[19]},
+		{"right_eyebrow", 17, 21, false, posePalette[0]},
+		{"left_eyebrow", 22, 26, false, posePalette[0]},
+		{"nose_bridge", 27, 30, false, posePalette[3]},
+		{"nose", 31, 35, false, posePalette[3]},
+		{"right_eye", 36, 41, true, posePalette[9]},
+		{"left_eye", 42, 47, true, posePalette[9]},
+		{"outer_lip", 48, 59, true, posePalette[12]},
+		{"inner_lip", 60, 67, true, posePalette[7]},
+	}
+
+	for _, f := range features {
+
+		for i := f.first; i <= f.last; i++ {
+			s.Names = append(s.Names, f.name+"_"+strconv.Itoa(i-f.first))
+			s.PointColors[i] = f.color
+
+			if i < f.last {
+				s.Skeleton = append(s.Skeleton, [2]int{i, i + 1})
+				s.LimbColors = append(s.LimbColors, f.color)
+			}
+		}
+
+		if f.closed {
+			s.Skeleton = append(s.Skeleton, [2]int{f.last, f.first})
+			s.LimbColors = append(s.LimbColors, f.color)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,87 @@
+package result
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeypointSchemaPresets(t *testing.T) {
+
+	schemas := []KeypointSchema{COCO17Schema(), Hand21Schema(), Face68Schema()}
+	edges := []int{19, 23, 63}
+
+	for i, s := range schemas {
+
+		n := s.KeyPointsNumber
+
+		if len(s.Names) != n || len(s.FlipIndex) != n || len(s.PointColors) != n {
+			t.Errorf("%s: expected %d names, flip indexes and colors, got %d, %d, %d",
+				s.Name, n, len(s.Names), len(s.FlipIndex), len(s.PointColors))
+		}
+
+		if len(s.Skeleton) != edges[i] || len(s.LimbColors) != len(s.Skeleton) {
+			t.Errorf("%s: expected %d limbs and colors, got %d, %d", s.Name,
+				edges[i], len(s.Skeleton), len(s.LimbColors))
+		}
+
+		for _, edge := range s.Skeleton {
+			if edge[0] < 0 || edge[0] >= n || edge[1] < 0 || edge[1] >= n {
+				t.Errorf("%s: skeleton edge %v out of range", s.Name, edge)
+			}
+		}
+
+		// flipping twice returns each keypoint to itself
+		for j, f := range s.FlipIndex {
+			if f < 0 || f >= n || s.FlipIndex[f] != j {
+				t.Errorf("%s: flip index %d of keypoint %d is not symmetric",
+					s.Name, f, j)
+			}
+		}
+	}
+
+	// left and right keypoints of the body swap names
+	coco := COCO17Schema()
+
+	for j, f := range coco.FlipIndex {
+
+		name := strings.Replace(coco.Names[j], "left", "right", 1)
+
+		if name == coco.Names[j] {
+			name = strings.Replace(coco.Names[j], "right", "left", 1)
+		}
+
+		if coco.Names[f] != name {
+			t.Errorf("expected %s to flip to %s, got %s", coco.Names[j], name,
+				coco.Names[f])
+		}
+	}
+}
+
+func TestKeypointSchemaFlip(t *testing.T) {
+
+	coco := COCO17Schema()
+	kps := make([]KeyPoint, coco.KeyPointsNumber)
+
+	for i := range kps {
+		kps[i] = KeyPoint{X: i, Y: 100 + i, Score: float32(i) / 20}
+	}
+
+	flipped := coco.Flip(kps, 640)
+
+	// left eye becomes the right eye mirrored across the image
+	if flipped[2] != (KeyPoint{X: 639 - 1, Y: 101, Score: kps[1].Score}) {
+		t.Errorf("unexpected flipped right eye %+v", flipped[2])
+	}
+
+	if flipped[0] != (KeyPoint{X: 639, Y: 100, Score: 0}) {
+		t.Errorf("unexpected flipped nose %+v", flipped[0])
+	}
+
+	back := coco.Flip(flipped, 640)
+
+	for i := range kps {
+		if back[i] != kps[i] {
+			t.Fatalf("expected flip to be reversible, got %+v", back)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package result
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// RLE is a COCO Run Length Encoding of a binary mask.  The mask is encoded in
+// column major order with Counts alternating between runs of background and
+// foreground pixels, starting with background.
+type RLE struct {
+	// Height of the mask in pixels
+	Height int
+	// Width of the mask in pixels
+	Width int
+	// Counts are the run lengths
+	Counts []int
+}
+
+// EncodeRLE returns the RLE of the pixels in the row major mask of the given
+// dimensions that have the given value
+func EncodeRLE(mask []uint8, width, height int, value uint8) RLE {
+	return EncodeRLERect(mask, width, height, value, image.Rect(0, 0, width, height))
+}
+
+// EncodeRLERect returns the RLE of the pixels in the row major mask of the
+// given dimensions that have the given value, only scanning the pixels within
+// the rect.  Pixels outside the rect are taken to be background, so the rect
+// must bound all pixels with the value for the RLE to match EncodeRLE().
+func EncodeRLERect(mask []uint8, width, height int, value uint8,
+	rect image.Rectangle) RLE {
+
+	rect = rect.Intersect(image.Rect(0, 0, width, height))
+
+	rle := RLE{
+		Height: height,
+		Width:  width,
+		Counts: make([]int, 0),
+	}
+
+	run := 0
+	fg := false
+
+	// add extends the current run by n pixels, starting a new run if the
+	// pixels are of the other kind
+	add := func(isFg bool, n int) {
+
+		if n == 0 {
+			return
+		}
+
+		if isFg != fg {
+			rle.Counts = append(rle.Counts, run)
+			run = 0
+			fg = isFg
+		}
+
+		run += n
+	}
+
+	if rect.Empty() {
+		rle.Counts = append(rle.Counts, width*height)
+		return rle
+	}
+
+	// columns left of the rect
+	add(false, rect.Min.X*height)
+
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+
+		add(false, rect.Min.Y)
+
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			add(mask[y*width+x] == value, 1)
+		}
+
+		add(false, height-rect.Max.Y)
+	}
+
+	// columns right of the rect
+	add(false, (width-rect.Max.X)*height)
+
+	rle.Counts = append(rle.Counts, run)
+
+	return rle
+}
+
+// Decode returns the row major mask of the RLE with foreground pixels set to
+// the given value and background pixels set to zero
+func (r RLE) Decode(value uint8) []uint8 {
+
+	mask := make([]uint8, r.Width*r.Height)
+	pos := 0
+	fg := false
+
+	for _, count := range r.Counts {
+		for i := 0; i < count && pos < len(mask); i++ {
+			if fg {
+				// convert column major position to row major
+				x := pos / r.Height
+				y := pos % r.Height
+				mask[y*r.Width+x] = value
+			}
+			pos++
+		}
+		fg = !fg
+	}
+
+	return mask
+}
+
+// Area returns the number of foreground pixels in the RLE
+func (r RLE) Area() int {
+
+	area := 0
+
+	for i := 1; i < len(r.Counts); i += 2 {
+		area += r.Counts[i]
+	}
+
+	return area
+}
+
+// String returns the compressed COCO string representation of the RLE counts
+// as used by pycocotools
+func (r RLE) String() string {
+
+	buf := make([]byte, 0, len(r.Counts)*2)
+
+	for i, count := range r.Counts {
+		x := int64(count)
+
+		if i > 2 {
+			x -= int64(r.Counts[i-2])
+		}
+
+		more := true
+
+		for more {
+			c := byte(x & 0x1f)
+			x >>= 5
+
+			if c&0x10 != 0 {
+				more = x != -1
+			} else {
+				more = x != 0
+			}
+
+			if more {
+				c |= 0x20
+			}
+
+			buf = append(buf, c+48)
+		}
+	}
+
+	return string(buf)
+}
+
+// ParseRLE returns the RLE from the compressed COCO string representation of
+// its counts
+func ParseRLE(counts string, width, height int) (RLE, error) {
+
+	rle := RLE{
+		Height: height,
+		Width:  width,
+		Counts: make([]int, 0),
+	}
+
+	p := 0
+
+	for p < len(counts) {
+		var x int64
+		k := 0
+		more := true
+
+		for more {
+			if p >= len(counts) {
+				return RLE{}, errors.New("truncated RLE counts string")
+			}
+
+			c := int64(counts[p]) - 48
+			x |= (c & 0x1f) << (5 * k)
+			more = c&0x20 != 0
+			p++
+			k++
+
+			if !more && c&0x10 != 0 {
+				x |= -1 << (5 * k)
+			}
+		}
+
+		if len(rle.Counts) > 2 {
+			x += int64(rle.Counts[len(rle.Counts)-2])
+		}
+
+		rle.Counts = append(rle.Counts, int(x))
+	}
+
+	return rle, nil
+}
+
+// rleJSON is the COCO JSON representation of an RLE
+type rleJSON struct {
+	Size   [2]int          `json:"size"`
+	Counts json.RawMessage `json:"counts"`
+}
+
+// MarshalJSON encodes the RLE in the COCO compressed format
+func (r RLE) MarshalJSON() ([]byte, error) {
+
+	counts, err := json.Marshal(r.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(rleJSON{
+		Size:   [2]int{r.Height, r.Width},
+		Counts: counts,
+	})
+}
+
+// UnmarshalJSON decodes the RLE from either the COCO compressed or
+// uncompressed format
+func (r *RLE) UnmarshalJSON(data []byte) error {
+
+	var raw rleJSON
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var str string
+
+	if err := json.Unmarshal(raw.Counts, &str); err == nil {
+		rle, err := ParseRLE(str, raw.Size[1], raw.Size[0])
+
+		if err != nil {
+			return err
+		}
+
+		*r = rle
+		return nil
+	}
+
+	var counts []int
+
+	if err := json.Unmarshal(raw.Counts, &counts); err != nil {
+		return fmt.Errorf("invalid RLE counts: %w", err)
+	}
+
+	*r = RLE{
+		Height: raw.Size[0],
+		Width:  raw.Size[1],
+		Counts: counts,
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestRLE(t *testing.T) {
+
+	width, height := 4, 3
+
+	// row major mask with two objects
+	mask := []uint8{
+		0, 1, 1, 0,
+		0, 1, 2, 2,
+		0, 0, 2, 2,
+	}
+
+	rle := EncodeRLE(mask, width, height, 1)
+
+	// column major runs: 3 bg, 2 fg, 1 bg, 1 fg, 5 bg
+	expected := []int{3, 2, 1, 1, 5}
+
+	if len(rle.Counts) != len(expected) {
+		t.Fatalf("expected counts %v, got %v", expected, rle.Counts)
+	}
+
+	for i := range expected {
+		if rle.Counts[i] != expected[i] {
+			t.Fatalf("expected counts %v, got %v", expected, rle.Counts)
+		}
+	}
+
+	if rle.Area() != 3 {
+		t.Errorf("expected area 3, got %d", rle.Area())
+	}
+
+	decoded := rle.Decode(1)
+
+	for i := range mask {
+		want := uint8(0)
+		if mask[i] == 1 {
+			want = 1
+		}
+
+		if decoded[i] != want {
+			t.Fatalf("decoded mask mismatch at %d, expected %d got %d", i, want, decoded[i])
+		}
+	}
+}
+
+func TestEncodeRLERect(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+	width, height := 13, 9
+
+	for n := 0; n < 50; n++ {
+
+		// random blob of value 1 within a random rect
+		rect := image.Rect(rng.Intn(width), rng.Intn(height),
+			rng.Intn(width+1), rng.Intn(height+1)).Canon()
+		mask := make([]uint8, width*height)
+
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				mask[y*width+x] = uint8(rng.Intn(2))
+			}
+		}
+
+		// reference column major encoding of the whole mask
+		var expected []int
+		run, fg := 0, false
+
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				if (mask[y*width+x] == 1) != fg {
+					expected = append(expected, run)
+					run, fg = 0, !fg
+				}
+				run++
+			}
+		}
+
+		expected = append(expected, run)
+
+		for _, rle := range []RLE{
+			EncodeRLERect(mask, width, height, 1, rect),
+			EncodeRLE(mask, width, height, 1),
+		} {
+
+			if len(rle.Counts) != len(expected) {
+				t.Fatalf("rect %v: expected counts %v, got %v", rect, expected, rle.Counts)
+			}
+
+			for i := range expected {
+				if rle.Counts[i] != expected[i] {
+					t.Fatalf("rect %v: expected counts %v, got %v", rect, expected, rle.Counts)
+				}
+			}
+		}
+	}
+}
+
+func TestRLEString(t *testing.T) {
+
+	tests := []struct {
+		counts []int
+	}{
+		{[]int{0}},
+		{[]int{3, 2, 7}},
+		{[]int{100, 5000, 20, 1, 300000}},
+		{[]int{0, 12, 3, 40, 1, 2, 900, 7}},
+	}
+
+	for _, tc := range tests {
+		rle := RLE{Width: 10, Height: 10, Counts: tc.counts}
+
+		parsed, err := ParseRLE(rle.String(), 10, 10)
+
+		if err != nil {
+			t.Fatalf("counts %v: unexpected error: %v", tc.counts, err)
+		}
+
+		if len(parsed.Counts) != len(tc.counts) {
+			t.Fatalf("counts %v: got %v", tc.counts, parsed.Counts)
+		}
+
+		for i := range tc.counts {
+			if parsed.Counts[i] != tc.counts[i] {
+				t.Errorf("counts %v: got %v", tc.counts, parsed.Counts)
+				break
+			}
+		}
+	}
+
+	// known value from pycocotools for counts [3, 2, 7]
+	if s := (RLE{Counts: []int{3, 2, 7}}).String(); s != "327" {
+		t.Errorf("expected compressed string \"327\", got %q", s)
+	}
+}
+
+func TestRLEJSON(t *testing.T) {
+
+	rle := RLE{Width: 4, Height: 3, Counts: []int{3, 2, 7}}
+
+	data, err := json.Marshal(rle)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(data, []byte(`{"size":[3,4],"counts":"327"}`)) {
+		t.Errorf("unexpected json %s", data)
+	}
+
+	var compressed, uncompressed RLE
+
+	if err := json.Unmarshal(data, &compressed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"size":[3,4],"counts":[3,2,7]}`), &uncompressed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, r := range []RLE{compressed, uncompressed} {
+		if r.Width != 4 || r.Height != 3 || len(r.Counts) != 3 || r.Counts[1] != 2 {
+			t.Errorf("unexpected decoded RLE %+v", r)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package postprocess
 
+// retinaFaceBoxPriors320 are the reference box priors of the 320x320 Model
+// used to verify the generated priors
 var retinaFaceBoxPriors320 = [][4]float32{
 	{0.012500, 0.012500, 0.050000, 0.050000},
 	{0.012500, 0.012500, 0.100000, 0.100000},
@@ -4203,6 +4205,8 @@ var retinaFaceBoxPriors320 = [][4]float32{
 	{0.950000, 0.950000, 1.600000, 1.600000},
 }
 
+// retinaFaceBoxPriors640 are the reference box priors of the 640x640 Model
+// used to verify the generated priors
 var retinaFaceBoxPriors640 = [][4]float32{
 	{0.006250, 0.006250, 0.025000, 0.025000},
 	{0.006250, 0.006250, 0.050000, 0.050000},
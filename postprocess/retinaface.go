@@ -2,9 +2,11 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 	"math"
+	"sync"
 )
 
 // RetinaFace defines the struct for the RetinaFace model inference post processing
@@ -14,6 +16,17 @@ type RetinaFace struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
+	// priors are the cached box priors for the input size
+	priors [][4]float32
+	// priorsWidth is the input width the priors were generated for
+	priorsWidth int
+	// priorsHeight is the input height the priors were generated for
+	priorsHeight int
+	// priorsMu protects the cached priors
+	priorsMu sync.Mutex
 }
 
 // RetinaFaceParams defines the struct containing the RetinaFace parameters to use
@@ -34,6 +47,18 @@ type RetinaFaceParams struct {
 	// KeyPointsNumber is the number of face landmark keypoints representing
 	// different features of the face
 	KeyPointsNumber int
+	// Steps are the feature map strides the box priors are generated for
+	Steps []int
+	// MinSizes are the box prior sizes in pixels at each step.  If there are
+	// fewer MinSizes than Steps the WIDERFACE Steps and MinSizes are used.
+	MinSizes [][]int
+	// InputWidth is the Model input width to generate the box priors for at
+	// construction.  If zero the priors are generated from the Model input
+	// attributes on the first detection
+	InputWidth int
+	// InputHeight is the Model input height to generate the box priors for
+	// at construction
+	InputHeight int
 }
 
 // WiderFaceParams returns an instance of RetinaFaceParams configured with
@@ -43,6 +68,8 @@ type RetinaFaceParams struct {
 // - VisThreshold: 0.4
 // - MaxObjectNumber: 128
 // - KeyPointsNumber: 5
+// - Steps: 8, 16, 32
+// - MinSizes: 16 32, 64 128, 256 512
 func WiderFaceParams() RetinaFaceParams {
 	return RetinaFaceParams{
 		NMSThreshold:    0.4,
@@ -50,15 +77,82 @@ func WiderFaceParams() RetinaFaceParams {
 		VisThreshold:    0.4,
 		MaxObjectNumber: 128,
 		KeyPointsNumber: 5,
+		Steps:           []int{8, 16, 32},
+		MinSizes:        [][]int{{16, 32}, {64, 128}, {256, 512}},
 	}
 }
 
 // NewRetinaFace returns an instance of the RetinaFace post processor
 func NewRetinaFace(p RetinaFaceParams) *RetinaFace {
-	return &RetinaFace{
+
+	// default to the WIDERFACE prior configuration, also if there are not
+	// MinSizes for every step
+	if len(p.Steps) == 0 || len(p.MinSizes) < len(p.Steps) {
+		def := WiderFaceParams()
+		p.Steps = def.Steps
+		p.MinSizes = def.MinSizes
+	}
+
+	r := &RetinaFace{
 		Params: p,
 		idGen:  result.NewIDGenerator(),
 	}
+
+	if p.InputWidth > 0 && p.InputHeight > 0 {
+		r.boxPriors(p.InputWidth, p.InputHeight)
+	}
+
+	return r
+}
+
+// boxPriors returns the box priors for the input size, generating and
+// caching them if the input size has changed
+func (r *RetinaFace) boxPriors(width, height int) [][4]float32 {
+
+	r.priorsMu.Lock()
+	defer r.priorsMu.Unlock()
+
+	if r.priors == nil || r.priorsWidth != width || r.priorsHeight != height {
+		r.priors = retinaFacePriors(r.Params.Steps, r.Params.MinSizes, width, height)
+		r.priorsWidth = width
+		r.priorsHeight = height
+	}
+
+	return r.priors
+}
+
+// retinaFacePriors generates the normalized center x, center y, width and
+// height of the box priors at each location of the feature map of each step
+func retinaFacePriors(steps []int, minSizes [][]int, width, height int) [][4]float32 {
+
+	priors := make([][4]float32, 0)
+
+	for k, step := range steps {
+
+		mapW := int(math.Ceil(float64(width) / float64(step)))
+		mapH := int(math.Ceil(float64(height) / float64(step)))
+
+		for i := 0; i < mapH; i++ {
+			for j := 0; j < mapW; j++ {
+				for _, size := range minSizes[k] {
+					priors = append(priors, [4]float32{
+						float32((float64(j) + 0.5) * float64(step) / float64(width)),
+						float32((float64(i) + 0.5) * float64(step) / float64(height)),
+						float32(float64(size) / float64(width)),
+						float32(float64(size) / float64(height)),
+					})
+				}
+			}
+		}
+	}
+
+	return priors
+}
+
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (r *RetinaFace) SetSuppressor(s nms.Suppressor) {
+	r.suppressor = s
 }
 
 // RetinaFaceResult defines a struct used for retina face detection results
@@ -90,20 +184,13 @@ func (r *RetinaFace) DetectFaces(outputs *rknnlite.Outputs,
 	modelWidth := outputs.InputAttributes().Width
 	modelHeight := outputs.InputAttributes().Height
 
-	var priorPtr [][4]float32
-	var numPriors int
-
-	// Determine priors based on model height
-	switch modelHeight {
-	case 320:
-		numPriors = 4200
-		priorPtr = retinaFaceBoxPriors320
-	case 640:
-		numPriors = 16800
-		priorPtr = retinaFaceBoxPriors640
-	default:
-		// model shape error
-		return nil
+	priorPtr := r.boxPriors(int(modelWidth), int(modelHeight))
+	numPriors := len(priorPtr)
+
+	// model shape error
+	if len(scores) < numPriors*2 || len(location) < numPriors*4 ||
+		len(landms) < numPriors*10 {
+		return RetinaFaceResult{}
 	}
 
 	filterIndices := make([]int, numPriors)
@@ -115,8 +202,14 @@ func (r *RetinaFace) DetectFaces(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(props, 0, validCount-1, filterIndices)
 
-	r.nms(validCount, location, filterIndices, r.Params.NMSThreshold,
-		resizer.SrcWidth(), resizer.SrcHeight())
+	sup := r.suppressor
+
+	if sup == nil {
+		sup = greedyNMS(r.Params.NMSThreshold)
+	}
+
+	r.suppress(sup, validCount, location, filterIndices, props,
+		modelWidth, modelHeight)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -156,13 +249,16 @@ func (r *RetinaFace) DetectFaces(outputs *rknnlite.Outputs,
 
 		allKeyPoints = append(allKeyPoints, keyPtData)
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, modelWidth) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, modelHeight) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, modelWidth) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, modelHeight) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, modelWidth) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, modelHeight) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, modelWidth) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, modelHeight) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: props[i],
 			ID:          r.idGen.GetNext(),
 		}
@@ -225,47 +321,59 @@ func (r *RetinaFace) filterValidResult(scores, loc, landms []float32,
 	return validCount
 }
 
-// nms implements a Non-Maximum Suppression (NMS) algorithm to filter
-// overlapping bounding boxes
-func (r *RetinaFace) nms(validCount int, outputLocations []float32, order []int,
-	threshold float32, width, height int) {
+// suppress runs the Suppressor over the normalized face locations scaled to
+// the Model input size.  The kept faces are ranked at the start of order with
+// props holding their scores and the remaining entries are set to -1
+func (r *RetinaFace) suppress(sup nms.Suppressor, validCount int,
+	outputLocations []float32, order []int, props []float32,
+	width, height uint32) {
+
+	dets := make([]result.DetectResult, 0, validCount)
+	orig := make(map[int]result.BoxRectF, validCount)
 
 	for i := 0; i < validCount; i++ {
+		n := order[i]
 
-		if order[i] == -1 {
-			continue
+		boxF := result.BoxRectF{
+			Left:   outputLocations[n*4+0] * float32(width),
+			Top:    outputLocations[n*4+1] * float32(height),
+			Right:  outputLocations[n*4+2] * float32(width),
+			Bottom: outputLocations[n*4+3] * float32(height),
 		}
 
-		n := order[i]
+		orig[n] = boxF
 
-		for j := i + 1; j < validCount; j++ {
+		dets = append(dets, result.DetectResult{
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: props[i],
+			ID:          int64(n),
+		})
+	}
 
-			m := order[j]
+	kept := sup.Suppress(dets)
 
-			if m == -1 {
-				continue
-			}
+	for i := 0; i < validCount; i++ {
+		order[i] = -1
+	}
 
-			// Calculate coordinates for bounding box n
-			xmin0 := outputLocations[n*4+0] * float32(width)
-			ymin0 := outputLocations[n*4+1] * float32(height)
-			xmax0 := outputLocations[n*4+2] * float32(width)
-			ymax0 := outputLocations[n*4+3] * float32(height)
+	for i, det := range kept {
+		n := int(det.ID)
 
-			// Calculate coordinates for bounding box m
-			xmin1 := outputLocations[m*4+0] * float32(width)
-			ymin1 := outputLocations[m*4+1] * float32(height)
-			xmax1 := outputLocations[m*4+2] * float32(width)
-			ymax1 := outputLocations[m*4+3] * float32(height)
+		order[i] = n
+		props[i] = det.Probability
 
-			// Calculate the IoU (Intersection over Union)
-			iou := calculateOverlap(xmin0, ymin0, xmax0, ymax0, xmin1, ymin1, xmax1, ymax1)
+		boxF := det.FloatBox()
 
-			// Suppress bounding box m if IoU exceeds the threshold
-			if iou > threshold {
-				order[j] = -1
-			}
+		if boxF == orig[n] {
+			continue
 		}
+
+		// box has been fused so update the face location
+		outputLocations[n*4+0] = boxF.Left / float32(width)
+		outputLocations[n*4+1] = boxF.Top / float32(height)
+		outputLocations[n*4+2] = boxF.Right / float32(width)
+		outputLocations[n*4+3] = boxF.Bottom / float32(height)
 	}
 }
 
@@ -0,0 +1,87 @@
+package postprocess
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRetinaFacePriors(t *testing.T) {
+
+	tests := []struct {
+		size  int
+		table [][4]float32
+	}{
+		{320, retinaFaceBoxPriors320},
+		{640, retinaFaceBoxPriors640},
+	}
+
+	for _, tc := range tests {
+
+		r := NewRetinaFace(WiderFaceParams())
+		priors := r.boxPriors(tc.size, tc.size)
+
+		if len(priors) != len(tc.table) {
+			t.Fatalf("%d: expected %d priors, got %d", tc.size, len(tc.table),
+				len(priors))
+		}
+
+		// the table is rounded to 6 decimal places
+		for i := range priors {
+			for j := 0; j < 4; j++ {
+				if math.Abs(float64(priors[i][j]-tc.table[i][j])) > 1e-6 {
+					t.Fatalf("%d: prior %d expected %v, got %v", tc.size, i,
+						tc.table[i], priors[i])
+				}
+			}
+		}
+	}
+}
+
+func TestRetinaFacePriorsCache(t *testing.T) {
+
+	p := WiderFaceParams()
+	p.InputWidth = 320
+	p.InputHeight = 320
+	r := NewRetinaFace(p)
+
+	if len(r.priors) != 4200 {
+		t.Fatalf("expected 4200 priors generated at construction, got %d",
+			len(r.priors))
+	}
+
+	if &r.boxPriors(320, 320)[0] != &r.priors[0] {
+		t.Errorf("expected cached priors for the same input size")
+	}
+
+	// non square input with custom min sizes
+	if n := len(r.boxPriors(640, 480)); n != 80*60*2+40*30*2+20*15*2 {
+		t.Errorf("unexpected number of priors %d for 640x480", n)
+	}
+
+	custom := NewRetinaFace(RetinaFaceParams{
+		Steps:    []int{16},
+		MinSizes: [][]int{{32}},
+	})
+
+	if priors := custom.boxPriors(100, 100); len(priors) != 49 ||
+		priors[0] != [4]float32{0.08, 0.08, 0.32, 0.32} {
+		t.Errorf("unexpected custom priors %d %v", len(priors), priors[0])
+	}
+}
+
+func TestRetinaFaceMismatchedMinSizes(t *testing.T) {
+
+	// three steps with only two min sizes falls back to WIDERFACE
+	r := NewRetinaFace(RetinaFaceParams{
+		Steps:    []int{8, 16, 32},
+		MinSizes: [][]int{{16, 32}, {64, 128}},
+	})
+
+	if len(r.Params.MinSizes) != 3 {
+		t.Fatalf("expected WIDERFACE min sizes, got %v", r.Params.MinSizes)
+	}
+
+	if n := len(r.boxPriors(320, 320)); n != 4200 {
+		t.Errorf("expected 4200 priors, got %d", n)
+	}
+}
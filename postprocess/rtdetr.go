@@ -0,0 +1,239 @@
+package postprocess
+
+import (
+	"sort"
+
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+// RTDETR defines the struct for RT-DETR, D-FINE and other DETR family
+// transformer detector inference post processing
+type RTDETR struct {
+	// Params are the Model configuration parameters
+	Params RTDETRParams
+	// nextID is a counter that increments and provides the next number
+	// for each detection result ID
+	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to apply
+	// to the decoded queries
+	suppressor nms.Suppressor
+}
+
+// RTDETRParams defines the struct containing the RTDETR parameters to use
+// for post processing operations
+type RTDETRParams struct {
+	// BoxThreshold is the minimum probability score required for a query's
+	// class to be returned as a detection
+	BoxThreshold float32
+	// ObjectClassNum is the number of different object classes the Model has
+	// been trained with
+	ObjectClassNum int
+	// MaxObjectNumber is the top-K number of query and class pairs with the
+	// highest scores returned
+	MaxObjectNumber int
+	// Sigmoid applies the sigmoid function to the class scores for Models
+	// that output logits.  Set to false for Models that output probabilities,
+	// such as the Ultralytics RT-DETR export.
+	Sigmoid bool
+}
+
+// RTDETRCOCOParams returns an instance of RTDETRParams configured with
+// default values for a Model trained on the COCO dataset featuring:
+// - Object Classes: 80
+// - Box Threshold: 0.5
+// - Maximum Object Number: 100
+// - Sigmoid: true
+func RTDETRCOCOParams() RTDETRParams {
+	return RTDETRParams{
+		BoxThreshold:    0.5,
+		ObjectClassNum:  80,
+		MaxObjectNumber: 100,
+		Sigmoid:         true,
+	}
+}
+
+// NewRTDETR returns an instance of the RTDETR post processor
+func NewRTDETR(p RTDETRParams) *RTDETR {
+	return &RTDETR{
+		Params: p,
+		idGen:  result.NewIDGenerator(),
+	}
+}
+
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to apply no NMS as the Model is NMS free.
+func (r *RTDETR) SetSuppressor(s nms.Suppressor) {
+	r.suppressor = s
+}
+
+// RTDETRResult defines a struct used for object detection results
+type RTDETRResult struct {
+	DetectResults []result.DetectResult
+}
+
+// GetDetectResults returns the object detection results containing bounding
+// boxes
+func (r RTDETRResult) GetDetectResults() []result.DetectResult {
+	return r.DetectResults
+}
+
+// rtdetrCandidate is a query and class pair above the box threshold
+type rtdetrCandidate struct {
+	class int
+	score float32
+	// box is the normalized center x, center y, width and height of the query
+	box [4]float32
+}
+
+// DetectObjects takes the RKNN outputs and runs the object detection process
+// then returns the results.  The Model can have a single output of shape
+// [1, queries, 4 + classes] or two outputs of the class scores
+// [1, queries, classes] and boxes [1, queries, 4].  Boxes are in normalized
+// center x, center y, width, height format relative to the Model input.
+func (r *RTDETR) DetectObjects(outputs *rknnlite.Outputs,
+	resizer *preprocess.Resizer) result.DetectionResult {
+
+	attrs := outputs.OutputAttributes()
+	tensors := make([]outputTensor, attrs.IONumber)
+
+	for i := range tensors {
+		tensors[i] = newOutputTensor(outputs, attrs, i)
+	}
+
+	cands, ok := r.decode(tensors)
+
+	if !ok {
+		// model shape error
+		return RTDETRResult{}
+	}
+
+	modelWidth := float32(outputs.InputAttributes().Width)
+	modelHeight := float32(outputs.InputAttributes().Height)
+	srcWidth := uint32(resizer.SrcWidth())
+	srcHeight := uint32(resizer.SrcHeight())
+	xPad := float32(resizer.XPad())
+	yPad := float32(resizer.YPad())
+	scale := resizer.ScaleFactor()
+
+	group := make([]result.DetectResult, 0, len(cands))
+
+	for _, cand := range cands {
+
+		cx := cand.box[0] * modelWidth
+		cy := cand.box[1] * modelHeight
+		w := cand.box[2] * modelWidth
+		h := cand.box[3] * modelHeight
+
+		// map back through the letterbox to the source image
+		boxF := result.BoxRectF{
+			Left:   clamp((cx-w/2-xPad)/scale, 0, srcWidth),
+			Top:    clamp((cy-h/2-yPad)/scale, 0, srcHeight),
+			Right:  clamp((cx+w/2-xPad)/scale, 0, srcWidth),
+			Bottom: clamp((cy+h/2-yPad)/scale, 0, srcHeight),
+		}
+
+		group = append(group, result.DetectResult{
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: cand.score,
+			Class:       cand.class,
+			ID:          r.idGen.GetNext(),
+		})
+	}
+
+	if r.suppressor != nil {
+		group = r.suppressor.Suppress(group)
+	}
+
+	return RTDETRResult{
+		DetectResults: group,
+	}
+}
+
+// decode returns the top-K query and class pairs scoring above the box
+// threshold from either the single combined output or the split score and box
+// outputs.  Returns false if the outputs do not match a known layout.
+func (r *RTDETR) decode(tensors []outputTensor) ([]rtdetrCandidate, bool) {
+
+	numClass := r.Params.ObjectClassNum
+
+	var scores, boxes outputTensor
+	var numQueries, scoreStride, boxStride, scoreOffset int
+
+	switch len(tensors) {
+	case 1:
+		// boxes followed by class scores on each row
+		out := tensors[0]
+		numQueries = out.len() / (4 + numClass)
+		scores, boxes = out, out
+		scoreStride, boxStride = 4+numClass, 4+numClass
+		scoreOffset = 4
+
+	case 2:
+		scores, boxes = tensors[0], tensors[1]
+		numQueries = (scores.len() + boxes.len()) / (4 + numClass)
+
+		// outputs are ordered by the export so find the box output by size
+		if boxes.len() != numQueries*4 {
+			scores, boxes = boxes, scores
+		}
+
+		scoreStride, boxStride = numClass, 4
+
+	default:
+		return nil, false
+	}
+
+	if numQueries == 0 || scores.len() < numQueries*scoreStride ||
+		boxes.len() < numQueries*boxStride {
+		return nil, false
+	}
+
+	// compare logits against the threshold so the sigmoid is only applied to
+	// the candidates kept
+	threshold := r.Params.BoxThreshold
+
+	if r.Params.Sigmoid {
+		threshold = unsigmoid(threshold)
+	}
+
+	cands := make([]rtdetrCandidate, 0)
+
+	for q := 0; q < numQueries; q++ {
+		for c := 0; c < numClass; c++ {
+
+			score := scores.at(q*scoreStride + scoreOffset + c)
+
+			if score < threshold {
+				continue
+			}
+
+			if r.Params.Sigmoid {
+				score = sigmoid(score)
+			}
+
+			n := q * boxStride
+
+			cands = append(cands, rtdetrCandidate{
+				class: c,
+				score: score,
+				box: [4]float32{boxes.at(n), boxes.at(n + 1), boxes.at(n + 2),
+					boxes.at(n + 3)},
+			})
+		}
+	}
+
+	// top-K over all query and class pairs
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].score > cands[j].score
+	})
+
+	if r.Params.MaxObjectNumber > 0 && len(cands) > r.Params.MaxObjectNumber {
+		cands = cands[:r.Params.MaxObjectNumber]
+	}
+
+	return cands, true
+}
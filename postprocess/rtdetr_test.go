@@ -0,0 +1,124 @@
+package postprocess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRTDETRDecode(t *testing.T) {
+
+	boxes := []float32{
+		0.5, 0.5, 0.2, 0.4,
+		0.25, 0.75, 0.1, 0.1,
+		0.1, 0.2, 0.3, 0.4,
+	}
+
+	// class probabilities of each query
+	probs := []float32{
+		0.9, 0.2,
+		0.6, 0.7,
+		0.1, 0.4,
+	}
+
+	// class logits of each query, sigmoid(2) = 0.8808 and sigmoid(0) = 0.5
+	logits := []float32{
+		2, -3,
+		-1, 0,
+		-2, -0.5,
+	}
+
+	// rows of box followed by the class scores
+	combined := make([]float32, 0)
+
+	for q := 0; q < 3; q++ {
+		combined = append(combined, boxes[q*4:q*4+4]...)
+		combined = append(combined, probs[q*2:q*2+2]...)
+	}
+
+	probResults := []rtdetrCandidate{
+		{class: 0, score: 0.9, box: [4]float32{0.5, 0.5, 0.2, 0.4}},
+		{class: 1, score: 0.7, box: [4]float32{0.25, 0.75, 0.1, 0.1}},
+		{class: 0, score: 0.6, box: [4]float32{0.25, 0.75, 0.1, 0.1}},
+	}
+
+	tests := []struct {
+		name     string
+		tensors  []outputTensor
+		sigmoid  bool
+		maxObj   int
+		expected []rtdetrCandidate
+		err      bool
+	}{
+		{
+			name:     "single output",
+			tensors:  []outputTensor{{buf: combined}},
+			maxObj:   100,
+			expected: probResults,
+		},
+		{
+			name:     "split outputs",
+			tensors:  []outputTensor{{buf: probs}, {buf: boxes}},
+			maxObj:   100,
+			expected: probResults,
+		},
+		{
+			name:     "split outputs with boxes first",
+			tensors:  []outputTensor{{buf: boxes}, {buf: probs}},
+			maxObj:   100,
+			expected: probResults,
+		},
+		{
+			name:    "sigmoid logits",
+			tensors: []outputTensor{{buf: logits}, {buf: boxes}},
+			sigmoid: true,
+			maxObj:  100,
+			expected: []rtdetrCandidate{
+				{class: 0, score: sigmoid(2), box: [4]float32{0.5, 0.5, 0.2, 0.4}},
+				{class: 1, score: 0.5, box: [4]float32{0.25, 0.75, 0.1, 0.1}},
+			},
+		},
+		{
+			name:     "top-k",
+			tensors:  []outputTensor{{buf: probs}, {buf: boxes}},
+			maxObj:   2,
+			expected: probResults[:2],
+		},
+		{
+			name:    "unknown output count",
+			tensors: []outputTensor{{buf: probs}, {buf: boxes}, {buf: boxes}},
+			err:     true,
+		},
+		{
+			name:    "empty output",
+			tensors: []outputTensor{{}},
+			err:     true,
+		},
+	}
+
+	for _, tc := range tests {
+
+		p := RTDETRCOCOParams()
+		p.ObjectClassNum = 2
+		p.Sigmoid = tc.sigmoid
+		p.MaxObjectNumber = tc.maxObj
+		r := NewRTDETR(p)
+
+		cands, ok := r.decode(tc.tensors)
+
+		if tc.err {
+			if ok {
+				t.Errorf("%s: expected shape error, got %+v", tc.name, cands)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("%s: unexpected shape error", tc.name)
+			continue
+		}
+
+		if !reflect.DeepEqual(cands, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, cands)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package postprocess
+
+import (
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+// SCRFD defines the struct for the SCRFD face detection model inference post
+// processing
+type SCRFD struct {
+	// Params are the Model configuration parameters
+	Params SCRFDParams
+	// nextID is a counter that increments and provides the next number
+	// for each detection result ID
+	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
+}
+
+// SCRFDParams defines the struct containing the SCRFD parameters to use for
+// post processing operations
+type SCRFDParams struct {
+	// ConfThreshold is the minimum probability score required for a face to
+	// be considered for processing
+	ConfThreshold float32
+	// NMSThreshold is the Non-Maximum Suppression threshold used for defining
+	// the maximum allowed Intersection Over Union (IoU) between two
+	// bounding boxes for both to be kept
+	NMSThreshold float32
+	// MaxObjectNumber is the maximum number of faces detected that can be
+	// returned
+	MaxObjectNumber int
+	// Strides are the feature map strides of the detection heads
+	Strides []int
+	// NumAnchors is the number of anchors at each feature map location
+	NumAnchors int
+	// KeyPointsNumber is the number of face landmark keypoints output by the
+	// Model.  Models exported without keypoint heads return no keypoints
+	KeyPointsNumber int
+}
+
+// SCRFDDefaultParams returns an instance of SCRFDParams configured with the
+// default values for the InsightFace SCRFD Models featuring:
+// - ConfThreshold: 0.5
+// - NMS Threshold: 0.4
+// - MaxObjectNumber: 128
+// - Strides: 8, 16, 32
+// - NumAnchors: 2
+// - KeyPointsNumber: 5
+func SCRFDDefaultParams() SCRFDParams {
+	return SCRFDParams{
+		ConfThreshold:   0.5,
+		NMSThreshold:    0.4,
+		MaxObjectNumber: 128,
+		Strides:         []int{8, 16, 32},
+		NumAnchors:      2,
+		KeyPointsNumber: 5,
+	}
+}
+
+// NewSCRFD returns an instance of the SCRFD post processor
+func NewSCRFD(p SCRFDParams) *SCRFD {
+	return &SCRFD{
+		Params: p,
+		idGen:  result.NewIDGenerator(),
+	}
+}
+
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (s *SCRFD) SetSuppressor(sup nms.Suppressor) {
+	s.suppressor = sup
+}
+
+// SCRFDResult defines a struct used for SCRFD face detection results
+type SCRFDResult struct {
+	DetectResults []result.DetectResult
+	KeyPoints     [][]result.KeyPoint
+}
+
+// GetDetectResults returns the object detection results containing bounding
+// boxes
+func (r SCRFDResult) GetDetectResults() []result.DetectResult {
+	return r.DetectResults
+}
+
+// GetKeyPoints returns the keypoints of the detected faces landmark features
+func (r SCRFDResult) GetKeyPoints() [][]result.KeyPoint {
+	return r.KeyPoints
+}
+
+// scrfdFace is a face decoded from the outputs in Model input coordinates
+type scrfdFace struct {
+	box   result.BoxRectF
+	score float32
+	// keyPoints are the x, y coordinates of the landmarks
+	keyPoints []float32
+}
+
+// DetectFaces takes the RKNN outputs and runs the face detection process
+// then returns the result.  The outputs are expected in the InsightFace
+// export order of the scores of each stride, followed by the bounding box
+// distances of each stride and then the optional keypoint offsets of each
+// stride, with each output holding a row per anchor.
+func (s *SCRFD) DetectFaces(outputs *rknnlite.Outputs,
+	resizer *preprocess.Resizer) result.DetectionResult {
+
+	modelWidth := int(outputs.InputAttributes().Width)
+	modelHeight := int(outputs.InputAttributes().Height)
+	attrs := outputs.OutputAttributes()
+
+	fmc := len(s.Params.Strides)
+	numKps := s.Params.KeyPointsNumber
+
+	switch int(attrs.IONumber) {
+	case 3 * fmc:
+	case 2 * fmc:
+		// model exported without keypoint heads
+		numKps = 0
+	default:
+		// model shape error
+		return SCRFDResult{}
+	}
+
+	faces := make([]scrfdFace, 0)
+
+	for i, stride := range s.Params.Strides {
+
+		scores := newOutputTensor(outputs, attrs, i)
+		boxes := newOutputTensor(outputs, attrs, i+fmc)
+
+		var kps outputTensor
+
+		if numKps > 0 {
+			kps = newOutputTensor(outputs, attrs, i+2*fmc)
+		}
+
+		gridW := modelWidth / stride
+		gridH := modelHeight / stride
+		numAnchors := gridW * gridH * s.Params.NumAnchors
+
+		// model shape error
+		if scores.len() < numAnchors || boxes.len() < numAnchors*4 ||
+			(numKps > 0 && kps.len() < numAnchors*numKps*2) {
+			return SCRFDResult{}
+		}
+
+		faces = s.decodeStride(scores, boxes, kps, gridW, gridH, stride,
+			numKps, faces)
+	}
+
+	kept := s.suppress(faces)
+
+	// collate faces into a result for returning
+	group := make([]result.DetectResult, 0, len(kept))
+	allKeyPoints := make([][]result.KeyPoint, 0, len(kept))
+
+	srcWidth := uint32(resizer.SrcWidth())
+	srcHeight := uint32(resizer.SrcHeight())
+	xPad := float32(resizer.XPad())
+	yPad := float32(resizer.YPad())
+	scale := resizer.ScaleFactor()
+
+	for _, det := range kept {
+
+		if len(group) >= s.Params.MaxObjectNumber {
+			break
+		}
+
+		face := faces[det.ID]
+		box := det.FloatBox()
+
+		keyPtData := make([]result.KeyPoint, numKps)
+
+		for j := range keyPtData {
+			keyPtData[j] = result.KeyPoint{
+				X: int(clamp((face.keyPoints[j*2]-xPad)/scale, 0, srcWidth)),
+				Y: int(clamp((face.keyPoints[j*2+1]-yPad)/scale, 0, srcHeight)),
+			}
+		}
+
+		allKeyPoints = append(allKeyPoints, keyPtData)
+
+		boxF := result.BoxRectF{
+			Left:   clamp((box.Left-xPad)/scale, 0, srcWidth),
+			Top:    clamp((box.Top-yPad)/scale, 0, srcHeight),
+			Right:  clamp((box.Right-xPad)/scale, 0, srcWidth),
+			Bottom: clamp((box.Bottom-yPad)/scale, 0, srcHeight),
+		}
+
+		group = append(group, result.DetectResult{
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: det.Probability,
+			ID:          s.idGen.GetNext(),
+		})
+	}
+
+	return SCRFDResult{
+		DetectResults: group,
+		KeyPoints:     allKeyPoints,
+	}
+}
+
+// decodeStride appends the faces of a stride scoring above the confidence
+// threshold to faces.  The scores, boxes and kps tensors hold a row per anchor
+// of the gridW x gridH feature map, with the box distances and keypoint
+// offsets in units of the stride.
+func (s *SCRFD) decodeStride(scores, boxes, kps outputTensor, gridW, gridH,
+	stride, numKps int, faces []scrfdFace) []scrfdFace {
+
+	numAnchors := gridW * gridH * s.Params.NumAnchors
+	st := float32(stride)
+
+	for n := 0; n < numAnchors; n++ {
+
+		score := scores.at(n)
+
+		if score < s.Params.ConfThreshold {
+			continue
+		}
+
+		// anchors at the same location share the same center
+		loc := n / s.Params.NumAnchors
+		cx := float32(loc%gridW) * st
+		cy := float32(loc/gridW) * st
+
+		face := scrfdFace{
+			box: result.BoxRectF{
+				Left:   cx - boxes.at(n*4+0)*st,
+				Top:    cy - boxes.at(n*4+1)*st,
+				Right:  cx + boxes.at(n*4+2)*st,
+				Bottom: cy + boxes.at(n*4+3)*st,
+			},
+			score:     score,
+			keyPoints: make([]float32, numKps*2),
+		}
+
+		for j := 0; j < numKps; j++ {
+			face.keyPoints[j*2] = cx + kps.at(n*numKps*2+j*2)*st
+			face.keyPoints[j*2+1] = cy + kps.at(n*numKps*2+j*2+1)*st
+		}
+
+		faces = append(faces, face)
+	}
+
+	return faces
+}
+
+// suppress filters the overlapping faces with the Suppressor, or greedy NMS
+// if none has been set.  The ID of the kept detection results is the index
+// of the face.
+func (s *SCRFD) suppress(faces []scrfdFace) []result.DetectResult {
+
+	dets := make([]result.DetectResult, len(faces))
+
+	for i, face := range faces {
+		dets[i] = result.DetectResult{
+			Box:         face.box.Rect(),
+			BoxF:        face.box,
+			Probability: face.score,
+			ID:          int64(i),
+		}
+	}
+
+	sup := s.suppressor
+
+	if sup == nil {
+		p := nms.DefaultParams()
+		p.IoUThreshold = s.Params.NMSThreshold
+		sup = nms.New(p)
+	}
+
+	return sup.Suppress(dets)
+}
+
+// GetFaceLandmarks returns the landmark keypoints for the detected faces
+func (s *SCRFD) GetFaceLandmarks(detectObjs result.DetectionResult) [][]result.KeyPoint {
+	return detectObjs.(SCRFDResult).GetKeyPoints()
+}
@@ -0,0 +1,76 @@
+package postprocess
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+)
+
+func TestSCRFDDecodeStride(t *testing.T) {
+
+	const (
+		grid   = 2
+		stride = 8
+		numKps = 2
+	)
+
+	p := SCRFDDefaultParams()
+	p.KeyPointsNumber = numKps
+	s := NewSCRFD(p)
+
+	numAnchors := grid * grid * p.NumAnchors
+
+	scores := make([]float32, numAnchors)
+	boxes := make([]float32, numAnchors*4)
+	kps := make([]float32, numAnchors*numKps*2)
+
+	// second anchor at location (1,0) and first anchor at location (1,1)
+	scores[3] = 0.9
+	scores[6] = 0.7
+	// below the confidence threshold
+	scores[0] = 0.4
+
+	copy(boxes[3*4:], []float32{1, 0.5, 2, 1})
+	copy(boxes[6*4:], []float32{0.25, 0.25, 0.5, 0.5})
+	copy(kps[3*numKps*2:], []float32{-1, 1, 1, 0.5})
+	copy(kps[6*numKps*2:], []float32{0, 0, 0.5, -0.5})
+
+	expected := []scrfdFace{
+		{
+			box:       result.BoxRectF{Left: 0, Top: -4, Right: 24, Bottom: 8},
+			score:     0.9,
+			keyPoints: []float32{0, 8, 16, 4},
+		},
+		{
+			box:       result.BoxRectF{Left: 6, Top: 6, Right: 12, Bottom: 12},
+			score:     0.7,
+			keyPoints: []float32{8, 8, 12, 4},
+		},
+	}
+
+	faces := s.decodeStride(outputTensor{buf: scores}, outputTensor{buf: boxes},
+		outputTensor{buf: kps}, grid, grid, stride, numKps, nil)
+
+	if !reflect.DeepEqual(faces, expected) {
+		t.Errorf("expected faces %+v, got %+v", expected, faces)
+	}
+
+	// model exported without keypoint heads
+	faces = s.decodeStride(outputTensor{buf: scores}, outputTensor{buf: boxes},
+		outputTensor{}, grid, grid, stride, 0, nil)
+
+	if len(faces) != 2 || len(faces[0].keyPoints) != 0 ||
+		faces[1].box != expected[1].box {
+		t.Errorf("unexpected faces without keypoints %+v", faces)
+	}
+}
+
+func TestSCRFDEmptyResultLandmarks(t *testing.T) {
+
+	s := NewSCRFD(SCRFDDefaultParams())
+
+	if kps := s.GetFaceLandmarks(SCRFDResult{}); len(kps) != 0 {
+		t.Errorf("expected no landmarks, got %v", kps)
+	}
+}
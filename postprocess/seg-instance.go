@@ -0,0 +1,190 @@
+package postprocess
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"gocv.io/x/gocv"
+)
+
+// SegInstance is the segment mask of a single detected object
+type SegInstance struct {
+	// ID is the ID of the detection result the mask belongs to
+	ID int64
+	// Area is the number of pixels in the mask
+	Area int
+	// Polygons are the simplified outlines of the separate regions of the
+	// mask in source image coordinates, ordered by descending area
+	Polygons [][]image.Point
+	// RotatedRect is the minimum area rotated rectangle enclosing the mask,
+	// with X and Y the top left of the rectangle before rotation around its
+	// center
+	RotatedRect result.BoxRectF
+	// RLE is the COCO run length encoding of the mask
+	RLE result.RLE
+}
+
+// Instances extracts the mask of each detected object from the segment mask
+// returned by SegmentMask() or TrackMask().  The epsilon is the maximum
+// distance in pixels between an outline and its simplified polygon, zero
+// keeps every contour point.  Objects with no pixels in the segment mask,
+// such as those not tracked, are skipped.
+func (s SegmentData) Instances(segMask SegMask, epsilon float64) ([]SegInstance, error) {
+
+	width := s.srcWidth
+	height := s.srcHeight
+
+	if len(segMask.Mask) != width*height {
+		return nil, fmt.Errorf("segment mask size %d does not match image %dx%d",
+			len(segMask.Mask), width, height)
+	}
+
+	// bounding rect and area of each object, indexed by mask value
+	bounds := make([]image.Rectangle, s.boxesNum+1)
+	areas := make([]int, s.boxesNum+1)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+
+			v := int(segMask.Mask[y*width+x])
+
+			if v == 0 || v > s.boxesNum {
+				continue
+			}
+
+			pt := image.Rect(x, y, x+1, y+1)
+
+			if areas[v] == 0 {
+				bounds[v] = pt
+			} else {
+				bounds[v] = bounds[v].Union(pt)
+			}
+
+			areas[v]++
+		}
+	}
+
+	maskMat, err := gocv.NewMatFromBytes(height, width, gocv.MatTypeCV8U, segMask.Mask)
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating mask Mat: %w", err)
+	}
+
+	defer maskMat.Close()
+
+	objMask := gocv.NewMat()
+	defer objMask.Close()
+
+	instances := make([]SegInstance, 0, s.boxesNum)
+
+	for b := 0; b < s.boxesNum; b++ {
+
+		v := b + 1
+
+		if areas[v] == 0 {
+			continue
+		}
+
+		rect := bounds[v]
+
+		// isolate the object within its bounding rect
+		roi := maskMat.Region(rect)
+		value := gocv.NewScalar(float64(v), 0, 0, 0)
+		gocv.InRangeWithScalar(roi, value, value, &objMask)
+		roi.Close()
+
+		polygons, points := s.outlines(objMask, rect.Min, epsilon)
+
+		inst := SegInstance{
+			ID:       s.detectIDs[b],
+			Area:     areas[v],
+			Polygons: polygons,
+			RLE: result.EncodeRLERect(segMask.Mask, width, height, uint8(v),
+				rect),
+		}
+
+		if len(points) > 0 {
+
+			pv := gocv.NewPointVectorFromPoints(points)
+			rr := gocv.MinAreaRect2f(pv)
+			pv.Close()
+
+			inst.RotatedRect = result.BoxRectF{
+				X:      rr.Center.X - rr.Width/2,
+				Y:      rr.Center.Y - rr.Height/2,
+				Width:  rr.Width,
+				Height: rr.Height,
+				Angle:  float32(rr.Angle * math.Pi / 180),
+				Mode:   result.ModeXYWH,
+			}
+		}
+
+		instances = append(instances, inst)
+	}
+
+	return instances, nil
+}
+
+// outlines returns the simplified polygons of the regions in the binary
+// object mask ordered by descending area, along with all their points, offset
+// to source image coordinates
+func (s SegmentData) outlines(objMask gocv.Mat, offset image.Point,
+	epsilon float64) ([][]image.Point, []image.Point) {
+
+	contours := gocv.FindContours(objMask, gocv.RetrievalExternal,
+		gocv.ChainApproxSimple)
+	defer contours.Close()
+
+	type polygon struct {
+		pts  []image.Point
+		area float64
+	}
+
+	polys := make([]polygon, 0, contours.Size())
+	points := make([]image.Point, 0)
+
+	for i := 0; i < contours.Size(); i++ {
+
+		contour := contours.At(i)
+		area := gocv.ContourArea(contour)
+
+		var pts []image.Point
+
+		if epsilon > 0 {
+			approx := gocv.ApproxPolyDP(contour, epsilon, true)
+			pts = approx.ToPoints()
+			approx.Close()
+		} else {
+			pts = contour.ToPoints()
+		}
+
+		for j := range pts {
+			pts[j] = pts[j].Add(offset)
+		}
+
+		points = append(points, pts...)
+
+		// a polygon needs at least 3 points, smaller regions still count
+		// towards the rotated rect
+		if len(pts) < 3 {
+			continue
+		}
+
+		polys = append(polys, polygon{pts, area})
+	}
+
+	sort.SliceStable(polys, func(i, j int) bool {
+		return polys[i].area > polys[j].area
+	})
+
+	res := make([][]image.Point, len(polys))
+
+	for i, p := range polys {
+		res[i] = p.pts
+	}
+
+	return res, points
+}
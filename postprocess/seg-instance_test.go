@@ -0,0 +1,83 @@
+package postprocess
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegmentInstances(t *testing.T) {
+
+	width, height := 20, 10
+	mask := make([]uint8, width*height)
+
+	fill := func(x0, y0, x1, y1 int, v uint8) {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				mask[y*width+x] = v
+			}
+		}
+	}
+
+	// object 1 is a 6x4 rectangle, object 2 is two squares and object 3 is
+	// not in the mask
+	fill(2, 1, 8, 5, 1)
+	fill(10, 1, 13, 4, 2)
+	fill(15, 5, 19, 9, 2)
+
+	segData := SegmentData{
+		boxesNum:  3,
+		detectIDs: []int64{11, 12, 13},
+		srcWidth:  width,
+		srcHeight: height,
+	}
+
+	instances, err := segData.Instances(SegMask{mask}, 1)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(instances))
+	}
+
+	rect := instances[0]
+
+	if rect.ID != 11 || rect.Area != 24 || rect.RLE.Area() != 24 ||
+		len(rect.Polygons) != 1 || len(rect.Polygons[0]) != 4 {
+		t.Errorf("unexpected rectangle instance %+v", rect)
+	}
+
+	// the contour runs through the pixel centers, so the rect is centered
+	// on (4.5, 2.5) with X and Y its top left
+	rr := rect.RotatedRect
+
+	if math.Abs(float64(rr.X+rr.Width/2-4.5)) > 0.01 ||
+		math.Abs(float64(rr.Y+rr.Height/2-2.5)) > 0.01 ||
+		rr.Width*rr.Height < 14.9 || rr.Width*rr.Height > 15.1 {
+		t.Errorf("unexpected rotated rect %+v", rr)
+	}
+
+	squares := instances[1]
+
+	if squares.ID != 12 || squares.Area != 25 || len(squares.Polygons) != 2 {
+		t.Fatalf("unexpected squares instance %+v", squares)
+	}
+
+	// largest region first
+	if squares.Polygons[0][0].X < 15 {
+		t.Errorf("expected larger square first, got %v", squares.Polygons)
+	}
+
+	decoded := squares.RLE.Decode(2)
+
+	for i := range mask {
+		if (mask[i] == 2) != (decoded[i] == 2) {
+			t.Fatalf("decoded RLE mismatch at %d", i)
+		}
+	}
+
+	if _, err := segData.Instances(SegMask{mask[:10]}, 1); err == nil {
+		t.Errorf("expected error for mask size mismatch")
+	}
+}
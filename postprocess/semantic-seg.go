@@ -0,0 +1,232 @@
+package postprocess
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/preprocess"
+	"gocv.io/x/gocv"
+)
+
+// SemanticSeg defines the struct for semantic segmentation model inference
+// post processing, such as DeepLabV3 and PP-LiteSeg, which classify every
+// pixel of the image
+type SemanticSeg struct {
+	// Params are the Model configuration parameters
+	Params SemanticSegParams
+}
+
+// SemanticSegParams defines the struct containing the semantic segmentation
+// parameters to use for post processing operations
+type SemanticSegParams struct {
+	// ObjectClassNum is the number of different classes the Model has been
+	// trained with, which is the number of channels of the NCHW or NHWC
+	// output tensor.  At most 256 classes are supported.
+	ObjectClassNum int
+}
+
+// DeepLabV3VOCParams returns an instance of SemanticSegParams configured for
+// a DeepLabV3 Model trained on the Pascal VOC dataset featuring:
+// - Object Classes: 21
+func DeepLabV3VOCParams() SemanticSegParams {
+	return SemanticSegParams{
+		ObjectClassNum: 21,
+	}
+}
+
+// PPLiteSegCityscapesParams returns an instance of SemanticSegParams
+// configured for a PP-LiteSeg Model trained on the Cityscapes dataset
+// featuring:
+// - Object Classes: 19
+func PPLiteSegCityscapesParams() SemanticSegParams {
+	return SemanticSegParams{
+		ObjectClassNum: 19,
+	}
+}
+
+// NewSemanticSeg returns an instance of the semantic segmentation post
+// processor
+func NewSemanticSeg(p SemanticSegParams) *SemanticSeg {
+	return &SemanticSeg{
+		Params: p,
+	}
+}
+
+// ClassArea is the area covered by a class in the segmentation class map
+type ClassArea struct {
+	// Class is the line number in the labels file of the class
+	Class int
+	// Pixels is the number of pixels of the class
+	Pixels int
+	// Ratio is the fraction of the image covered by the class from 0.0 to 1.0
+	Ratio float32
+}
+
+// Segment takes the RKNN outputs and writes the class of each pixel of the
+// source image to the classMap as a single channel uint8 Mat.  The letterbox
+// padding added by the resizer is removed and the map is upsampled to the
+// source image size.  The area of each class present in the map is returned
+// in class order.
+func (s *SemanticSeg) Segment(outputs *rknnlite.Outputs,
+	resizer *preprocess.Resizer, classMap *gocv.Mat) ([]ClassArea, error) {
+
+	if s.Params.ObjectClassNum <= 0 || s.Params.ObjectClassNum > 256 {
+		return nil, fmt.Errorf("invalid number of classes %d", s.Params.ObjectClassNum)
+	}
+
+	attrs := outputs.OutputAttributes()
+
+	if attrs.IONumber == 0 || len(attrs.Fmts) == 0 {
+		return nil, fmt.Errorf("model has no output tensors")
+	}
+
+	var outH, outW int
+	nhwc := false
+
+	switch attrs.Fmts[0] {
+	case rknnlite.TensorNCHW:
+		outH = int(attrs.DimHeights[0])
+		outW = int(attrs.DimWidths[0])
+
+	case rknnlite.TensorNHWC:
+		if len(attrs.Dims) == 0 || len(attrs.Dims[0]) < 4 {
+			return nil, fmt.Errorf("invalid NHWC output tensor dimensions")
+		}
+
+		outH = int(attrs.Dims[0][1])
+		outW = int(attrs.Dims[0][2])
+		nhwc = true
+
+	default:
+		return nil, fmt.Errorf("unsupported output tensor format %s, expected NCHW or NHWC",
+			attrs.Fmts[0])
+	}
+
+	scores := newOutputTensor(outputs, attrs, 0)
+
+	if scores.len() < s.Params.ObjectClassNum*outH*outW {
+		return nil, fmt.Errorf("output size %d is less than %d classes of %dx%d",
+			scores.len(), s.Params.ObjectClassNum, outH, outW)
+	}
+
+	classes := argmaxClasses(scores, s.Params.ObjectClassNum, outH*outW, nhwc)
+
+	outMat, err := gocv.NewMatFromBytes(outH, outW, gocv.MatTypeCV8U, classes)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class map mat: %w", err)
+	}
+
+	defer outMat.Close()
+
+	// crop the letterbox padding in output map coordinates
+	in := outputs.InputAttributes()
+	crop := letterboxCrop(resizer, int(in.Width), int(in.Height), outW, outH)
+
+	region := outMat.Region(crop)
+	defer region.Close()
+
+	// nearest neighbour so class labels are not blended
+	err = gocv.Resize(region, classMap,
+		image.Pt(resizer.SrcWidth(), resizer.SrcHeight()), 0, 0,
+		gocv.InterpolationNearestNeighbor)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to resize class map: %w", err)
+	}
+
+	buf, err := classMap.DataPtrUint8()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read class map: %w", err)
+	}
+
+	return classAreas(buf, s.Params.ObjectClassNum), nil
+}
+
+// argmaxClasses returns the class with the highest score of each pixel from
+// the NCHW scores of the classes, or the NHWC scores if nhwc is set
+func argmaxClasses(scores outputTensor, numClass, numPixels int, nhwc bool) []uint8 {
+
+	// NCHW scores are planes of each class, NHWC scores are the classes of
+	// each pixel
+	pixelStride, classStride := 1, numPixels
+
+	if nhwc {
+		pixelStride, classStride = numClass, 1
+	}
+
+	classes := make([]uint8, numPixels)
+	best := make([]float32, numPixels)
+
+	for i := range best {
+		best[i] = scores.at(i * pixelStride)
+	}
+
+	for c := 1; c < numClass; c++ {
+
+		offset := c * classStride
+
+		for i := range best {
+			if v := scores.at(offset + i*pixelStride); v > best[i] {
+				best[i] = v
+				classes[i] = uint8(c)
+			}
+		}
+	}
+
+	return classes
+}
+
+// letterboxCrop returns the region of an output map of outW x outH covering
+// the source image within the letterboxed Model input of modelW x modelH
+func letterboxCrop(resizer *preprocess.Resizer, modelW, modelH, outW, outH int) image.Rectangle {
+
+	scaleX := float32(outW) / float32(modelW)
+	scaleY := float32(outH) / float32(modelH)
+
+	x0 := float32(resizer.XPad())
+	y0 := float32(resizer.YPad())
+	x1 := x0 + float32(resizer.SrcWidth())*resizer.ScaleFactor()
+	y1 := y0 + float32(resizer.SrcHeight())*resizer.ScaleFactor()
+
+	rect := image.Rect(
+		int(x0*scaleX+0.5), int(y0*scaleY+0.5),
+		int(x1*scaleX+0.5), int(y1*scaleY+0.5),
+	).Intersect(image.Rect(0, 0, outW, outH))
+
+	// use the whole map if the crop rounds to nothing
+	if rect.Empty() {
+		return image.Rect(0, 0, outW, outH)
+	}
+
+	return rect
+}
+
+// classAreas returns the area of each class present in the class map
+func classAreas(classMap []uint8, numClass int) []ClassArea {
+
+	counts := make([]int, 256)
+
+	for _, c := range classMap {
+		counts[c]++
+	}
+
+	areas := make([]ClassArea, 0)
+
+	for c := 0; c < numClass; c++ {
+
+		if counts[c] == 0 {
+			continue
+		}
+
+		areas = append(areas, ClassArea{
+			Class:  c,
+			Pixels: counts[c],
+			Ratio:  float32(counts[c]) / float32(len(classMap)),
+		})
+	}
+
+	return areas
+}
@@ -0,0 +1,179 @@
+package postprocess
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+func TestArgmaxClasses(t *testing.T) {
+
+	// 3 classes of 4 pixels in NCHW order
+	scores := []float32{
+		0.1, 0.5, 0.3, 0.2,
+		0.7, 0.2, 0.3, 0.1,
+		0.2, 0.3, 0.1, 0.9,
+	}
+
+	// the same scores in NHWC order
+	nhwc := []float32{
+		0.1, 0.7, 0.2,
+		0.5, 0.2, 0.3,
+		0.3, 0.3, 0.1,
+		0.2, 0.1, 0.9,
+	}
+
+	qnt := make([]int8, len(scores))
+
+	for i, v := range scores {
+		qnt[i] = qntF32ToAffine(v, -128, 1.0/255)
+	}
+
+	tests := []struct {
+		name     string
+		scores   outputTensor
+		numClass int
+		nhwc     bool
+		expected []uint8
+	}{
+		{
+			name:     "float",
+			scores:   outputTensor{buf: scores},
+			numClass: 3,
+			// ties keep the lower class
+			expected: []uint8{1, 0, 0, 2},
+		},
+		{
+			name:     "int8",
+			scores:   outputTensor{qnt: qnt, zp: -128, scale: 1.0 / 255},
+			numClass: 3,
+			expected: []uint8{1, 0, 0, 2},
+		},
+		{
+			name:     "nhwc",
+			scores:   outputTensor{buf: nhwc},
+			numClass: 3,
+			nhwc:     true,
+			expected: []uint8{1, 0, 0, 2},
+		},
+		{
+			name:     "single class",
+			scores:   outputTensor{buf: scores},
+			numClass: 1,
+			expected: []uint8{0, 0, 0, 0},
+		},
+	}
+
+	for _, tc := range tests {
+
+		classes := argmaxClasses(tc.scores, tc.numClass, 4, tc.nhwc)
+
+		if !reflect.DeepEqual(classes, tc.expected) {
+			t.Errorf("%s: expected classes %v, got %v", tc.name, tc.expected, classes)
+		}
+	}
+}
+
+func TestLetterboxCrop(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		srcWidth  int
+		srcHeight int
+		outSize   int
+		expected  image.Rectangle
+	}{
+		{
+			name:      "no padding",
+			srcWidth:  1280,
+			srcHeight: 1280,
+			outSize:   160,
+			expected:  image.Rect(0, 0, 160, 160),
+		},
+		{
+			name:      "landscape padded top and bottom",
+			srcWidth:  1280,
+			srcHeight: 720,
+			outSize:   160,
+			expected:  image.Rect(0, 35, 160, 125),
+		},
+		{
+			name:      "portrait padded left and right",
+			srcWidth:  720,
+			srcHeight: 1280,
+			outSize:   160,
+			expected:  image.Rect(35, 0, 125, 160),
+		},
+		{
+			name:      "output map same size as input",
+			srcWidth:  1280,
+			srcHeight: 720,
+			outSize:   640,
+			expected:  image.Rect(0, 140, 640, 500),
+		},
+		{
+			name:      "crop rounds to nothing",
+			srcWidth:  1,
+			srcHeight: 1000,
+			outSize:   160,
+			expected:  image.Rect(0, 0, 160, 160),
+		},
+	}
+
+	for _, tc := range tests {
+
+		resizer := preprocess.NewResizer(tc.srcWidth, tc.srcHeight, 640, 640)
+		crop := letterboxCrop(resizer, 640, 640, tc.outSize, tc.outSize)
+		resizer.Close()
+
+		if crop != tc.expected {
+			t.Errorf("%s: expected crop %v, got %v", tc.name, tc.expected, crop)
+		}
+	}
+}
+
+func TestClassAreas(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		classMap []uint8
+		numClass int
+		expected []ClassArea
+	}{
+		{
+			name:     "classes in order",
+			classMap: []uint8{2, 0, 2, 2},
+			numClass: 3,
+			expected: []ClassArea{
+				{Class: 0, Pixels: 1, Ratio: 0.25},
+				{Class: 2, Pixels: 3, Ratio: 0.75},
+			},
+		},
+		{
+			name:     "classes beyond the class number are ignored",
+			classMap: []uint8{0, 1, 4, 4},
+			numClass: 2,
+			expected: []ClassArea{
+				{Class: 0, Pixels: 1, Ratio: 0.25},
+				{Class: 1, Pixels: 1, Ratio: 0.25},
+			},
+		},
+		{
+			name:     "empty map",
+			classMap: []uint8{},
+			numClass: 2,
+			expected: []ClassArea{},
+		},
+	}
+
+	for _, tc := range tests {
+
+		areas := classAreas(tc.classMap, tc.numClass)
+
+		if !reflect.DeepEqual(areas, tc.expected) {
+			t.Errorf("%s: expected areas %+v, got %+v", tc.name, tc.expected, areas)
+		}
+	}
+}
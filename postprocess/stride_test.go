@@ -0,0 +1,179 @@
+package postprocess
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// quantizedTensors returns an int8 output tensor of the values and a float32
+// output tensor of the same values after dequantization, so both tensors read
+// identical values
+func quantizedTensors(vals []float32, zp int32, scale float32) (outputTensor, outputTensor) {
+
+	qnt := make([]int8, len(vals))
+	deq := make([]float32, len(vals))
+
+	for i, v := range vals {
+		qnt[i] = qntF32ToAffine(v, zp, scale)
+		deq[i] = deqntAffineToF32(qnt[i], zp, scale)
+	}
+
+	return outputTensor{qnt: qnt, zp: zp, scale: scale}, outputTensor{buf: deq}
+}
+
+// randomValues returns n random values between min and max
+func randomValues(rng *rand.Rand, n int, min, max float32) []float32 {
+
+	vals := make([]float32, n)
+
+	for i := range vals {
+		vals[i] = min + rng.Float32()*(max-min)
+	}
+
+	return vals
+}
+
+func TestProcessStrideFloatMatchesInt8(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+
+	const (
+		grid     = 8
+		gridLen  = grid * grid
+		stride   = 8
+		classNum = 3
+		dflLen   = 16
+	)
+
+	// probability outputs and raw box or logit outputs
+	probZP, probScale := int32(-128), float32(1.0/255)
+	logitZP, logitScale := int32(0), float32(4.0/127)
+
+	probs := func(n int) (outputTensor, outputTensor) {
+		return quantizedTensors(randomValues(rng, n, 0, 1), probZP, probScale)
+	}
+
+	logits := func(n int) (outputTensor, outputTensor) {
+		return quantizedTensors(randomValues(rng, n, -4, 4), logitZP, logitScale)
+	}
+
+	v5Params := YOLOv5COCOParams()
+	v5Params.ObjectClassNum = classNum
+	v5Params.ProbBoxSize = classNum + 5
+	v5 := NewYOLOv5(v5Params)
+	v5I8, v5F32 := probs(3 * v5Params.ProbBoxSize * gridLen)
+
+	xParams := YOLOXCOCOParams()
+	xParams.ObjectClassNum = classNum
+	xParams.ProbBoxSize = classNum + 5
+	yx := NewYOLOX(xParams)
+	xI8, xF32 := probs(xParams.ProbBoxSize * gridLen)
+
+	dflParams := YOLOv8COCOParams()
+	dflParams.ObjectClassNum = classNum
+	v8 := NewYOLOv8(dflParams)
+	boxI8, boxF32 := logits(4 * dflLen * gridLen)
+	scoreI8, scoreF32 := probs(classNum * gridLen)
+	sumI8, sumF32 := probs(gridLen)
+
+	v10Params := YOLOv10COCOParams()
+	v10Params.ObjectClassNum = classNum
+	v10 := NewYOLOv10(v10Params)
+
+	v11Params := YOLOv11COCOParams()
+	v11Params.ObjectClassNum = classNum
+	v11 := NewYOLOv11(v11Params)
+
+	y26Params := YOLO26COCOParams()
+	y26Params.ObjectClassNum = classNum
+	y26 := NewYOLO26(y26Params)
+	ltrbI8, ltrbF32 := quantizedTensors(randomValues(rng, 4*gridLen, 0, 3), logitZP, logitScale)
+	y26ScoreI8, y26ScoreF32 := logits(classNum * gridLen)
+
+	tests := []struct {
+		name    string
+		process func(int8Output bool, data *strideData) int
+	}{
+		{
+			name: "yolov5",
+			process: func(int8Output bool, data *strideData) int {
+				if int8Output {
+					return v5.processStride(v5I8, v5Params.Strides[0], data)
+				}
+				return v5.processStride(v5F32, v5Params.Strides[0], data)
+			},
+		},
+		{
+			name: "yolox",
+			process: func(int8Output bool, data *strideData) int {
+				s := YOLOStride{Size: stride}
+				if int8Output {
+					return yx.processStride(xI8, s, data)
+				}
+				return yx.processStride(xF32, s, data)
+			},
+		},
+		{
+			name: "yolov8 with score sum",
+			process: func(int8Output bool, data *strideData) int {
+				if int8Output {
+					return v8.processStride(boxI8, scoreI8, sumI8, grid, grid, stride, dflLen, data)
+				}
+				return v8.processStride(boxF32, scoreF32, sumF32, grid, grid, stride, dflLen, data)
+			},
+		},
+		{
+			name: "yolov10",
+			process: func(int8Output bool, data *strideData) int {
+				if int8Output {
+					return v10.processStride(boxI8, scoreI8, sumI8, grid, grid, stride, dflLen, data)
+				}
+				return v10.processStride(boxF32, scoreF32, sumF32, grid, grid, stride, dflLen, data)
+			},
+		},
+		{
+			name: "yolov11 without score sum",
+			process: func(int8Output bool, data *strideData) int {
+				if int8Output {
+					return v11.processStride(boxI8, scoreI8, outputTensor{}, grid, grid, stride, dflLen, data)
+				}
+				return v11.processStride(boxF32, scoreF32, outputTensor{}, grid, grid, stride, dflLen, data)
+			},
+		},
+		{
+			name: "yolo26",
+			process: func(int8Output bool, data *strideData) int {
+				if int8Output {
+					return y26.processStride(ltrbI8, y26ScoreI8, grid, grid, stride, data)
+				}
+				return y26.processStride(ltrbF32, y26ScoreF32, grid, grid, stride, data)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+
+		dataI8 := &strideData{height: grid * stride, width: grid * stride}
+		dataF32 := &strideData{height: grid * stride, width: grid * stride}
+
+		countI8 := tc.process(true, dataI8)
+		countF32 := tc.process(false, dataF32)
+
+		if countI8 == 0 {
+			t.Errorf("%s: expected detections from synthetic stride", tc.name)
+			continue
+		}
+
+		if countI8 != countF32 {
+			t.Errorf("%s: expected %d float detections, got %d", tc.name, countI8, countF32)
+			continue
+		}
+
+		if !reflect.DeepEqual(dataI8.filterBoxes, dataF32.filterBoxes) ||
+			!reflect.DeepEqual(dataI8.objProbs, dataF32.objProbs) ||
+			!reflect.DeepEqual(dataI8.classID, dataF32.classID) {
+			t.Errorf("%s: int8 and float32 outputs decoded to different boxes", tc.name)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package postprocess
+
+import (
+	"fmt"
+
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+// YOLOProcessor defines an interface for the different versions of YOLO
+// post processors used for object detection
+type YOLOProcessor interface {
+	DetectObjects(outputs *rknnlite.Outputs,
+		resizer *preprocess.Resizer) result.DetectionResult
+}
+
+// YOLOFamily identifies the output head layout of a YOLO model
+type YOLOFamily string
+
+const (
+	// FamilyYOLOv5 are anchor based heads with one output per stride of
+	// 3*(5+classes) channels
+	FamilyYOLOv5 YOLOFamily = "yolov5"
+	// FamilyYOLOX are anchor free heads with one output per stride of
+	// 5+classes channels
+	FamilyYOLOX YOLOFamily = "yolox"
+	// FamilyYOLOv8 are DFL heads with box, score and optional score sum outputs
+	// per stride.  YOLOv10 and YOLOv11 models share the same output layout
+	// and are decoded with the YOLOv8 post processor
+	FamilyYOLOv8 YOLOFamily = "yolov8"
+	// FamilyYOLO26 are heads with direct box regression (no DFL) and a score
+	// output per stride
+	FamilyYOLO26 YOLOFamily = "yolo26"
+	// FamilyYOLONAS are heads with decoded boxes and class scores over all
+	// anchors in two outputs
+	FamilyYOLONAS YOLOFamily = "yolonas"
+)
+
+// YOLOLayout describes the output tensor layout of a YOLO model as detected
+// from its output tensor attributes
+type YOLOLayout struct {
+	// Family is the head family of the model
+	Family YOLOFamily
+	// ObjectClassNum is the number of object classes the Model outputs
+	ObjectClassNum int
+	// DFLLen is the length of the Distribution Focal Loss bins for each box
+	// side, zero if the head has no DFL
+	DFLLen int
+	// Strides are the stride sizes of each output branch, nil for heads
+	// without per stride outputs
+	Strides []int
+}
+
+// YOLOAuto defines the struct for a YOLO post processor that has been
+// configured automatically from the Model's output tensor layout
+type YOLOAuto struct {
+	// Layout is the output tensor layout detected from the Model
+	Layout YOLOLayout
+	// Processor is the post processor built for the detected head family
+	Processor YOLOProcessor
+}
+
+// NewYOLOAuto returns an instance of a YOLO post processor configured from
+// the output tensor attributes of the given runtime.  The head family, number
+// of object classes and strides are detected from the number of outputs, their
+// dimensions, DFL length, and class channels.  If labels are provided their
+// count must match the number of classes the Model outputs, pass nil to skip
+// this check.  Thresholds use the same defaults as the COCO parameters of each
+// post processor.
+func NewYOLOAuto(rt *rknnlite.Runtime, labels []string) (*YOLOAuto, error) {
+
+	inAttrs := rt.InputAttrs()
+
+	if len(inAttrs) == 0 {
+		return nil, fmt.Errorf("model has no input tensors")
+	}
+
+	// set default vars where inputAttr is NCHW
+	inHeight := int(inAttrs[0].Dims[2])
+
+	if inAttrs[0].Fmt == rknnlite.TensorNHWC {
+		inHeight = int(inAttrs[0].Dims[1])
+	}
+
+	layout, err := DetectYOLOLayout(rt.OutputAttrs(), inHeight, len(labels))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if labels != nil && len(labels) != layout.ObjectClassNum {
+		return nil, fmt.Errorf("model outputs %d classes but %d labels were given",
+			layout.ObjectClassNum, len(labels))
+	}
+
+	y := &YOLOAuto{
+		Layout: layout,
+	}
+
+	switch layout.Family {
+	case FamilyYOLOv5:
+		p := YOLOv5COCOParams()
+		p.ObjectClassNum = layout.ObjectClassNum
+		p.ProbBoxSize = layout.ObjectClassNum + 5
+
+		// keep the COCO anchors by branch position but use the detected stride
+		// sizes
+		for i, size := range layout.Strides {
+			p.Strides[i].Size = size
+		}
+
+		y.Processor = NewYOLOv5(p)
+
+	case FamilyYOLOX:
+		p := YOLOXCOCOParams()
+		p.ObjectClassNum = layout.ObjectClassNum
+		p.ProbBoxSize = layout.ObjectClassNum + 5
+		p.Strides = make([]YOLOStride, len(layout.Strides))
+
+		for i, size := range layout.Strides {
+			p.Strides[i] = YOLOStride{Size: size}
+		}
+
+		y.Processor = NewYOLOX(p)
+
+	case FamilyYOLOv8:
+		p := YOLOv8COCOParams()
+		p.ObjectClassNum = layout.ObjectClassNum
+		y.Processor = NewYOLOv8(p)
+
+	case FamilyYOLO26:
+		p := YOLO26COCOParams()
+		p.ObjectClassNum = layout.ObjectClassNum
+		y.Processor = NewYOLO26(p)
+
+	case FamilyYOLONAS:
+		p := YOLONASCOCOParams()
+		p.ObjectClassNum = layout.ObjectClassNum
+		y.Processor = NewYOLONAS(p)
+	}
+
+	return y, nil
+}
+
+// DetectObjects takes the RKNN outputs and runs the object detection process
+// of the detected head family then returns the results
+func (y *YOLOAuto) DetectObjects(outputs *rknnlite.Outputs,
+	resizer *preprocess.Resizer) result.DetectionResult {
+	return y.Processor.DetectObjects(outputs, resizer)
+}
+
+// DetectYOLOLayout identifies the YOLO head family from the Model's output
+// tensor attributes.  The inHeight is the pixel height of the Model input used
+// to calculate stride sizes.  The numLabels is used to resolve layouts whose
+// channel count is valid for more than one family and must match the classes
+// of a YOLOv5 or YOLOX head, pass zero if unknown in which case an ambiguous
+// channel count is detected as YOLOv5.
+func DetectYOLOLayout(attrs []rknnlite.TensorAttr, inHeight int,
+	numLabels int) (YOLOLayout, error) {
+
+	switch len(attrs) {
+	case 2:
+		// YOLO-NAS has boxes [1, anchors, 4] and scores [1, anchors, classes]
+		if attrs[0].NDims == 3 && attrs[1].NDims == 3 && attrs[0].Dims[2] == 4 &&
+			attrs[0].Dims[1] == attrs[1].Dims[1] {
+			return YOLOLayout{
+				Family:         FamilyYOLONAS,
+				ObjectClassNum: int(attrs[1].Dims[2]),
+			}, nil
+		}
+
+	case 3:
+		return detectAnchorLayout(attrs, inHeight, numLabels)
+
+	case 6, 9:
+		return detectBranchLayout(attrs, inHeight)
+	}
+
+	return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout with %d outputs: %s",
+		len(attrs), layoutString(attrs))
+}
+
+// detectAnchorLayout identifies the YOLOv5 and YOLOX heads which have a single
+// output per stride containing box, objectness and class channels
+func detectAnchorLayout(attrs []rknnlite.TensorAttr, inHeight int,
+	numLabels int) (YOLOLayout, error) {
+
+	channels := int(attrs[0].Dims[1])
+
+	strides, err := branchStrides(attrs, 1, inHeight)
+
+	if err != nil {
+		return YOLOLayout{}, err
+	}
+
+	for _, attr := range attrs {
+		if int(attr.Dims[1]) != channels {
+			return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout, channel count differs between outputs: %s",
+				layoutString(attrs))
+		}
+	}
+
+	// a YOLOv5 head has 3 anchors per grid cell, a YOLOX head has none.  A
+	// channel count divisible by 3 is valid for both, such as 18 channels
+	// being a 1 class YOLOv5 or 13 class YOLOX head, so without labels to
+	// resolve it the YOLOv5 head is assumed
+	v5 := channels%3 == 0 && channels/3 > 5
+	x := channels > 5
+
+	if numLabels > 0 {
+		v5 = v5 && channels/3-5 == numLabels
+		x = !v5 && x && channels-5 == numLabels
+
+		if !v5 && !x {
+			return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout, %d channels does not match %d labels: %s",
+				channels, numLabels, layoutString(attrs))
+		}
+	}
+
+	if v5 {
+		return YOLOLayout{
+			Family:         FamilyYOLOv5,
+			ObjectClassNum: channels/3 - 5,
+			Strides:        strides,
+		}, nil
+	}
+
+	if x {
+		return YOLOLayout{
+			Family:         FamilyYOLOX,
+			ObjectClassNum: channels - 5,
+			Strides:        strides,
+		}, nil
+	}
+
+	return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout with %d channels: %s",
+		channels, layoutString(attrs))
+}
+
+// detectBranchLayout identifies the YOLOv8 and YOLO26 heads which have a box
+// and score output per stride, plus an optional score sum output
+func detectBranchLayout(attrs []rknnlite.TensorAttr,
+	inHeight int) (YOLOLayout, error) {
+
+	outputPerBranch := len(attrs) / 3
+
+	strides, err := branchStrides(attrs, outputPerBranch, inHeight)
+
+	if err != nil {
+		return YOLOLayout{}, err
+	}
+
+	boxChannels := int(attrs[0].Dims[1])
+	classNum := int(attrs[1].Dims[1])
+
+	for i := 0; i < 3; i++ {
+		if int(attrs[i*outputPerBranch].Dims[1]) != boxChannels ||
+			int(attrs[i*outputPerBranch+1].Dims[1]) != classNum {
+			return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout, channel count differs between branches: %s",
+				layoutString(attrs))
+		}
+
+		if outputPerBranch == 3 && attrs[i*outputPerBranch+2].Dims[1] != 1 {
+			return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout, score sum output has %d channels: %s",
+				attrs[i*outputPerBranch+2].Dims[1], layoutString(attrs))
+		}
+	}
+
+	switch {
+	case boxChannels == 4 && outputPerBranch == 2:
+		return YOLOLayout{
+			Family:         FamilyYOLO26,
+			ObjectClassNum: classNum,
+			Strides:        strides,
+		}, nil
+
+	case boxChannels > 4 && boxChannels%4 == 0:
+		return YOLOLayout{
+			Family:         FamilyYOLOv8,
+			ObjectClassNum: classNum,
+			DFLLen:         boxChannels / 4,
+			Strides:        strides,
+		}, nil
+	}
+
+	return YOLOLayout{}, fmt.Errorf("unknown YOLO output layout with %d box channels: %s",
+		boxChannels, layoutString(attrs))
+}
+
+// branchStrides calculates the stride size of each of the 3 output branches
+// from the grid height of the first output in each branch
+func branchStrides(attrs []rknnlite.TensorAttr, outputPerBranch int,
+	inHeight int) ([]int, error) {
+
+	strides := make([]int, 3)
+
+	for i := 0; i < 3; i++ {
+		attr := attrs[i*outputPerBranch]
+		gridH := int(attr.Dims[2])
+
+		if attr.NDims != 4 || gridH == 0 || inHeight%gridH != 0 {
+			return nil, fmt.Errorf("unknown YOLO output layout, output %d is not a stride grid: %s",
+				i*outputPerBranch, layoutString(attrs))
+		}
+
+		strides[i] = inHeight / gridH
+	}
+
+	return strides, nil
+}
+
+// layoutString formats the dimensions of the output tensors for use in error
+// messages
+func layoutString(attrs []rknnlite.TensorAttr) string {
+
+	str := ""
+
+	for i, attr := range attrs {
+		if i > 0 {
+			str += ", "
+		}
+
+		str += fmt.Sprintf("%v", attr.Dims[:attr.NDims])
+	}
+
+	return str
+}
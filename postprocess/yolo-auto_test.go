@@ -0,0 +1,172 @@
+package postprocess
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/swdee/go-rknnlite"
+)
+
+// tensorAttr returns the attributes of an output tensor with the given dims
+func tensorAttr(dims ...uint32) rknnlite.TensorAttr {
+
+	attr := rknnlite.TensorAttr{NDims: uint32(len(dims))}
+	copy(attr.Dims[:], dims)
+
+	return attr
+}
+
+// branchAttrs returns the outputs of a stride branch head with the channels
+// of each output per branch for a 640x640 input
+func branchAttrs(channels ...uint32) []rknnlite.TensorAttr {
+
+	attrs := make([]rknnlite.TensorAttr, 0)
+
+	for _, grid := range []uint32{80, 40, 20} {
+		for _, c := range channels {
+			attrs = append(attrs, tensorAttr(1, c, grid, grid))
+		}
+	}
+
+	return attrs
+}
+
+func TestDetectYOLOLayout(t *testing.T) {
+
+	strides := []int{8, 16, 32}
+
+	tests := []struct {
+		name      string
+		attrs     []rknnlite.TensorAttr
+		numLabels int
+		expected  YOLOLayout
+		err       bool
+	}{
+		{
+			name:     "yolov5 coco",
+			attrs:    branchAttrs(255),
+			expected: YOLOLayout{Family: FamilyYOLOv5, ObjectClassNum: 80, Strides: strides},
+		},
+		{
+			name:      "yolov5 coco with labels",
+			attrs:     branchAttrs(255),
+			numLabels: 80,
+			expected:  YOLOLayout{Family: FamilyYOLOv5, ObjectClassNum: 80, Strides: strides},
+		},
+		{
+			name:     "yolox coco",
+			attrs:    branchAttrs(85),
+			expected: YOLOLayout{Family: FamilyYOLOX, ObjectClassNum: 80, Strides: strides},
+		},
+		{
+			name:     "ambiguous channels without labels",
+			attrs:    branchAttrs(18),
+			expected: YOLOLayout{Family: FamilyYOLOv5, ObjectClassNum: 1, Strides: strides},
+		},
+		{
+			name:      "ambiguous channels with yolox labels",
+			attrs:     branchAttrs(18),
+			numLabels: 13,
+			expected:  YOLOLayout{Family: FamilyYOLOX, ObjectClassNum: 13, Strides: strides},
+		},
+		{
+			name:      "ambiguous channels with yolov5 labels",
+			attrs:     branchAttrs(18),
+			numLabels: 1,
+			expected:  YOLOLayout{Family: FamilyYOLOv5, ObjectClassNum: 1, Strides: strides},
+		},
+		{
+			name:      "labels match neither head",
+			attrs:     branchAttrs(18),
+			numLabels: 3,
+			err:       true,
+		},
+		{
+			name:      "yolox labels mismatch",
+			attrs:     branchAttrs(85),
+			numLabels: 20,
+			err:       true,
+		},
+		{
+			name:     "yolov8 with score sum",
+			attrs:    branchAttrs(64, 80, 1),
+			expected: YOLOLayout{Family: FamilyYOLOv8, ObjectClassNum: 80, DFLLen: 16, Strides: strides},
+		},
+		{
+			name:     "yolov8 without score sum",
+			attrs:    branchAttrs(64, 80),
+			expected: YOLOLayout{Family: FamilyYOLOv8, ObjectClassNum: 80, DFLLen: 16, Strides: strides},
+		},
+		{
+			name:     "yolo26",
+			attrs:    branchAttrs(4, 80),
+			expected: YOLOLayout{Family: FamilyYOLO26, ObjectClassNum: 80, Strides: strides},
+		},
+		{
+			name: "yolo-nas",
+			attrs: []rknnlite.TensorAttr{
+				tensorAttr(1, 8400, 4), tensorAttr(1, 8400, 80),
+			},
+			expected: YOLOLayout{Family: FamilyYOLONAS, ObjectClassNum: 80},
+		},
+		{
+			name: "yolo-nas anchor count differs",
+			attrs: []rknnlite.TensorAttr{
+				tensorAttr(1, 8400, 4), tensorAttr(1, 2100, 80),
+			},
+			err: true,
+		},
+		{
+			name:  "unknown output count",
+			attrs: []rknnlite.TensorAttr{tensorAttr(1, 84, 8400)},
+			err:   true,
+		},
+		{
+			name: "channels differ between outputs",
+			attrs: []rknnlite.TensorAttr{
+				tensorAttr(1, 255, 80, 80), tensorAttr(1, 255, 40, 40),
+				tensorAttr(1, 85, 20, 20),
+			},
+			err: true,
+		},
+		{
+			name:  "score sum with multiple channels",
+			attrs: branchAttrs(64, 80, 2),
+			err:   true,
+		},
+		{
+			name:  "box channels not divisible for dfl",
+			attrs: branchAttrs(6, 80),
+			err:   true,
+		},
+		{
+			name: "grid is not a stride of the input",
+			attrs: []rknnlite.TensorAttr{
+				tensorAttr(1, 255, 80, 80), tensorAttr(1, 255, 40, 40),
+				tensorAttr(1, 255, 21, 21),
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range tests {
+
+		layout, err := DetectYOLOLayout(tc.attrs, 640, tc.numLabels)
+
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got layout %+v", tc.name, layout)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(layout, tc.expected) {
+			t.Errorf("%s: expected layout %+v, got %+v", tc.name, tc.expected, layout)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 )
@@ -13,6 +14,9 @@ type YOLO26 struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLO26Params defines the struct containing the YOLO26 parameters to use
@@ -56,6 +60,12 @@ func NewYOLO26(p YOLO26Params) *YOLO26 {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLO26) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLO26Result defines a struct used for object detection results
 type YOLO26Result struct {
 	DetectResults []result.DetectResult
@@ -90,12 +100,8 @@ func (y *YOLO26) DetectObjects(outputs *rknnlite.Outputs,
 		stride := int(data.height) / gridH
 
 		validCount += y.processStride(
-			outputs.Output[boxIdx].BufInt,
-			outputs.OutputAttributes().ZPs[boxIdx],
-			outputs.OutputAttributes().Scales[boxIdx],
-			outputs.Output[scoreIdx].BufInt,
-			outputs.OutputAttributes().ZPs[scoreIdx],
-			outputs.OutputAttributes().Scales[scoreIdx],
+			newOutputTensor(outputs, outputs.OutputAttributes(), boxIdx),
+			newOutputTensor(outputs, outputs.OutputAttributes(), scoreIdx),
 			gridH, gridW, stride,
 			data,
 		)
@@ -112,15 +118,8 @@ func (y *YOLO26) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	classSet := make(map[int]bool)
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	group := make([]result.DetectResult, 0)
 	lastCount := 0
@@ -137,14 +136,17 @@ func (y *YOLO26) DetectObjects(outputs *rknnlite.Outputs,
 		x2 := x1 + data.filterBoxes[n*4+2]
 		y2 := y1 + data.filterBoxes[n*4+3]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		detectResult := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
-			Probability: data.objProbs[n],
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: data.objProbs[i],
 			Class:       data.classID[n],
 			ID:          y.idGen.GetNext(),
 		}
@@ -158,34 +160,29 @@ func (y *YOLO26) DetectObjects(outputs *rknnlite.Outputs,
 	}
 }
 
-// processStride processes the given stride
-func (y *YOLO26) processStride(boxTensor []int8, boxZP int32, boxScale float32,
-	scoreTensor []int8, scoreZP int32, scoreScale float32,
-	gridH int, gridW int, stride int,
-	data *strideData) int {
+// processStride processes the given stride of int8 or float32 output tensors
+func (y *YOLO26) processStride(boxTensor, scoreTensor outputTensor,
+	gridH int, gridW int, stride int, data *strideData) int {
 
 	validCount := 0
 	gridLen := gridH * gridW
 
-	// Convert probability threshold to logit, then to INT8.
-	// This lets us compare raw INT8 class scores without calling sigmoid
-	// for every class at every grid cell.
+	// compare raw class scores against the threshold as a logit to avoid
+	// calling sigmoid for every class at every grid cell
 	scoreThresLogit := unsigmoid(y.Params.BoxThreshold)
-	scoreThresI8 := qntF32ToAffine(scoreThresLogit, scoreZP, scoreScale)
 
 	for i := 0; i < gridH; i++ {
 		for j := 0; j < gridW; j++ {
 
 			baseOffset := i*gridW + j
 			maxClassID := -1
-			maxScoreI8 := scoreThresI8
+			maxScore := scoreThresLogit
 
 			for c := 0; c < y.Params.ObjectClassNum; c++ {
-				scoreOffset := baseOffset + c*gridLen
-				score := scoreTensor[scoreOffset]
+				score := scoreTensor.at(baseOffset + c*gridLen)
 
-				if score > maxScoreI8 {
-					maxScoreI8 = score
+				if score > maxScore {
+					maxScore = score
 					maxClassID = c
 				}
 			}
@@ -194,10 +191,10 @@ func (y *YOLO26) processStride(boxTensor []int8, boxZP int32, boxScale float32,
 				continue
 			}
 
-			left := deqntAffineToF32(boxTensor[baseOffset+0*gridLen], boxZP, boxScale)
-			top := deqntAffineToF32(boxTensor[baseOffset+1*gridLen], boxZP, boxScale)
-			right := deqntAffineToF32(boxTensor[baseOffset+2*gridLen], boxZP, boxScale)
-			bottom := deqntAffineToF32(boxTensor[baseOffset+3*gridLen], boxZP, boxScale)
+			left := boxTensor.at(baseOffset + 0*gridLen)
+			top := boxTensor.at(baseOffset + 1*gridLen)
+			right := boxTensor.at(baseOffset + 2*gridLen)
+			bottom := boxTensor.at(baseOffset + 3*gridLen)
 
 			cx := float32(j) + 0.5
 			cy := float32(i) + 0.5
@@ -214,10 +211,8 @@ func (y *YOLO26) processStride(boxTensor []int8, boxZP int32, boxScale float32,
 				continue
 			}
 
-			score := sigmoid(deqntAffineToF32(maxScoreI8, scoreZP, scoreScale))
-
 			data.filterBoxes = append(data.filterBoxes, x1, y1, w, h)
-			data.objProbs = append(data.objProbs, score)
+			data.objProbs = append(data.objProbs, sigmoid(maxScore))
 			data.classID = append(data.classID, maxClassID)
 			validCount++
 		}
@@ -2,9 +2,9 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
-	"sort"
 )
 
 // YOLONAS defines the struct for YOLONAS model inference post processing
@@ -14,6 +14,9 @@ type YOLONAS struct {
 	// idGen is the counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLONASParams defines the struct containing the YOLONASParams parameters to use
@@ -57,6 +60,12 @@ func NewYOLONAS(p YOLONASParams) *YOLONAS {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLONAS) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLONASResult defines a struct used for object detection results
 type YOLONASResult struct {
 	DetectResults []result.DetectResult
@@ -73,17 +82,13 @@ func (r YOLONASResult) GetDetectResults() []result.DetectResult {
 func (y *YOLONAS) DetectObjects(outputs *rknnlite.Outputs,
 	resizer *preprocess.Resizer) result.DetectionResult {
 
-	boxTensor := outputs.Output[0].BufInt
-	scoreTensor := outputs.Output[1].BufInt
+	attrs := outputs.OutputAttributes()
+	boxTensor := newOutputTensor(outputs, attrs, 0)
+	scoreTensor := newOutputTensor(outputs, attrs, 1)
 
-	dflLen := int(outputs.OutputAttributes().DimForDFL)
+	dflLen := int(attrs.DimForDFL)
 	numClasses := y.Params.ObjectClassNum
 
-	boxZP := outputs.OutputAttributes().ZPs[0]
-	boxScale := outputs.OutputAttributes().Scales[0]
-	scoreZP := outputs.OutputAttributes().ZPs[1]
-	scoreScale := outputs.OutputAttributes().Scales[1]
-
 	inWidth := outputs.InputAttributes().Width
 	inHeight := outputs.InputAttributes().Height
 
@@ -99,11 +104,11 @@ func (y *YOLONAS) DetectObjects(outputs *rknnlite.Outputs,
 			break
 		}
 
-		// dequantize raw box outputs, corner-based decode (x1,y1,x2,y2)
-		x1p := deqntAffineToF32(boxTensor[a*4+0], boxZP, boxScale)
-		y1p := deqntAffineToF32(boxTensor[a*4+1], boxZP, boxScale)
-		x2p := deqntAffineToF32(boxTensor[a*4+2], boxZP, boxScale)
-		y2p := deqntAffineToF32(boxTensor[a*4+3], boxZP, boxScale)
+		// corner-based decode (x1,y1,x2,y2)
+		x1p := boxTensor.at(a*4 + 0)
+		y1p := boxTensor.at(a*4 + 1)
+		x2p := boxTensor.at(a*4 + 2)
+		y2p := boxTensor.at(a*4 + 3)
 
 		// undo letterbox
 		x1 := (x1p - float32(resizer.XPad())) / resizer.ScaleFactor()
@@ -116,10 +121,8 @@ func (y *YOLONAS) DetectObjects(outputs *rknnlite.Outputs,
 		bestClass := 0
 
 		for c := 0; c < numClasses; c++ {
-			// direct dequantitize a probability in [0..1]
-			p := deqntAffineToF32(scoreTensor[a*numClasses+c], scoreZP, scoreScale)
 
-			if p > bestProb {
+			if p := scoreTensor.at(a*numClasses + c); p > bestProb {
 				bestProb = p
 				bestClass = c
 			}
@@ -130,13 +133,16 @@ func (y *YOLONAS) DetectObjects(outputs *rknnlite.Outputs,
 			continue
 		}
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, inWidth),
+			Top:    clamp(y1, 0, inHeight),
+			Right:  clamp(x2, 0, inWidth),
+			Bottom: clamp(y2, 0, inHeight),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, inWidth)),
-				Top:    int(clamp(y1, 0, inHeight)),
-				Right:  int(clamp(x2, 0, inWidth)),
-				Bottom: int(clamp(y2, 0, inHeight)),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: bestProb,
 			Class:       bestClass,
 			ID:          y.idGen.GetNext(),
@@ -146,51 +152,18 @@ func (y *YOLONAS) DetectObjects(outputs *rknnlite.Outputs,
 		lastCount++
 	}
 
-	return YOLONASResult{
-		DetectResults: y.nms(group, y.Params.NMSThreshold),
-	}
-}
-
-// nms applies non maximum supression to detection results
-func (y *YOLONAS) nms(dets []result.DetectResult, iouThresh float32) []result.DetectResult {
-
-	// sort descending by Probability
-	sort.Slice(dets, func(i, j int) bool {
-		return dets[i].Probability > dets[j].Probability
-	})
-
-	keep := make([]result.DetectResult, 0, len(dets))
-	used := make([]bool, len(dets))
-
-	for i := range dets {
-		if used[i] {
-			continue
-		}
-
-		keep = append(keep, dets[i])
-
-		for j := i + 1; j < len(dets); j++ {
-			if used[j] {
-				continue
-			}
-
-			xmin0 := float32(dets[i].Box.Left)
-			ymin0 := float32(dets[i].Box.Top)
-			xmax0 := float32(dets[i].Box.Right)
-			ymax0 := float32(dets[i].Box.Bottom)
+	sup := y.suppressor
 
-			xmin1 := float32(dets[j].Box.Left)
-			ymin1 := float32(dets[j].Box.Top)
-			xmax1 := float32(dets[j].Box.Right)
-			ymax1 := float32(dets[j].Box.Bottom)
-
-			iou := calculateOverlap(xmin0, ymin0, xmax0, ymax0, xmin1, ymin1, xmax1, ymax1)
-
-			if iou > iouThresh {
-				used[j] = true
-			}
-		}
+	if sup == nil {
+		// class agnostic greedy NMS
+		sup = nms.New(nms.Params{
+			Method:        nms.Greedy,
+			IoUThreshold:  y.Params.NMSThreshold,
+			ClassAgnostic: true,
+		})
 	}
 
-	return keep
+	return YOLONASResult{
+		DetectResults: sup.Suppress(group),
+	}
 }
@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 )
@@ -13,6 +14,9 @@ type YOLOv10 struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv10Params defines the struct containing the YOLOv10 parameters to use
@@ -61,6 +65,12 @@ func NewYOLOv10(p YOLOv10Params) *YOLOv10 {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to apply no NMS as the Model is NMS free.
+func (y *YOLOv10) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLOv10Result defines a struct used for object detection results
 type YOLOv10Result struct {
 	DetectResults []result.DetectResult
@@ -82,40 +92,34 @@ func (y *YOLOv10) DetectObjects(outputs *rknnlite.Outputs,
 	validCount := 0
 	stride := 0
 
+	attrs := outputs.OutputAttributes()
+
 	// distribution focal loss (DFL)
-	dflLen := int(outputs.OutputAttributes().DimForDFL / 4)
+	dflLen := int(attrs.DimForDFL / 4)
 
-	outputPerBranch := int(outputs.OutputAttributes().IONumber / 3)
+	outputPerBranch := int(attrs.IONumber / 3)
 
 	for i := 0; i < 3; i++ {
 
-		scoreSum := make([]int8, 0)
-		scoreSumZp := int32(0)
-		scoreSumScale := float32(1.0)
+		boxIdx := i * outputPerBranch
+		scoreIdx := i*outputPerBranch + 1
+
+		var scoreSum outputTensor
 
 		if outputPerBranch == 3 {
-			scoreSum = outputs.Output[i*outputPerBranch+2].BufInt
-			scoreSumZp = outputs.OutputAttributes().ZPs[i*outputPerBranch+2]
-			scoreSumScale = outputs.OutputAttributes().Scales[i*outputPerBranch+2]
+			scoreSum = newOutputTensor(outputs, attrs, i*outputPerBranch+2)
 		}
 
-		boxIdx := i * outputPerBranch
-		scoreIdx := i*outputPerBranch + 1
-
-		gridH := int(outputs.OutputAttributes().DimHeights[boxIdx])
-		gridW := int(outputs.OutputAttributes().DimWidths[boxIdx])
+		gridH := int(attrs.DimHeights[boxIdx])
+		gridW := int(attrs.DimWidths[boxIdx])
 
 		stride = int(data.height) / gridH
 
-		// same as process_i8 in C code
+		// same as process_i8 and process_fp32 in C code
 		validCount += y.processStride(
-			outputs.Output[boxIdx].BufInt,
-			outputs.OutputAttributes().ZPs[boxIdx],
-			outputs.OutputAttributes().Scales[boxIdx],
-			outputs.Output[scoreIdx].BufInt,
-			outputs.OutputAttributes().ZPs[scoreIdx],
-			outputs.OutputAttributes().Scales[scoreIdx],
-			scoreSum, scoreSumZp, scoreSumScale,
+			newOutputTensor(outputs, attrs, boxIdx),
+			newOutputTensor(outputs, attrs, scoreIdx),
+			scoreSum,
 			gridH, gridW, stride, dflLen,
 			data,
 		)
@@ -136,6 +140,10 @@ func (y *YOLOv10) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
+	if y.suppressor != nil {
+		suppressCandidates(y.suppressor, validCount, data, indexArray, 4, false)
+	}
+
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
 	lastCount := 0
@@ -153,13 +161,16 @@ func (y *YOLOv10) DetectObjects(outputs *rknnlite.Outputs,
 		id := data.classID[n]
 		objConf := data.objProbs[i]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -174,41 +185,41 @@ func (y *YOLOv10) DetectObjects(outputs *rknnlite.Outputs,
 	}
 }
 
-// processStride processes the given stride
-func (y *YOLOv10) processStride(boxTensor []int8, boxZP int32, boxScale float32,
-	scoreTensor []int8, scoreZP int32, scoreScale float32,
-	scoreSumTensor []int8, scoreSumZP int32, scoreSumScale float32,
-	gridH int, gridW int, stride int, dflLen int,
-	data *strideData) int {
+// processStride processes the given stride of int8 or float32 output tensors.
+// Each class above the box threshold is returned as a detection.
+func (y *YOLOv10) processStride(boxTensor, scoreTensor, scoreSumTensor outputTensor,
+	gridH int, gridW int, stride int, dflLen int, data *strideData) int {
 
 	validCount := 0
 	gridLen := gridH * gridW
-	scoreThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreZP, scoreScale)
-	scoreSumThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreSumZP, scoreSumScale)
 
 	for i := 0; i < gridH; i++ {
 	kLoop:
 		for j := 0; j < gridW; j++ {
 
 			offset := i*gridW + j
-			maxClassID := -1
 
 			// Quick filtering using score sum
-			if scoreSumTensor != nil && len(scoreSumTensor) > 0 {
-				if scoreSumTensor[offset] < scoreSumThresI8 {
+			if scoreSumTensor.len() > 0 {
+				if scoreSumTensor.at(offset) < y.Params.BoxThreshold {
 					continue
 				}
 			}
 
-			maxScore := int8(-scoreZP)
+			// the box is shared by all classes of the grid cell so is only
+			// computed once a class is above the threshold
+			var box []float32
 
 			for c := 0; c < y.Params.ObjectClassNum; c++ {
 
-				scoreOffset := i*gridW + j + c*gridLen
+				score := scoreTensor.at(offset + c*gridLen)
+
+				if score <= y.Params.BoxThreshold {
+					continue
+				}
+
+				if box == nil {
 
-				if scoreTensor[scoreOffset] > scoreThresI8 {
-					maxScore = scoreTensor[scoreOffset]
-					maxClassID = c
 					beforeDFL := make([]float32, 4*dflLen)
 
 					for k := 0; k < dflLen*4; k++ {
@@ -218,28 +229,27 @@ func (y *YOLOv10) processStride(boxTensor []int8, boxZP int32, boxScale float32,
 						// sometimes the offset would be greater than key in boxTensor
 						// when running inference on streaming so need to check
 						// to stop panic.
-						if offset > len(boxTensor) {
+						if offset+k*gridLen >= boxTensor.len() {
 							break kLoop
 						}
 
-						beforeDFL[k] = deqntAffineToF32(boxTensor[offset], boxZP, boxScale)
-						offset += gridLen
+						beforeDFL[k] = boxTensor.at(offset + k*gridLen)
 					}
 
-					box := computeDFL(beforeDFL[:], dflLen)
-
-					x1 := (-box[0] + float32(j) + 0.5) * float32(stride)
-					y1 := (-box[1] + float32(i) + 0.5) * float32(stride)
-					x2 := (box[2] + float32(j) + 0.5) * float32(stride)
-					y2 := (box[3] + float32(i) + 0.5) * float32(stride)
-					w := x2 - x1
-					h := y2 - y1
-					data.filterBoxes = append(data.filterBoxes, x1, y1, w, h)
-
-					data.objProbs = append(data.objProbs, deqntAffineToF32(maxScore, scoreZP, scoreScale))
-					data.classID = append(data.classID, maxClassID)
-					validCount++
+					box = computeDFL(beforeDFL[:], dflLen)
 				}
+
+				x1 := (-box[0] + float32(j) + 0.5) * float32(stride)
+				y1 := (-box[1] + float32(i) + 0.5) * float32(stride)
+				x2 := (box[2] + float32(j) + 0.5) * float32(stride)
+				y2 := (box[3] + float32(i) + 0.5) * float32(stride)
+				w := x2 - x1
+				h := y2 - y1
+				data.filterBoxes = append(data.filterBoxes, x1, y1, w, h)
+
+				data.objProbs = append(data.objProbs, score)
+				data.classID = append(data.classID, c)
+				validCount++
 			}
 		}
 	}
@@ -0,0 +1,260 @@
+package postprocess
+
+import (
+	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+// YOLOv11Pose defines the struct for YOLOv11 pose model inference post
+// processing
+type YOLOv11Pose struct {
+	// Params are the Model configuration parameters
+	Params YOLOv11PoseParams
+	// nextID is a counter that increments and provides the next number
+	// for each detection result ID
+	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
+}
+
+// YOLOv11PoseParams defines the struct containing the YOLOv11 pose parameters
+// to use for post processing operations
+type YOLOv11PoseParams struct {
+	// BoxThreshold is the minimum probability score required for a bounding box
+	// region to be considered for processing
+	BoxThreshold float32
+	// NMSThreshold is the Non-Maximum Suppression threshold used for defining
+	// the maximum allowed Intersection Over Union (IoU) between two
+	// bounding boxes for both to be kept
+	NMSThreshold float32
+	// ObjectClassNum is the number of different object classes the Model has
+	// been trained with
+	ObjectClassNum int
+	// MaxObjectNumber is the maximum number of objects detected that can be
+	// returned
+	MaxObjectNumber int
+	// Schema is the keypoint schema the pose model is trained on, which sets
+	// the number of keypoints of each object.  Defaults to COCO 17 if not set.
+	Schema result.KeypointSchema
+}
+
+// YOLOv11PoseCOCOParams returns an instance of YOLOv11PoseParams configured
+// with default values for a Model trained on the COCO dataset featuring:
+// - Object Classes: 1
+// - Box Threshold: 0.25
+// - NMS Threshold: 0.45
+// - Maximum Object Number: 64
+// - KeyPoints Schema: COCO 17
+func YOLOv11PoseCOCOParams() YOLOv11PoseParams {
+	return YOLOv11PoseParams{
+		BoxThreshold:    0.25,
+		NMSThreshold:    0.45,
+		ObjectClassNum:  1,
+		MaxObjectNumber: 64,
+		Schema:          result.COCO17Schema(),
+	}
+}
+
+// YOLOv11PoseHandParams returns an instance of YOLOv11PoseParams configured
+// with default values for a Model trained on the Ultralytics hand keypoints
+// dataset featuring:
+// - Object Classes: 1
+// - Box Threshold: 0.25
+// - NMS Threshold: 0.45
+// - Maximum Object Number: 64
+// - KeyPoints Schema: Hand 21
+func YOLOv11PoseHandParams() YOLOv11PoseParams {
+	p := YOLOv11PoseCOCOParams()
+	p.Schema = result.Hand21Schema()
+	return p
+}
+
+// NewYOLOv11Pose returns an instance of the YOLOv11Pose post processor
+func NewYOLOv11Pose(p YOLOv11PoseParams) *YOLOv11Pose {
+
+	if p.Schema.KeyPointsNumber == 0 {
+		p.Schema = result.COCO17Schema()
+	}
+
+	return &YOLOv11Pose{
+		Params: p,
+		idGen:  result.NewIDGenerator(),
+	}
+}
+
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv11Pose) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
+// YOLOv11PoseResult defines a struct used for object detection results
+type YOLOv11PoseResult struct {
+	DetectResults []result.DetectResult
+	KeyPoints     [][]result.KeyPoint
+}
+
+// GetDetectResults returns the object detection results containing bounding
+// boxes
+func (r YOLOv11PoseResult) GetDetectResults() []result.DetectResult {
+	return r.DetectResults
+}
+
+// GetKeyPoints returns the keypoints of each detected object
+func (r YOLOv11PoseResult) GetKeyPoints() [][]result.KeyPoint {
+	return r.KeyPoints
+}
+
+// DetectObjects takes the RKNN outputs and runs the object detection process
+// then returns the results.  The Model has box, score and optional score sum
+// outputs per stride, the same as YOLOv11 object detection, followed by the
+// keypoints output of shape [1, keypoints, 3, anchors].
+func (y *YOLOv11Pose) DetectObjects(outputs *rknnlite.Outputs,
+	resizer *preprocess.Resizer) result.DetectionResult {
+
+	attrs := outputs.OutputAttributes()
+	data := newStrideData(outputs)
+
+	// distribution focal loss (DFL)
+	dflLen := int(attrs.DimForDFL / 4)
+
+	// the last output is the keypoints
+	outputPerBranch := int(attrs.IONumber-1) / 3
+	kptsIdx := int(attrs.IONumber) - 1
+
+	if outputPerBranch < 2 || dflLen == 0 {
+		// model shape error
+		return YOLOv11PoseResult{}
+	}
+
+	validCount := 0
+	index := 0
+
+	for i := 0; i < 3; i++ {
+
+		boxIdx := i * outputPerBranch
+		scoreIdx := boxIdx + 1
+
+		var scoreSum outputTensor
+
+		if outputPerBranch == 3 {
+			scoreSum = newOutputTensor(outputs, attrs, boxIdx+2)
+		}
+
+		gridH := int(attrs.DimHeights[boxIdx])
+		gridW := int(attrs.DimWidths[boxIdx])
+		stride := int(data.height) / gridH
+
+		validCount += y.processStride(
+			newOutputTensor(outputs, attrs, boxIdx),
+			newOutputTensor(outputs, attrs, scoreIdx),
+			scoreSum,
+			gridH, gridW, stride, dflLen,
+			data, index,
+		)
+
+		index += gridH * gridW
+	}
+
+	if validCount <= 0 {
+		// no object detected
+		return YOLOv11PoseResult{}
+	}
+
+	// index is now the total number of anchors over all strides
+	kpts := newOutputTensor(outputs, attrs, kptsIdx)
+
+	if kpts.len() < y.Params.Schema.KeyPointsNumber*3*index {
+		// model shape error
+		return YOLOv11PoseResult{}
+	}
+
+	// indexArray is used to keep and index of detect objects contained in
+	// the stride "data" variable
+	var indexArray []int
+
+	for i := 0; i < validCount; i++ {
+		indexArray = append(indexArray, i)
+	}
+
+	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
+
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 5)
+
+	// collate objects into a result for returning
+	group := make([]result.DetectResult, 0)
+	allKeyPoints := make([][]result.KeyPoint, 0)
+	lastCount := 0
+
+	for i := 0; i < validCount; i++ {
+		if indexArray[i] == -1 || lastCount >= y.Params.MaxObjectNumber {
+			continue
+		}
+		n := indexArray[i]
+
+		x1 := data.filterBoxes[n*5+0] - float32(resizer.XPad())
+		y1 := data.filterBoxes[n*5+1] - float32(resizer.YPad())
+		x2 := x1 + data.filterBoxes[n*5+2]
+		y2 := y1 + data.filterBoxes[n*5+3]
+		keyPointsIdx := data.filterBoxes[n*5+4]
+
+		allKeyPoints = append(allKeyPoints, decodeKeyPoints(kpts,
+			y.Params.Schema.KeyPointsNumber, index, int(keyPointsIdx), resizer))
+
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
+		group = append(group, result.DetectResult{
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
+			Probability: data.objProbs[i],
+			Class:       data.classID[n],
+			ID:          y.idGen.GetNext(),
+		})
+
+		lastCount++
+	}
+
+	return YOLOv11PoseResult{
+		DetectResults: group,
+		KeyPoints:     allKeyPoints,
+	}
+}
+
+// processStride processes the given stride of int8 or float32 output tensors
+func (y *YOLOv11Pose) processStride(boxTensor, scoreTensor,
+	scoreSumTensor outputTensor, gridH int, gridW int, stride int, dflLen int,
+	data *strideData, index int) int {
+
+	gridLen := gridH * gridW
+
+	if boxTensor.len() < 4*dflLen*gridLen ||
+		scoreTensor.len() < y.Params.ObjectClassNum*gridLen {
+		// model shape error
+		return 0
+	}
+
+	return decodeDFLStride(boxTensor, scoreTensor, scoreSumTensor, gridH, gridW,
+		stride, dflLen, y.Params.ObjectClassNum, y.Params.BoxThreshold,
+		func(offset int, box [4]float32, score float32, classID int) {
+			// the fifth value is the anchor index of the keypoints
+			data.filterBoxes = append(data.filterBoxes, box[0], box[1], box[2],
+				box[3], float32(index+offset))
+			data.objProbs = append(data.objProbs, score)
+			data.classID = append(data.classID, classID)
+		})
+}
+
+// GetPoseEstimation returns the keypoints for the detection objects used in
+// pose estimation
+func (y *YOLOv11Pose) GetPoseEstimation(detectObjs result.DetectionResult) [][]result.KeyPoint {
+	return detectObjs.(YOLOv11PoseResult).GetKeyPoints()
+}
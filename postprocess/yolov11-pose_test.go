@@ -0,0 +1,82 @@
+package postprocess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestYOLOv11PoseSchemaDefault(t *testing.T) {
+
+	if y := NewYOLOv11Pose(YOLOv11PoseParams{}); y.Params.Schema.Name != "coco-17" ||
+		y.Params.Schema.KeyPointsNumber != 17 {
+		t.Errorf("expected coco-17 schema, got %s of %d keypoints",
+			y.Params.Schema.Name, y.Params.Schema.KeyPointsNumber)
+	}
+
+	if y := NewYOLOv11Pose(YOLOv11PoseHandParams()); y.Params.Schema.Name != "hand-21" {
+		t.Errorf("expected hand-21 schema, got %s", y.Params.Schema.Name)
+	}
+}
+
+func TestYOLOv11PoseProcessStride(t *testing.T) {
+
+	const (
+		grid    = 2
+		stride  = 16
+		dflLen  = 2
+		classes = 2
+		index   = 100
+	)
+
+	gridLen := grid * grid
+
+	boxes := make([]float32, 4*dflLen*gridLen)
+
+	for i := range boxes {
+		boxes[i] = float32(i%5) * 0.5
+	}
+
+	// first class at anchor 1 and second class at anchor 3
+	scores := make([]float32, classes*gridLen)
+	scores[1] = 0.8
+	scores[gridLen+3] = 0.6
+
+	params := YOLOv11PoseCOCOParams()
+	params.ObjectClassNum = classes
+	pose := NewYOLOv11Pose(params)
+
+	detect := NewYOLOv11(YOLOv11Params{
+		BoxThreshold:   params.BoxThreshold,
+		ObjectClassNum: classes,
+	})
+
+	poseData := &strideData{}
+	detectData := &strideData{}
+
+	n := pose.processStride(outputTensor{buf: boxes}, outputTensor{buf: scores},
+		outputTensor{}, grid, grid, stride, dflLen, poseData, index)
+
+	detect.processStride(outputTensor{buf: boxes}, outputTensor{buf: scores},
+		outputTensor{}, grid, grid, stride, dflLen, detectData)
+
+	if n != 2 || !reflect.DeepEqual(poseData.objProbs, []float32{0.8, 0.6}) ||
+		!reflect.DeepEqual(poseData.classID, []int{0, 1}) {
+		t.Fatalf("unexpected detections %d %v %v", n, poseData.objProbs,
+			poseData.classID)
+	}
+
+	// the pose boxes are the detection boxes with the keypoint anchor index
+	for i := 0; i < n; i++ {
+
+		box := poseData.filterBoxes[i*5 : i*5+4]
+
+		if !reflect.DeepEqual(box, detectData.filterBoxes[i*4:i*4+4]) {
+			t.Errorf("detection %d: expected box %v, got %v", i,
+				detectData.filterBoxes[i*4:i*4+4], box)
+		}
+	}
+
+	if poseData.filterBoxes[4] != index+1 || poseData.filterBoxes[9] != index+3 {
+		t.Errorf("unexpected keypoint anchor indexes %v", poseData.filterBoxes)
+	}
+}
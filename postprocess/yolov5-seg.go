@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 	"github.com/swdee/go-rknnlite/tracker"
@@ -20,6 +21,9 @@ type YOLOv5Seg struct {
 	bufPool *bufferPool
 	// bufPoolInit is a flag to indicate if the buffer pool has been initialized
 	bufPoolInit bool
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv5SegParams defines the struct containing the YOLOv5Seg parameters to use
@@ -120,6 +124,12 @@ func NewYOLOv5Seg(p YOLOv5SegParams) *YOLOv5Seg {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv5Seg) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // newStrideDataSeg returns an initialised instance of strideData
 func newStrideDataSeg(outputs *rknnlite.Outputs, protoSize int) *strideData {
 
@@ -158,6 +168,12 @@ type SegmentData struct {
 	data *strideData
 	// number of boxes
 	boxesNum int
+	// detectIDs are the IDs of the detection results of each box
+	detectIDs []int64
+	// srcWidth is the width of the source image the segment mask is for
+	srcWidth int
+	// srcHeight is the height of the source image the segment mask is for
+	srcHeight int
 }
 
 // GetDetectResults returns the object detection results containing bounding
@@ -206,18 +222,8 @@ func (y *YOLOv5Seg) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -241,14 +247,17 @@ func (y *YOLOv5Seg) DetectObjects(outputs *rknnlite.Outputs,
 				data.filterSegments[n*y.Params.PrototypeChannel+k])
 		}
 
+		// have left the clamps on here versus C code original
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width),
+			Top:    clamp(y1, 0, data.height),
+			Right:  clamp(x2, 0, data.width),
+			Bottom: clamp(y2, 0, data.height),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				// have left the clamps on here versus C code original
-				Left:   int(clamp(x1, 0, data.width)),
-				Top:    int(clamp(y1, 0, data.height)),
-				Right:  int(clamp(x2, 0, data.width)),
-				Bottom: int(clamp(y2, 0, data.height)),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -263,9 +272,14 @@ func (y *YOLOv5Seg) DetectObjects(outputs *rknnlite.Outputs,
 		filterBoxesByNMS: make([]int, boxesNum*4),
 		data:             data,
 		boxesNum:         boxesNum,
+		detectIDs:        make([]int64, boxesNum),
+		srcWidth:         resizer.SrcWidth(),
+		srcHeight:        resizer.SrcHeight(),
 	}
 
 	for i := 0; i < boxesNum; i++ {
+		segData.detectIDs[i] = group[i].ID
+
 		// store filter boxes at their original size for segment mask calculations
 		segData.filterBoxesByNMS[i*4+0] = group[i].Box.Left
 		segData.filterBoxesByNMS[i*4+1] = group[i].Box.Top
@@ -277,6 +291,11 @@ func (y *YOLOv5Seg) DetectObjects(outputs *rknnlite.Outputs,
 		group[i].Box.Top = boxReverse(group[i].Box.Top, resizer.YPad(), resizer.ScaleFactor())
 		group[i].Box.Right = boxReverse(group[i].Box.Right, resizer.XPad(), resizer.ScaleFactor())
 		group[i].Box.Bottom = boxReverse(group[i].Box.Bottom, resizer.YPad(), resizer.ScaleFactor())
+
+		group[i].BoxF.Left = boxReverseF(group[i].BoxF.Left, resizer.XPad(), resizer.ScaleFactor())
+		group[i].BoxF.Top = boxReverseF(group[i].BoxF.Top, resizer.YPad(), resizer.ScaleFactor())
+		group[i].BoxF.Right = boxReverseF(group[i].BoxF.Right, resizer.XPad(), resizer.ScaleFactor())
+		group[i].BoxF.Bottom = boxReverseF(group[i].BoxF.Bottom, resizer.YPad(), resizer.ScaleFactor())
 	}
 
 	res := YOLOv5SegResult{
@@ -376,9 +395,16 @@ func (y *YOLOv5Seg) SegmentMask(detectObjs result.DetectionResult,
 	return SegMask{realMask}
 }
 
+// Instances extracts the polygons, area, rotated rect and RLE of each object's
+// mask from the segment mask returned by SegmentMask() or TrackMask()
+func (y *YOLOv5Seg) Instances(detectObjs result.DetectionResult, segMask SegMask,
+	epsilon float64) ([]SegInstance, error) {
+	return detectObjs.(YOLOv5SegResult).GetSegmentData().Instances(segMask, epsilon)
+}
+
 // TrackMask creates segment mask data for tracked objects
 func (y *YOLOv5Seg) TrackMask(detectObjs result.DetectionResult,
-	trackObjs []*tracker.STrack, resizer *preprocess.Resizer) SegMask {
+	trackObjs []tracker.Track, resizer *preprocess.Resizer) SegMask {
 
 	// handle segment masks
 	detRes := detectObjs.(YOLOv5SegResult).GetDetectResults()
@@ -488,8 +514,10 @@ func (y *YOLOv5Seg) TrackMask(detectObjs result.DetectionResult,
 func (y *YOLOv5Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 	data *strideData) int {
 
-	gridH := int(outputs.OutputAttributes().DimHeights[inputID])
-	gridW := int(outputs.OutputAttributes().DimWidths[inputID])
+	attrs := outputs.OutputAttributes()
+
+	gridH := int(attrs.DimHeights[inputID])
+	gridW := int(attrs.DimWidths[inputID])
 	stride := int(data.height) / gridH
 
 	validCount := 0
@@ -500,43 +528,35 @@ func (y *YOLOv5Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 	}
 
 	if inputID == 6 {
-		inputProto := outputs.Output[inputID].BufInt
-		zpProto := data.outZPs[inputID]
-		scaleProto := data.outScales[inputID]
+		protoTensor := newOutputTensor(outputs, attrs, inputID)
 
 		for i := 0; i < y.protoSize; i++ {
-			data.proto[i] = deqntAffineToF32(inputProto[i], zpProto, scaleProto)
+			data.proto[i] = protoTensor.at(i)
 		}
 
 		return validCount
 	}
 
-	input := outputs.Output[inputID].BufInt
-	inputSeg := outputs.Output[inputID+1].BufInt
-	zp := data.outZPs[inputID]
-	scale := data.outScales[inputID]
-	zpSeg := data.outZPs[inputID+1]
-	scaleSeg := data.outScales[inputID+1]
-
-	thresI8 := qntF32ToAffine(y.Params.BoxThreshold, zp, scale)
+	input := newOutputTensor(outputs, attrs, inputID)
+	inputSeg := newOutputTensor(outputs, attrs, inputID+1)
 
 	for a := 0; a < 3; a++ {
 		for i := 0; i < gridH; i++ {
 			for j := 0; j < gridW; j++ {
 
-				boxConfidence := input[(y.Params.ProbBoxSize*a+4)*gridLen+i*gridW+j]
+				boxConfidence := input.at((y.Params.ProbBoxSize*a+4)*gridLen + i*gridW + j)
 
-				if boxConfidence >= thresI8 {
+				if boxConfidence >= y.Params.BoxThreshold {
 
 					offset := (y.Params.ProbBoxSize*a)*gridLen + i*gridW + j
 					offsetSeg := (y.Params.PrototypeChannel*a)*gridLen + i*gridW + j
 					inPtr := offset // Used as a starting index into input
 					inPtrSeg := offsetSeg
 
-					boxX := (deqntAffineToF32(input[inPtr], zp, scale))*2.0 - 0.5
-					boxY := (deqntAffineToF32(input[inPtr+gridLen], zp, scale))*2.0 - 0.5
-					boxW := (deqntAffineToF32(input[inPtr+2*gridLen], zp, scale)) * 2.0
-					boxH := (deqntAffineToF32(input[inPtr+3*gridLen], zp, scale)) * 2.0
+					boxX := input.at(inPtr)*2.0 - 0.5
+					boxY := input.at(inPtr+gridLen)*2.0 - 0.5
+					boxW := input.at(inPtr+2*gridLen) * 2.0
+					boxH := input.at(inPtr+3*gridLen) * 2.0
 
 					boxX = (boxX + float32(j)) * float32(stride)
 					boxY = (boxY + float32(i)) * float32(stride)
@@ -545,30 +565,26 @@ func (y *YOLOv5Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 					boxX -= boxW / 2.0
 					boxY -= boxH / 2.0
 
-					maxClassProbs := input[inPtr+5*gridLen]
+					maxClassProbs := input.at(inPtr + 5*gridLen)
 					maxClassID := 0
 
 					for k := 1; k < y.Params.ObjectClassNum; k++ {
-						prob := input[inPtr+(5+k)*gridLen]
+						prob := input.at(inPtr + (5+k)*gridLen)
 						if prob > maxClassProbs {
 							maxClassID = k
 							maxClassProbs = prob
 						}
 					}
 
-					boxConfF32 := deqntAffineToF32(boxConfidence, zp, scale)
-					classProbF32 := deqntAffineToF32(maxClassProbs, zp, scale)
-					limitScore := boxConfF32 * classProbF32
+					limitScore := boxConfidence * maxClassProbs
 
 					if limitScore > y.Params.BoxThreshold {
 						for k := 0; k < y.Params.PrototypeChannel; k++ {
-							segElementFP := deqntAffineToF32(inputSeg[inPtrSeg+k*gridLen], zpSeg, scaleSeg)
-							data.filterSegments = append(data.filterSegments, segElementFP)
+							data.filterSegments = append(data.filterSegments,
+								inputSeg.at(inPtrSeg+k*gridLen))
 						}
 
-						data.objProbs = append(data.objProbs,
-							deqntAffineToF32(maxClassProbs, zp, scale)*deqntAffineToF32(boxConfidence, zp, scale),
-						)
+						data.objProbs = append(data.objProbs, limitScore)
 						data.classID = append(data.classID, maxClassID)
 						data.filterBoxes = append(data.filterBoxes, boxX, boxY, boxW, boxH)
 						validCount++
@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 )
@@ -13,6 +14,9 @@ type YOLOv5 struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv5Params defines the struct containing the YOLOv5 parameters to use
@@ -98,6 +102,12 @@ func NewYOLOv5(p YOLOv5Params) *YOLOv5 {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv5) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // strideData is a struct used to hold all Stride data used during the post
 // processing
 type strideData struct {
@@ -166,8 +176,10 @@ func (y *YOLOv5) DetectObjects(outputs *rknnlite.Outputs,
 
 	// process each stride
 	for i, stride := range y.Params.Strides {
-		validCount += y.processStride(outputs.Output[i].BufInt, stride, data,
-			data.outZPs[i], data.outScales[i])
+		validCount += y.processStride(
+			newOutputTensor(outputs, outputs.OutputAttributes(), i),
+			stride, data,
+		)
 	}
 
 	if validCount <= 0 {
@@ -185,18 +197,8 @@ func (y *YOLOv5) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -215,13 +217,16 @@ func (y *YOLOv5) DetectObjects(outputs *rknnlite.Outputs,
 		id := data.classID[n]
 		objConf := data.objProbs[i]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -236,9 +241,10 @@ func (y *YOLOv5) DetectObjects(outputs *rknnlite.Outputs,
 	}
 }
 
-// processStride processes the given stride
-func (y *YOLOv5) processStride(input []int8, stride YOLOStride,
-	data *strideData, zp int32, scale float32) int {
+// processStride processes the given stride of an int8 or float32 output
+// tensor
+func (y *YOLOv5) processStride(input outputTensor, stride YOLOStride,
+	data *strideData) int {
 
 	// calculate grid size
 	gridH := int(data.height) / stride.Size
@@ -246,23 +252,22 @@ func (y *YOLOv5) processStride(input []int8, stride YOLOStride,
 
 	validCount := 0
 	gridLen := gridH * gridW
-	thresI8 := qntF32ToAffine(y.Params.BoxThreshold, zp, scale)
 
 	for a := 0; a < 3; a++ {
 		for i := 0; i < gridH; i++ {
 			for j := 0; j < gridW; j++ {
 
-				boxConfidence := input[(y.Params.ProbBoxSize*a+4)*gridLen+i*gridW+j]
+				boxConfidence := input.at((y.Params.ProbBoxSize*a+4)*gridLen + i*gridW + j)
 
-				if boxConfidence >= thresI8 {
+				if boxConfidence >= y.Params.BoxThreshold {
 
 					offset := (y.Params.ProbBoxSize*a)*gridLen + i*gridW + j
 					inPtr := offset // Used as a starting index into input
 
-					boxX := (deqntAffineToF32(input[inPtr], zp, scale))*2.0 - 0.5
-					boxY := (deqntAffineToF32(input[inPtr+gridLen], zp, scale))*2.0 - 0.5
-					boxW := (deqntAffineToF32(input[inPtr+2*gridLen], zp, scale)) * 2.0
-					boxH := (deqntAffineToF32(input[inPtr+3*gridLen], zp, scale)) * 2.0
+					boxX := input.at(inPtr)*2.0 - 0.5
+					boxY := input.at(inPtr+gridLen)*2.0 - 0.5
+					boxW := input.at(inPtr+2*gridLen) * 2.0
+					boxH := input.at(inPtr+3*gridLen) * 2.0
 
 					boxX = (boxX + float32(j)) * float32(stride.Size)
 					boxY = (boxY + float32(i)) * float32(stride.Size)
@@ -271,18 +276,18 @@ func (y *YOLOv5) processStride(input []int8, stride YOLOStride,
 					boxX -= boxW / 2.0
 					boxY -= boxH / 2.0
 
-					maxClassProbs := input[inPtr+5*gridLen]
+					maxClassProbs := input.at(inPtr + 5*gridLen)
 					maxClassID := 0
 
 					for k := 1; k < y.Params.ObjectClassNum; k++ {
-						prob := input[inPtr+(5+k)*gridLen]
+						prob := input.at(inPtr + (5+k)*gridLen)
 						if prob > maxClassProbs {
 							maxClassID = k
 							maxClassProbs = prob
 						}
 					}
 
-					limitScore := deqntAffineToF32(maxClassProbs, zp, scale) * deqntAffineToF32(boxConfidence, zp, scale)
+					limitScore := maxClassProbs * boxConfidence
 
 					if limitScore >= y.Params.BoxThreshold {
 						data.objProbs = append(data.objProbs, limitScore)
@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 )
@@ -13,6 +14,9 @@ type YOLOv8Pose struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv8PoseParams defines the struct containing the YOLOv8 parameters to use
@@ -31,8 +35,15 @@ type YOLOv8PoseParams struct {
 	// MaxObjectNumber is the maximum number of objects detected that can be
 	// returned
 	MaxObjectNumber int
+	// Schema is the keypoint schema the pose model is trained on, which sets
+	// the number of keypoints of each object.  Defaults to the COCO 17
+	// keypoints schema if not set.
+	Schema result.KeypointSchema
 	// KeyPointsNumber is the number of COCO keypoints representing different parts
 	// of the body the pose model is trained on
+	//
+	// Deprecated: set the Schema instead.  KeyPointsNumber is only used when
+	// the Schema has not been set.
 	KeyPointsNumber int
 }
 
@@ -42,25 +53,48 @@ type YOLOv8PoseParams struct {
 // - Box Threshold: 0.5
 // - NMS Threshold: 0.4
 // - Maximum Object Number: 64
-// - KeyPoints Number: 17
+// - KeyPoints Schema: COCO 17
 func YOLOv8PoseCOCOParams() YOLOv8PoseParams {
 	return YOLOv8PoseParams{
 		BoxThreshold:    0.5,
 		NMSThreshold:    0.4,
 		ObjectClassNum:  1,
 		MaxObjectNumber: 64,
+		Schema:          result.COCO17Schema(),
 		KeyPointsNumber: 17,
 	}
 }
 
 // NewYOLOv8Pose returns an instance of the YOLOv8Pose post processor
 func NewYOLOv8Pose(p YOLOv8PoseParams) *YOLOv8Pose {
+
+	// params configured before keypoint schemas only set the number of
+	// keypoints
+	if p.Schema.KeyPointsNumber == 0 {
+		p.Schema = result.COCO17Schema()
+
+		if p.KeyPointsNumber > 0 && p.KeyPointsNumber != p.Schema.KeyPointsNumber {
+			p.Schema = result.KeypointSchema{
+				Name:            "custom",
+				KeyPointsNumber: p.KeyPointsNumber,
+			}
+		}
+	}
+
+	p.KeyPointsNumber = p.Schema.KeyPointsNumber
+
 	return &YOLOv8Pose{
 		Params: p,
 		idGen:  result.NewIDGenerator(),
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv8Pose) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLOv8PoseResult defines a struct used for object detection results
 type YOLOv8PoseResult struct {
 	DetectResults []result.DetectResult
@@ -78,7 +112,8 @@ func (r YOLOv8PoseResult) GetKeyPoints() [][]result.KeyPoint {
 }
 
 // DetectObjects takes the RKNN outputs and runs the object detection process
-// then returns the results
+// then returns the results.  The Model has a fused box and score output per
+// stride followed by the keypoints output of shape [1, keypoints, 3, anchors].
 func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 	resizer *preprocess.Resizer) result.DetectionResult {
 
@@ -88,16 +123,16 @@ func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 	stride := 0
 	index := 0
 
+	attrs := outputs.OutputAttributes()
+
 	for i := 0; i < 3; i++ {
-		gridH := int(outputs.OutputAttributes().DimHeights[i])
-		gridW := int(outputs.OutputAttributes().DimWidths[i])
+		gridH := int(attrs.DimHeights[i])
+		gridW := int(attrs.DimWidths[i])
 		stride = int(data.height) / gridH
 
-		// same as process_i8 in C code
+		// same as process_i8 and process_fp32 in C code
 		validCount += y.processStride(
-			outputs.Output[i].BufInt,
-			outputs.OutputAttributes().ZPs[i],
-			outputs.OutputAttributes().Scales[i],
+			newOutputTensor(outputs, attrs, i),
 			gridH, gridW, stride,
 			data, index,
 		)
@@ -110,6 +145,14 @@ func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 		return YOLOv8PoseResult{}
 	}
 
+	// index is now the total number of anchors over all strides
+	kpts := newOutputTensor(outputs, attrs, 3)
+
+	if kpts.len() < y.Params.Schema.KeyPointsNumber*3*index {
+		// model shape error
+		return YOLOv8PoseResult{}
+	}
+
 	// indexArray is used to keep and index of detect objects contained in
 	// the stride "data" variable
 	var indexArray []int
@@ -120,18 +163,8 @@ func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 5)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 5)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -150,48 +183,24 @@ func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 		y2 := y1 + data.filterBoxes[n*5+3]
 		keyPointsIdx := data.filterBoxes[n*5+4]
 
-		keyPtData := make([]result.KeyPoint, 0)
-
-		for j := 0; j < y.Params.KeyPointsNumber; j++ {
-			/*
-				kpX := outputs.Output[3].BufInt[j*3*8400+0*8400+int(keyPointsIdx)]
-				kpY := outputs.Output[3].BufInt[j*3*8400+1*8400+int(keyPointsIdx)]
-				kpScore := outputs.Output[3].BufInt[j*3*8400+2*8400+int(keyPointsIdx)]
-
-				kp := KeyPoint{
-					X:     int(float32(int(kpX)-resizer.XPad()) / resizer.ScaleFactor()),
-					Y:     int(float32(int(kpY)-resizer.YPad()) / resizer.ScaleFactor()),
-					Score: float32(kpScore),
-				}
-			*/
-
-			// float16
-			kpX := outputs.Output[3].BufFloat[j*3*8400+0*8400+int(keyPointsIdx)]
-			kpY := outputs.Output[3].BufFloat[j*3*8400+1*8400+int(keyPointsIdx)]
-			kpScore := outputs.Output[3].BufFloat[j*3*8400+2*8400+int(keyPointsIdx)]
-
-			kp := result.KeyPoint{
-				X:     int(float32(int(kpX)-resizer.XPad()) / resizer.ScaleFactor()),
-				Y:     int(float32(int(kpY)-resizer.YPad()) / resizer.ScaleFactor()),
-				Score: float32(kpScore),
-			}
-
-			keyPtData = append(keyPtData, kp)
-
-		}
+		keyPtData := decodeKeyPoints(kpts, y.Params.Schema.KeyPointsNumber,
+			index, int(keyPointsIdx), resizer)
 
 		allKeyPoints = append(allKeyPoints, keyPtData)
 
 		id := data.classID[n]
 		objConf := data.objProbs[i]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -208,13 +217,14 @@ func (y *YOLOv8Pose) DetectObjects(outputs *rknnlite.Outputs,
 }
 
 // processStride processes the given stride
-func (y *YOLOv8Pose) processStride(boxTensor []int8, boxZP int32, boxScale float32,
+func (y *YOLOv8Pose) processStride(boxTensor outputTensor,
 	gridH int, gridW int, stride int, data *strideData, index int) int {
 
 	inputLocLen := 64
 	validCount := 0
 
-	thresI8 := qntF32ToAffine(unsigmoid(y.Params.BoxThreshold), boxZP, boxScale)
+	// compare the raw logits against the threshold before applying sigmoid
+	thresLogit := unsigmoid(y.Params.BoxThreshold)
 
 	for h := 0; h < gridH; h++ {
 		for w := 0; w < gridW; w++ {
@@ -222,15 +232,15 @@ func (y *YOLOv8Pose) processStride(boxTensor []int8, boxZP int32, boxScale float
 
 				offset := (inputLocLen+a)*gridW*gridH + h*gridW + w
 
-				if boxTensor[offset] >= thresI8 {
+				if logit := boxTensor.at(offset); logit >= thresLogit {
 
-					boxConfF32 := sigmoid(deqntAffineToF32(boxTensor[offset], boxZP, boxScale))
+					boxConfF32 := sigmoid(logit)
 
 					// allocate space for loc array and fill it with dequantized values
 					loc := make([]float32, inputLocLen)
 
 					for i := 0; i < inputLocLen; i++ {
-						loc[i] = deqntAffineToF32(boxTensor[i*gridW*gridH+h*gridW+w], boxZP, boxScale)
+						loc[i] = boxTensor.at(i*gridW*gridH + h*gridW + w)
 					}
 
 					// apply softmax
@@ -287,3 +297,27 @@ func (y *YOLOv8Pose) processStride(boxTensor []int8, boxZP int32, boxScale float
 func (y *YOLOv8Pose) GetPoseEstimation(detectObjs result.DetectionResult) [][]result.KeyPoint {
 	return detectObjs.(YOLOv8PoseResult).GetKeyPoints()
 }
+
+// decodeKeyPoints returns the keypoints of the anchor from the keypoints
+// output of shape [1, keypoints, 3, anchors] mapped back to the source image
+func decodeKeyPoints(kpts outputTensor, keyPointsNum, numAnchors, anchor int,
+	resizer *preprocess.Resizer) []result.KeyPoint {
+
+	keyPts := make([]result.KeyPoint, 0, keyPointsNum)
+
+	for j := 0; j < keyPointsNum; j++ {
+
+		offset := j*3*numAnchors + anchor
+		kpX := kpts.at(offset)
+		kpY := kpts.at(offset + numAnchors)
+		kpScore := kpts.at(offset + 2*numAnchors)
+
+		keyPts = append(keyPts, result.KeyPoint{
+			X:     int((kpX - float32(resizer.XPad())) / resizer.ScaleFactor()),
+			Y:     int((kpY - float32(resizer.YPad())) / resizer.ScaleFactor()),
+			Score: kpScore,
+		})
+	}
+
+	return keyPts
+}
@@ -0,0 +1,142 @@
+package postprocess
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"github.com/swdee/go-rknnlite/preprocess"
+)
+
+func TestYOLOv8PoseSchemaDefault(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		params   YOLOv8PoseParams
+		schema   string
+		keyPoint int
+	}{
+		{
+			name:     "coco params",
+			params:   YOLOv8PoseCOCOParams(),
+			schema:   "coco-17",
+			keyPoint: 17,
+		},
+		{
+			name:     "neither set",
+			params:   YOLOv8PoseParams{},
+			schema:   "coco-17",
+			keyPoint: 17,
+		},
+		{
+			name:     "deprecated coco keypoints number",
+			params:   YOLOv8PoseParams{KeyPointsNumber: 17},
+			schema:   "coco-17",
+			keyPoint: 17,
+		},
+		{
+			name:     "deprecated custom keypoints number",
+			params:   YOLOv8PoseParams{KeyPointsNumber: 5},
+			schema:   "custom",
+			keyPoint: 5,
+		},
+		{
+			name: "schema takes precedence",
+			params: YOLOv8PoseParams{
+				Schema:          result.Hand21Schema(),
+				KeyPointsNumber: 17,
+			},
+			schema:   "hand-21",
+			keyPoint: 21,
+		},
+	}
+
+	for _, tc := range tests {
+
+		y := NewYOLOv8Pose(tc.params)
+
+		if y.Params.Schema.Name != tc.schema ||
+			y.Params.Schema.KeyPointsNumber != tc.keyPoint ||
+			y.Params.KeyPointsNumber != tc.keyPoint {
+			t.Errorf("%s: expected schema %s of %d keypoints, got %s of %d",
+				tc.name, tc.schema, tc.keyPoint, y.Params.Schema.Name,
+				y.Params.Schema.KeyPointsNumber)
+		}
+	}
+}
+
+func TestDecodeKeyPoints(t *testing.T) {
+
+	const (
+		keyPointsNum = 2
+		numAnchors   = 4
+		anchor       = 2
+	)
+
+	// keypoints output of shape [1, keypoints, 3, anchors] with the x, y and
+	// score of the anchor set and every other anchor holding junk values
+	kpts := make([]float32, keyPointsNum*3*numAnchors)
+
+	for i := range kpts {
+		kpts[i] = -1000
+	}
+
+	set := func(kp int, x, y, score float32) {
+		kpts[kp*3*numAnchors+anchor] = x
+		kpts[kp*3*numAnchors+numAnchors+anchor] = y
+		kpts[kp*3*numAnchors+2*numAnchors+anchor] = score
+	}
+
+	set(0, 200, 200, 0.9)
+	set(1, 300, 400, 0.4)
+
+	tests := []struct {
+		name      string
+		srcWidth  int
+		srcHeight int
+		expected  []result.KeyPoint
+	}{
+		{
+			name:      "no padding",
+			srcWidth:  640,
+			srcHeight: 640,
+			expected: []result.KeyPoint{
+				{X: 200, Y: 200, Score: 0.9},
+				{X: 300, Y: 400, Score: 0.4},
+			},
+		},
+		{
+			// scale 0.5 with 140 pixels of padding top and bottom
+			name:      "landscape letterbox",
+			srcWidth:  1280,
+			srcHeight: 720,
+			expected: []result.KeyPoint{
+				{X: 400, Y: 120, Score: 0.9},
+				{X: 600, Y: 520, Score: 0.4},
+			},
+		},
+		{
+			// scale 0.5 with 140 pixels of padding left and right
+			name:      "portrait letterbox",
+			srcWidth:  720,
+			srcHeight: 1280,
+			expected: []result.KeyPoint{
+				{X: 120, Y: 400, Score: 0.9},
+				{X: 320, Y: 800, Score: 0.4},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+
+		resizer := preprocess.NewResizer(tc.srcWidth, tc.srcHeight, 640, 640)
+		keyPts := decodeKeyPoints(outputTensor{buf: kpts}, keyPointsNum,
+			numAnchors, anchor, resizer)
+		resizer.Close()
+
+		if !reflect.DeepEqual(keyPts, tc.expected) {
+			t.Errorf("%s: expected keypoints %+v, got %+v", tc.name, tc.expected,
+				keyPts)
+		}
+	}
+}
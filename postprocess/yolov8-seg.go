@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 	"github.com/swdee/go-rknnlite/tracker"
@@ -29,6 +30,9 @@ type YOLOv8Seg struct {
 	bufPool *bufferPool
 	// bufPoolInit is a flag to indicate if the buffer pool has been initialized
 	bufPoolInit bool
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv8SegParams defines the struct containing the YOLOv8Seg parameters to use
@@ -90,6 +94,12 @@ func NewYOLOv8Seg(p YOLOv8SegParams) *YOLOv8Seg {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv8Seg) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLOv8SegResult defines a struct used for object detection results
 type YOLOv8SegResult struct {
 	DetectResults []result.DetectResult
@@ -145,18 +155,8 @@ func (y *YOLOv8Seg) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -180,13 +180,16 @@ func (y *YOLOv8Seg) DetectObjects(outputs *rknnlite.Outputs,
 				data.filterSegments[n*y.Params.PrototypeChannel+k])
 		}
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width),
+			Top:    clamp(y1, 0, data.height),
+			Right:  clamp(x2, 0, data.width),
+			Bottom: clamp(y2, 0, data.height),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width)),
-				Top:    int(clamp(y1, 0, data.height)),
-				Right:  int(clamp(x2, 0, data.width)),
-				Bottom: int(clamp(y2, 0, data.height)),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -201,9 +204,14 @@ func (y *YOLOv8Seg) DetectObjects(outputs *rknnlite.Outputs,
 		filterBoxesByNMS: make([]int, boxesNum*4),
 		data:             data,
 		boxesNum:         boxesNum,
+		detectIDs:        make([]int64, boxesNum),
+		srcWidth:         resizer.SrcWidth(),
+		srcHeight:        resizer.SrcHeight(),
 	}
 
 	for i := 0; i < boxesNum; i++ {
+		segData.detectIDs[i] = group[i].ID
+
 		// store filter boxes at their original size for segment mask calculations
 		segData.filterBoxesByNMS[i*4+0] = group[i].Box.Left
 		segData.filterBoxesByNMS[i*4+1] = group[i].Box.Top
@@ -215,6 +223,11 @@ func (y *YOLOv8Seg) DetectObjects(outputs *rknnlite.Outputs,
 		group[i].Box.Top = boxReverse(group[i].Box.Top, resizer.YPad(), resizer.ScaleFactor())
 		group[i].Box.Right = boxReverse(group[i].Box.Right, resizer.XPad(), resizer.ScaleFactor())
 		group[i].Box.Bottom = boxReverse(group[i].Box.Bottom, resizer.YPad(), resizer.ScaleFactor())
+
+		group[i].BoxF.Left = boxReverseF(group[i].BoxF.Left, resizer.XPad(), resizer.ScaleFactor())
+		group[i].BoxF.Top = boxReverseF(group[i].BoxF.Top, resizer.YPad(), resizer.ScaleFactor())
+		group[i].BoxF.Right = boxReverseF(group[i].BoxF.Right, resizer.XPad(), resizer.ScaleFactor())
+		group[i].BoxF.Bottom = boxReverseF(group[i].BoxF.Bottom, resizer.YPad(), resizer.ScaleFactor())
 	}
 
 	res := YOLOv8SegResult{
@@ -229,8 +242,10 @@ func (y *YOLOv8Seg) DetectObjects(outputs *rknnlite.Outputs,
 func (y *YOLOv8Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 	data *strideData, dflLen int) int {
 
-	gridH := int(outputs.OutputAttributes().DimHeights[inputID])
-	gridW := int(outputs.OutputAttributes().DimWidths[inputID])
+	attrs := outputs.OutputAttributes()
+
+	gridH := int(attrs.DimHeights[inputID])
+	gridW := int(attrs.DimWidths[inputID])
 	stride := int(data.height) / gridH
 
 	validCount := 0
@@ -242,35 +257,19 @@ func (y *YOLOv8Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 	}
 
 	if inputID == 12 {
-		inputProto := outputs.Output[inputID].BufInt
-		zpProto := data.outZPs[inputID]
-		scaleProto := data.outScales[inputID]
+		protoTensor := newOutputTensor(outputs, attrs, inputID)
 
 		for i := 0; i < y.protoSize; i++ {
-			data.proto[i] = deqntAffineToF32(inputProto[i], zpProto, scaleProto)
+			data.proto[i] = protoTensor.at(i)
 		}
 
 		return validCount
 	}
 
-	boxTensor := outputs.Output[inputID].BufInt
-	boxZP := data.outZPs[inputID]
-	boxScale := data.outScales[inputID]
-
-	scoreTensor := outputs.Output[inputID+1].BufInt
-	scoreZP := data.outZPs[inputID+1]
-	scoreScale := data.outScales[inputID+1]
-
-	scoreSumTensor := outputs.Output[inputID+2].BufInt
-	scoreSumZP := data.outZPs[inputID+2]
-	scoreSumScale := data.outScales[inputID+2]
-
-	segTensor := outputs.Output[inputID+3].BufInt
-	segZP := data.outZPs[inputID+3]
-	segScale := data.outScales[inputID+3]
-
-	scoreThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreZP, scoreScale)
-	scoreSumThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreSumZP, scoreSumScale)
+	boxTensor := newOutputTensor(outputs, attrs, inputID)
+	scoreTensor := newOutputTensor(outputs, attrs, inputID+1)
+	scoreSumTensor := newOutputTensor(outputs, attrs, inputID+2)
+	segTensor := newOutputTensor(outputs, attrs, inputID+3)
 
 	for i := 0; i < gridH; i++ {
 		for j := 0; j < gridW; j++ {
@@ -279,38 +278,39 @@ func (y *YOLOv8Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 			maxClassID := -1
 
 			offsetSeg := i*gridW + j
-			inPtrSeg := segTensor[offsetSeg:]
 
 			// Quick filtering using score sum
-			if scoreSumTensor != nil {
-				if scoreSumTensor[offset] < scoreSumThresI8 {
+			if scoreSumTensor.len() > 0 {
+				if scoreSumTensor.at(offset) < y.Params.BoxThreshold {
 					continue
 				}
 			}
 
-			maxScore := int8(-scoreZP)
+			maxScore := y.Params.BoxThreshold
 
 			for c := 0; c < y.Params.ObjectClassNum; c++ {
-				if scoreTensor[offset] > scoreThresI8 && scoreTensor[offset] > maxScore {
-					maxScore = scoreTensor[offset]
+
+				if score := scoreTensor.at(offset); score > maxScore {
+					maxScore = score
 					maxClassID = c
 				}
+
 				offset += gridLen
 			}
 
 			// Compute box
-			if maxScore > scoreThresI8 {
+			if maxClassID >= 0 {
 
 				for k := 0; k < y.Params.PrototypeChannel; k++ {
-					segElementFP := deqntAffineToF32(inPtrSeg[k*gridLen], segZP, segScale)
-					data.filterSegments = append(data.filterSegments, segElementFP)
+					data.filterSegments = append(data.filterSegments,
+						segTensor.at(offsetSeg+k*gridLen))
 				}
 
 				offset = i*gridW + j
 				beforeDFL := make([]float32, 4*dflLen)
 
 				for k := 0; k < dflLen*4; k++ {
-					beforeDFL[k] = deqntAffineToF32(boxTensor[offset], boxZP, boxScale)
+					beforeDFL[k] = boxTensor.at(offset)
 					offset += gridLen
 				}
 
@@ -324,7 +324,7 @@ func (y *YOLOv8Seg) processStride(outputs *rknnlite.Outputs, inputID int,
 				h := y2 - y1
 				data.filterBoxes = append(data.filterBoxes, x1, y1, w, h)
 
-				data.objProbs = append(data.objProbs, deqntAffineToF32(maxScore, scoreZP, scoreScale))
+				data.objProbs = append(data.objProbs, maxScore)
 				data.classID = append(data.classID, maxClassID)
 				validCount++
 			}
@@ -422,9 +422,16 @@ func (y *YOLOv8Seg) SegmentMask(detectObjs result.DetectionResult,
 	return SegMask{realMask}
 }
 
+// Instances extracts the polygons, area, rotated rect and RLE of each object's
+// mask from the segment mask returned by SegmentMask() or TrackMask()
+func (y *YOLOv8Seg) Instances(detectObjs result.DetectionResult, segMask SegMask,
+	epsilon float64) ([]SegInstance, error) {
+	return detectObjs.(YOLOv8SegResult).GetSegmentData().Instances(segMask, epsilon)
+}
+
 // TrackMask creates segment mask data for tracked objects
 func (y *YOLOv8Seg) TrackMask(detectObjs result.DetectionResult,
-	trackObjs []*tracker.STrack, resizer *preprocess.Resizer) SegMask {
+	trackObjs []tracker.Track, resizer *preprocess.Resizer) SegMask {
 
 	detRes := detectObjs.(YOLOv8SegResult).GetDetectResults()
 	segData := detectObjs.(YOLOv8SegResult).GetSegmentData()
@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 )
@@ -13,6 +14,9 @@ type YOLOv8 struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOv8Params defines the struct containing the YOLOv8 parameters to use
@@ -56,6 +60,12 @@ func NewYOLOv8(p YOLOv8Params) *YOLOv8 {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOv8) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLOv8Result defines a struct used for object detection results
 type YOLOv8Result struct {
 	DetectResults []result.DetectResult
@@ -77,40 +87,34 @@ func (y *YOLOv8) DetectObjects(outputs *rknnlite.Outputs,
 	validCount := 0
 	stride := 0
 
+	attrs := outputs.OutputAttributes()
+
 	// distribution focal loss (DFL)
-	dflLen := int(outputs.OutputAttributes().DimForDFL / 4)
+	dflLen := int(attrs.DimForDFL / 4)
 
-	outputPerBranch := int(outputs.OutputAttributes().IONumber / 3)
+	outputPerBranch := int(attrs.IONumber / 3)
 
 	for i := 0; i < 3; i++ {
 
-		scoreSum := make([]int8, 0)
-		scoreSumZp := int32(0)
-		scoreSumScale := float32(1.0)
+		boxIdx := i * outputPerBranch
+		scoreIdx := i*outputPerBranch + 1
+
+		var scoreSum outputTensor
 
 		if outputPerBranch == 3 {
-			scoreSum = outputs.Output[i*outputPerBranch+2].BufInt
-			scoreSumZp = outputs.OutputAttributes().ZPs[i*outputPerBranch+2]
-			scoreSumScale = outputs.OutputAttributes().Scales[i*outputPerBranch+2]
+			scoreSum = newOutputTensor(outputs, attrs, i*outputPerBranch+2)
 		}
 
-		boxIdx := i * outputPerBranch
-		scoreIdx := i*outputPerBranch + 1
-
-		gridH := int(outputs.OutputAttributes().DimHeights[boxIdx])
-		gridW := int(outputs.OutputAttributes().DimWidths[boxIdx])
+		gridH := int(attrs.DimHeights[boxIdx])
+		gridW := int(attrs.DimWidths[boxIdx])
 
 		stride = int(data.height) / gridH
 
-		// same as process_i8 in C code
+		// same as process_i8 and process_fp32 in C code
 		validCount += y.processStride(
-			outputs.Output[boxIdx].BufInt,
-			outputs.OutputAttributes().ZPs[boxIdx],
-			outputs.OutputAttributes().Scales[boxIdx],
-			outputs.Output[scoreIdx].BufInt,
-			outputs.OutputAttributes().ZPs[scoreIdx],
-			outputs.OutputAttributes().Scales[scoreIdx],
-			scoreSum, scoreSumZp, scoreSumScale,
+			newOutputTensor(outputs, attrs, boxIdx),
+			newOutputTensor(outputs, attrs, scoreIdx),
+			scoreSum,
 			gridH, gridW, stride, dflLen,
 			data,
 		)
@@ -131,18 +135,8 @@ func (y *YOLOv8) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -161,13 +155,16 @@ func (y *YOLOv8) DetectObjects(outputs *rknnlite.Outputs,
 		id := data.classID[n]
 		objConf := data.objProbs[i]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -182,49 +179,57 @@ func (y *YOLOv8) DetectObjects(outputs *rknnlite.Outputs,
 	}
 }
 
-// processStride processes the given stride
-func (y *YOLOv8) processStride(boxTensor []int8, boxZP int32, boxScale float32,
-	scoreTensor []int8, scoreZP int32, scoreScale float32,
-	scoreSumTensor []int8, scoreSumZP int32, scoreSumScale float32,
-	gridH int, gridW int, stride int, dflLen int,
-	data *strideData) int {
+// processStride processes the given stride of int8 or float32 output tensors
+func (y *YOLOv8) processStride(boxTensor, scoreTensor, scoreSumTensor outputTensor,
+	gridH int, gridW int, stride int, dflLen int, data *strideData) int {
 
 	validCount := 0
 	gridLen := gridH * gridW
-	scoreThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreZP, scoreScale)
-	scoreSumThresI8 := qntF32ToAffine(y.Params.BoxThreshold, scoreSumZP, scoreSumScale)
 
 	for i := 0; i < gridH; i++ {
+	kLoop:
 		for j := 0; j < gridW; j++ {
 
 			offset := i*gridW + j
 			maxClassID := -1
 
 			// Quick filtering using score sum
-			if scoreSumTensor != nil {
-				if scoreSumTensor[offset] < scoreSumThresI8 {
+			if scoreSumTensor.len() > 0 {
+				if scoreSumTensor.at(offset) < y.Params.BoxThreshold {
 					continue
 				}
 			}
 
-			maxScore := int8(-scoreZP)
+			maxScore := y.Params.BoxThreshold
 
 			for c := 0; c < y.Params.ObjectClassNum; c++ {
-				if scoreTensor[offset] > scoreThresI8 && scoreTensor[offset] > maxScore {
-					maxScore = scoreTensor[offset]
+
+				if score := scoreTensor.at(offset); score > maxScore {
+					maxScore = score
 					maxClassID = c
 				}
+
 				offset += gridLen
 			}
 
-			// Compute box
-			if maxScore > scoreThresI8 {
+			// compute box
+			if maxClassID >= 0 {
 
 				offset = i*gridW + j
 				beforeDFL := make([]float32, 4*dflLen)
 
 				for k := 0; k < dflLen*4; k++ {
-					beforeDFL[k] = deqntAffineToF32(boxTensor[offset], boxZP, boxScale)
+
+					// check offset for being out of bounds of boxTensor value
+					// this check is not apart of the C++ code, however we found
+					// sometimes the offset would be greater than key in boxTensor
+					// when running inference on streaming so need to check
+					// to stop panic.
+					if offset >= boxTensor.len() {
+						break kLoop
+					}
+
+					beforeDFL[k] = boxTensor.at(offset)
 					offset += gridLen
 				}
 
@@ -238,7 +243,7 @@ func (y *YOLOv8) processStride(boxTensor []int8, boxZP int32, boxScale float32,
 				h := y2 - y1
 				data.filterBoxes = append(data.filterBoxes, x1, y1, w, h)
 
-				data.objProbs = append(data.objProbs, deqntAffineToF32(maxScore, scoreZP, scoreScale))
+				data.objProbs = append(data.objProbs, maxScore)
 				data.classID = append(data.classID, maxClassID)
 				validCount++
 			}
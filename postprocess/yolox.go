@@ -2,6 +2,7 @@ package postprocess
 
 import (
 	"github.com/swdee/go-rknnlite"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"github.com/swdee/go-rknnlite/preprocess"
 	"math"
@@ -14,6 +15,9 @@ type YOLOX struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm to use in
+	// place of the built in NMS
+	suppressor nms.Suppressor
 }
 
 // YOLOXParams defines the struct containing the YOLOX parameters to use
@@ -83,6 +87,12 @@ func NewYOLOX(p YOLOXParams) *YOLOX {
 	}
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used to filter
+// overlapping detections.  Set to nil to use the built in NMS.
+func (y *YOLOX) SetSuppressor(s nms.Suppressor) {
+	y.suppressor = s
+}
+
 // YOLOXResult defines a struct used for object detection results
 type YOLOXResult struct {
 	DetectResults []result.DetectResult
@@ -106,8 +116,10 @@ func (y *YOLOX) DetectObjects(outputs *rknnlite.Outputs,
 
 	// process each stride
 	for i, stride := range y.Params.Strides {
-		validCount += y.processStride(outputs.Output[i].BufInt, stride, data,
-			data.outZPs[i], data.outScales[i])
+		validCount += y.processStride(
+			newOutputTensor(outputs, outputs.OutputAttributes(), i),
+			stride, data,
+		)
 	}
 
 	if validCount <= 0 {
@@ -125,18 +137,8 @@ func (y *YOLOX) DetectObjects(outputs *rknnlite.Outputs,
 
 	quickSortIndiceInverse(data.objProbs, 0, validCount-1, indexArray)
 
-	// create a unique set of ClassID (ie: eliminate any multiples found)
-	classSet := make(map[int]bool)
-
-	for _, id := range data.classID {
-		classSet[id] = true
-	}
-
-	// for each classID in the classSet calculate the NMS
-	for c := range classSet {
-		nms(validCount, data.filterBoxes, data.classID, indexArray, c,
-			y.Params.NMSThreshold, 4)
-	}
+	applyNMS(y.suppressor, validCount, data, indexArray,
+		y.Params.NMSThreshold, 4)
 
 	// collate objects into a result for returning
 	group := make([]result.DetectResult, 0)
@@ -155,13 +157,16 @@ func (y *YOLOX) DetectObjects(outputs *rknnlite.Outputs,
 		id := data.classID[n]
 		objConf := data.objProbs[i]
 
+		boxF := result.BoxRectF{
+			Left:   clamp(x1, 0, data.width) / resizer.ScaleFactor(),
+			Top:    clamp(y1, 0, data.height) / resizer.ScaleFactor(),
+			Right:  clamp(x2, 0, data.width) / resizer.ScaleFactor(),
+			Bottom: clamp(y2, 0, data.height) / resizer.ScaleFactor(),
+		}
+
 		result := result.DetectResult{
-			Box: result.BoxRect{
-				Left:   int(clamp(x1, 0, data.width) / resizer.ScaleFactor()),
-				Top:    int(clamp(y1, 0, data.height) / resizer.ScaleFactor()),
-				Right:  int(clamp(x2, 0, data.width) / resizer.ScaleFactor()),
-				Bottom: int(clamp(y2, 0, data.height) / resizer.ScaleFactor()),
-			},
+			Box:         boxF.Rect(),
+			BoxF:        boxF,
 			Probability: objConf,
 			Class:       id,
 			ID:          y.idGen.GetNext(),
@@ -176,9 +181,10 @@ func (y *YOLOX) DetectObjects(outputs *rknnlite.Outputs,
 	}
 }
 
-// processStride processes the given stride
-func (y *YOLOX) processStride(input []int8, stride YOLOStride,
-	data *strideData, zp int32, scale float32) int {
+// processStride processes the given stride of an int8 or float32 output
+// tensor
+func (y *YOLOX) processStride(input outputTensor, stride YOLOStride,
+	data *strideData) int {
 
 	// calculate grid size
 	gridH := int(data.height) / stride.Size
@@ -186,35 +192,34 @@ func (y *YOLOX) processStride(input []int8, stride YOLOStride,
 
 	validCount := 0
 	gridLen := gridH * gridW
-	thresI8 := qntF32ToAffine(y.Params.BoxThreshold, zp, scale)
 
 	for i := 0; i < gridH; i++ {
 		for j := 0; j < gridW; j++ {
 
-			boxConfidence := input[4*gridLen+i*gridW+j]
+			boxConfidence := input.at(4*gridLen + i*gridW + j)
 
-			if boxConfidence >= thresI8 {
+			if boxConfidence >= y.Params.BoxThreshold {
 
 				offset := i*gridW + j
 				inPtr := offset // Used as a starting index into input
 
-				maxClassProbs := input[inPtr+5*gridLen]
+				maxClassProbs := input.at(inPtr + 5*gridLen)
 				maxClassID := 0
 
 				for k := 1; k < y.Params.ObjectClassNum; k++ {
-					prob := input[inPtr+(5+k)*gridLen]
+					prob := input.at(inPtr + (5+k)*gridLen)
 					if prob > maxClassProbs {
 						maxClassID = k
 						maxClassProbs = prob
 					}
 				}
 
-				if maxClassProbs > thresI8 {
+				if maxClassProbs > y.Params.BoxThreshold {
 
-					boxX := (deqntAffineToF32(input[inPtr], zp, scale))
-					boxY := (deqntAffineToF32(input[inPtr+gridLen], zp, scale))
-					boxW := (deqntAffineToF32(input[inPtr+2*gridLen], zp, scale))
-					boxH := (deqntAffineToF32(input[inPtr+3*gridLen], zp, scale))
+					boxX := input.at(inPtr)
+					boxY := input.at(inPtr + gridLen)
+					boxW := input.at(inPtr + 2*gridLen)
+					boxH := input.at(inPtr + 3*gridLen)
 
 					boxX = (boxX + float32(j)) * float32(stride.Size)
 					boxY = (boxY + float32(i)) * float32(stride.Size)
@@ -224,9 +229,7 @@ func (y *YOLOX) processStride(input []int8, stride YOLOStride,
 					boxX -= boxW / 2.0
 					boxY -= boxH / 2.0
 
-					data.objProbs = append(data.objProbs,
-						deqntAffineToF32(maxClassProbs, zp, scale)*deqntAffineToF32(boxConfidence, zp, scale),
-					)
+					data.objProbs = append(data.objProbs, maxClassProbs*boxConfidence)
 					data.classID = append(data.classID, maxClassID)
 					data.filterBoxes = append(data.filterBoxes, boxX, boxY, boxW, boxH)
 					validCount++
@@ -0,0 +1,153 @@
+package preprocess
+
+import (
+	"fmt"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+)
+
+var (
+	// ArcFaceTemplate are the positions of the left eye, right eye, nose,
+	// left and right mouth corner landmarks of a face aligned for the 112x112
+	// input of ArcFace and MobileFaceNet models.  This is the same order as
+	// the landmarks returned by RetinaFace.
+	ArcFaceTemplate = []gocv.Point2f{
+		{X: 38.2946, Y: 51.6963},
+		{X: 73.5318, Y: 51.5014},
+		{X: 56.0252, Y: 71.7366},
+		{X: 41.5493, Y: 92.3655},
+		{X: 70.7299, Y: 92.2041},
+	}
+)
+
+// FaceAligner defines the struct used to warp faces to a landmark template
+// so they are upright, centered and scaled as expected by face recognition
+// models
+type FaceAligner struct {
+	// template are the landmark positions in the aligned face
+	template []gocv.Point2f
+	// width is the width of the aligned face
+	width int
+	// height is the height of the aligned face
+	height int
+	// transform is the 2x3 affine matrix of the last alignment
+	transform gocv.Mat
+}
+
+// NewFaceAligner returns a FaceAligner that warps faces to the 112x112
+// ArcFace template
+func NewFaceAligner() *FaceAligner {
+	return NewFaceAlignerWithTemplate(ArcFaceTemplate, 112, 112)
+}
+
+// NewFaceAlignerWithTemplate returns a FaceAligner that warps faces so their
+// landmarks are at the template positions in an image of width and height
+func NewFaceAlignerWithTemplate(template []gocv.Point2f, width, height int) *FaceAligner {
+	return &FaceAligner{
+		template:  template,
+		width:     width,
+		height:    height,
+		transform: gocv.NewMatWithSize(2, 3, gocv.MatTypeCV64F),
+	}
+}
+
+// Close frees memory allocated by the aligner
+func (f *FaceAligner) Close() error {
+	return f.transform.Close()
+}
+
+// Align warps the face with the landmarks in the src image to the template
+// and writes the aligned face to dest.  The landmarks must be in the src
+// image coordinates and the same order as the template, such as those
+// returned by RetinaFace.GetFaceLandmarks()
+func (f *FaceAligner) Align(src gocv.Mat, dest *gocv.Mat,
+	landmarks []result.KeyPoint) error {
+
+	if len(landmarks) != len(f.template) {
+		return fmt.Errorf("expected %d landmarks, got %d", len(f.template),
+			len(landmarks))
+	}
+
+	points := make([]gocv.Point2f, len(landmarks))
+
+	for i, kp := range landmarks {
+		points[i] = gocv.Point2f{X: float32(kp.X), Y: float32(kp.Y)}
+	}
+
+	m, err := SimilarityTransform(points, f.template)
+
+	if err != nil {
+		return err
+	}
+
+	for row := 0; row < 2; row++ {
+		for col := 0; col < 3; col++ {
+			f.transform.SetDoubleAt(row, col, m[row][col])
+		}
+	}
+
+	return gocv.WarpAffineWithParams(src, dest, f.transform,
+		image.Pt(f.width, f.height), gocv.InterpolationLinear,
+		gocv.BorderConstant, color.RGBA{})
+}
+
+// SimilarityTransform returns the 2x3 affine matrix of the rotation, uniform
+// scale and translation that maps the src points onto the dst points with
+// the least squared error, as given by the Umeyama method
+func SimilarityTransform(src, dst []gocv.Point2f) ([2][3]float64, error) {
+
+	var m [2][3]float64
+
+	if len(src) != len(dst) || len(src) < 2 {
+		return m, fmt.Errorf("need at least 2 point pairs, got %d and %d",
+			len(src), len(dst))
+	}
+
+	n := float64(len(src))
+
+	// centroids
+	var srcX, srcY, dstX, dstY float64
+
+	for i := range src {
+		srcX += float64(src[i].X)
+		srcY += float64(src[i].Y)
+		dstX += float64(dst[i].X)
+		dstY += float64(dst[i].Y)
+	}
+
+	srcX /= n
+	srcY /= n
+	dstX /= n
+	dstY /= n
+
+	// for 2D points the rotation and scale minimising the error reduce to
+	// the dot and cross products of the centered points
+	var dot, cross, variance float64
+
+	for i := range src {
+
+		sx := float64(src[i].X) - srcX
+		sy := float64(src[i].Y) - srcY
+		dx := float64(dst[i].X) - dstX
+		dy := float64(dst[i].Y) - dstY
+
+		dot += sx*dx + sy*dy
+		cross += sx*dy - sy*dx
+		variance += sx*sx + sy*sy
+	}
+
+	if variance == 0 {
+		return m, fmt.Errorf("src points are all the same")
+	}
+
+	// scaled cosine and sine of the rotation
+	a := dot / variance
+	b := cross / variance
+
+	m[0] = [3]float64{a, -b, dstX - (a*srcX - b*srcY)}
+	m[1] = [3]float64{b, a, dstY - (b*srcX + a*srcY)}
+
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package preprocess
+
+import (
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"gocv.io/x/gocv"
+	"math"
+	"testing"
+)
+
+func TestSimilarityTransform(t *testing.T) {
+
+	// rotate 30 degrees, scale by 0.5 and translate the template
+	angle := math.Pi / 6
+	scale := 0.5
+	a := scale * math.Cos(angle)
+	b := scale * math.Sin(angle)
+
+	src := make([]gocv.Point2f, len(ArcFaceTemplate))
+
+	for i, p := range ArcFaceTemplate {
+		src[i] = gocv.Point2f{
+			X: float32(a*float64(p.X) - b*float64(p.Y) + 200),
+			Y: float32(b*float64(p.X) + a*float64(p.Y) + 50),
+		}
+	}
+
+	m, err := SimilarityTransform(src, ArcFaceTemplate)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// mapping the src points must give back the template
+	for i, p := range src {
+
+		x := m[0][0]*float64(p.X) + m[0][1]*float64(p.Y) + m[0][2]
+		y := m[1][0]*float64(p.X) + m[1][1]*float64(p.Y) + m[1][2]
+
+		if math.Abs(x-float64(ArcFaceTemplate[i].X)) > 1e-3 ||
+			math.Abs(y-float64(ArcFaceTemplate[i].Y)) > 1e-3 {
+			t.Errorf("point %d: expected %v, got (%.4f, %.4f)", i,
+				ArcFaceTemplate[i], x, y)
+		}
+	}
+
+	if _, err := SimilarityTransform(src[:1], ArcFaceTemplate[:1]); err == nil {
+		t.Errorf("expected error for a single point")
+	}
+}
+
+func TestFaceAlign(t *testing.T) {
+
+	// face at twice the template size offset into a larger image
+	landmarks := make([]result.KeyPoint, len(ArcFaceTemplate))
+
+	for i, p := range ArcFaceTemplate {
+		landmarks[i] = result.KeyPoint{
+			X: int(math.Round(float64(p.X)*2 + 100)),
+			Y: int(math.Round(float64(p.Y)*2 + 80)),
+		}
+	}
+
+	img := gocv.NewMatWithSize(480, 640, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	aligned := gocv.NewMat()
+	defer aligned.Close()
+
+	aligner := NewFaceAligner()
+	defer aligner.Close()
+
+	if err := aligner.Align(img, &aligned, landmarks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if aligned.Cols() != 112 || aligned.Rows() != 112 {
+		t.Errorf("expected 112x112 aligned face, got %dx%d", aligned.Cols(),
+			aligned.Rows())
+	}
+
+	if math.Abs(aligner.transform.GetDoubleAt(0, 0)-0.5) > 0.01 {
+		t.Errorf("expected scale of 0.5, got %f", aligner.transform.GetDoubleAt(0, 0))
+	}
+
+	if err := aligner.Align(img, &aligned, landmarks[:3]); err == nil {
+		t.Errorf("expected error for missing landmarks")
+	}
+}
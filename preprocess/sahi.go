@@ -2,12 +2,12 @@ package preprocess
 
 import (
 	"errors"
+	"github.com/swdee/go-rknnlite/postprocess/nms"
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"gocv.io/x/gocv"
 	"image"
 	"image/color"
 	"math"
-	"sort"
 )
 
 // SAHI defines the struct used for Slicing Aided Hyper Inference
@@ -27,6 +27,9 @@ type SAHI struct {
 	// nextID is a counter that increments and provides the next number
 	// for each detection result ID
 	idGen *result.IDGenerator
+	// suppressor is an optional Non-Maximum Suppression algorithm used to
+	// merge the slice detection results in place of the built in cluster NMS
+	suppressor nms.Suppressor
 }
 
 // sahiResult defines a struct to store a slice and its detection results
@@ -70,6 +73,13 @@ func NewSAHI(sliceWidth, sliceHeight int, overlapWidth, overlapHeight float32) *
 	return s
 }
 
+// SetSuppressor sets the Non-Maximum Suppression algorithm used by
+// GetDetectResults() to merge detections from overlapping slices.  Set to nil
+// to use the built in class agnostic cluster NMS.
+func (s *SAHI) SetSuppressor(sup nms.Suppressor) {
+	s.suppressor = sup
+}
+
 // computePositions returns the start‐coordinates (0‐based) of each tile
 // along one axis, and the computed tile length.  It guarantees:
 //
@@ -172,6 +182,9 @@ func (s *SAHI) AddResult(slice Slice, res []result.DetectResult) {
 //   - smallBoxOverlapThreshold is the percentage represented from a value of
 //     0 to 1 that small boxes need to overlap by to be discarded which occurs when
 //     and object sits on the slice/tile overlap boundary
+//
+// If a Suppressor has been set with SetSuppressor() it is used instead and
+// both thresholds are ignored.
 func (s *SAHI) GetDetectResults(iouThreshold, smallBoxOverlapThresh float32) []result.DetectResult {
 
 	// collate objects into a result for returning
@@ -184,6 +197,8 @@ func (s *SAHI) GetDetectResults(iouThreshold, smallBoxOverlapThresh float32) []r
 		for _, dr := range sr.det {
 			// remap the coordinates of the detection result to the global coordinates
 			// of the source image
+			boxF := dr.FloatBox()
+
 			gresult := result.DetectResult{
 				Box: result.BoxRect{
 					Left:   sr.slice.X + dr.Box.Left,
@@ -191,6 +206,12 @@ func (s *SAHI) GetDetectResults(iouThreshold, smallBoxOverlapThresh float32) []r
 					Right:  sr.slice.X + dr.Box.Right,
 					Bottom: sr.slice.Y + dr.Box.Bottom,
 				},
+				BoxF: result.BoxRectF{
+					Left:   float32(sr.slice.X) + boxF.Left,
+					Top:    float32(sr.slice.Y) + boxF.Top,
+					Right:  float32(sr.slice.X) + boxF.Right,
+					Bottom: float32(sr.slice.Y) + boxF.Bottom,
+				},
 				Probability: dr.Probability,
 				Class:       dr.Class,
 				ID:          s.idGen.GetNext(),
@@ -200,13 +221,19 @@ func (s *SAHI) GetDetectResults(iouThreshold, smallBoxOverlapThresh float32) []r
 		}
 	}
 
-	// Sort by descending probability
-	sort.Slice(group, func(i, j int) bool {
-		return group[i].Probability > group[j].Probability
-	})
+	sup := s.suppressor
 
-	// run class-aware NMS
-	return s.nmsCluster(group, iouThreshold, smallBoxOverlapThresh)
+	if sup == nil {
+		// class agnostic cluster NMS keeping the largest box of each cluster
+		sup = nms.New(nms.Params{
+			Method:          nms.Cluster,
+			IoUThreshold:    iouThreshold,
+			ClassAgnostic:   true,
+			SmallBoxOverlap: smallBoxOverlapThresh,
+		})
+	}
+
+	return sup.Suppress(group)
 }
 
 // FreeResults releases stored detection results.  This should be called after
@@ -215,131 +242,6 @@ func (s *SAHI) FreeResults() {
 	s.results = s.results[:0]
 }
 
-// iou computes the Intersection-over-Union of two boxes.
-func (s *SAHI) iou(a, b result.BoxRect) float32 {
-	x1 := max(a.Left, b.Left)
-	y1 := max(a.Top, b.Top)
-	x2 := min(a.Right, b.Right)
-	y2 := min(a.Bottom, b.Bottom)
-
-	w := float32(max(0, x2-x1))
-	h := float32(max(0, y2-y1))
-	inter := w * h
-
-	areaA := float32((a.Right - a.Left) * (a.Bottom - a.Top))
-	areaB := float32((b.Right - b.Left) * (b.Bottom - b.Top))
-
-	return inter / (areaA + areaB - inter)
-}
-
-// max returns max between two numbers
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
-
-// min returns minimum between two numbers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-// nmsCluster picks one box per overlapping cluster (class‐agnostic),
-// choosing the larghest area (tie‐break on confidence), and uses both IoU
-// and small‐box overlap tests to form clusters.
-//   - detections must be sorted descending by Probability before calling.
-//   - iouThreshold is your overlap cutoff (e.g. 0.45)
-//   - smallBoxOverlapThresh is the fraction of the small box’s area that must
-//     be overlapped to consider it a duplicate (e.g. 0.7)
-func (s *SAHI) nmsCluster(dets []result.DetectResult,
-	iouThreshold, smallBoxOverlapThresh float32) []result.DetectResult {
-
-	n := len(dets)
-	suppressed := make([]bool, n)
-	keep := make([]result.DetectResult, 0, n)
-
-	for i, base := range dets {
-		if suppressed[i] {
-			continue
-		}
-
-		// start a new cluster with "base"
-		cluster := []result.DetectResult{base}
-		suppressed[i] = true
-
-		for j := i + 1; j < n; j++ {
-			if suppressed[j] {
-				continue
-			}
-
-			other := dets[j]
-
-			// decide if "other" belongs in this cluster
-			inCluster := false
-
-			// IoU test
-			if s.iou(base.Box, other.Box) > iouThreshold {
-				inCluster = true
-			} else {
-				// small‐box self‐overlap test
-				inter := s.intersectionArea(base.Box, other.Box)
-				areaOther := s.boxArea(other.Box)
-				if areaOther > 0 && float32(inter)/float32(areaOther) > smallBoxOverlapThresh {
-					inCluster = true
-				}
-			}
-
-			if !inCluster {
-				continue
-			}
-
-			// assign to this cluster
-			suppressed[j] = true
-			cluster = append(cluster, other)
-		}
-
-		// pick the single largest‐area box (tie‐break on probability)
-		best := cluster[0]
-		bestArea := s.boxArea(best.Box)
-
-		for _, c := range cluster[1:] {
-			a := s.boxArea(c.Box)
-
-			if a > bestArea || (a == bestArea && c.Probability > best.Probability) {
-				best = c
-				bestArea = a
-			}
-		}
-
-		keep = append(keep, best)
-	}
-
-	return keep
-}
-
-// intersectionArea returns the raw pixel-area of overlap between two boxes.
-func (s *SAHI) intersectionArea(a, b result.BoxRect) int {
-	x1 := max(a.Left, b.Left)
-	y1 := max(a.Top, b.Top)
-	x2 := min(a.Right, b.Right)
-	y2 := min(a.Bottom, b.Bottom)
-
-	if x2 <= x1 || y2 <= y1 {
-		return 0
-	}
-
-	return (x2 - x1) * (y2 - y1)
-}
-
-// boxArea returns the pixel‐area of a single box.
-func (s *SAHI) boxArea(a result.BoxRect) int {
-	return max(0, a.Right-a.Left) * max(0, a.Bottom-a.Top)
-}
-
 // Mat returns the slices Mat after cropping and resizing to be used
 // for running inference on
 func (s *Slice) Mat() *gocv.Mat {
@@ -0,0 +1,160 @@
+package render
+
+import (
+	"fmt"
+	"github.com/swdee/go-rknnlite/analytics"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"math"
+)
+
+// AnalyticsStyle defines the parameters used for rendering analytics zones
+// and lines
+type AnalyticsStyle struct {
+	// ZoneColor is the color of the zone outline, fill and label
+	ZoneColor color.RGBA
+	// ZoneAlpha is the opacity of the zone fill from 0 to 1.  Set to 0 to
+	// only draw the outline
+	ZoneAlpha float64
+	// LineColor is the color of the counting lines and label
+	LineColor color.RGBA
+	// LineThickness is the thickness of the zone outlines and counting lines
+	LineThickness int
+	// ArrowLength is the length of the arrow drawn at the middle of a line
+	// showing the In direction.  Set to 0 to disable
+	ArrowLength int
+	// Font for the name and count labels
+	Font Font
+}
+
+// DefaultAnalyticsStyle returns default analytics style settings
+func DefaultAnalyticsStyle() AnalyticsStyle {
+	return AnalyticsStyle{
+		ZoneColor:     Yellow,
+		ZoneAlpha:     0.25,
+		LineColor:     Pink,
+		LineThickness: 2,
+		ArrowLength:   30,
+		Font:          DefaultFont(),
+	}
+}
+
+// Zones draws the analytics zones on the source image labeled with their
+// name and current occupancy
+func Zones(img *gocv.Mat, zones []*analytics.Zone, style AnalyticsStyle) {
+
+	// keep a record of all box labels for later rendering
+	boxLabels := make([]boxLabel, 0)
+
+	for _, z := range zones {
+
+		if len(z.Polygon) < 3 {
+			continue
+		}
+
+		pts := make([]image.Point, len(z.Polygon))
+
+		for i, p := range z.Polygon {
+			pts[i] = image.Pt(int(p.X), int(p.Y))
+		}
+
+		ptsVec := gocv.NewPointsVector()
+		ptsVec.Append(gocv.NewPointVectorFromPoints(pts))
+
+		if style.ZoneAlpha > 0 {
+			// blend filled polygon over the image
+			overlay := img.Clone()
+			gocv.FillPoly(&overlay, ptsVec, style.ZoneColor)
+			gocv.AddWeighted(overlay, style.ZoneAlpha, *img, 1-style.ZoneAlpha, 0, img)
+			overlay.Close()
+		}
+
+		gocv.Polylines(img, ptsVec, true, style.ZoneColor, style.LineThickness)
+		ptsVec.Close()
+
+		occupancy := 0
+
+		for _, n := range z.Occupancy() {
+			occupancy += n
+		}
+
+		text := fmt.Sprintf("%s %d/%d", z.Name, occupancy, z.Total())
+		boxLabels = append(boxLabels,
+			analyticsLabel(text, topLeft(pts), style.ZoneColor, style.Font))
+	}
+
+	drawBoxLabels(img, boxLabels, style.Font)
+}
+
+// CountLines draws the analytics lines on the source image with an arrow
+// pointing in the In direction and labeled with their in and out counts
+func CountLines(img *gocv.Mat, lines []*analytics.Line, style AnalyticsStyle) {
+
+	// keep a record of all box labels for later rendering
+	boxLabels := make([]boxLabel, 0)
+
+	for _, l := range lines {
+
+		start := image.Pt(int(l.Start.X), int(l.Start.Y))
+		end := image.Pt(int(l.End.X), int(l.End.Y))
+
+		gocv.Line(img, start, end, style.LineColor, style.LineThickness)
+
+		if style.ArrowLength > 0 {
+			// the In direction is to the right of the line which in image
+			// coordinates is the normal (-dy, dx)
+			dx := float64(end.X - start.X)
+			dy := float64(end.Y - start.Y)
+			length := math.Hypot(dx, dy)
+
+			if length > 0 {
+				mid := image.Pt((start.X+end.X)/2, (start.Y+end.Y)/2)
+				scale := float64(style.ArrowLength) / length
+				tip := image.Pt(mid.X+int(-dy*scale), mid.Y+int(dx*scale))
+
+				gocv.ArrowedLine(img, mid, tip, style.LineColor, style.LineThickness)
+			}
+		}
+
+		text := fmt.Sprintf("%s in %d out %d", l.Name,
+			l.Total(analytics.In), l.Total(analytics.Out))
+		boxLabels = append(boxLabels,
+			analyticsLabel(text, topLeft([]image.Point{start, end}), style.LineColor,
+				style.Font))
+	}
+
+	drawBoxLabels(img, boxLabels, style.Font)
+}
+
+// analyticsLabel returns the box label for the text placed above the point
+func analyticsLabel(text string, pt image.Point, clr color.RGBA,
+	font Font) boxLabel {
+
+	textSize := gocv.GetTextSize(text, font.Face, font.Scale, font.Thickness)
+
+	// create box for placing text on
+	bRect := image.Rect(pt.X, pt.Y-textSize.Y-font.TopPad-font.BottomPad,
+		pt.X+textSize.X+font.LeftPad+font.RightPad, pt.Y)
+
+	return boxLabel{
+		rect:    bRect,
+		clr:     clr,
+		text:    text,
+		textPos: image.Pt(pt.X+font.LeftPad, pt.Y-font.BottomPad),
+	}
+}
+
+// topLeft returns the top most point, using the left most point for ties
+func topLeft(pts []image.Point) image.Point {
+
+	top := pts[0]
+
+	for _, p := range pts[1:] {
+		if p.Y < top.Y || (p.Y == top.Y && p.X < top.X) {
+			top = p
+		}
+	}
+
+	return top
+}
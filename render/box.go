@@ -73,7 +73,7 @@ func DetectionBoxes(img *gocv.Mat, detectResults []result.DetectResult,
 
 // TrackerBoxes renders the bounding boxes around the object detected for
 // tracker results
-func TrackerBoxes(img *gocv.Mat, trackResults []*tracker.STrack,
+func TrackerBoxes(img *gocv.Mat, trackResults []tracker.Track,
 	classNames []string, font Font, lineThickness int) {
 
 	// keep a record of all box labels for later rendering
@@ -241,7 +241,7 @@ func drawBoxLabels(img *gocv.Mat, boxLabels []boxLabel, font Font) {
 	}
 }
 
-func TrackerOrientedBoundingBoxes(img *gocv.Mat, trackResults []*tracker.STrack,
+func TrackerOrientedBoundingBoxes(img *gocv.Mat, trackResults []tracker.Track,
 	detectResults []result.DetectResult, classNames []string, font Font,
 	lineThickness int) {
 
@@ -253,6 +253,12 @@ func TrackerOrientedBoundingBoxes(img *gocv.Mat, trackResults []*tracker.STrack,
 		// detResult will be an empty struct if not found.   This is ok as it means
 		// the Box.Angle will be 0, ie: no rotation
 		detResult := getDetectResultByID(tResult.GetDetectionID(), detectResults)
+		angle := detResult.Box.Angle
+
+		// use the tracked angle of oriented bounding box tracks
+		if ot, ok := tResult.(orientedTrack); ok && ot.IsOriented() {
+			angle = ot.GetAngle()
+		}
 
 		// Get the color for this object
 		colorIndex := tResult.GetTrackID() % len(classColors)
@@ -264,7 +270,7 @@ func TrackerOrientedBoundingBoxes(img *gocv.Mat, trackResults []*tracker.STrack,
 			int(tResult.GetRect().Y()),
 			int(tResult.GetRect().Width()),
 			int(tResult.GetRect().Height()),
-			detResult.Box.Angle,
+			angle,
 		)
 
 		// label text to use
@@ -277,6 +283,12 @@ func TrackerOrientedBoundingBoxes(img *gocv.Mat, trackResults []*tracker.STrack,
 	drawBoxLabels(img, boxLabels, font)
 }
 
+// orientedTrack is a tracked object that can be an oriented bounding box
+type orientedTrack interface {
+	IsOriented() bool
+	GetAngle() float32
+}
+
 // getDetectResultByID returns the detection result from the Detection ID
 func getDetectResultByID(detectID int64,
 	detectResults []result.DetectResult) result.DetectResult {
@@ -4,40 +4,20 @@ import (
 	"github.com/swdee/go-rknnlite/postprocess/result"
 	"gocv.io/x/gocv"
 	"image"
-)
-
-/* skeleton keypoints
-0: Nose
-1: Left Eye
-2: Right Eye
-3: Left Ear
-4: Right Ear
-5: Left Shoulder
-6: Right Shoulder
-7: Left Elbow
-8: Right Elbow
-9: Left Wrist
-10: Right Wrist
-11: Left Hip
-12: Right Hip
-13: Left Knee
-14: Right Knee
-15: Left Ankle
-16: Right Ankle
-*/
-
-var (
-	// skeleton defines the pose skeleton points to draw lines between.  The numbers
-	// are paired, so (16,14) means draw line from right ankle to right knee.
-	skeleton = [38]int{16, 14, 14, 12, 17, 15, 15, 13, 12, 13, 6, 12, 7, 13, 6, 7, 6, 8,
-		7, 9, 8, 10, 9, 11, 2, 3, 1, 2, 1, 3, 2, 4, 3, 5, 4, 6, 5, 7}
-	// keyPointsTotal is the number of keypoints in a skeleton
-	keyPointsTotal = 17
+	"image/color"
 )
 
 // PoseKeyPoints renders the provided pose estimation keypoints for all objects
+// using the COCO 17 keypoint skeleton
 func PoseKeyPoints(img *gocv.Mat, keyPoints [][]result.KeyPoint,
 	lineThickness int) {
+	SchemaKeyPoints(img, keyPoints, result.COCO17Schema(), lineThickness)
+}
+
+// SchemaKeyPoints renders the provided keypoints for all objects with the
+// skeleton and colors of the keypoint schema
+func SchemaKeyPoints(img *gocv.Mat, keyPoints [][]result.KeyPoint,
+	schema result.KeypointSchema, lineThickness int) {
 
 	// for each object
 	for i := 0; i < len(keyPoints); i++ {
@@ -46,23 +26,37 @@ func PoseKeyPoints(img *gocv.Mat, keyPoints [][]result.KeyPoint,
 		keyPoint := keyPoints[i]
 
 		// draw skeleton lines
-		for j := 0; j < len(skeleton)/2; j++ {
-			x1 := keyPoint[skeleton[2*j]-1].X
-			y1 := keyPoint[skeleton[2*j]-1].Y
-			x2 := keyPoint[skeleton[2*j+1]-1].X
-			y2 := keyPoint[skeleton[2*j+1]-1].Y
+		for j, limb := range schema.Skeleton {
+
+			if limb[0] >= len(keyPoint) || limb[1] >= len(keyPoint) {
+				continue
+			}
+
+			p1 := image.Pt(keyPoint[limb[0]].X, keyPoint[limb[0]].Y)
+			p2 := image.Pt(keyPoint[limb[1]].X, keyPoint[limb[1]].Y)
 
-			gocv.Line(img, image.Pt(x1, y1), image.Pt(x2, y2), limbColors[j], lineThickness)
+			gocv.Line(img, p1, p2, schemaColor(schema.LimbColors, j), lineThickness)
 		}
 
 		// draw circles at skeleton joints
-		for j := 0; j < keyPointsTotal; j++ {
+		for j := 0; j < len(keyPoint); j++ {
 			gocv.Circle(img, image.Pt(keyPoint[j].X, keyPoint[j].Y),
-				3, keyPointColors[j], -1) // style.CircleRadius
+				3, schemaColor(schema.PointColors, j), -1) // style.CircleRadius
 		}
 	}
 }
 
+// schemaColor returns the schema color at the index, falling back to the
+// class colors when the schema does not define one
+func schemaColor(colors []color.RGBA, idx int) color.RGBA {
+
+	if idx < len(colors) {
+		return colors[idx]
+	}
+
+	return classColors[idx%len(classColors)]
+}
+
 // FaceKeyPoints renders the provided face landmark keypoints for all
 // faces detected
 func FaceKeyPoints(img *gocv.Mat, keyPoints [][]result.KeyPoint) {
@@ -254,7 +254,7 @@ func getSegMaskIDFromDetectionID(detectID int64,
 // contained in the mask from inferencing.  The epsilon value effects the shape
 // of the polygon outline.   The higher the value the more round it becomes.
 func TrackerOutlines(img *gocv.Mat, segMask []uint8,
-	trackResults []*tracker.STrack, detectResults []result.DetectResult,
+	trackResults []tracker.Track, detectResults []result.DetectResult,
 	minArea float64, classNames []string, font Font, lineThickness int,
 	epsilon float64) error {
 
@@ -408,7 +408,7 @@ func TrackerOutlines(img *gocv.Mat, segMask []uint8,
 // top of the whole image.  alpha is the amount of opacity to apply to the mask
 // overlay.
 func TrackerMask(img *gocv.Mat, segMask []uint8,
-	trackResults []*tracker.STrack, detectResults []result.DetectResult,
+	trackResults []tracker.Track, detectResults []result.DetectResult,
 	alpha float32) {
 
 	boxesNum := len(trackResults)
@@ -465,7 +465,7 @@ func TrackerMask(img *gocv.Mat, segMask []uint8,
 }
 
 // getTrackIDFromDetectID
-func getTrackIDFromDetectID(detectID int64, trackResults []*tracker.STrack) int {
+func getTrackIDFromDetectID(detectID int64, trackResults []tracker.Track) int {
 
 	for _, tResults := range trackResults {
 		if tResults.GetDetectionID() == detectID {
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"fmt"
+
+	"gocv.io/x/gocv"
+)
+
+// ColorizeClassMap paints each pixel of the semantic segmentation class map
+// with the color of its class and writes the BGR image to dest
+func ColorizeClassMap(classMap gocv.Mat, dest *gocv.Mat) error {
+
+	classes, err := classMap.DataPtrUint8()
+
+	if err != nil {
+		return fmt.Errorf("failed to read class map: %w", err)
+	}
+
+	if dest.Rows() != classMap.Rows() || dest.Cols() != classMap.Cols() ||
+		dest.Type() != gocv.MatTypeCV8UC3 {
+		dest.Close()
+		*dest = gocv.NewMatWithSize(classMap.Rows(), classMap.Cols(), gocv.MatTypeCV8UC3)
+	}
+
+	buf, err := dest.DataPtrUint8()
+
+	if err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+
+	for i, cls := range classes {
+
+		col := classColors[int(cls)%len(classColors)]
+
+		buf[i*3+0] = col.B
+		buf[i*3+1] = col.G
+		buf[i*3+2] = col.R
+	}
+
+	return nil
+}
+
+// ClassMapOverlay renders the semantic segmentation class map as a
+// transparent overlay on top of the whole image.  Pixels of the background
+// class are left unchanged, set background to -1 to overlay all classes.
+func ClassMapOverlay(img *gocv.Mat, classMap gocv.Mat, alpha float32,
+	background int) error {
+
+	if img.Rows() != classMap.Rows() || img.Cols() != classMap.Cols() {
+		return fmt.Errorf("class map size %dx%d does not match image %dx%d",
+			classMap.Cols(), classMap.Rows(), img.Cols(), img.Rows())
+	}
+
+	classes, err := classMap.DataPtrUint8()
+
+	if err != nil {
+		return fmt.Errorf("failed to read class map: %w", err)
+	}
+
+	// get pointer to image Mat so we can directly manipulate its pixels
+	buf, err := img.DataPtrUint8()
+
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+
+	invA := 1.0 - alpha
+
+	for i, cls := range classes {
+
+		if int(cls) == background {
+			continue
+		}
+
+		pixelPos := i * 3
+		col := classColors[int(cls)%len(classColors)]
+
+		// calculate blended colors based on alpha transparency
+		buf[pixelPos+0] = uint8(float32(buf[pixelPos+0])*invA + float32(col.B)*alpha)
+		buf[pixelPos+1] = uint8(float32(buf[pixelPos+1])*invA + float32(col.G)*alpha)
+		buf[pixelPos+2] = uint8(float32(buf[pixelPos+2])*invA + float32(col.R)*alpha)
+	}
+
+	return nil
+}
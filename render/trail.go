@@ -36,7 +36,7 @@ func DefaultTrailStyle() TrailStyle {
 }
 
 // Trail draws the tracker trail lines on the source image.
-func Trail(img *gocv.Mat, trackResults []*tracker.STrack,
+func Trail(img *gocv.Mat, trackResults []tracker.Track,
 	trail *tracker.Trail, style TrailStyle) {
 
 	// draw trail
@@ -0,0 +1,48 @@
+package tracker
+
+// BoTSORT represents the BoT-SORT tracker.  It extends the BYTE association
+// by fusing the IoU distance with the detection score and ReID appearance
+// distance, and compensates the Kalman state for camera motion when frames
+// are passed with UpdateWithFrame.  ReID is enabled with UseReID and the
+// camera motion method can be changed with UseGMC.
+type BoTSORT struct {
+	*BYTETracker
+}
+
+// NewBoTSORT initializes and returns a new BoT-SORT tracker
+func NewBoTSORT(frameRate int, trackBuffer int, trackThresh float32,
+	highThresh float32, matchThresh float32) *BoTSORT {
+
+	p := DefaultBYTETrackerParams()
+	p.FrameRate = frameRate
+	p.TrackBuffer = trackBuffer
+	p.TrackThresh = trackThresh
+	p.HighThresh = highThresh
+	p.MatchThresh = matchThresh
+
+	return NewBoTSORTWithParams(p)
+}
+
+// NewBoTSORTWithParams initializes and returns a new BoT-SORT tracker with the
+// given parameters
+func NewBoTSORTWithParams(p BYTETrackerParams) *BoTSORT {
+
+	bt := NewBYTETrackerWithParams(p)
+
+	bt.fuse = true
+	bt.proximityThresh = 0.5
+	bt.appearanceThresh = 0.25
+	bt.gmc = newGMC(GMCSparseOptFlow)
+
+	return &BoTSORT{
+		BYTETracker: bt,
+	}
+}
+
+// SetFusionThresholds sets the IoU distance (proximity) and ReID distance
+// (appearance) thresholds above which ReID distances are rejected when fused
+// with the IoU distance.  Defaults to 0.5 and 0.25.
+func (b *BoTSORT) SetFusionThresholds(proximity, appearance float32) {
+	b.proximityThresh = proximity
+	b.appearanceThresh = appearance
+}
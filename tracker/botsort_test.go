@@ -0,0 +1,190 @@
+package tracker
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoTSORTTracking(t *testing.T) {
+
+	bs := NewBoTSORT(30, 30, 0.5, 0.6, 0.8)
+
+	for frame := 0; frame < 10; frame++ {
+
+		tracks, err := bs.Update(movingObjects(frame))
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		ids := trackIDs(tracks)
+
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Fatalf("frame %d: expected track IDs [1 2], got %v", frame, ids)
+		}
+	}
+}
+
+func TestBoTSORTCameraMotion(t *testing.T) {
+
+	bs := NewBoTSORT(30, 30, 0.5, 0.6, 0.8)
+
+	box := func(x float32) []Object {
+		return []Object{NewObject(NewRect(x, 200, 50, 120), 0, 0.9, 1)}
+	}
+
+	for frame := 0; frame < 3; frame++ {
+		if _, err := bs.Update(box(100)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// camera pans so the stationary object moves 80 pixels in the frame
+	// which has no overlap with its predicted position
+	bs.cameraMotion = affine{{1, 0, 80}, {0, 1, 0}}
+
+	tracks, err := bs.Update(box(180))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tracks) != 1 || tracks[0].GetTrackID() != 1 {
+		t.Fatalf("expected track 1 after camera motion, got %v", trackIDs(tracks))
+	}
+
+	if !almostEqual(tracks[0].GetRect().X(), 180, 1) {
+		t.Errorf("expected track at 180, got %f", tracks[0].GetRect().X())
+	}
+
+	if !bs.cameraMotion.isIdentity() {
+		t.Errorf("expected camera motion to be consumed")
+	}
+}
+
+func TestFuseScore(t *testing.T) {
+
+	dets := []*STrack{
+		NewSTrack(NewRect(0, 0, 10, 10), 0.5, 0, 0),
+		NewSTrack(NewRect(0, 0, 10, 10), 1.0, 0, 0),
+	}
+
+	cost := fuseScore([][]float32{{0.2, 0.2}}, dets)
+
+	if !almostEqual(cost[0][0], 0.6, 1e-6) || !almostEqual(cost[0][1], 0.2, 1e-6) {
+		t.Errorf("unexpected fused cost %v", cost)
+	}
+}
+
+func TestFusedDistance(t *testing.T) {
+
+	bs := NewBoTSORT(30, 30, 0.5, 0.6, 0.8)
+	bs.useReid = true
+
+	feature := []float32{1, 0, 0}
+
+	track := NewSTrack(NewRect(0, 0, 100, 100), 0.9, 0, 0)
+	track.WithFeature(feature, 0.9, 30)
+
+	// close in position and appearance
+	near := NewSTrack(NewRect(10, 0, 100, 100), 1.0, 0, 0)
+	near.WithFeature(feature, 0.9, 30)
+
+	// same appearance but too far away
+	far := NewSTrack(NewRect(500, 0, 100, 100), 1.0, 0, 0)
+	far.WithFeature(feature, 0.9, 30)
+
+	cost := bs.calcFusedDistance([]*STrack{track}, []*STrack{near, far})
+
+	if !almostEqual(cost[0][0], 0, 1e-6) {
+		t.Errorf("expected appearance distance of 0, got %f", cost[0][0])
+	}
+
+	if !almostEqual(cost[0][1], 1, 1e-6) {
+		t.Errorf("expected distant detection cost of 1, got %f", cost[0][1])
+	}
+}
+
+func TestFusedDistanceMethod(t *testing.T) {
+
+	bs := NewBoTSORT(30, 30, 0.5, 0.6, 0.8)
+	bs.useReid = true
+	bs.appearanceThresh = 0.5
+
+	track := NewSTrack(NewRect(0, 0, 100, 100), 0.9, 0, 0)
+	track.WithFeature([]float32{1, 0, 0}, 0.9, 30)
+
+	// IoU distance of 0.46 with a cosine distance of 0.2 and euclidean
+	// distance of 0.63 before scaling
+	det := NewSTrack(NewRect(30, 0, 100, 100), 1.0, 0, 0)
+	det.WithFeature([]float32{0.8, 0.6, 0}, 0.9, 30)
+
+	tests := []struct {
+		name     string
+		reid     *reID
+		expected float32
+	}{
+		{"default cosine", nil, 0.1},
+		{"cosine", &reID{dist: Cosine}, 0.1},
+		{"euclidean", &reID{dist: Euclidean}, float32(math.Sqrt(0.4)) / 2},
+	}
+
+	for _, tc := range tests {
+
+		bs.reid = tc.reid
+		cost := bs.calcFusedDistance([]*STrack{track}, []*STrack{det})
+
+		if !almostEqual(cost[0][0], tc.expected, 1e-5) {
+			t.Errorf("%s: expected appearance distance %f, got %f", tc.name,
+				tc.expected, cost[0][0])
+		}
+	}
+}
+
+func TestApplyAffine(t *testing.T) {
+
+	s := NewSTrack(NewRect(100, 100, 50, 100), 0.9, 0, 0)
+	s.Activate(1, 1)
+
+	s.applyAffine(identityAffine())
+
+	if !almostEqual(s.GetRect().X(), 100, 1e-4) || !almostEqual(s.GetRect().Height(), 100, 1e-4) {
+		t.Errorf("expected identity to leave track unchanged, got %v", s.GetRect())
+	}
+
+	// scale by 2 and translate
+	s.applyAffine(affine{{2, 0, 10}, {0, 2, -5}})
+
+	if !almostEqual(s.mean[0], 260, 1e-3) || !almostEqual(s.mean[1], 295, 1e-3) ||
+		!almostEqual(s.GetRect().Height(), 200, 1e-3) || !almostEqual(s.GetRect().Width(), 100, 1e-3) {
+		t.Errorf("unexpected warped state %v", s.mean)
+	}
+
+	if !almostEqual(float32(s.covariance.At(3, 3)), float32(4*s.kalmanFilter.stdWeightPosition*s.kalmanFilter.stdWeightPosition*4*100*100), 1e-2) {
+		t.Errorf("unexpected warped covariance %f", s.covariance.At(3, 3))
+	}
+}
+
+func TestApplyAffineRotation(t *testing.T) {
+
+	s := NewSTrack(NewRect(100, 100, 50, 100), 0.9, 0, 0)
+	s.Activate(1, 1)
+
+	s.covariance.Set(0, 0, 4)
+	s.covariance.Set(1, 1, 9)
+
+	// rotate by 90 degrees about the origin
+	s.applyAffine(affine{{0, -1, 0}, {1, 0, 0}})
+
+	if !almostEqual(s.mean[0], -150, 1e-3) || !almostEqual(s.mean[1], 125, 1e-3) ||
+		!almostEqual(s.mean[2], 0.5, 1e-4) || !almostEqual(s.mean[3], 100, 1e-3) {
+		t.Errorf("unexpected rotated state %v", s.mean)
+	}
+
+	// the x and y position variances are swapped by the rotation
+	if !almostEqual(float32(s.covariance.At(0, 0)), 9, 1e-3) ||
+		!almostEqual(float32(s.covariance.At(1, 1)), 4, 1e-3) ||
+		!almostEqual(float32(s.covariance.At(0, 1)), 0, 1e-3) {
+		t.Errorf("unexpected rotated covariance %v", s.covariance.RawMatrix().Data[:10])
+	}
+}
@@ -2,7 +2,9 @@ package tracker
 
 import (
 	"fmt"
+	"github.com/swdee/go-rknnlite/postprocess/reid"
 	"math"
+	"time"
 )
 
 // BYTETracker represents the BYTE Tracker
@@ -14,7 +16,11 @@ type BYTETracker struct {
 	// Matching threshold for associations
 	matchThresh float32
 	// Maximum time an object can be lost before being remove
-	maxTimeLost int
+	maxTimeLost time.Duration
+	// frameInterval is the nominal time between frames
+	frameInterval time.Duration
+	// timestamp of the last update
+	timestamp time.Time
 	// Current frame ID
 	frameID int
 	// Counter for assigning unique track IDs
@@ -29,20 +35,84 @@ type BYTETracker struct {
 	reid *reID
 	// useReid is a flag to indicate if ReID supported tracking is to be used
 	useReid bool
+	// fuse is a flag to indicate the first association fuses the IoU distance
+	// with the detection score and ReID distance as BoT-SORT does
+	fuse bool
+	// proximityThresh is the IoU distance above which ReID distances are
+	// rejected when fusing
+	proximityThresh float32
+	// appearanceThresh is the ReID distance above which ReID distances are
+	// rejected when fusing
+	appearanceThresh float32
+	// gmc estimates the camera motion between frames passed to UpdateWithFrame
+	gmc *gmc
+	// cameraMotion is the camera motion transform to warp the tracks by in
+	// the next update
+	cameraMotion affine
+	// events dispatches track lifecycle events
+	events eventEmitter
+	// classMatching defines how labels are used in the association
+	classMatching ClassMatching
+	// labelMismatchCost is the cost added for differing labels when using
+	// PenaliseClassMismatch
+	labelMismatchCost float32
+	// classes are the per class thresholds keyed by label
+	classes map[int]ClassParams
+	// schedule defines when ReID features are extracted
+	schedule ReIDSchedule
+	// pending are the features being extracted asynchronously, nil if none
+	pending *pendingFeatures
 }
 
 // NewBYTETracker initializes and returns a new BYTETracker
 func NewBYTETracker(frameRate int, trackBuffer int, trackThresh float32,
 	highThresh float32, matchThresh float32) *BYTETracker {
 
+	p := DefaultBYTETrackerParams()
+	p.FrameRate = frameRate
+	p.TrackBuffer = trackBuffer
+	p.TrackThresh = trackThresh
+	p.HighThresh = highThresh
+	p.MatchThresh = matchThresh
+
+	return NewBYTETrackerWithParams(p)
+}
+
+// NewBYTETrackerWithParams initializes and returns a new BYTETracker with the
+// given parameters
+func NewBYTETrackerWithParams(p BYTETrackerParams) *BYTETracker {
+
+	maxTimeLost := p.LostTimeout
+
+	if maxTimeLost == 0 {
+		maxTimeLost = lostTimeout(p.FrameRate, p.TrackBuffer)
+	}
+
+	classes := make(map[int]ClassParams, len(p.Classes))
+
+	for label, cp := range p.Classes {
+		classes[label] = cp
+	}
+
 	return &BYTETracker{
-		trackThresh: trackThresh,
-		highThresh:  highThresh,
-		matchThresh: matchThresh,
-		maxTimeLost: int(float32(frameRate) / 30.0 * float32(trackBuffer)),
+		trackThresh:       p.TrackThresh,
+		highThresh:        p.HighThresh,
+		matchThresh:       p.MatchThresh,
+		maxTimeLost:       maxTimeLost,
+		frameInterval:     frameInterval(p.FrameRate),
+		cameraMotion:      identityAffine(),
+		classMatching:     p.ClassMatching,
+		labelMismatchCost: p.LabelMismatchCost,
+		classes:           classes,
 	}
 }
 
+// SetLostTimeout sets the time a track can be lost before being removed,
+// overriding the time derived from the frame rate and track buffer
+func (bt *BYTETracker) SetLostTimeout(timeout time.Duration) {
+	bt.maxTimeLost = timeout
+}
+
 // Reset clears the tracked data and resets everything
 func (bt *BYTETracker) Reset() {
 	bt.frameID = 0
@@ -50,10 +120,47 @@ func (bt *BYTETracker) Reset() {
 	bt.trackedStracks = make([]*STrack, 0)
 	bt.lostStracks = make([]*STrack, 0)
 	bt.removedStracks = make([]*STrack, 0)
+	bt.cameraMotion = identityAffine()
+	bt.timestamp = time.Time{}
+	bt.discardPending()
+
+	if bt.gmc != nil {
+		bt.gmc.reset()
+	}
+}
+
+// Update updates the tracker with new detections, assuming the frame was
+// captured one frame interval after the previous update
+func (bt *BYTETracker) Update(objects []Object) ([]Track, error) {
+	return bt.UpdateAt(objects, bt.timestamp.Add(bt.frameInterval))
+}
+
+// UpdateAt updates the tracker with new detections from the frame captured at
+// the timestamp.  The tracks are predicted forward by the time elapsed since
+// the previous update, so frames can be dropped or skipped.
+func (bt *BYTETracker) UpdateAt(objects []Object, timestamp time.Time) ([]Track, error) {
+
+	stracks, err := bt.update(objects, timestamp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toTracks(stracks), nil
 }
 
-// Update updates the tracker with new detections
-func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
+// update runs the BYTE association of the detections returning the activated
+// STracks
+func (bt *BYTETracker) update(objects []Object, timestamp time.Time) ([]*STrack, error) {
+
+	dt, err := frameDelta(bt.timestamp, timestamp, bt.frameInterval)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bt.timestamp = timestamp
+	bt.events.timestamp = timestamp
 
 	// Step 1: Get detections
 	bt.frameID++
@@ -66,11 +173,15 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 		strack := NewSTrack(NewRect(object.Rect.X(), object.Rect.Y(), object.Rect.Width(), object.Rect.Height()),
 			object.Prob, object.ID, object.Label)
 
-		if bt.useReid {
-			strack.WithFeature(object.Feature, 0.9, 30)
+		if bt.useReid && object.Feature != nil {
+			strack.WithFeature(object.Feature, featureAlpha, featureQueueSize)
+		}
+
+		if object.Oriented {
+			strack.WithAngle(object.Angle)
 		}
 
-		if object.Prob >= bt.trackThresh {
+		if object.Prob >= bt.trackThreshFor(object.Label) {
 			detStracks = append(detStracks, strack)
 		} else {
 			detLowStracks = append(detLowStracks, strack)
@@ -92,20 +203,37 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 
 	// predict current pose by KF
 	for _, strack := range strackPool {
-		strack.Predict()
+		strack.PredictDt(dt)
+	}
+
+	// compensate for camera motion
+	if !bt.cameraMotion.isIdentity() {
+		for _, strack := range strackPool {
+			strack.applyAffine(bt.cameraMotion)
+		}
+
+		for _, strack := range nonActiveStracks {
+			strack.applyAffine(bt.cameraMotion)
+		}
+
+		bt.cameraMotion = identityAffine()
 	}
 
 	// Step 2: First association, using IoU or feature distance matching
 	var currentTrackedStracks, remainTrackedStracks, remainDetStracks, refindStracks []*STrack
 	var costMatrix [][]float32
 
-	if bt.useReid {
+	if bt.fuse {
+		costMatrix = bt.calcFusedDistance(strackPool, detStracks)
+	} else if bt.useReid {
 		costMatrix = bt.calcFeatureDistance(strackPool, detStracks)
 	} else {
 		costMatrix = bt.calcIouDistance(strackPool, detStracks)
 	}
 
-	matchesIdx, unmatchTrackIdx, unmatchDetectionIdx, err := bt.linearAssignment(
+	costMatrix = bt.applyClassCost(costMatrix, strackPool, detStracks)
+
+	matchesIdx, unmatchTrackIdx, unmatchDetectionIdx, err := linearAssignment(
 		costMatrix,
 		len(strackPool), len(detStracks), bt.matchThresh,
 	)
@@ -119,6 +247,8 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 		track := strackPool[matchIdx[0]]
 		det := detStracks[matchIdx[1]]
 
+		track.lastSeen = timestamp
+
 		if track.GetSTrackState() == Tracked {
 			err := track.Update(det, bt.frameID)
 			if err != nil {
@@ -128,6 +258,7 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 		} else {
 			track.ReActivate(det, bt.frameID, -1) // Providing the default new track ID
 			refindStracks = append(refindStracks, track)
+			bt.events.emit(TrackReactivated, track)
 		}
 	}
 
@@ -145,8 +276,13 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 	// using low score IOU detections
 	var currentLostStracks []*STrack
 
-	matchesIdx, unmatchTrackIdx, unmatchDetectionIdx, err = bt.linearAssignment(
+	costMatrix = bt.applyClassCost(
 		bt.calcIouDistance(remainTrackedStracks, detLowStracks),
+		remainTrackedStracks, detLowStracks,
+	)
+
+	matchesIdx, unmatchTrackIdx, unmatchDetectionIdx, err = linearAssignment(
+		costMatrix,
 		len(remainTrackedStracks), len(detLowStracks), 0.5,
 	)
 
@@ -157,6 +293,8 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 	for _, matchIdx := range matchesIdx {
 		track := remainTrackedStracks[matchIdx[0]]
 		det := detLowStracks[matchIdx[1]]
+		track.lastSeen = timestamp
+
 		if track.GetSTrackState() == Tracked {
 			err := track.Update(det, bt.frameID)
 			if err != nil {
@@ -166,6 +304,7 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 		} else {
 			track.ReActivate(det, bt.frameID, -1) // Providing the default new track ID
 			refindStracks = append(refindStracks, track)
+			bt.events.emit(TrackReactivated, track)
 		}
 	}
 
@@ -174,6 +313,7 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 		if track.GetSTrackState() != Lost {
 			track.MarkAsLost()
 			currentLostStracks = append(currentLostStracks, track)
+			bt.events.emit(TrackLost, track)
 		}
 	}
 
@@ -181,8 +321,16 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 	// Match non-active to unmatched remainingDetStracks (high confidence only)
 	var currentRemovedStracks []*STrack
 
-	matchesIdx, unmatchUnconfirmedIdx, unmatchDetectionIdx, err := bt.linearAssignment(
-		bt.calcIouDistance(nonActiveStracks, remainDetStracks),
+	costMatrix = bt.calcIouDistance(nonActiveStracks, remainDetStracks)
+
+	if bt.fuse {
+		costMatrix = fuseScore(costMatrix, remainDetStracks)
+	}
+
+	costMatrix = bt.applyClassCost(costMatrix, nonActiveStracks, remainDetStracks)
+
+	matchesIdx, unmatchUnconfirmedIdx, unmatchDetectionIdx, err := linearAssignment(
+		costMatrix,
 		len(nonActiveStracks), len(remainDetStracks), 0.7,
 	)
 
@@ -191,11 +339,15 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 	}
 
 	for _, matchIdx := range matchesIdx {
-		err := nonActiveStracks[matchIdx[0]].Update(remainDetStracks[matchIdx[1]], bt.frameID)
+		track := nonActiveStracks[matchIdx[0]]
+		track.lastSeen = timestamp
+
+		err := track.Update(remainDetStracks[matchIdx[1]], bt.frameID)
 		if err != nil {
 			return nil, fmt.Errorf("error updating track, step 4: %w", err)
 		}
-		currentTrackedStracks = append(currentTrackedStracks, nonActiveStracks[matchIdx[0]])
+		currentTrackedStracks = append(currentTrackedStracks, track)
+		bt.events.emit(TrackActivated, track)
 	}
 
 	for _, unmatchIdx := range unmatchUnconfirmedIdx {
@@ -206,19 +358,32 @@ func (bt *BYTETracker) Update(objects []Object) ([]*STrack, error) {
 
 	for _, unmatchIdx := range unmatchDetectionIdx {
 		track := remainDetStracks[unmatchIdx]
-		if track.GetScore() < bt.highThresh {
+		if track.GetScore() < bt.highThreshFor(track.GetLabel()) {
 			continue
 		}
 		bt.trackIDCount++
 		track.Activate(bt.frameID, bt.trackIDCount)
+		track.firstSeen = timestamp
+		track.lastSeen = timestamp
 		currentTrackedStracks = append(currentTrackedStracks, track)
+
+		if track.IsActivated() {
+			bt.events.emit(TrackActivated, track)
+		}
 	}
 
 	// Step 5: Update state - Time-based removal of old lost tracks
 	for _, lostStrack := range bt.lostStracks {
-		if bt.frameID-lostStrack.GetFrameID() > bt.maxTimeLost {
+		// skip tracks removed on the previous update which are only dropped
+		// from the lost list below
+		if lostStrack.GetSTrackState() == Removed {
+			continue
+		}
+
+		if timestamp.Sub(lostStrack.lastSeen) > bt.lostTimeoutFor(lostStrack.GetLabel()) {
 			lostStrack.MarkAsRemoved()
 			currentRemovedStracks = append(currentRemovedStracks, lostStrack)
+			bt.events.emit(TrackRemoved, lostStrack)
 		}
 	}
 
@@ -285,7 +450,7 @@ func (bt *BYTETracker) subStracks(aTlist []*STrack, bTlist []*STrack) []*STrack
 
 // removeDuplicateStracks removes duplicate tracks
 func (bt *BYTETracker) removeDuplicateStracks(aStracks []*STrack, bStracks []*STrack, aRes *[]*STrack, bRes *[]*STrack) {
-	ious := bt.calcIouDistance(aStracks, bStracks)
+	ious := bt.applyClassCost(bt.calcIouDistance(aStracks, bStracks), aStracks, bStracks)
 	overlappingCombinations := [][2]int{}
 	for i := range ious {
 		for j := range ious[i] {
@@ -318,7 +483,7 @@ func (bt *BYTETracker) removeDuplicateStracks(aStracks []*STrack, bStracks []*ST
 }
 
 // linearAssignment performs linear assignment using the Hungarian algorithm
-func (bt *BYTETracker) linearAssignment(costMatrix [][]float32, costMatrixSize,
+func linearAssignment(costMatrix [][]float32, costMatrixSize,
 	costMatrixSizeSize int, thresh float32) (matchesIdx [][2]int,
 	unmatchTrackIdx, unmatchDetectionIdx []int, fatalErr error) {
 
@@ -332,7 +497,7 @@ func (bt *BYTETracker) linearAssignment(costMatrix [][]float32, costMatrixSize,
 		return
 	}
 
-	rowsol, colsol, _, fatalErr := bt.execLapjv(costMatrix, true, thresh)
+	rowsol, colsol, _, fatalErr := execLapjv(costMatrix, true, thresh)
 
 	if fatalErr != nil {
 		return
@@ -355,7 +520,7 @@ func (bt *BYTETracker) linearAssignment(costMatrix [][]float32, costMatrixSize,
 }
 
 // calcIous calculates the Intersection over Union (IoU) between two sets of rectangles
-func (bt *BYTETracker) calcIous(aRects, bRects []Rect) [][]float32 {
+func calcIous(aRects, bRects []Rect) [][]float32 {
 
 	var ious [][]float32
 	if len(aRects)*len(bRects) == 0 {
@@ -378,16 +543,7 @@ func (bt *BYTETracker) calcIous(aRects, bRects []Rect) [][]float32 {
 // calcIouDistance calculates the IoU distance between two sets of tracks
 func (bt *BYTETracker) calcIouDistance(aTracks, bTracks []*STrack) [][]float32 {
 
-	var aRects, bRects []Rect
-	for _, track := range aTracks {
-		aRects = append(aRects, *track.GetRect())
-	}
-
-	for _, track := range bTracks {
-		bRects = append(bRects, *track.GetRect())
-	}
-
-	ious := bt.calcIous(aRects, bRects)
+	ious := calcStrackIous(aTracks, bTracks)
 
 	var costMatrix [][]float32
 
@@ -405,7 +561,7 @@ func (bt *BYTETracker) calcIouDistance(aTracks, bTracks []*STrack) [][]float32 {
 }
 
 // execLapjv executes the LAPJV algorithm for linear assignment problem solving
-func (bt *BYTETracker) execLapjv(cost [][]float32, extendCost bool,
+func execLapjv(cost [][]float32, extendCost bool,
 	costLimit float32) (rowsol []int, colsol []int, opt float64, err error) {
 
 	// Default value for returnCost. This was a parameter passed to the function
@@ -530,6 +686,14 @@ func (bt *BYTETracker) execLapjv(cost [][]float32, extendCost bool,
 // of the specified STracks
 func (bt *BYTETracker) calcFeatureDistance(tracks, detections []*STrack) [][]float32 {
 
+	// an empty cost matrix leaves all tracks and detections unmatched
+	if len(tracks)*len(detections) == 0 {
+		return nil
+	}
+
+	// pairs without features, as extraction was skipped by the ReID
+	// schedule, use the IoU distance
+	ious := calcStrackIous(tracks, detections)
 	cost := make([][]float32, len(tracks))
 
 	for i, tr := range tracks {
@@ -537,9 +701,75 @@ func (bt *BYTETracker) calcFeatureDistance(tracks, detections []*STrack) [][]flo
 		cost[i] = make([]float32, len(detections))
 
 		for j, det := range detections {
-			cost[i][j] = tr.BestMatchDistance(det.feature)
+			if tr.hasFeature && det.hasFeature {
+				cost[i][j] = tr.BestMatchDistance(det.feature)
+			} else {
+				cost[i][j] = 1 - ious[i][j]
+			}
 		}
 	}
 
 	return cost
 }
+
+// calcFusedDistance calculates the BoT-SORT cost between the tracks and
+// detections being the IoU distance fused with the detection score, and if
+// ReID is used, the minimum of that and the ReID distance of those tracks that
+// are close enough in both position and appearance
+func (bt *BYTETracker) calcFusedDistance(tracks, detections []*STrack) [][]float32 {
+
+	iouDist := bt.calcIouDistance(tracks, detections)
+	cost := fuseScore(iouDist, detections)
+
+	if !bt.useReid {
+		return cost
+	}
+
+	for i, tr := range tracks {
+		for j, det := range detections {
+
+			embDist := float32(1.0)
+
+			if tr.hasFeature && det.hasFeature {
+				embDist = bt.embeddingDistance(tr.smoothFeature, det.feature)
+			}
+
+			if embDist > bt.appearanceThresh || iouDist[i][j] > bt.proximityThresh {
+				embDist = 1.0
+			}
+
+			cost[i][j] = min(cost[i][j], embDist)
+		}
+	}
+
+	return cost
+}
+
+// embeddingDistance returns the ReID distance between two L2 normalized
+// features scaled from 0 to 1, using the distance method set by UseReID() or
+// cosine distance if none has been set
+func (bt *BYTETracker) embeddingDistance(a, b []float32) float32 {
+
+	if bt.reid != nil && bt.reid.dist == Euclidean {
+		return reid.EuclideanDistance(a, b) / 2
+	}
+
+	return max(reid.CosineDistance(a, b), 0) / 2
+}
+
+// fuseScore weights the IoU similarity of the cost matrix by the detection
+// scores
+func fuseScore(cost [][]float32, detections []*STrack) [][]float32 {
+
+	fused := make([][]float32, len(cost))
+
+	for i := range cost {
+		fused[i] = make([]float32, len(cost[i]))
+
+		for j := range cost[i] {
+			fused[i][j] = 1 - (1-cost[i][j])*detections[j].GetScore()
+		}
+	}
+
+	return fused
+}
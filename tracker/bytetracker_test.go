@@ -3,6 +3,7 @@ package tracker
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 // convertDetections takes the matrix of YOLO object detections and converts
@@ -225,3 +226,96 @@ func TestBYTETracker(t *testing.T) {
 		*/
 	}
 }
+
+func TestBYTETrackerUpdateAt(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	start := time.Unix(1000, 0)
+	interval := time.Second / 30
+
+	// object moves 20 pixels per frame with inference run every 4th frame
+	for frame := 0; frame <= 40; frame += 4 {
+
+		objects := []Object{
+			NewObject(NewRect(100+float32(frame)*20, 100, 200, 200), 0, 0.9, int64(frame)),
+		}
+
+		tracks, err := bt.UpdateAt(objects, start.Add(time.Duration(frame)*interval))
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		if ids := trackIDs(tracks); len(ids) != 1 || ids[0] != 1 {
+			t.Fatalf("frame %d: expected track IDs [1], got %v", frame, ids)
+		}
+	}
+
+	// velocity is estimated per frame interval rather than per update
+	if vx := bt.trackedStracks[0].mean[4]; !almostEqual(vx, 20, 2) {
+		t.Errorf("expected x velocity of 20 pixels per frame, got %v", vx)
+	}
+
+	_, err := bt.UpdateAt(nil, start)
+
+	if err == nil {
+		t.Errorf("expected error for timestamp before previous update")
+	}
+}
+
+func TestBYTETrackerLostTimeout(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.SetLostTimeout(500 * time.Millisecond)
+
+	var removed time.Duration
+	bt.SetEventHandler(EventFuncs{
+		Removed: func(e Event) {
+			removed = bt.timestamp.Sub(time.Unix(0, 0))
+		},
+	})
+
+	// object is seen for the first second then updates continue every
+	// 100ms without it
+	for ms := 0; ms <= 2000; ms += 100 {
+
+		var objects []Object
+
+		if ms <= 1000 {
+			objects = append(objects, NewObject(NewRect(100, 100, 50, 100), 0, 0.9, 1))
+		}
+
+		if _, err := bt.UpdateAt(objects, time.Unix(0, 0).Add(time.Duration(ms)*time.Millisecond)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if removed != 1600*time.Millisecond {
+		t.Errorf("expected track removed at 1.6s, got %v", removed)
+	}
+}
+
+func TestBYTETrackerReIDNoDetections(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.useReid = true
+
+	obj := NewObject(NewRect(100, 100, 50, 100), 0, 0.9, 1)
+	obj.Feature = []float32{1, 0, 0}
+
+	if _, err := bt.Update([]Object{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the track has no detection to match against so the feature distance
+	// cost matrix is empty
+	tracks, err := bt.Update(nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tracks) != 0 {
+		t.Errorf("expected no tracked objects, got %d", len(tracks))
+	}
+}
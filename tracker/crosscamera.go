@@ -0,0 +1,411 @@
+package tracker
+
+import (
+	"github.com/swdee/go-rknnlite/postprocess/reid"
+	"sync"
+	"time"
+)
+
+// CameraTransition defines the time window for an object to travel from the
+// view of one camera to another
+type CameraTransition struct {
+	// From is the camera the track was lost on
+	From string
+	// To is the camera the track is activated on
+	To string
+	// MinTime is the minimum time between the track being lost on the From
+	// camera and activated on the To camera
+	MinTime time.Duration
+	// MaxTime is the maximum time between the track being lost on the From
+	// camera and activated on the To camera
+	MaxTime time.Duration
+}
+
+// CrossCameraParams defines the struct containing the parameters for the
+// CrossCamera coordinator
+type CrossCameraParams struct {
+	// Dist is the distance method used to compare track embeddings.  An
+	// unknown method uses Cosine.
+	Dist DistanceMethod
+	// Threshold is the maximum embedding distance for a new track to be matched
+	// to a lost track on another camera
+	Threshold float32
+	// Transitions are the time windows between camera pairs.  A transition
+	// from a camera to itself allows a track removed by the tracker to be
+	// matched when the object re-enters the same view.
+	Transitions []CameraTransition
+	// DefaultWindow is the maximum time between being lost and activated for
+	// pairs of different cameras not listed in Transitions.  If zero only the
+	// listed Transitions are matched.
+	DefaultWindow time.Duration
+	// MatchLabel requires the object label/class of matched tracks to be
+	// the same
+	MatchLabel bool
+}
+
+// DefaultCrossCameraParams returns an instance of CrossCameraParams allowing
+// transitions between any cameras within 30 seconds
+func DefaultCrossCameraParams() CrossCameraParams {
+	return CrossCameraParams{
+		Dist:          Cosine,
+		Threshold:     0.3,
+		DefaultWindow: 30 * time.Second,
+		MatchLabel:    true,
+	}
+}
+
+// CameraTrack identifies a track on a camera
+type CameraTrack struct {
+	// Camera is the name of the camera
+	Camera string
+	// TrackID is the ID of the track assigned by the camera's tracker
+	TrackID int
+}
+
+// GlobalAssignment is published when a track is assigned a global ID
+type GlobalAssignment struct {
+	CameraTrack
+	// GlobalID is the ID of the object across all cameras
+	GlobalID int
+	// Matched is true if the track was matched to a lost track and took its
+	// global ID, otherwise a new global ID was created
+	Matched bool
+	// From is the lost track matched
+	From CameraTrack
+	// Distance is the embedding distance to the lost track matched
+	Distance float32
+}
+
+// crossCameraTrack is a lost track that can be matched by tracks activated on
+// other cameras
+type crossCameraTrack struct {
+	CameraTrack
+	// globalID is the global ID of the track
+	globalID int
+	// label is the object label/class of the track
+	label int
+	// feature is the smoothed ReID embedding of the track
+	feature []float32
+	// lostAt is the time the track was lost
+	lostAt time.Time
+}
+
+// CrossCamera coordinates the BYTETrackers of several cameras so the same
+// object keeps a global ID as it moves between them.  When a track is
+// activated on a camera its ReID embedding is compared against the tracks
+// recently lost on other cameras, within the camera transition time window,
+// and if close enough it takes over the global ID of the lost track.  Each
+// camera's tracker must use ReID and be updated with timestamps from a common
+// clock, such as with UpdateWithFrameAt().  CrossCamera is safe for use by
+// trackers running in separate goroutines.
+type CrossCamera struct {
+	// params are the coordinator parameters
+	params CrossCameraParams
+	// distance is the function of the distance method
+	distance func(a, b []float32) float32
+	// transitions are the camera transition windows keyed by from and to
+	// camera
+	transitions map[[2]string]CameraTransition
+	// maxWindow is the longest transition window, after which lost tracks are
+	// no longer kept
+	maxWindow time.Duration
+	// globalIDCount is the last global ID assigned
+	globalIDCount int
+	// globalIDs are the global IDs of the tracks on each camera
+	globalIDs map[CameraTrack]int
+	// lost are the lost tracks that can be matched
+	lost map[CameraTrack]*crossCameraTrack
+	// handler receives the global ID assignments, nil if not set
+	handler func(a GlobalAssignment)
+	// mu protects the coordinator state
+	mu sync.Mutex
+}
+
+// NewCrossCamera returns a CrossCamera coordinator with the parameters
+func NewCrossCamera(params CrossCameraParams) *CrossCamera {
+
+	distance, err := reid.DistanceFunc(params.Dist)
+
+	if err != nil {
+		distance = reid.CosineDistance
+	}
+
+	cc := &CrossCamera{
+		params:      params,
+		distance:    distance,
+		transitions: make(map[[2]string]CameraTransition),
+		maxWindow:   params.DefaultWindow,
+		globalIDs:   make(map[CameraTrack]int),
+		lost:        make(map[CameraTrack]*crossCameraTrack),
+	}
+
+	for _, t := range params.Transitions {
+
+		cc.transitions[[2]string{t.From, t.To}] = t
+
+		if t.MaxTime > cc.maxWindow {
+			cc.maxWindow = t.MaxTime
+		}
+	}
+
+	return cc
+}
+
+// AddCamera registers the tracker of the named camera with the coordinator.
+// An event handler already set on the tracker continues to receive events.
+func (cc *CrossCamera) AddCamera(camera string, bt *BYTETracker) {
+	bt.SetEventHandler(cc.Handler(camera, bt.events.handler))
+}
+
+// Handler returns an EventHandler for the named camera that forwards events
+// to the next handler, which can be nil.  Use it to register trackers with
+// their own SetEventHandler().
+func (cc *CrossCamera) Handler(camera string, next EventHandler) EventHandler {
+	return &crossCameraHandler{
+		cc:     cc,
+		camera: camera,
+		next:   next,
+	}
+}
+
+// SetAssignmentHandler sets the function to receive global ID assignments.
+// It is called synchronously from the tracker's Update so should not block.
+func (cc *CrossCamera) SetAssignmentHandler(h func(a GlobalAssignment)) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.handler = h
+}
+
+// GlobalID returns the global ID of the track on the camera
+func (cc *CrossCamera) GlobalID(camera string, trackID int) (int, bool) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	id, ok := cc.globalIDs[CameraTrack{camera, trackID}]
+
+	return id, ok
+}
+
+// Mapping returns a copy of the global IDs of all tracks on each camera that
+// have not been removed
+func (cc *CrossCamera) Mapping() map[CameraTrack]int {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	res := make(map[CameraTrack]int, len(cc.globalIDs))
+
+	for k, v := range cc.globalIDs {
+		res[k] = v
+	}
+
+	return res
+}
+
+// Reset clears all tracks and restarts global IDs from 1
+func (cc *CrossCamera) Reset() {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.globalIDCount = 0
+	cc.globalIDs = make(map[CameraTrack]int)
+	cc.lost = make(map[CameraTrack]*crossCameraTrack)
+}
+
+// activated assigns a global ID to the newly activated track
+func (cc *CrossCamera) activated(camera string, e Event) {
+
+	cc.mu.Lock()
+
+	key := CameraTrack{camera, e.TrackID}
+
+	if _, ok := cc.globalIDs[key]; ok {
+		cc.mu.Unlock()
+		return
+	}
+
+	cc.prune(e.Timestamp)
+
+	a := GlobalAssignment{CameraTrack: key}
+
+	if best, dist := cc.bestMatch(key, e); best != nil {
+		a.GlobalID = best.globalID
+		a.Matched = true
+		a.From = best.CameraTrack
+		a.Distance = dist
+		delete(cc.lost, best.CameraTrack)
+
+	} else {
+		cc.globalIDCount++
+		a.GlobalID = cc.globalIDCount
+	}
+
+	cc.globalIDs[key] = a.GlobalID
+	handler := cc.handler
+
+	cc.mu.Unlock()
+
+	if handler != nil {
+		handler(a)
+	}
+}
+
+// bestMatch returns the closest lost track to the activated track within the
+// camera transition window and distance threshold, or nil if there is none
+func (cc *CrossCamera) bestMatch(key CameraTrack, e Event) (*crossCameraTrack, float32) {
+
+	if e.Feature == nil {
+		return nil, 0
+	}
+
+	var best *crossCameraTrack
+	var bestDist float32
+
+	for _, l := range cc.lost {
+
+		if l.feature == nil || len(l.feature) != len(e.Feature) {
+			continue
+		}
+
+		if cc.params.MatchLabel && l.label != e.Label {
+			continue
+		}
+
+		minTime, maxTime, ok := cc.window(l.Camera, key.Camera)
+		elapsed := e.Timestamp.Sub(l.lostAt)
+
+		if !ok || elapsed < minTime || elapsed > maxTime {
+			continue
+		}
+
+		dist := cc.distance(l.feature, e.Feature)
+
+		if dist > cc.params.Threshold || (best != nil && dist >= bestDist) {
+			continue
+		}
+
+		best = l
+		bestDist = dist
+	}
+
+	return best, bestDist
+}
+
+// window returns the transition time window between the cameras
+func (cc *CrossCamera) window(from, to string) (time.Duration, time.Duration, bool) {
+
+	if t, ok := cc.transitions[[2]string{from, to}]; ok {
+		return t.MinTime, t.MaxTime, true
+	}
+
+	if from == to || cc.params.DefaultWindow <= 0 {
+		return 0, 0, false
+	}
+
+	return 0, cc.params.DefaultWindow, true
+}
+
+// prune drops lost tracks older than the longest transition window
+func (cc *CrossCamera) prune(now time.Time) {
+
+	for k, l := range cc.lost {
+		if now.Sub(l.lostAt) > cc.maxWindow {
+			delete(cc.lost, k)
+		}
+	}
+}
+
+// lostTrack records the lost track so it can be matched on other cameras
+func (cc *CrossCamera) lostTrack(camera string, e Event) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	key := CameraTrack{camera, e.TrackID}
+	globalID, ok := cc.globalIDs[key]
+
+	if !ok {
+		return
+	}
+
+	cc.lost[key] = &crossCameraTrack{
+		CameraTrack: key,
+		globalID:    globalID,
+		label:       e.Label,
+		feature:     e.Feature,
+		lostAt:      e.Timestamp,
+	}
+}
+
+// reactivated drops the lost track as it was found again on its camera
+func (cc *CrossCamera) reactivated(camera string, e Event) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	delete(cc.lost, CameraTrack{camera, e.TrackID})
+}
+
+// removed drops the global ID of the removed track.  The lost track is kept
+// to be matched until its transition windows expire.
+func (cc *CrossCamera) removed(camera string, e Event) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	delete(cc.globalIDs, CameraTrack{camera, e.TrackID})
+}
+
+// crossCameraHandler is the EventHandler of a camera's tracker
+type crossCameraHandler struct {
+	// cc is the coordinator
+	cc *CrossCamera
+	// camera is the name of the camera
+	camera string
+	// next is the handler to forward events to, nil if not set
+	next EventHandler
+}
+
+// OnActivated assigns the track a global ID
+func (h *crossCameraHandler) OnActivated(e Event) {
+
+	h.cc.activated(h.camera, e)
+
+	if h.next != nil {
+		h.next.OnActivated(e)
+	}
+}
+
+// OnLost records the track as lost
+func (h *crossCameraHandler) OnLost(e Event) {
+
+	h.cc.lostTrack(h.camera, e)
+
+	if h.next != nil {
+		h.next.OnLost(e)
+	}
+}
+
+// OnReactivated drops the track from those lost
+func (h *crossCameraHandler) OnReactivated(e Event) {
+
+	h.cc.reactivated(h.camera, e)
+
+	if h.next != nil {
+		h.next.OnReactivated(e)
+	}
+}
+
+// OnRemoved drops the global ID of the track
+func (h *crossCameraHandler) OnRemoved(e Event) {
+
+	h.cc.removed(h.camera, e)
+
+	if h.next != nil {
+		h.next.OnRemoved(e)
+	}
+}
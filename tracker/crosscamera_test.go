@@ -0,0 +1,186 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+// reidObjects returns a detection with the ReID embedding
+func reidObjects(feature []float32, id int64) []Object {
+
+	obj := NewObject(NewRect(100, 100, 50, 100), 0, 0.9, id)
+	obj.Feature = feature
+
+	return []Object{obj}
+}
+
+func TestCrossCameraTrackers(t *testing.T) {
+
+	params := DefaultCrossCameraParams()
+	params.DefaultWindow = 0
+	params.Transitions = []CameraTransition{
+		{From: "A", To: "B", MinTime: time.Second, MaxTime: 5 * time.Second},
+	}
+
+	cc := NewCrossCamera(params)
+
+	var assigned []GlobalAssignment
+
+	cc.SetAssignmentHandler(func(a GlobalAssignment) {
+		assigned = append(assigned, a)
+	})
+
+	camA := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	camA.useReid = true
+	log := &eventLog{}
+	camA.SetEventHandler(log.handler())
+	cc.AddCamera("A", camA)
+
+	camB := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	camB.useReid = true
+	cc.AddCamera("B", camB)
+
+	camC := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	camC.useReid = true
+	cc.AddCamera("C", camC)
+
+	person := []float32{0.9, 0.1, 0.3, 0.2}
+	start := time.Unix(1000, 0)
+
+	// person walks through camera A then disappears
+	for i := 0; i < 5; i++ {
+		if _, err := camA.UpdateAt(reidObjects(person, int64(i)), start.Add(time.Duration(i)*100*time.Millisecond)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// another object with an unrelated embedding replaces them
+	other := NewObject(NewRect(600, 400, 50, 100), 0, 0.9, 5)
+	other.Feature = []float32{-0.9, -0.1, -0.3, -0.2}
+
+	if _, err := camA.UpdateAt([]Object{other}, start.Add(500*time.Millisecond)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// and appears on camera B with a similar embedding
+	if _, err := camB.UpdateAt(reidObjects([]float32{0.85, 0.15, 0.3, 0.2}, 1),
+		start.Add(2*time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// camera C has no transition from camera A
+	if _, err := camC.UpdateAt(reidObjects(person, 1), start.Add(2*time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(assigned) != 3 {
+		t.Fatalf("expected 3 assignments, got %+v", assigned)
+	}
+
+	if a := assigned[1]; !a.Matched || a.GlobalID != 1 || a.Camera != "B" ||
+		a.From != (CameraTrack{"A", 1}) {
+		t.Errorf("expected camera B track matched to global ID 1, got %+v", a)
+	}
+
+	if a := assigned[2]; a.Matched || a.GlobalID != 2 {
+		t.Errorf("expected camera C track with new global ID 2, got %+v", a)
+	}
+
+	if id, ok := cc.GlobalID("B", 1); !ok || id != 1 {
+		t.Errorf("expected global ID 1 for camera B track 1, got %d", id)
+	}
+
+	if m := cc.Mapping(); len(m) != 3 || m[CameraTrack{"A", 1}] != 1 ||
+		m[CameraTrack{"C", 1}] != 2 {
+		t.Errorf("unexpected mapping %v", m)
+	}
+
+	// existing event handler still receives events
+	if len(log.events) != 2 || log.events[0].Type != TrackActivated ||
+		log.events[1].Type != TrackLost || log.events[1].Feature == nil ||
+		!log.events[1].Timestamp.Equal(start.Add(500*time.Millisecond)) {
+		t.Errorf("unexpected events %+v", log.events)
+	}
+}
+
+func TestCrossCameraWindow(t *testing.T) {
+
+	params := DefaultCrossCameraParams()
+	params.DefaultWindow = 2 * time.Second
+	params.Transitions = []CameraTransition{
+		{From: "A", To: "B", MinTime: time.Second, MaxTime: 5 * time.Second},
+	}
+
+	cc := NewCrossCamera(params)
+	camA := cc.Handler("A", nil)
+	camB := cc.Handler("B", nil)
+	camC := cc.Handler("C", nil)
+
+	feat := []float32{1, 0, 0}
+	start := time.Unix(1000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	camA.OnActivated(Event{TrackID: 1, Timestamp: at(0), Feature: feat})
+	camA.OnLost(Event{TrackID: 1, Timestamp: at(time.Second), Feature: feat})
+
+	tests := []struct {
+		name     string
+		handler  EventHandler
+		camera   string
+		event    Event
+		globalID int
+	}{
+		{"before minimum transition time", camB, "B",
+			Event{TrackID: 1, Timestamp: at(1500 * time.Millisecond), Feature: feat}, 2},
+		{"different label", camB, "B",
+			Event{TrackID: 2, Label: 1, Timestamp: at(3 * time.Second), Feature: feat}, 3},
+		{"different appearance", camB, "B",
+			Event{TrackID: 3, Timestamp: at(3 * time.Second), Feature: []float32{0, 1, 0}}, 4},
+		{"no feature", camB, "B",
+			Event{TrackID: 4, Timestamp: at(3 * time.Second)}, 5},
+		{"after default window", camC, "C",
+			Event{TrackID: 1, Timestamp: at(3500 * time.Millisecond), Feature: feat}, 6},
+		{"within transition window", camB, "B",
+			Event{TrackID: 5, Timestamp: at(4 * time.Second), Feature: feat}, 1},
+		{"lost track already matched", camC, "C",
+			Event{TrackID: 2, Timestamp: at(4 * time.Second), Feature: feat}, 7},
+	}
+
+	for _, tc := range tests {
+
+		tc.handler.OnActivated(tc.event)
+
+		if id, _ := cc.GlobalID(tc.camera, tc.event.TrackID); id != tc.globalID {
+			t.Errorf("%s: expected global ID %d, got %d", tc.name, tc.globalID, id)
+		}
+	}
+
+	// lost tracks are pruned after the longest window
+	camB.OnLost(Event{TrackID: 5, Timestamp: at(5 * time.Second), Feature: feat})
+	camA.OnActivated(Event{TrackID: 2, Timestamp: at(11 * time.Second), Feature: feat})
+
+	if id, _ := cc.GlobalID("A", 2); id != 8 {
+		t.Errorf("expected new global ID 8 after lost track expired, got %d", id)
+	}
+
+	// reactivated tracks are no longer lost
+	camB.OnLost(Event{TrackID: 3, Timestamp: at(12 * time.Second), Feature: feat})
+	camB.OnReactivated(Event{TrackID: 3, Timestamp: at(12 * time.Second)})
+	camC.OnActivated(Event{TrackID: 3, Timestamp: at(13 * time.Second), Feature: feat})
+
+	if id, _ := cc.GlobalID("C", 3); id != 9 {
+		t.Errorf("expected new global ID 9 for reactivated lost track, got %d", id)
+	}
+
+	camB.OnRemoved(Event{TrackID: 3})
+
+	if _, ok := cc.GlobalID("B", 3); ok {
+		t.Errorf("expected removed track to have no global ID")
+	}
+
+	cc.Reset()
+
+	if len(cc.Mapping()) != 0 {
+		t.Errorf("expected mapping cleared on reset")
+	}
+}
@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"math"
+)
+
+// linearSumAssignment solves the rectangular linear assignment problem
+// returning the row and column indexes of the assignment that minimises the
+// total cost.  Every row is assigned if there are no more rows than columns,
+// otherwise every column is assigned.
+func linearSumAssignment(cost [][]float64) ([]int, []int) {
+
+	n := len(cost)
+
+	if n == 0 || len(cost[0]) == 0 {
+		return nil, nil
+	}
+
+	m := len(cost[0])
+
+	if n > m {
+		// solve the transpose so there are no more rows than columns
+		t := make([][]float64, m)
+
+		for j := range t {
+			t[j] = make([]float64, n)
+
+			for i := range cost {
+				t[j][i] = cost[i][j]
+			}
+		}
+
+		cols, rows := linearSumAssignment(t)
+		sortPairs(rows, cols)
+
+		return rows, cols
+	}
+
+	// shortest augmenting path Hungarian algorithm with 1 based indexes where
+	// p[j] is the row assigned to column j
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+
+			j0 = j1
+
+			if p[j0] == 0 {
+				break
+			}
+		}
+
+		// augment along the path
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	rows := make([]int, 0, n)
+	cols := make([]int, 0, n)
+
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			rows = append(rows, p[j]-1)
+			cols = append(cols, j-1)
+		}
+	}
+
+	sortPairs(rows, cols)
+
+	return rows, cols
+}
+
+// sortPairs sorts the assignment pairs by row
+func sortPairs(rows, cols []int) {
+	for i := 1; i < len(rows); i++ {
+		for j := i; j > 0 && rows[j] < rows[j-1]; j-- {
+			rows[j], rows[j-1] = rows[j-1], rows[j]
+			cols[j], cols[j-1] = cols[j-1], cols[j]
+		}
+	}
+}
@@ -0,0 +1,669 @@
+/*
+Package eval provides Multi-Object Tracking evaluation of tracker results
+against MOTChallenge ground truth.  It calculates the CLEAR MOT (MOTA, MOTP,
+ID switches), Identity (IDF1) and HOTA metrics following the TrackEval
+implementation so the tracker parameters can be tuned on labelled sequences.
+*/
+package eval
+
+import (
+	"math"
+	"sort"
+)
+
+// Params defines the struct containing the evaluation parameters
+type Params struct {
+	// IoUThreshold is the minimum IoU for a track to match ground truth in
+	// the CLEAR and Identity metrics
+	IoUThreshold float64
+	// Classes are the ground truth classes evaluated, if empty all classes
+	// are evaluated
+	Classes []int
+	// DistractorClasses are ground truth classes that are not evaluated and
+	// where tracks matching them are removed rather than counted as false
+	// positives
+	DistractorClasses []int
+}
+
+// DefaultParams returns an instance of Params evaluating all ground truth
+// classes at an IoU threshold of 0.5
+func DefaultParams() Params {
+	return Params{
+		IoUThreshold: 0.5,
+	}
+}
+
+// MOTChallengeParams returns an instance of Params for the MOT17 and MOT20
+// pedestrian benchmark where class 1 is evaluated and the person on vehicle,
+// static person, distractor and reflection classes are distractors
+func MOTChallengeParams() Params {
+	return Params{
+		IoUThreshold:      0.5,
+		Classes:           []int{1},
+		DistractorClasses: []int{2, 7, 8, 12},
+	}
+}
+
+// Metrics are the tracking evaluation results of a sequence
+type Metrics struct {
+	// MOTA is the Multiple Object Tracking Accuracy
+	MOTA float64
+	// MOTP is the Multiple Object Tracking Precision being the mean IoU of
+	// matched tracks
+	MOTP float64
+	// TP is the number of ground truth boxes matched by a track
+	TP int
+	// FP is the number of track boxes not matching ground truth
+	FP int
+	// FN is the number of ground truth boxes not matched by a track
+	FN int
+	// IDSW is the number of identity switches
+	IDSW int
+	// Frag is the number of times a ground truth trajectory is interrupted
+	Frag int
+	// MT is the number of ground truth trajectories tracked for at least 80%
+	// of their life
+	MT int
+	// PT is the number of ground truth trajectories partially tracked
+	PT int
+	// ML is the number of ground truth trajectories tracked for less than 20%
+	// of their life
+	ML int
+
+	// IDF1 is the ratio of correctly identified detections over the average
+	// number of ground truth and computed detections
+	IDF1 float64
+	// IDP is the identification precision
+	IDP float64
+	// IDR is the identification recall
+	IDR float64
+	// IDTP is the number of identity true positives
+	IDTP int
+	// IDFP is the number of identity false positives
+	IDFP int
+	// IDFN is the number of identity false negatives
+	IDFN int
+
+	// HOTA is the Higher Order Tracking Accuracy averaged over the
+	// localisation thresholds 0.05 to 0.95
+	HOTA float64
+	// DetA is the detection accuracy component of HOTA
+	DetA float64
+	// AssA is the association accuracy component of HOTA
+	AssA float64
+	// DetRe is the detection recall of HOTA
+	DetRe float64
+	// DetPr is the detection precision of HOTA
+	DetPr float64
+	// AssRe is the association recall of HOTA
+	AssRe float64
+	// AssPr is the association precision of HOTA
+	AssPr float64
+	// LocA is the localisation accuracy of HOTA
+	LocA float64
+
+	// GTIDs is the number of ground truth trajectories
+	GTIDs int
+	// TrackIDs is the number of tracks
+	TrackIDs int
+}
+
+// frameData are the ground truth and tracks of a frame prepared for
+// evaluation with ID's mapped to contiguous indexes
+type frameData struct {
+	gtIDs []int
+	trIDs []int
+	// sim is the IoU indexed by [ground truth][track]
+	sim [][]float64
+}
+
+// epsilon is the tolerance used when comparing scores to thresholds
+const epsilon = 1e-9
+
+// Evaluate calculates the tracking metrics of the tracker results against
+// the ground truth
+func Evaluate(gt, res *Sequence, p Params) Metrics {
+
+	frames, numGT, numTR := prepare(gt, res, p)
+
+	m := Metrics{
+		GTIDs:    numGT,
+		TrackIDs: numTR,
+	}
+
+	m.clear(frames, numGT, p.IoUThreshold)
+	m.identity(frames, numGT, numTR, p.IoUThreshold)
+	m.hota(frames, numGT, numTR)
+
+	return m
+}
+
+// prepare filters the ground truth and tracks of each frame, maps their ID's
+// to indexes and calculates their IoU's
+func prepare(gt, res *Sequence, p Params) ([]frameData, int, int) {
+
+	gtIndex := make(map[int]int)
+	trIndex := make(map[int]int)
+
+	seen := make(map[int]bool)
+	numbers := make([]int, 0)
+
+	for _, f := range append(gt.FrameNumbers(), res.FrameNumbers()...) {
+		if !seen[f] {
+			seen[f] = true
+			numbers = append(numbers, f)
+		}
+	}
+
+	sort.Ints(numbers)
+
+	frames := make([]frameData, 0, len(numbers))
+
+	for _, f := range numbers {
+		gts := gt.Frames[f]
+		trs := res.Frames[f]
+
+		// remove tracks matching distractor ground truth
+		if len(p.DistractorClasses) > 0 && len(gts) > 0 && len(trs) > 0 {
+			score := make([][]float64, len(gts))
+
+			for i, g := range gts {
+				score[i] = make([]float64, len(trs))
+
+				for j, t := range trs {
+					if iou := boxIoU(g, t); iou >= 0.5-epsilon {
+						score[i][j] = -iou
+					}
+				}
+			}
+
+			remove := make(map[int]bool)
+			rows, cols := linearSumAssignment(score)
+
+			for k := range rows {
+				if score[rows[k]][cols[k]] < -epsilon &&
+					contains(p.DistractorClasses, gts[rows[k]].Class) {
+					remove[cols[k]] = true
+				}
+			}
+
+			kept := make([]Entry, 0, len(trs))
+
+			for j, t := range trs {
+				if !remove[j] {
+					kept = append(kept, t)
+				}
+			}
+
+			trs = kept
+		}
+
+		// keep considered ground truth of the evaluated classes
+		kept := make([]Entry, 0, len(gts))
+
+		for _, g := range gts {
+			if g.Conf == 0 || contains(p.DistractorClasses, g.Class) {
+				continue
+			}
+
+			if len(p.Classes) > 0 && !contains(p.Classes, g.Class) {
+				continue
+			}
+
+			kept = append(kept, g)
+		}
+
+		gts = kept
+
+		fd := frameData{
+			gtIDs: make([]int, len(gts)),
+			trIDs: make([]int, len(trs)),
+			sim:   make([][]float64, len(gts)),
+		}
+
+		for i, g := range gts {
+			fd.gtIDs[i] = index(gtIndex, g.ID)
+			fd.sim[i] = make([]float64, len(trs))
+
+			for j, t := range trs {
+				fd.sim[i][j] = boxIoU(g, t)
+			}
+		}
+
+		for j, t := range trs {
+			fd.trIDs[j] = index(trIndex, t.ID)
+		}
+
+		frames = append(frames, fd)
+	}
+
+	return frames, len(gtIndex), len(trIndex)
+}
+
+// clear calculates the CLEAR MOT metrics
+func (m *Metrics) clear(frames []frameData, numGT int, threshold float64) {
+
+	// prevID is the last track matched to each ground truth and prevStep is
+	// the track matched in the previous frame, -1 for none
+	prevID := filledInts(numGT, -1)
+	prevStep := filledInts(numGT, -1)
+	gtCount := make([]int, numGT)
+	matchedCount := make([]int, numGT)
+	fragCount := make([]int, numGT)
+	motpSum := 0.0
+
+	for _, f := range frames {
+		if len(f.gtIDs) == 0 {
+			m.FP += len(f.trIDs)
+			continue
+		}
+
+		for _, g := range f.gtIDs {
+			gtCount[g]++
+		}
+
+		if len(f.trIDs) == 0 {
+			m.FN += len(f.gtIDs)
+			continue
+		}
+
+		// prefer keeping the matches of the previous frame
+		score := make([][]float64, len(f.gtIDs))
+
+		for i, g := range f.gtIDs {
+			score[i] = make([]float64, len(f.trIDs))
+
+			for j, t := range f.trIDs {
+				if f.sim[i][j] < threshold-epsilon {
+					continue
+				}
+
+				score[i][j] = -f.sim[i][j]
+
+				if prevStep[g] == t {
+					score[i][j] -= 1000
+				}
+			}
+		}
+
+		rows, cols := linearSumAssignment(score)
+		step := filledInts(numGT, -1)
+		matched := 0
+
+		for k := range rows {
+			i, j := rows[k], cols[k]
+
+			if score[i][j] >= -epsilon {
+				continue
+			}
+
+			matched++
+			g := f.gtIDs[i]
+			t := f.trIDs[j]
+
+			if prevID[g] != -1 && prevID[g] != t {
+				m.IDSW++
+			}
+
+			matchedCount[g]++
+
+			if prevStep[g] == -1 {
+				fragCount[g]++
+			}
+
+			prevID[g] = t
+			step[g] = t
+			motpSum += f.sim[i][j]
+		}
+
+		prevStep = step
+
+		m.TP += matched
+		m.FN += len(f.gtIDs) - matched
+		m.FP += len(f.trIDs) - matched
+	}
+
+	for g := range gtCount {
+		if gtCount[g] == 0 {
+			continue
+		}
+
+		ratio := float64(matchedCount[g]) / float64(gtCount[g])
+
+		switch {
+		case ratio > 0.8:
+			m.MT++
+		case ratio >= 0.2:
+			m.PT++
+		}
+
+		if fragCount[g] > 0 {
+			m.Frag += fragCount[g] - 1
+		}
+	}
+
+	m.ML = numGT - m.MT - m.PT
+	m.MOTA = float64(m.TP-m.FP-m.IDSW) / math.Max(1, float64(m.TP+m.FN))
+	m.MOTP = motpSum / math.Max(1, float64(m.TP))
+}
+
+// identity calculates the Identity metrics by globally matching ground truth
+// trajectories to tracks to maximise the number of identity true positives
+func (m *Metrics) identity(frames []frameData, numGT, numTR int, threshold float64) {
+
+	if numGT == 0 || numTR == 0 {
+		total := 0
+
+		for _, f := range frames {
+			total += len(f.gtIDs)
+			m.IDFP += len(f.trIDs)
+		}
+
+		m.IDFN = total
+		m.setIdentityRatios()
+
+		return
+	}
+
+	potential := newMatrix(numGT, numTR)
+	gtCount := make([]float64, numGT)
+	trCount := make([]float64, numTR)
+
+	for _, f := range frames {
+		for i, g := range f.gtIDs {
+			for j, t := range f.trIDs {
+				if f.sim[i][j] >= threshold-epsilon {
+					potential[g][t]++
+				}
+			}
+			gtCount[g]++
+		}
+
+		for _, t := range f.trIDs {
+			trCount[t]++
+		}
+	}
+
+	// each ground truth and track may also be matched to a dummy so the
+	// matrix is square
+	size := numGT + numTR
+	fp := newMatrix(size, size)
+	fn := newMatrix(size, size)
+
+	for i := numGT; i < size; i++ {
+		for j := 0; j < numTR; j++ {
+			fp[i][j] = 1e10
+		}
+	}
+
+	for i := 0; i < numGT; i++ {
+		for j := numTR; j < size; j++ {
+			fn[i][j] = 1e10
+		}
+	}
+
+	for g := 0; g < numGT; g++ {
+		for t := 0; t < numTR; t++ {
+			fn[g][t] = gtCount[g] - potential[g][t]
+		}
+		fn[g][numTR+g] = gtCount[g]
+	}
+
+	for t := 0; t < numTR; t++ {
+		for g := 0; g < numGT; g++ {
+			fp[g][t] = trCount[t] - potential[g][t]
+		}
+		fp[numGT+t][t] = trCount[t]
+	}
+
+	cost := newMatrix(size, size)
+
+	for i := range cost {
+		for j := range cost[i] {
+			cost[i][j] = fn[i][j] + fp[i][j]
+		}
+	}
+
+	rows, cols := linearSumAssignment(cost)
+	idfn, idfp, total := 0.0, 0.0, 0.0
+
+	for k := range rows {
+		idfn += fn[rows[k]][cols[k]]
+		idfp += fp[rows[k]][cols[k]]
+	}
+
+	for _, c := range gtCount {
+		total += c
+	}
+
+	m.IDFN = int(math.Round(idfn))
+	m.IDFP = int(math.Round(idfp))
+	m.IDTP = int(math.Round(total - idfn))
+	m.setIdentityRatios()
+}
+
+// setIdentityRatios calculates IDF1, IDP and IDR from the identity counts
+func (m *Metrics) setIdentityRatios() {
+	tp, fp, fn := float64(m.IDTP), float64(m.IDFP), float64(m.IDFN)
+
+	m.IDR = tp / math.Max(1, tp+fn)
+	m.IDP = tp / math.Max(1, tp+fp)
+	m.IDF1 = tp / math.Max(1, tp+0.5*fp+0.5*fn)
+}
+
+// hota calculates the HOTA metrics averaged over the localisation thresholds
+func (m *Metrics) hota(frames []frameData, numGT, numTR int) {
+
+	alphas := make([]float64, 19)
+
+	for a := range alphas {
+		alphas[a] = 0.05 * float64(a+1)
+	}
+
+	// global alignment score between each ground truth and track
+	potential := newMatrix(numGT, numTR)
+	gtCount := make([]float64, numGT)
+	trCount := make([]float64, numTR)
+
+	for _, f := range frames {
+		rowSum := make([]float64, len(f.gtIDs))
+		colSum := make([]float64, len(f.trIDs))
+
+		for i := range f.gtIDs {
+			for j := range f.trIDs {
+				rowSum[i] += f.sim[i][j]
+				colSum[j] += f.sim[i][j]
+			}
+		}
+
+		for i, g := range f.gtIDs {
+			for j, t := range f.trIDs {
+				denom := rowSum[i] + colSum[j] - f.sim[i][j]
+
+				if denom > epsilon {
+					potential[g][t] += f.sim[i][j] / denom
+				}
+			}
+			gtCount[g]++
+		}
+
+		for _, t := range f.trIDs {
+			trCount[t]++
+		}
+	}
+
+	align := newMatrix(numGT, numTR)
+
+	for g := range align {
+		for t := range align[g] {
+			align[g][t] = potential[g][t] / (gtCount[g] + trCount[t] - potential[g][t])
+		}
+	}
+
+	tp := make([]float64, len(alphas))
+	fn := make([]float64, len(alphas))
+	fp := make([]float64, len(alphas))
+	loc := make([]float64, len(alphas))
+	matches := make([][][]float64, len(alphas))
+
+	for a := range alphas {
+		matches[a] = newMatrix(numGT, numTR)
+	}
+
+	for _, f := range frames {
+		if len(f.gtIDs) == 0 || len(f.trIDs) == 0 {
+			for a := range alphas {
+				fp[a] += float64(len(f.trIDs))
+				fn[a] += float64(len(f.gtIDs))
+			}
+			continue
+		}
+
+		score := make([][]float64, len(f.gtIDs))
+
+		for i, g := range f.gtIDs {
+			score[i] = make([]float64, len(f.trIDs))
+
+			for j, t := range f.trIDs {
+				score[i][j] = -align[g][t] * f.sim[i][j]
+			}
+		}
+
+		rows, cols := linearSumAssignment(score)
+
+		for a, alpha := range alphas {
+			n := 0
+
+			for k := range rows {
+				s := f.sim[rows[k]][cols[k]]
+
+				if s < alpha-epsilon {
+					continue
+				}
+
+				n++
+				loc[a] += s
+				matches[a][f.gtIDs[rows[k]]][f.trIDs[cols[k]]]++
+			}
+
+			tp[a] += float64(n)
+			fn[a] += float64(len(f.gtIDs) - n)
+			fp[a] += float64(len(f.trIDs) - n)
+		}
+	}
+
+	var sum struct {
+		hota, detA, assA, detRe, detPr, assRe, assPr, locA float64
+	}
+
+	for a := range alphas {
+		assA, assRe, assPr := 0.0, 0.0, 0.0
+
+		for g := 0; g < numGT; g++ {
+			for t := 0; t < numTR; t++ {
+				c := matches[a][g][t]
+
+				if c == 0 {
+					continue
+				}
+
+				assA += c * c / math.Max(1, gtCount[g]+trCount[t]-c)
+				assRe += c * c / math.Max(1, gtCount[g])
+				assPr += c * c / math.Max(1, trCount[t])
+			}
+		}
+
+		assA /= math.Max(1, tp[a])
+		assRe /= math.Max(1, tp[a])
+		assPr /= math.Max(1, tp[a])
+		detA := tp[a] / math.Max(1, tp[a]+fn[a]+fp[a])
+
+		sum.hota += math.Sqrt(detA * assA)
+		sum.detA += detA
+		sum.assA += assA
+		sum.detRe += tp[a] / math.Max(1, tp[a]+fn[a])
+		sum.detPr += tp[a] / math.Max(1, tp[a]+fp[a])
+		sum.assRe += assRe
+		sum.assPr += assPr
+		sum.locA += math.Max(1e-10, loc[a]) / math.Max(1e-10, tp[a])
+	}
+
+	n := float64(len(alphas))
+	m.HOTA = sum.hota / n
+	m.DetA = sum.detA / n
+	m.AssA = sum.assA / n
+	m.DetRe = sum.detRe / n
+	m.DetPr = sum.detPr / n
+	m.AssRe = sum.assRe / n
+	m.AssPr = sum.assPr / n
+	m.LocA = sum.locA / n
+}
+
+// boxIoU returns the IoU of the bounding boxes of two entries
+func boxIoU(a, b Entry) float64 {
+
+	iw := math.Min(a.X+a.Width, b.X+b.Width) - math.Max(a.X, b.X)
+	ih := math.Min(a.Y+a.Height, b.Y+b.Height) - math.Max(a.Y, b.Y)
+
+	if iw <= 0 || ih <= 0 {
+		return 0
+	}
+
+	inter := iw * ih
+	union := a.Width*a.Height + b.Width*b.Height - inter
+
+	if union <= 0 {
+		return 0
+	}
+
+	return inter / union
+}
+
+// index returns the contiguous index of the ID assigning the next index if
+// it has not been seen before
+func index(m map[int]int, id int) int {
+
+	if i, ok := m[id]; ok {
+		return i
+	}
+
+	m[id] = len(m)
+
+	return m[id]
+}
+
+// contains returns true if the value is in the slice
+func contains(s []int, v int) bool {
+
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newMatrix returns a zeroed matrix of the given size
+func newMatrix(rows, cols int) [][]float64 {
+
+	m := make([][]float64, rows)
+
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+
+	return m
+}
+
+// filledInts returns a slice of the given length with all values set to v
+func filledInts(n, v int) []int {
+
+	s := make([]int, n)
+
+	for i := range s {
+		s[i] = v
+	}
+
+	return s
+}
@@ -0,0 +1,262 @@
+package eval
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/swdee/go-rknnlite/tracker"
+)
+
+// box is an object bounding box in a synthetic sequence
+type box struct {
+	id         int
+	x, y, w, h float64
+}
+
+// sequence returns a Sequence of the boxes per frame starting from frame 1
+func sequence(frames [][]box) *Sequence {
+
+	seq := NewSequence()
+
+	for f, boxes := range frames {
+		for _, b := range boxes {
+			seq.Add(Entry{
+				Frame:      f + 1,
+				ID:         b.id,
+				X:          b.x,
+				Y:          b.y,
+				Width:      b.w,
+				Height:     b.h,
+				Conf:       1,
+				Class:      1,
+				Visibility: 1,
+			})
+		}
+	}
+
+	return seq
+}
+
+// walking returns the frames of two objects moving across the image
+func walking(n int) [][]box {
+
+	frames := make([][]box, n)
+
+	for f := range frames {
+		frames[f] = []box{
+			{1, 100 + float64(f)*5, 200, 50, 120},
+			{2, 400 - float64(f)*5, 220, 60, 130},
+		}
+	}
+
+	return frames
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestPerfectTracking(t *testing.T) {
+
+	gt := sequence(walking(10))
+	m := Evaluate(gt, gt, DefaultParams())
+
+	if m.TP != 20 || m.FP != 0 || m.FN != 0 || m.IDSW != 0 {
+		t.Errorf("unexpected counts %+v", m)
+	}
+
+	for name, v := range map[string]float64{
+		"MOTA": m.MOTA, "MOTP": m.MOTP, "IDF1": m.IDF1, "HOTA": m.HOTA,
+		"DetA": m.DetA, "AssA": m.AssA, "LocA": m.LocA,
+	} {
+		if !closeTo(v, 1) {
+			t.Errorf("expected %s of 1, got %f", name, v)
+		}
+	}
+
+	if m.MT != 2 || m.PT != 0 || m.ML != 0 || m.GTIDs != 2 || m.TrackIDs != 2 {
+		t.Errorf("unexpected trajectory counts %+v", m)
+	}
+}
+
+func TestIDSwitch(t *testing.T) {
+
+	frames := [][]box{
+		{{1, 10, 10, 20, 40}},
+		{{1, 12, 10, 20, 40}},
+		{{1, 14, 10, 20, 40}},
+		{{1, 16, 10, 20, 40}},
+	}
+
+	gt := sequence(frames)
+	res := sequence(frames)
+
+	// track ID changes half way through
+	for f := 3; f <= 4; f++ {
+		res.Frames[f][0].ID = 2
+	}
+
+	m := Evaluate(gt, res, DefaultParams())
+
+	if m.IDSW != 1 || m.TP != 4 || !closeTo(m.MOTA, 0.75) {
+		t.Errorf("expected 1 ID switch and MOTA 0.75, got %+v", m)
+	}
+
+	if m.IDTP != 2 || m.IDFP != 2 || m.IDFN != 2 || !closeTo(m.IDF1, 0.5) {
+		t.Errorf("expected IDF1 0.5, got %+v", m)
+	}
+
+	if !closeTo(m.DetA, 1) || !closeTo(m.AssA, 0.5) || !closeTo(m.HOTA, math.Sqrt(0.5)) {
+		t.Errorf("expected HOTA of sqrt(0.5), got %+v", m)
+	}
+}
+
+func TestMissesAndFalsePositives(t *testing.T) {
+
+	gtFrames := walking(4)
+	resFrames := walking(4)
+
+	// object 2 is missed in frame 2 and a false positive is added in frame 3
+	resFrames[1] = resFrames[1][:1]
+	resFrames[2] = append(resFrames[2], box{3, 600, 10, 20, 20})
+
+	// frame 4 box of object 1 is shifted to an IoU of 0.6
+	resFrames[3][0].x += 12.5
+
+	m := Evaluate(sequence(gtFrames), sequence(resFrames), DefaultParams())
+
+	if m.TP != 7 || m.FN != 1 || m.FP != 1 || m.IDSW != 0 {
+		t.Errorf("unexpected counts %+v", m)
+	}
+
+	if !closeTo(m.MOTA, 0.75) {
+		t.Errorf("expected MOTA 0.75, got %f", m.MOTA)
+	}
+
+	if !closeTo(m.MOTP, 6.6/7) {
+		t.Errorf("expected MOTP %f, got %f", 6.6/7, m.MOTP)
+	}
+
+	// object 2 is interrupted once
+	if m.Frag != 1 {
+		t.Errorf("expected 1 fragmentation, got %d", m.Frag)
+	}
+}
+
+func TestDistractors(t *testing.T) {
+
+	gt := sequence([][]box{{{1, 10, 10, 20, 40}, {2, 100, 10, 20, 40}}})
+	gt.Frames[1][1].Class = 7
+
+	res := sequence([][]box{{{1, 10, 10, 20, 40}, {2, 100, 10, 20, 40}}})
+
+	m := Evaluate(gt, res, MOTChallengeParams())
+
+	if m.TP != 1 || m.FP != 0 || m.FN != 0 || m.GTIDs != 1 {
+		t.Errorf("expected distractor to be removed, got %+v", m)
+	}
+
+	m = Evaluate(gt, res, DefaultParams())
+
+	if m.TP != 2 {
+		t.Errorf("expected all classes to be evaluated, got %+v", m)
+	}
+}
+
+func TestReadMOT(t *testing.T) {
+
+	data := "1,1,10.5,20,30,40,1,1,0.8\n" +
+		"\n" +
+		"2,1,11,20,30,40,0.95,-1,-1,-1\n"
+
+	seq, err := ReadMOT(strings.NewReader(data))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Entry{Frame: 1, ID: 1, X: 10.5, Y: 20, Width: 30, Height: 40,
+		Conf: 1, Class: 1, Visibility: 0.8}
+
+	if len(seq.Frames) != 2 || seq.Frames[1][0] != want {
+		t.Errorf("unexpected entries %+v", seq.Frames)
+	}
+
+	if _, err := ReadMOT(strings.NewReader("1,2,3\n")); err == nil {
+		t.Errorf("expected error for short line")
+	}
+}
+
+func TestWriteBYTETracker(t *testing.T) {
+
+	bt := tracker.NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	frames := walking(10)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	for f, boxes := range frames {
+		objects := make([]tracker.Object, len(boxes))
+
+		for i, b := range boxes {
+			objects[i] = tracker.NewObject(
+				tracker.NewRect(float32(b.x), float32(b.y), float32(b.w), float32(b.h)),
+				0, 0.9, int64(i+1))
+		}
+
+		tracks, err := bt.Update(objects)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := w.WriteFrame(f+1, tracks); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	res, err := ReadMOT(&buf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Frames) != 10 || len(res.Frames[1]) != 2 || res.Frames[1][0].Conf != 0.9 {
+		t.Fatalf("unexpected written tracks %+v", res.Frames[1])
+	}
+
+	m := Evaluate(sequence(frames), res, DefaultParams())
+
+	if !closeTo(m.MOTA, 1) || !closeTo(m.IDF1, 1) || m.IDSW != 0 {
+		t.Errorf("expected perfect tracking, got %+v", m)
+	}
+}
+
+func TestLinearSumAssignment(t *testing.T) {
+
+	cost := [][]float64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+
+	rows, cols := linearSumAssignment(cost)
+	total := 0.0
+
+	for k := range rows {
+		total += cost[rows[k]][cols[k]]
+	}
+
+	if len(rows) != 3 || total != 5 {
+		t.Errorf("expected total cost 5, got %f with %v %v", total, rows, cols)
+	}
+
+	// more rows than columns
+	rows, cols = linearSumAssignment([][]float64{{1}, {0}, {2}})
+
+	if len(rows) != 1 || rows[0] != 1 || cols[0] != 0 {
+		t.Errorf("unexpected assignment %v %v", rows, cols)
+	}
+}
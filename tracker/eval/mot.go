@@ -0,0 +1,203 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/swdee/go-rknnlite/tracker"
+)
+
+// Entry is a single line of a MOTChallenge ground truth or result file
+type Entry struct {
+	// Frame is the frame number starting from 1
+	Frame int
+	// ID is the object or track ID
+	ID int
+	// X is the left of the bounding box
+	X float64
+	// Y is the top of the bounding box
+	Y float64
+	// Width of the bounding box
+	Width float64
+	// Height of the bounding box
+	Height float64
+	// Conf is the detection confidence for results.  For ground truth it is
+	// the flag marking if the entry is considered (1) or ignored (0)
+	Conf float64
+	// Class is the object class of ground truth, -1 for results
+	Class int
+	// Visibility is the visibility ratio of ground truth, -1 for results
+	Visibility float64
+}
+
+// Sequence defines the struct holding the entries of a sequence by frame
+type Sequence struct {
+	// Frames are the entries keyed by frame number
+	Frames map[int][]Entry
+}
+
+// NewSequence returns an empty Sequence
+func NewSequence() *Sequence {
+	return &Sequence{
+		Frames: make(map[int][]Entry),
+	}
+}
+
+// Add adds the entries to the sequence
+func (s *Sequence) Add(entries ...Entry) {
+	for _, e := range entries {
+		s.Frames[e.Frame] = append(s.Frames[e.Frame], e)
+	}
+}
+
+// AddTracks adds the tracks returned by the tracker for the frame to the
+// sequence
+func (s *Sequence) AddTracks(frame int, tracks []tracker.Track) {
+	for _, t := range tracks {
+		s.Add(trackEntry(frame, t))
+	}
+}
+
+// FrameNumbers returns the frame numbers of the sequence in ascending order
+func (s *Sequence) FrameNumbers() []int {
+
+	frames := make([]int, 0, len(s.Frames))
+
+	for f := range s.Frames {
+		frames = append(frames, f)
+	}
+
+	sort.Ints(frames)
+
+	return frames
+}
+
+// LoadMOT loads a MOTChallenge ground truth or result txt file
+func LoadMOT(path string) (*Sequence, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening MOT file: %w", err)
+	}
+
+	defer f.Close()
+
+	return ReadMOT(f)
+}
+
+// ReadMOT reads MOTChallenge comma separated lines being
+// "frame,id,left,top,width,height,conf,class,visibility" for ground truth or
+// "frame,id,left,top,width,height,conf,x,y,z" for results.  Missing trailing
+// columns default to -1.
+func ReadMOT(r io.Reader) (*Sequence, error) {
+
+	seq := NewSequence()
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("line %d: expected at least 6 values, got %d",
+				line, len(fields))
+		}
+
+		vals := []float64{-1, -1, -1, -1, -1, -1, -1, -1, -1}
+
+		for i := 0; i < len(fields) && i < len(vals); i++ {
+			v, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value: %w", line, err)
+			}
+
+			vals[i] = v
+		}
+
+		seq.Add(Entry{
+			Frame:      int(vals[0]),
+			ID:         int(vals[1]),
+			X:          vals[2],
+			Y:          vals[3],
+			Width:      vals[4],
+			Height:     vals[5],
+			Conf:       vals[6],
+			Class:      int(vals[7]),
+			Visibility: vals[8],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return seq, nil
+}
+
+// Writer defines the struct for writing tracker results in the MOTChallenge
+// result format
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+// WriteFrame writes the tracks returned by the tracker for the frame as
+// "frame,id,left,top,width,height,conf,-1,-1,-1" lines.  MOTChallenge frame
+// numbers start from 1.
+func (w *Writer) WriteFrame(frame int, tracks []tracker.Track) error {
+
+	for _, t := range tracks {
+		if err := w.Write(trackEntry(frame, t)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write writes a single entry in the MOTChallenge result format
+func (w *Writer) Write(e Entry) error {
+
+	_, err := fmt.Fprintf(w.w, "%d,%d,%.2f,%.2f,%.2f,%.2f,%.4f,-1,-1,-1\n",
+		e.Frame, e.ID, e.X, e.Y, e.Width, e.Height, e.Conf)
+
+	return err
+}
+
+// trackEntry returns the result Entry of the track
+func trackEntry(frame int, t tracker.Track) Entry {
+
+	rect := t.GetRect()
+
+	return Entry{
+		Frame:      frame,
+		ID:         t.GetTrackID(),
+		X:          float64(rect.X()),
+		Y:          float64(rect.Y()),
+		Width:      float64(rect.Width()),
+		Height:     float64(rect.Height()),
+		Conf:       float64(t.GetScore()),
+		Class:      -1,
+		Visibility: -1,
+	}
+}
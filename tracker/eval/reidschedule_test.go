@@ -0,0 +1,205 @@
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/swdee/go-rknnlite/tracker"
+)
+
+const (
+	// crowdFrames is the number of frames of the crowd sequence
+	crowdFrames = 120
+	// crowdPeople is the number of people in the crowd sequence
+	crowdPeople = 8
+	// crowdFeatureDim is the dimension of the simulated ReID features
+	crowdFeatureDim = 32
+	// detectLatency is the simulated time of object detection on each frame
+	detectLatency = 3 * time.Millisecond
+	// reidLatency is the simulated time of ReID inference per object
+	reidLatency = 250 * time.Microsecond
+)
+
+// crowdSequence returns the ground truth of people walking past each other
+// in pairs, each occluded for 10 frames after which they reappear further
+// ahead than their motion predicts, so ReID is needed to keep their identity.
+// The identity features of each person are also returned.
+func crowdSequence() ([][]box, [][]float32) {
+
+	rng := rand.New(rand.NewSource(1))
+	frames := make([][]box, crowdFrames)
+	identities := make([][]float32, crowdPeople)
+
+	for p := range identities {
+
+		identities[p] = make([]float32, crowdFeatureDim)
+
+		for i := range identities[p] {
+			identities[p][i] = float32(rng.NormFloat64())
+		}
+	}
+
+	for f := range frames {
+		for p := 0; p < crowdPeople; p++ {
+
+			// pairs walk in opposite directions along the same row
+			x := 100 + float64(f)*6
+			y := 80 + float64(p/2)*160
+
+			if p%2 == 1 {
+				x = 900 - float64(f)*5
+				y += 20
+			}
+
+			occludedFrom := 30 + p*8
+
+			if f >= occludedFrom && f < occludedFrom+10 {
+				continue
+			}
+
+			// reappear 60 pixels further ahead
+			if f >= occludedFrom+10 {
+				if p%2 == 0 {
+					x += 60
+				} else {
+					x -= 60
+				}
+			}
+
+			frames[f] = append(frames[f], box{p + 1, x, y, 50, 120})
+		}
+	}
+
+	return frames, identities
+}
+
+// crowdExtractor returns a FeatureExtractor simulating ReID inference of the
+// crowd sequence returning noisy identity features
+func crowdExtractor(identities [][]float32, extracted *int) tracker.FeatureExtractor {
+
+	rng := rand.New(rand.NewSource(2))
+
+	return func(objects []tracker.Object) ([][]float32, error) {
+
+		time.Sleep(time.Duration(len(objects)) * reidLatency)
+
+		features := make([][]float32, len(objects))
+
+		for i, obj := range objects {
+
+			id := identities[obj.ID%crowdPeople]
+			features[i] = make([]float32, len(id))
+
+			for j := range id {
+				features[i][j] = id[j] + 0.2*float32(rng.NormFloat64())
+			}
+		}
+
+		*extracted += len(objects)
+
+		return features, nil
+	}
+}
+
+// benchmarkReIDSchedule runs the crowd sequence through a BYTETracker with
+// the ReID schedule reporting the IDF1, tracker update latency per frame
+// and number of objects extracted per frame
+func benchmarkReIDSchedule(b *testing.B, useReID bool, schedule tracker.ReIDSchedule) {
+
+	frames, identities := crowdSequence()
+	gt := sequence(frames)
+	start := time.Unix(1000, 0)
+
+	var idf1 float64
+	var updateTime time.Duration
+	var extracted int
+
+	for n := 0; n < b.N; n++ {
+
+		bt := tracker.NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+		bt.SetReIDSchedule(schedule)
+		extract := crowdExtractor(identities, &extracted)
+		res := NewSequence()
+
+		for f, boxes := range frames {
+
+			// object detection runs before the tracker update
+			time.Sleep(detectLatency)
+
+			objects := make([]tracker.Object, len(boxes))
+
+			for i, bx := range boxes {
+				// object IDs are unique per frame and identify the person
+				// for the simulated extractor
+				objects[i] = tracker.NewObject(
+					tracker.NewRect(float32(bx.x), float32(bx.y), float32(bx.w), float32(bx.h)),
+					0, 0.9, int64(bx.id+f*crowdPeople))
+			}
+
+			ts := start.Add(time.Duration(f) * time.Second / 30)
+			updateStart := time.Now()
+
+			var tracks []tracker.Track
+			var err error
+
+			if useReID {
+				tracks, err = bt.UpdateWithExtractor(objects, ts, extract)
+			} else {
+				tracks, err = bt.UpdateAt(objects, ts)
+			}
+
+			updateTime += time.Since(updateStart)
+
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+
+			res.AddTracks(f+1, tracks)
+		}
+
+		// wait for any asynchronous extraction of the last frame
+		bt.Reset()
+
+		idf1 = Evaluate(gt, res, DefaultParams()).IDF1
+	}
+
+	total := float64(b.N * crowdFrames)
+
+	b.ReportMetric(math.Round(idf1*1000)/1000, "IDF1")
+	b.ReportMetric(float64(updateTime.Microseconds())/1000/total, "update-ms/frame")
+	b.ReportMetric(float64(extracted)/total, "extracted/frame")
+}
+
+// BenchmarkReIDSchedule compares the tracker update latency and IDF1 of the
+// ReID scheduling policies on a simulated crowd sequence
+func BenchmarkReIDSchedule(b *testing.B) {
+
+	b.Run("NoReID", func(b *testing.B) {
+		benchmarkReIDSchedule(b, false, tracker.ReIDSchedule{})
+	})
+
+	b.Run("EveryFrame", func(b *testing.B) {
+		benchmarkReIDSchedule(b, true, tracker.ReIDSchedule{})
+	})
+
+	b.Run("Interval5", func(b *testing.B) {
+		benchmarkReIDSchedule(b, true, tracker.ReIDSchedule{Interval: 5})
+	})
+
+	b.Run("SkipSeparated", func(b *testing.B) {
+		benchmarkReIDSchedule(b, true, tracker.ReIDSchedule{
+			SkipSeparated: true,
+			SeparationIoU: 0.1,
+		})
+	})
+
+	b.Run("Async", func(b *testing.B) {
+		benchmarkReIDSchedule(b, true, tracker.ReIDSchedule{Async: true})
+	})
+
+	b.Run("Interval5Async", func(b *testing.B) {
+		benchmarkReIDSchedule(b, true, tracker.ReIDSchedule{Interval: 5, Async: true})
+	})
+}
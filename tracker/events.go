@@ -0,0 +1,165 @@
+package tracker
+
+import (
+	"time"
+)
+
+// EventType defines the type of track lifecycle event
+type EventType int
+
+const (
+	// TrackActivated is when a new track is confirmed
+	TrackActivated EventType = 1
+	// TrackLost is when a track is no longer matched to a detection
+	TrackLost EventType = 2
+	// TrackReactivated is when a lost track is matched to a detection again
+	TrackReactivated EventType = 3
+	// TrackRemoved is when a track has been lost for longer than the track
+	// buffer and is removed
+	TrackRemoved EventType = 4
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case TrackActivated:
+		return "activated"
+	case TrackLost:
+		return "lost"
+	case TrackReactivated:
+		return "reactivated"
+	case TrackRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change in the lifecycle of a track
+type Event struct {
+	// Type of event
+	Type EventType
+	// TrackID is the unique ID of the track
+	TrackID int
+	// Label is the object label/class of the track
+	Label int
+	// FirstFrame is the frame ID the track started on
+	FirstFrame int
+	// LastFrame is the frame ID the track was last matched to a detection
+	LastFrame int
+	// Rect is the last bounding box of the track
+	Rect Rect
+	// Dwell is the duration between the timestamps of the first frame and the
+	// frame the track was last matched to a detection
+	Dwell time.Duration
+	// Timestamp is the time of the frame the event occurred on
+	Timestamp time.Time
+	// Feature is a copy of the EMA smoothed ReID embedding of the track, nil
+	// if ReID is not used
+	Feature []float32
+}
+
+// EventHandler defines the interface for receiving track lifecycle events.
+// The handler is called synchronously from the tracker's Update so should
+// not block or call the tracker.
+type EventHandler interface {
+	// OnActivated is called when a new track is confirmed
+	OnActivated(e Event)
+	// OnLost is called when a track is no longer matched to a detection
+	OnLost(e Event)
+	// OnReactivated is called when a lost track is matched again
+	OnReactivated(e Event)
+	// OnRemoved is called when a lost track is removed
+	OnRemoved(e Event)
+}
+
+// EventFuncs is an EventHandler that calls the functions set so only the
+// events of interest need to be handled
+type EventFuncs struct {
+	Activated   func(e Event)
+	Lost        func(e Event)
+	Reactivated func(e Event)
+	Removed     func(e Event)
+}
+
+// OnActivated calls the Activated function if set
+func (f EventFuncs) OnActivated(e Event) {
+	if f.Activated != nil {
+		f.Activated(e)
+	}
+}
+
+// OnLost calls the Lost function if set
+func (f EventFuncs) OnLost(e Event) {
+	if f.Lost != nil {
+		f.Lost(e)
+	}
+}
+
+// OnReactivated calls the Reactivated function if set
+func (f EventFuncs) OnReactivated(e Event) {
+	if f.Reactivated != nil {
+		f.Reactivated(e)
+	}
+}
+
+// OnRemoved calls the Removed function if set
+func (f EventFuncs) OnRemoved(e Event) {
+	if f.Removed != nil {
+		f.Removed(e)
+	}
+}
+
+// eventEmitter creates and dispatches track lifecycle events to the handler
+type eventEmitter struct {
+	// handler to dispatch events to, nil if not set
+	handler EventHandler
+	// timestamp is the time of the frame being updated
+	timestamp time.Time
+}
+
+// emit dispatches the event of the given type for the track
+func (em *eventEmitter) emit(t EventType, s *STrack) {
+
+	if em.handler == nil {
+		return
+	}
+
+	rect := s.GetRect()
+
+	e := Event{
+		Type:       t,
+		TrackID:    s.GetTrackID(),
+		Label:      s.GetLabel(),
+		FirstFrame: s.GetStartFrameID(),
+		LastFrame:  s.GetFrameID(),
+		Rect:       NewRect(rect.X(), rect.Y(), rect.Width(), rect.Height()),
+		Dwell:      s.lastSeen.Sub(s.firstSeen),
+		Timestamp:  em.timestamp,
+	}
+
+	if s.smoothFeature != nil {
+		e.Feature = append([]float32(nil), s.smoothFeature...)
+	}
+
+	switch t {
+	case TrackActivated:
+		em.handler.OnActivated(e)
+	case TrackLost:
+		em.handler.OnLost(e)
+	case TrackReactivated:
+		em.handler.OnReactivated(e)
+	case TrackRemoved:
+		em.handler.OnRemoved(e)
+	}
+}
+
+// SetEventHandler sets the handler to receive track lifecycle events
+func (bt *BYTETracker) SetEventHandler(h EventHandler) {
+	bt.events.handler = h
+}
+
+// SetEventHandler sets the handler to receive track lifecycle events
+func (oc *OCSORT) SetEventHandler(h EventHandler) {
+	oc.events.handler = h
+}
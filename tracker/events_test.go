@@ -0,0 +1,183 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+// eventLog records the events received
+type eventLog struct {
+	frame  int
+	events []loggedEvent
+}
+
+// loggedEvent is an event and the frame it was received on
+type loggedEvent struct {
+	frame int
+	Event
+}
+
+// handler returns an EventHandler recording the events
+func (l *eventLog) handler() EventHandler {
+
+	add := func(e Event) {
+		l.events = append(l.events, loggedEvent{l.frame, e})
+	}
+
+	return EventFuncs{
+		Activated:   add,
+		Lost:        add,
+		Reactivated: add,
+		Removed:     add,
+	}
+}
+
+// objectsAt returns the stationary object A if visible and object B from
+// frame 2
+func objectsAt(frame int, visibleA bool) []Object {
+
+	var objects []Object
+
+	if visibleA {
+		objects = append(objects, NewObject(NewRect(100, 100, 50, 100), 2, 0.9, 1))
+	}
+
+	if frame >= 2 {
+		objects = append(objects, NewObject(NewRect(400, 100, 50, 100), 0, 0.9, 2))
+	}
+
+	return objects
+}
+
+// checkEvents compares the logged events type, frame and track ID
+func checkEvents(t *testing.T, got []loggedEvent, want []loggedEvent) {
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(got), got)
+	}
+
+	for i := range want {
+		if got[i].frame != want[i].frame || got[i].Type != want[i].Type ||
+			got[i].TrackID != want[i].TrackID {
+			t.Errorf("event %d: expected %s of track %d on frame %d, got %s of track %d on frame %d",
+				i, want[i].Type, want[i].TrackID, want[i].frame,
+				got[i].Type, got[i].TrackID, got[i].frame)
+		}
+	}
+}
+
+func TestBYTETrackerEvents(t *testing.T) {
+
+	bt := NewBYTETracker(30, 3, 0.5, 0.6, 0.8)
+	log := &eventLog{}
+	bt.SetEventHandler(log.handler())
+
+	for log.frame = 1; log.frame <= 10; log.frame++ {
+
+		// object A is missing on frames 4 to 5 and after frame 6
+		visibleA := log.frame <= 3 || log.frame == 6
+
+		if _, err := bt.Update(objectsAt(log.frame, visibleA)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	checkEvents(t, log.events, []loggedEvent{
+		{1, Event{Type: TrackActivated, TrackID: 1}},
+		{3, Event{Type: TrackActivated, TrackID: 2}},
+		{4, Event{Type: TrackLost, TrackID: 1}},
+		{6, Event{Type: TrackReactivated, TrackID: 1}},
+		{7, Event{Type: TrackLost, TrackID: 1}},
+		{10, Event{Type: TrackRemoved, TrackID: 1}},
+	})
+
+	lost := log.events[2]
+
+	if lost.Label != 2 || lost.FirstFrame != 1 || lost.LastFrame != 3 ||
+		lost.Dwell != 2*bt.frameInterval || lost.Rect.X() != 100 {
+		t.Errorf("unexpected lost event %+v", lost.Event)
+	}
+
+	activated := log.events[1]
+
+	if activated.FirstFrame != 2 || activated.LastFrame != 3 {
+		t.Errorf("unexpected activated event %+v", activated.Event)
+	}
+
+	removed := log.events[5]
+
+	if removed.LastFrame != 6 || removed.Dwell != 5*bt.frameInterval {
+		t.Errorf("unexpected removed event %+v", removed.Event)
+	}
+}
+
+func TestOCSORTEvents(t *testing.T) {
+
+	oc := NewOCSORT(30, 3, 0.6, 0.3)
+	log := &eventLog{}
+	oc.SetEventHandler(log.handler())
+
+	for log.frame = 1; log.frame <= 10; log.frame++ {
+
+		visibleA := log.frame <= 3 || log.frame == 6
+
+		if _, err := oc.Update(objectsAt(log.frame, visibleA)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	checkEvents(t, log.events, []loggedEvent{
+		{1, Event{Type: TrackActivated, TrackID: 1}},
+		{2, Event{Type: TrackActivated, TrackID: 2}},
+		{4, Event{Type: TrackLost, TrackID: 1}},
+		{6, Event{Type: TrackReactivated, TrackID: 1}},
+		{7, Event{Type: TrackLost, TrackID: 1}},
+		{10, Event{Type: TrackRemoved, TrackID: 1}},
+	})
+}
+
+func TestEventDwell(t *testing.T) {
+
+	start := time.Unix(1000, 0)
+
+	// object is seen on irregularly spaced frames then missed
+	offsets := []time.Duration{0, time.Second, 1500 * time.Millisecond, 2 * time.Second}
+
+	trackers := []struct {
+		name    string
+		tracker interface {
+			UpdateAt(objects []Object, timestamp time.Time) ([]Track, error)
+			SetEventHandler(h EventHandler)
+		}
+	}{
+		{"bytetrack", NewBYTETracker(30, 300, 0.5, 0.6, 0.8)},
+		{"ocsort", NewOCSORT(30, 300, 0.6, 0.3)},
+	}
+
+	for _, tc := range trackers {
+
+		var lost []Event
+
+		tc.tracker.SetEventHandler(EventFuncs{
+			Lost: func(e Event) { lost = append(lost, e) },
+		})
+
+		for i, offset := range offsets {
+
+			var objects []Object
+
+			if i < 3 {
+				objects = append(objects, NewObject(NewRect(100, 100, 50, 100), 2, 0.9, 1))
+			}
+
+			if _, err := tc.tracker.UpdateAt(objects, start.Add(offset)); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+		}
+
+		// dwell is the time between the update timestamps not the frame rate
+		if len(lost) != 1 || lost[0].Dwell != 1500*time.Millisecond {
+			t.Errorf("%s: expected lost event with dwell of 1.5s, got %+v", tc.name, lost)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package tracker
+
+import (
+	"fmt"
+	"gocv.io/x/gocv"
+	"image"
+	"math"
+)
+
+// GMCMethod defines the method used to estimate the global camera motion
+// between frames
+type GMCMethod int
+
+const (
+	// GMCSparseOptFlow tracks good features to track between frames with
+	// Lucas-Kanade sparse optical flow
+	GMCSparseOptFlow GMCMethod = 1
+	// GMCORB matches ORB feature descriptors between frames
+	GMCORB GMCMethod = 2
+)
+
+// gmc estimates the global camera motion between consecutive frames
+type gmc struct {
+	// method used to find matching points between frames
+	method GMCMethod
+	// downscale is the factor frames are reduced by before estimation
+	downscale int
+	// prevFrame is the previous grayscale downscaled frame
+	prevFrame gocv.Mat
+	// prevPoints are the features detected in the previous frame for sparse
+	// optical flow
+	prevPoints gocv.Mat
+	// prevKeyPoints are the ORB keypoints of the previous frame
+	prevKeyPoints []gocv.KeyPoint
+	// prevDesc are the ORB descriptors of the previous frame
+	prevDesc gocv.Mat
+	// initialized is a flag to indicate a previous frame exists
+	initialized bool
+}
+
+// UseGMC sets up global motion compensation on the BYTETracker instance.  The
+// camera motion between frames passed to UpdateWithFrame is estimated as an
+// affine transform which is applied to the tracks Kalman state before
+// association, so moving PTZ or vehicle mounted cameras do not break the IoU
+// matching.
+func (bt *BYTETracker) UseGMC(method GMCMethod) {
+
+	if bt.gmc != nil {
+		bt.gmc.reset()
+	}
+
+	bt.gmc = newGMC(method)
+}
+
+// newGMC returns a camera motion estimator using the given method
+func newGMC(method GMCMethod) *gmc {
+	return &gmc{
+		method:    method,
+		downscale: 2,
+	}
+}
+
+// apply estimates the affine transform of the camera motion from the previous
+// frame to the given frame
+func (g *gmc) apply(frame gocv.Mat) (affine, error) {
+
+	gray := gocv.NewMat()
+
+	if frame.Channels() == 3 {
+		if err := gocv.CvtColor(frame, &gray, gocv.ColorBGRToGray); err != nil {
+			gray.Close()
+			return identityAffine(), err
+		}
+	} else {
+		frame.CopyTo(&gray)
+	}
+
+	if g.downscale > 1 {
+		err := gocv.Resize(gray, &gray, image.Pt(gray.Cols()/g.downscale,
+			gray.Rows()/g.downscale), 0, 0, gocv.InterpolationLinear)
+
+		if err != nil {
+			gray.Close()
+			return identityAffine(), err
+		}
+	}
+
+	switch g.method {
+	case GMCSparseOptFlow:
+		return g.applySparseOptFlow(gray)
+	case GMCORB:
+		return g.applyORB(gray)
+	default:
+		gray.Close()
+		return identityAffine(), fmt.Errorf("unknown GMC method %d", g.method)
+	}
+}
+
+// applySparseOptFlow estimates the camera motion by tracking good features
+// of the previous frame with sparse optical flow
+func (g *gmc) applySparseOptFlow(gray gocv.Mat) (affine, error) {
+
+	h := identityAffine()
+	points := gocv.NewMat()
+
+	if err := gocv.GoodFeaturesToTrack(gray, &points, 1000, 0.01, 1); err != nil {
+		gray.Close()
+		points.Close()
+		return h, err
+	}
+
+	if !g.initialized || g.prevPoints.Empty() {
+		g.store(gray, points, nil, gocv.NewMat())
+		return h, nil
+	}
+
+	// find the previous features in the current frame
+	next := gocv.NewMat()
+	status := gocv.NewMat()
+	errs := gocv.NewMat()
+	defer next.Close()
+	defer status.Close()
+	defer errs.Close()
+
+	err := gocv.CalcOpticalFlowPyrLK(g.prevFrame, gray, g.prevPoints, next,
+		&status, &errs)
+
+	if err != nil {
+		g.store(gray, points, nil, gocv.NewMat())
+		return h, err
+	}
+
+	var prevPts, currPts []gocv.Point2f
+
+	for i := 0; i < status.Rows(); i++ {
+		if status.GetUCharAt(i, 0) != 1 {
+			continue
+		}
+
+		p := g.prevPoints.GetVecfAt(i, 0)
+		c := next.GetVecfAt(i, 0)
+
+		prevPts = append(prevPts, gocv.Point2f{X: p[0], Y: p[1]})
+		currPts = append(currPts, gocv.Point2f{X: c[0], Y: c[1]})
+	}
+
+	g.store(gray, points, nil, gocv.NewMat())
+
+	if len(prevPts) <= 4 {
+		// not enough matching points, assume no camera motion
+		return h, nil
+	}
+
+	return estimateAffine(prevPts, currPts, float64(g.downscale)), nil
+}
+
+// applyORB estimates the camera motion by matching ORB feature descriptors
+// of the previous frame
+func (g *gmc) applyORB(gray gocv.Mat) (affine, error) {
+
+	h := identityAffine()
+
+	orb := gocv.NewORB()
+	defer orb.Close()
+
+	// ignore features at the image border
+	mask := gocv.Zeros(gray.Rows(), gray.Cols(), gocv.MatTypeCV8U)
+	defer mask.Close()
+
+	border := image.Rect(int(0.02*float64(gray.Cols())), int(0.02*float64(gray.Rows())),
+		int(0.98*float64(gray.Cols())), int(0.98*float64(gray.Rows())))
+	roi := mask.Region(border)
+	roi.SetTo(gocv.NewScalar(255, 0, 0, 0))
+	roi.Close()
+
+	keyPoints, desc := orb.DetectAndCompute(gray, mask)
+
+	if !g.initialized || g.prevDesc.Empty() || desc.Empty() {
+		g.store(gray, gocv.NewMat(), keyPoints, desc)
+		return h, nil
+	}
+
+	matcher := gocv.NewBFMatcherWithParams(gocv.NormHamming, false)
+	defer matcher.Close()
+
+	matches := matcher.KnnMatch(g.prevDesc, desc, 2)
+
+	// keep matches passing the ratio test that have not moved too far
+	maxDistX := 0.25 * float64(gray.Cols())
+	maxDistY := 0.25 * float64(gray.Rows())
+
+	var prevPts, currPts []gocv.Point2f
+
+	for _, m := range matches {
+		if len(m) < 2 || m[0].Distance >= 0.9*m[1].Distance {
+			continue
+		}
+
+		p := g.prevKeyPoints[m[0].QueryIdx]
+		c := keyPoints[m[0].TrainIdx]
+
+		if math.Abs(p.X-c.X) >= maxDistX || math.Abs(p.Y-c.Y) >= maxDistY {
+			continue
+		}
+
+		prevPts = append(prevPts, gocv.Point2f{X: float32(p.X), Y: float32(p.Y)})
+		currPts = append(currPts, gocv.Point2f{X: float32(c.X), Y: float32(c.Y)})
+	}
+
+	g.store(gray, gocv.NewMat(), keyPoints, desc)
+
+	if len(prevPts) <= 4 {
+		// not enough matching points, assume no camera motion
+		return h, nil
+	}
+
+	return estimateAffine(prevPts, currPts, float64(g.downscale)), nil
+}
+
+// store keeps the frame and its features for estimating the next transform
+func (g *gmc) store(frame, points gocv.Mat, keyPoints []gocv.KeyPoint,
+	desc gocv.Mat) {
+
+	g.reset()
+
+	g.prevFrame = frame
+	g.prevPoints = points
+	g.prevKeyPoints = keyPoints
+	g.prevDesc = desc
+	g.initialized = true
+}
+
+// reset clears the previous frame so the next frame starts a new sequence
+func (g *gmc) reset() {
+
+	if g.initialized {
+		g.prevFrame.Close()
+		g.prevPoints.Close()
+		g.prevDesc.Close()
+	}
+
+	g.prevKeyPoints = nil
+	g.initialized = false
+}
+
+// estimateAffine estimates the partial affine transform (rotation, uniform
+// scale and translation) from the previous to current points with RANSAC and
+// scales the translation back to full frame size
+func estimateAffine(prevPts, currPts []gocv.Point2f, downscale float64) affine {
+
+	h := identityAffine()
+
+	from := gocv.NewPoint2fVectorFromPoints(prevPts)
+	to := gocv.NewPoint2fVectorFromPoints(currPts)
+	defer from.Close()
+	defer to.Close()
+
+	m := gocv.EstimateAffinePartial2D(from, to)
+	defer m.Close()
+
+	if m.Empty() {
+		return h
+	}
+
+	for r := 0; r < 2; r++ {
+		for c := 0; c < 3; c++ {
+			h[r][c] = m.GetDoubleAt(r, c)
+		}
+	}
+
+	h[0][2] *= downscale
+	h[1][2] *= downscale
+
+	return h
+}
@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"gocv.io/x/gocv"
+	"math"
+	"testing"
+)
+
+func TestEstimateAffine(t *testing.T) {
+
+	prev := []gocv.Point2f{
+		{X: 10, Y: 10}, {X: 200, Y: 15}, {X: 190, Y: 160},
+		{X: 20, Y: 150}, {X: 100, Y: 80}, {X: 60, Y: 120},
+	}
+
+	// camera panned by (8, -4) pixels on the downscaled frame
+	curr := make([]gocv.Point2f, len(prev))
+
+	for i, p := range prev {
+		curr[i] = gocv.Point2f{X: p.X + 8, Y: p.Y - 4}
+	}
+
+	h := estimateAffine(prev, curr, 2)
+
+	expected := affine{{1, 0, 16}, {0, 1, -8}}
+
+	for r := 0; r < 2; r++ {
+		for c := 0; c < 3; c++ {
+			if math.Abs(h[r][c]-expected[r][c]) > 1e-3 {
+				t.Fatalf("expected transform %v, got %v", expected, h)
+			}
+		}
+	}
+
+	// rotation of 10 degrees about the origin
+	sin, cos := math.Sincos(10 * math.Pi / 180)
+
+	for i, p := range prev {
+		x, y := float64(p.X), float64(p.Y)
+		curr[i] = gocv.Point2f{X: float32(cos*x - sin*y), Y: float32(sin*x + cos*y)}
+	}
+
+	h = estimateAffine(prev, curr, 1)
+
+	expected = affine{{cos, -sin, 0}, {sin, cos, 0}}
+
+	for r := 0; r < 2; r++ {
+		for c := 0; c < 3; c++ {
+			if math.Abs(h[r][c]-expected[r][c]) > 1e-2 {
+				t.Fatalf("expected transform %v, got %v", expected, h)
+			}
+		}
+	}
+}
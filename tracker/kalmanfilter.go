@@ -36,19 +36,7 @@ type KalmanFilter struct {
 // NewKalmanFilter initializes and returns a new KalmanFilter
 func NewKalmanFilter(stdWeightPosition, stdWeightVelocity float32) *KalmanFilter {
 
-	ndim := 4
-	dt := float32(1.0)
-
-	// create identity matrix for motionMat
-	motionMat := mat.NewDense(8, 8, nil)
-
-	for i := 0; i < 8; i++ {
-		motionMat.Set(i, i, float64(1.0))
-	}
-
-	for i := 0; i < ndim; i++ {
-		motionMat.Set(i, ndim+i, float64(dt))
-	}
+	motionMat := transitionMat(1.0)
 
 	// create updateMat as a 4x8 matrix with first 4 diagonal elements set to 1
 	updateMat := mat.NewDense(4, 8, nil)
@@ -65,6 +53,26 @@ func NewKalmanFilter(stdWeightPosition, stdWeightVelocity float32) *KalmanFilter
 	}
 }
 
+// transitionMat returns the constant velocity motion model advancing the
+// state by dt time steps
+func transitionMat(dt float32) *mat.Dense {
+
+	ndim := 4
+
+	// create identity matrix for motionMat
+	motionMat := mat.NewDense(8, 8, nil)
+
+	for i := 0; i < 8; i++ {
+		motionMat.Set(i, i, float64(1.0))
+	}
+
+	for i := 0; i < ndim; i++ {
+		motionMat.Set(i, ndim+i, float64(dt))
+	}
+
+	return motionMat
+}
+
 // Initiate initializes the state mean and covariance
 func (kf *KalmanFilter) Initiate(mean StateMean, covariance *StateCov,
 	measurement DetectBox) {
@@ -103,6 +111,14 @@ func (kf *KalmanFilter) Initiate(mean StateMean, covariance *StateCov,
 
 // Predict predicts the next state mean and covariance
 func (kf *KalmanFilter) Predict(mean StateMean, covariance *StateCov) {
+	kf.PredictDt(mean, covariance, 1)
+}
+
+// PredictDt predicts the state mean and covariance dt time steps ahead, where
+// a time step is the nominal interval between frames.  The process noise is
+// scaled by dt so uncertainty grows with the time elapsed, allowing for
+// dropped frames or inference run on every Nth frame.
+func (kf *KalmanFilter) PredictDt(mean StateMean, covariance *StateCov, dt float32) {
 
 	// initialize the standard deviation array for the state variables
 	std := make(StateMean, 8)
@@ -119,7 +135,7 @@ func (kf *KalmanFilter) Predict(mean StateMean, covariance *StateCov) {
 	tmp := make(StateMean, 8)
 
 	for i, v := range std {
-		tmp[i] = v * v
+		tmp[i] = v * v * dt
 	}
 
 	// create the motion covariance matrix with variances on the diagonal
@@ -138,8 +154,14 @@ func (kf *KalmanFilter) Predict(mean StateMean, covariance *StateCov) {
 
 	meanMat := mat.NewDense(8, 1, meanVec.RawVector().Data)
 
+	motionMat := kf.motionMat
+
+	if dt != 1 {
+		motionMat = transitionMat(dt)
+	}
+
 	// predict the next state mean using the motion model
-	meanMat.Mul(kf.motionMat, meanMat)
+	meanMat.Mul(motionMat, meanMat)
 
 	for i := 0; i < 8; i++ {
 		mean[i] = float32(meanMat.At(i, 0))
@@ -147,8 +169,8 @@ func (kf *KalmanFilter) Predict(mean StateMean, covariance *StateCov) {
 
 	// predict the next state covariance using the motion model
 	cov := covariance.Dense
-	cov.Mul(kf.motionMat, cov)
-	cov.Mul(cov, kf.motionMat.T())
+	cov.Mul(motionMat, cov)
+	cov.Mul(cov, motionMat.T())
 	cov.Add(cov, motionCov)
 }
 
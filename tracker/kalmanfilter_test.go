@@ -135,3 +135,45 @@ func TestKalmanFilter(t *testing.T) {
 		)
 	}
 }
+
+func TestKalmanFilterPredictDt(t *testing.T) {
+
+	kf := NewKalmanFilter(1.0/20, 1.0/160)
+
+	newState := func() (StateMean, *StateCov) {
+		mean := StateMean{100, 200, 0.5, 50, 4, -2, 0, 1}
+		cov := &StateCov{mat.NewDense(8, 8, nil)}
+
+		for i := 0; i < 8; i++ {
+			cov.Set(i, i, 1)
+		}
+
+		return mean, cov
+	}
+
+	// a single time step is the same as Predict
+	mean1, cov1 := newState()
+	kf.Predict(mean1, cov1)
+
+	meanDt, covDt := newState()
+	kf.PredictDt(meanDt, covDt, 1)
+
+	if !floatsEqual(mean1, meanDt, 1e-6) || !matricesEqual(cov1, covDt, 1e-9) {
+		t.Errorf("expected PredictDt(1) to equal Predict, got %v and %v", meanDt, mean1)
+	}
+
+	// three time steps moves by three times the velocity
+	mean3, cov3 := newState()
+	kf.PredictDt(mean3, cov3, 3)
+
+	expected := StateMean{112, 194, 0.5, 53, 4, -2, 0, 1}
+
+	if !floatsEqual(mean3, expected, 1e-4) {
+		t.Errorf("expected mean %v, got %v", expected, mean3)
+	}
+
+	// uncertainty grows with the time elapsed
+	if cov3.At(0, 0) <= cov1.At(0, 0) || cov3.At(4, 4) <= cov1.At(4, 4) {
+		t.Errorf("expected larger covariance, got %v and %v", cov3.At(0, 0), cov1.At(0, 0))
+	}
+}
@@ -0,0 +1,71 @@
+package tracker
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"math"
+)
+
+// affine is a 2x3 affine transform mapping points of the previous frame to
+// the current frame
+type affine [2][3]float64
+
+// identityAffine returns the affine transform for no camera motion
+func identityAffine() affine {
+	return affine{
+		{1, 0, 0},
+		{0, 1, 0},
+	}
+}
+
+// isIdentity returns true if the transform has no effect
+func (h affine) isIdentity() bool {
+	return h == identityAffine()
+}
+
+// applyAffine warps the Kalman state mean and covariance of the track by the
+// camera motion transform so its prediction is in the coordinates of the
+// current frame.  The rotation is applied to the position and velocity, the
+// height is scaled by the transform scale and the aspect ratio is unchanged.
+func (s *STrack) applyAffine(h affine) {
+
+	scale := math.Sqrt(math.Abs(h[0][0]*h[1][1] - h[0][1]*h[1][0]))
+
+	// build the 8x8 transform of the xyah state and its velocities
+	t := mat.NewDense(8, 8, nil)
+
+	for _, o := range []int{0, 4} {
+		t.Set(o, o, h[0][0])
+		t.Set(o, o+1, h[0][1])
+		t.Set(o+1, o, h[1][0])
+		t.Set(o+1, o+1, h[1][1])
+		t.Set(o+2, o+2, 1)
+		t.Set(o+3, o+3, scale)
+	}
+
+	mean := mat.NewVecDense(8, nil)
+
+	for i, v := range s.mean {
+		mean.SetVec(i, float64(v))
+	}
+
+	var warped mat.VecDense
+	warped.MulVec(t, mean)
+
+	for i := range s.mean {
+		s.mean[i] = float32(warped.AtVec(i))
+	}
+
+	s.mean[0] += float32(h[0][2])
+	s.mean[1] += float32(h[1][2])
+
+	var tmp, cov mat.Dense
+	tmp.Mul(t, s.covariance)
+	cov.Mul(&tmp, t.T())
+	s.covariance.Copy(&cov)
+
+	if s.oriented {
+		s.angleKF.angle += math.Atan2(h[1][0], h[0][0])
+	}
+
+	s.updateRect()
+}
@@ -0,0 +1,146 @@
+package tracker
+
+import (
+	"github.com/swdee/go-rknnlite/postprocess/nms"
+	"github.com/swdee/go-rknnlite/postprocess/result"
+	"math"
+)
+
+const (
+	// angleStdPosition is the process noise standard deviation of the angle
+	// per frame in radians
+	angleStdPosition = 0.02
+	// angleStdVelocity is the process noise standard deviation of the angular
+	// velocity per frame
+	angleStdVelocity = 0.005
+	// angleStdMeasurement is the standard deviation of the detected angle
+	angleStdMeasurement = 0.1
+)
+
+// angleFilter is a constant velocity Kalman filter of the rotation angle of
+// an oriented bounding box.  It runs alongside the xyah KalmanFilter of the
+// STrack as the angle is independent of the box center and size.
+type angleFilter struct {
+	// angle is the filtered angle in radians
+	angle float64
+	// velocity is the angular velocity in radians per frame
+	velocity float64
+	// cov is the 2x2 state covariance
+	cov [2][2]float64
+}
+
+// newAngleFilter returns an angle filter initialised with the measured angle
+func newAngleFilter(angle float32) angleFilter {
+	return angleFilter{
+		angle: float64(angle),
+		cov: [2][2]float64{
+			{4 * angleStdMeasurement * angleStdMeasurement, 0},
+			{0, 100 * angleStdVelocity * angleStdVelocity},
+		},
+	}
+}
+
+// predict advances the angle dt frame intervals
+func (f *angleFilter) predict(dt float64) {
+
+	c := f.cov
+
+	f.angle += f.velocity * dt
+
+	f.cov[0][0] = c[0][0] + dt*(c[0][1]+c[1][0]) + dt*dt*c[1][1] +
+		angleStdPosition*angleStdPosition*dt
+	f.cov[0][1] = c[0][1] + dt*c[1][1]
+	f.cov[1][0] = c[1][0] + dt*c[1][1]
+	f.cov[1][1] = c[1][1] + angleStdVelocity*angleStdVelocity*dt
+}
+
+// update corrects the angle with the measured angle.  The measurement is
+// taken as the closest equivalent angle as a box rotated by Pi is the same
+// box.
+func (f *angleFilter) update(measured float32) {
+
+	c := f.cov
+	innovation := wrapAngle(float64(measured) - f.angle)
+
+	s := c[0][0] + angleStdMeasurement*angleStdMeasurement
+	k0 := c[0][0] / s
+	k1 := c[1][0] / s
+
+	f.angle += k0 * innovation
+	f.velocity += k1 * innovation
+
+	f.cov[0][0] = (1 - k0) * c[0][0]
+	f.cov[0][1] = (1 - k0) * c[0][1]
+	f.cov[1][0] = c[1][0] - k1*c[0][0]
+	f.cov[1][1] = c[1][1] - k1*c[0][1]
+}
+
+// wrapAngle returns the angle wrapped to the range [-Pi/2, Pi/2)
+func wrapAngle(a float64) float64 {
+
+	a = math.Mod(a+math.Pi/2, math.Pi)
+
+	if a < 0 {
+		a += math.Pi
+	}
+
+	return a - math.Pi/2
+}
+
+// WithAngle sets the rotation angle in radians of an oriented bounding box
+// detection.  The rect is the box before rotation around its center.
+func (s *STrack) WithAngle(angle float32) {
+	s.oriented = true
+	s.angleKF = newAngleFilter(angle)
+}
+
+// IsOriented returns true if the track is of an oriented bounding box
+func (s *STrack) IsOriented() bool {
+	return s.oriented
+}
+
+// GetAngle returns the tracked rotation angle in radians of an oriented
+// bounding box around the center of the rect
+func (s *STrack) GetAngle() float32 {
+	return float32(s.angleKF.angle)
+}
+
+// orientedBox returns the rect and angle of the track as an oriented box
+func (s *STrack) orientedBox() result.BoxRectF {
+	return result.BoxRectF{
+		X:      s.rect.X(),
+		Y:      s.rect.Y(),
+		Width:  s.rect.Width(),
+		Height: s.rect.Height(),
+		Angle:  s.GetAngle(),
+		Mode:   result.ModeXYWH,
+	}
+}
+
+// calcStrackIous calculates the IoU between two sets of tracks, using the
+// rotated IoU when both tracks are oriented bounding boxes
+func calcStrackIous(aTracks, bTracks []*STrack) [][]float32 {
+
+	var ious [][]float32
+
+	if len(aTracks)*len(bTracks) == 0 {
+		return ious
+	}
+
+	ious = make([][]float32, len(aTracks))
+
+	for ai, a := range aTracks {
+
+		ious[ai] = make([]float32, len(bTracks))
+
+		for bi, b := range bTracks {
+			if a.oriented && b.oriented {
+				ious[ai][bi] = nms.IoU(a.orientedBox(), b.orientedBox())
+			} else {
+				ious[ai][bi] = b.GetRect().CalcIoU(*a.GetRect())
+			}
+		}
+	}
+
+	return ious
+}
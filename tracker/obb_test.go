@@ -0,0 +1,166 @@
+package tracker
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWrapAngle(t *testing.T) {
+
+	tests := []struct {
+		angle, want float64
+	}{
+		{0, 0},
+		{0.5, 0.5},
+		{math.Pi - 0.1, -0.1},
+		{-math.Pi + 0.1, 0.1},
+		{math.Pi / 2, -math.Pi / 2},
+		{2*math.Pi + 0.3, 0.3},
+	}
+
+	for _, tc := range tests {
+		if got := wrapAngle(tc.angle); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("wrapAngle(%v): expected %v, got %v", tc.angle, tc.want, got)
+		}
+	}
+}
+
+func TestAngleFilter(t *testing.T) {
+
+	f := newAngleFilter(0)
+
+	// box rotating at 0.05 radians per frame
+	for frame := 1; frame <= 30; frame++ {
+		f.predict(1)
+		f.update(float32(frame) * 0.05)
+	}
+
+	if math.Abs(f.angle-1.5) > 0.02 || math.Abs(f.velocity-0.05) > 0.01 {
+		t.Errorf("expected angle 1.5 and velocity 0.05, got %v and %v", f.angle, f.velocity)
+	}
+
+	// a measurement rotated by Pi is the same box so the angle stays close
+	f.predict(1)
+	f.update(1.55 - math.Pi)
+
+	if math.Abs(f.angle-1.55) > 0.02 {
+		t.Errorf("expected angle 1.55, got %v", f.angle)
+	}
+}
+
+func TestBYTETrackerOriented(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+
+	// two long thin boxes crossing in an X shape have the same axis aligned
+	// rect so can only be told apart by their rotation
+	rect := NewRect(100, 180, 200, 40)
+	angles := map[int64]float32{1: math.Pi / 4, 2: -math.Pi / 4}
+
+	for frame := 0; frame < 10; frame++ {
+
+		a := NewOrientedObject(rect, angles[1], 0, 0.9, 1)
+		b := NewOrientedObject(rect, angles[2], 0, 0.8, 2)
+
+		// swap the detection order each frame
+		objects := []Object{a, b}
+
+		if frame%2 == 1 {
+			objects = []Object{b, a}
+		}
+
+		tracks, err := bt.Update(objects)
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		if len(tracks) != 2 {
+			t.Fatalf("frame %d: expected 2 tracks, got %d", frame, len(tracks))
+		}
+
+		for _, tr := range tracks {
+
+			s := tr.(*STrack)
+
+			if !s.IsOriented() {
+				t.Fatalf("frame %d: expected oriented track", frame)
+			}
+
+			// track IDs follow the detection IDs of the first frame
+			want := angles[int64(s.GetTrackID())]
+
+			if s.GetDetectionID() != int64(s.GetTrackID()) ||
+				!almostEqual(s.GetAngle(), want, 1e-3) {
+				t.Errorf("frame %d: track %d matched detection %d with angle %v",
+					frame, s.GetTrackID(), s.GetDetectionID(), s.GetAngle())
+			}
+		}
+	}
+}
+
+func TestOrientedSnapshotAffine(t *testing.T) {
+
+	s := NewSTrack(NewRect(100, 100, 80, 20), 0.9, 1, 0)
+	s.WithAngle(0.3)
+	s.Activate(1, 1)
+
+	got, err := restoreSTrack(s.snapshot())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !got.IsOriented() || got.angleKF != s.angleKF {
+		t.Errorf("expected angle filter %+v, got %+v", s.angleKF, got.angleKF)
+	}
+
+	// camera rotation is added to the track angle
+	rot := 0.1
+	s.applyAffine(affine{
+		{math.Cos(rot), -math.Sin(rot), 0},
+		{math.Sin(rot), math.Cos(rot), 0},
+	})
+
+	if !almostEqual(s.GetAngle(), 0.4, 1e-5) {
+		t.Errorf("expected angle 0.4, got %v", s.GetAngle())
+	}
+}
+
+func TestOCSORTOriented(t *testing.T) {
+
+	oc := NewOCSORT(30, 30, 0.6, 0.3)
+
+	// crossing boxes with the same axis aligned rect, as TestBYTETrackerOriented
+	rect := NewRect(100, 180, 200, 40)
+	angles := map[int64]float32{1: math.Pi / 4, 2: -math.Pi / 4}
+
+	for frame := 0; frame < 10; frame++ {
+
+		a := NewOrientedObject(rect, angles[1], 0, 0.9, 1)
+		b := NewOrientedObject(rect, angles[2], 0, 0.8, 2)
+
+		objects := []Object{a, b}
+
+		if frame%2 == 1 {
+			objects = []Object{b, a}
+		}
+
+		tracks, err := oc.Update(objects)
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		if len(tracks) != 2 {
+			t.Fatalf("frame %d: expected 2 tracks, got %d", frame, len(tracks))
+		}
+
+		for _, tr := range tracks {
+			if tr.GetDetectionID() != int64(tr.GetTrackID()) {
+				t.Errorf("frame %d: track %d matched detection %d",
+					frame, tr.GetTrackID(), tr.GetDetectionID())
+			}
+		}
+	}
+}
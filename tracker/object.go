@@ -13,6 +13,12 @@ type Object struct {
 	ID int64
 	// Feature is a ReID embedding feature
 	Feature []float32
+	// Oriented is a flag to indicate the object is an oriented bounding box
+	// detection rotated by Angle
+	Oriented bool
+	// Angle is the rotation in radians of an oriented bounding box around the
+	// center of Rect
+	Angle float32
 }
 
 // NewObject is a constructor function for the Object struct
@@ -24,3 +30,18 @@ func NewObject(rect Rect, label int, prob float32, id int64) Object {
 		ID:    id,
 	}
 }
+
+// NewOrientedObject is a constructor function for the Object struct of an
+// oriented bounding box detection.  The rect is the box before rotation by
+// angle radians around its center.
+func NewOrientedObject(rect Rect, angle float32, label int, prob float32,
+	id int64) Object {
+	return Object{
+		Rect:     rect,
+		Label:    label,
+		Prob:     prob,
+		ID:       id,
+		Oriented: true,
+		Angle:    angle,
+	}
+}
@@ -0,0 +1,673 @@
+package tracker
+
+import (
+	"fmt"
+	"gonum.org/v1/gonum/mat"
+	"math"
+	"time"
+)
+
+// OCSORT represents the Observation-Centric SORT tracker.  It extends SORT
+// with a velocity direction consistency (VDC) cost in the association,
+// observation-centric recovery (OCR) of lost tracks by their last observation
+// and observation-centric re-update (ORU) of the Kalman state when a lost
+// track is recovered.
+type OCSORT struct {
+	// detThresh is the detection score threshold for the first association
+	// and creating new tracks
+	detThresh float32
+	// lowThresh is the minimum detection score used in the second BYTE
+	// association
+	lowThresh float32
+	// iouThresh is the minimum IoU for an association
+	iouThresh float32
+	// maxAge is the time a track can go unmatched before being removed
+	maxAge time.Duration
+	// frameInterval is the nominal time between frames
+	frameInterval time.Duration
+	// timestamp of the last update
+	timestamp time.Time
+	// minHits is the number of consecutive matches before a track is output
+	minHits int
+	// deltaT is the number of frames back the observation used for the
+	// velocity direction is taken from
+	deltaT int
+	// inertia is the weight of the velocity direction consistency cost
+	inertia float32
+	// Current frame ID
+	frameID int
+	// Counter for assigning unique track IDs
+	trackIDCount int
+	// tracks are the current tracks
+	tracks []*ocTrack
+	// events dispatches track lifecycle events
+	events eventEmitter
+}
+
+// ocTrack is an STrack with the observation history used by OC-SORT
+type ocTrack struct {
+	*STrack
+	// lastObservation is the last detection box matched to the track
+	lastObservation Rect
+	// lastObservationAngle is the angle of the last detection matched to an
+	// oriented bounding box track
+	lastObservationAngle float32
+	// lastObservationAge is the track age of the last observation
+	lastObservationAge int
+	// observations are the detection boxes matched keyed by track age
+	observations map[int]Rect
+	// velocity is the normalised (dy, dx) direction of motion between
+	// observations
+	velocity [2]float32
+	// age is the number of frames since the track was created
+	age int
+	// timeSinceUpdate is the number of frames since the track was matched
+	timeSinceUpdate int
+	// hitStreak is the number of consecutive frames the track was matched
+	hitStreak int
+	// deltaT is the number of frames back the observation used for the
+	// velocity direction is taken from
+	deltaT int
+	// frozen is a flag to indicate the Kalman state was saved when the track
+	// was first unmatched
+	frozen bool
+	// frozenMean is the saved Kalman mean used for ORU
+	frozenMean StateMean
+	// frozenCov is the saved Kalman covariance used for ORU
+	frozenCov StateCov
+	// gapSteps are the time steps of the predictions made whilst frozen used
+	// to replay the gap in observations for ORU
+	gapSteps []float32
+}
+
+// NewOCSORT initializes and returns a new OC-SORT tracker
+func NewOCSORT(frameRate int, trackBuffer int, detThresh float32,
+	iouThresh float32) *OCSORT {
+
+	return &OCSORT{
+		detThresh:     detThresh,
+		lowThresh:     0.1,
+		iouThresh:     iouThresh,
+		maxAge:        lostTimeout(frameRate, trackBuffer),
+		frameInterval: frameInterval(frameRate),
+		minHits:       3,
+		deltaT:        3,
+		inertia:       0.2,
+	}
+}
+
+// SetLostTimeout sets the time a track can go unmatched before being removed,
+// overriding the time derived from the frame rate and track buffer
+func (oc *OCSORT) SetLostTimeout(timeout time.Duration) {
+	oc.maxAge = timeout
+}
+
+// Reset clears the tracked data and resets everything
+func (oc *OCSORT) Reset() {
+	oc.frameID = 0
+	oc.trackIDCount = 0
+	oc.tracks = make([]*ocTrack, 0)
+	oc.timestamp = time.Time{}
+}
+
+// Update updates the tracker with new detections, assuming the frame was
+// captured one frame interval after the previous update
+func (oc *OCSORT) Update(objects []Object) ([]Track, error) {
+	return oc.UpdateAt(objects, oc.timestamp.Add(oc.frameInterval))
+}
+
+// UpdateAt updates the tracker with new detections from the frame captured at
+// the timestamp.  The tracks are predicted forward by the time elapsed since
+// the previous update, so frames can be dropped or skipped.
+func (oc *OCSORT) UpdateAt(objects []Object, timestamp time.Time) ([]Track, error) {
+
+	dt, err := frameDelta(oc.timestamp, timestamp, oc.frameInterval)
+
+	if err != nil {
+		return nil, err
+	}
+
+	oc.timestamp = timestamp
+	oc.events.timestamp = timestamp
+	oc.frameID++
+
+	// split detections by score
+	var dets, detsLow []*STrack
+
+	for _, object := range objects {
+
+		strack := NewSTrack(NewRect(object.Rect.X(), object.Rect.Y(), object.Rect.Width(), object.Rect.Height()),
+			object.Prob, object.ID, object.Label)
+
+		if object.Oriented {
+			strack.WithAngle(object.Angle)
+		}
+
+		if object.Prob > oc.detThresh {
+			dets = append(dets, strack)
+		} else if object.Prob > oc.lowThresh {
+			detsLow = append(detsLow, strack)
+		}
+	}
+
+	// predict current pose by KF
+	for _, tr := range oc.tracks {
+		tr.predict(dt)
+	}
+
+	// Step 1: First association of high score detections using IoU and
+	// velocity direction consistency
+	matches, unmatchDets, unmatchTracks, err := oc.associate(dets)
+
+	if err != nil {
+		return nil, fmt.Errorf("fatal error in association, step 1: %w", err)
+	}
+
+	for _, m := range matches {
+		if err := oc.updateTrack(oc.tracks[m[1]], dets[m[0]]); err != nil {
+			return nil, fmt.Errorf("error updating track, step 1: %w", err)
+		}
+	}
+
+	// Step 2: BYTE association of low score detections with the unmatched
+	// tracks predicted position
+	if len(detsLow) > 0 && len(unmatchTracks) > 0 {
+
+		boxes := make([]*STrack, len(unmatchTracks))
+
+		for i, idx := range unmatchTracks {
+			boxes[i] = oc.tracks[idx].STrack
+		}
+
+		matches, _, remain, err := oc.matchIoU(detsLow, boxes)
+
+		if err != nil {
+			return nil, fmt.Errorf("fatal error in association, step 2: %w", err)
+		}
+
+		for _, m := range matches {
+			if err := oc.updateTrack(oc.tracks[unmatchTracks[m[1]]], detsLow[m[0]]); err != nil {
+				return nil, fmt.Errorf("error updating track, step 2: %w", err)
+			}
+		}
+
+		unmatchTracks = selectIdx(unmatchTracks, remain)
+	}
+
+	// Step 3: Observation-centric recovery, associating the remaining high
+	// score detections with the unmatched tracks last observation
+	if len(unmatchDets) > 0 && len(unmatchTracks) > 0 {
+
+		leftDets := make([]*STrack, len(unmatchDets))
+		leftTracks := make([]*STrack, len(unmatchTracks))
+
+		for i, idx := range unmatchDets {
+			leftDets[i] = dets[idx]
+		}
+
+		for i, idx := range unmatchTracks {
+			leftTracks[i] = oc.tracks[idx].observedBox()
+		}
+
+		matches, remainDets, remainTracks, err := oc.matchIoU(leftDets, leftTracks)
+
+		if err != nil {
+			return nil, fmt.Errorf("fatal error in association, step 3: %w", err)
+		}
+
+		for _, m := range matches {
+			if err := oc.updateTrack(oc.tracks[unmatchTracks[m[1]]], dets[unmatchDets[m[0]]]); err != nil {
+				return nil, fmt.Errorf("error updating track, step 3: %w", err)
+			}
+		}
+
+		unmatchDets = selectIdx(unmatchDets, remainDets)
+		unmatchTracks = selectIdx(unmatchTracks, remainTracks)
+	}
+
+	for _, idx := range unmatchTracks {
+		tr := oc.tracks[idx]
+		lost := tr.frozen
+
+		tr.markMissed()
+
+		if !lost && tr.isActivated {
+			oc.events.emit(TrackLost, tr.STrack)
+		}
+	}
+
+	// Step 4: Init new tracks from unmatched high score detections
+	for _, idx := range unmatchDets {
+		oc.trackIDCount++
+		tr := newOCTrack(dets[idx], oc.frameID, oc.trackIDCount, oc.deltaT)
+		tr.firstSeen = timestamp
+		tr.lastSeen = timestamp
+		oc.tracks = append(oc.tracks, tr)
+	}
+
+	// Step 5: Output confirmed tracks and remove old lost tracks
+	var outputTracks []Track
+	var keep []*ocTrack
+
+	for _, tr := range oc.tracks {
+
+		if tr.timeSinceUpdate < 1 && (tr.hitStreak >= oc.minHits || oc.frameID <= oc.minHits) {
+			if !tr.isActivated {
+				tr.isActivated = true
+				oc.events.emit(TrackActivated, tr.STrack)
+			}
+
+			outputTracks = append(outputTracks, tr)
+		}
+
+		if timestamp.Sub(tr.lastSeen) > oc.maxAge {
+			tr.MarkAsRemoved()
+
+			if tr.isActivated {
+				oc.events.emit(TrackRemoved, tr.STrack)
+			}
+
+			continue
+		}
+
+		keep = append(keep, tr)
+	}
+
+	oc.tracks = keep
+
+	return outputTracks, nil
+}
+
+// updateTrack updates the track with the matched detection
+func (oc *OCSORT) updateTrack(tr *ocTrack, det *STrack) error {
+
+	lost := tr.frozen
+
+	if err := tr.update(det, oc.frameID); err != nil {
+		return err
+	}
+
+	tr.lastSeen = oc.timestamp
+
+	if lost && tr.isActivated {
+		oc.events.emit(TrackReactivated, tr.STrack)
+	}
+
+	return nil
+}
+
+// associate matches the detections to the tracks by IoU plus the velocity
+// direction consistency cost.  Returns the matched [detection, track] index
+// pairs and the unmatched detection and track indexes.
+func (oc *OCSORT) associate(dets []*STrack) (matches [][2]int,
+	unmatchDets, unmatchTracks []int, err error) {
+
+	if len(oc.tracks) == 0 || len(dets) == 0 {
+		return nil, seq(len(dets)), seq(len(oc.tracks)), nil
+	}
+
+	tracks := make([]*STrack, len(oc.tracks))
+
+	for j, tr := range oc.tracks {
+		tracks[j] = tr.STrack
+	}
+
+	ious := calcStrackIous(dets, tracks)
+
+	// check for a trivial one to one assignment
+	oneToOne := true
+	rowHits := make([]int, len(dets))
+	colHits := make([]int, len(tracks))
+
+	for i := range ious {
+		for j := range ious[i] {
+			if ious[i][j] > oc.iouThresh {
+				rowHits[i]++
+				colHits[j]++
+			}
+		}
+	}
+
+	for _, n := range append(rowHits, colHits...) {
+		if n > 1 {
+			oneToOne = false
+			break
+		}
+	}
+
+	var pairs [][2]int
+
+	if oneToOne {
+		for i := range ious {
+			for j := range ious[i] {
+				if ious[i][j] > oc.iouThresh {
+					pairs = append(pairs, [2]int{i, j})
+				}
+			}
+		}
+
+	} else {
+		cost := make([][]float32, len(dets))
+
+		for i, det := range dets {
+			cost[i] = make([]float32, len(tracks))
+
+			for j, tr := range oc.tracks {
+				cost[i][j] = -(ious[i][j] + oc.angleCost(tr, det))
+			}
+		}
+
+		pairs, _, _, err = linearAssignment(cost, len(dets), len(tracks), math.MaxFloat32)
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	matches, unmatchDets, unmatchTracks = filterMatches(pairs, ious,
+		oc.iouThresh, len(dets), len(tracks))
+
+	return matches, unmatchDets, unmatchTracks, nil
+}
+
+// angleCost returns the velocity direction consistency cost between the
+// track and detection, being the similarity of the track velocity direction
+// and the direction from the track's observation deltaT frames ago to the
+// detection, weighted by the inertia and detection score
+func (oc *OCSORT) angleCost(tr *ocTrack, det *STrack) float32 {
+
+	prev, ok := tr.previousObservation(oc.deltaT)
+
+	if !ok {
+		return 0
+	}
+
+	dir := speedDirection(prev, *det.GetRect())
+
+	cos := tr.velocity[1]*dir[1] + tr.velocity[0]*dir[0]
+	cos = max(-1, min(1, cos))
+
+	diff := (math.Pi/2 - math.Abs(math.Acos(float64(cos)))) / math.Pi
+
+	return oc.inertia * float32(diff) * det.GetScore()
+}
+
+// matchIoU matches the detection boxes to the track boxes by IoU
+func (oc *OCSORT) matchIoU(dets, tracks []*STrack) ([][2]int, []int, []int, error) {
+
+	ious := calcStrackIous(dets, tracks)
+	best := float32(0)
+
+	for i := range ious {
+		for j := range ious[i] {
+			best = max(best, ious[i][j])
+		}
+	}
+
+	if best <= oc.iouThresh {
+		return nil, seq(len(dets)), seq(len(tracks)), nil
+	}
+
+	cost := make([][]float32, len(ious))
+
+	for i := range ious {
+		cost[i] = make([]float32, len(ious[i]))
+
+		for j := range ious[i] {
+			cost[i][j] = -ious[i][j]
+		}
+	}
+
+	pairs, _, _, err := linearAssignment(cost, len(dets), len(tracks), math.MaxFloat32)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	matches, unmatchDets, unmatchTracks := filterMatches(pairs, ious,
+		oc.iouThresh, len(dets), len(tracks))
+
+	return matches, unmatchDets, unmatchTracks, nil
+}
+
+// newOCTrack creates and activates a track for the detection
+func newOCTrack(det *STrack, frameID, trackID, deltaT int) *ocTrack {
+
+	det.Activate(frameID, trackID)
+
+	// tracks are activated once they are output
+	det.isActivated = false
+
+	rect := *det.GetRect()
+
+	return &ocTrack{
+		STrack:               det,
+		lastObservation:      rect,
+		lastObservationAngle: det.GetAngle(),
+		observations:         map[int]Rect{0: rect},
+		deltaT:               deltaT,
+	}
+}
+
+// observedBox returns the last observation of the track as an STrack for
+// calculating its IoU with the detections
+func (t *ocTrack) observedBox() *STrack {
+	return &STrack{
+		rect:     t.lastObservation,
+		oriented: t.oriented,
+		angleKF:  angleFilter{angle: float64(t.lastObservationAngle)},
+	}
+}
+
+// predict advances the track state by one frame, being dt frame intervals
+func (t *ocTrack) predict(dt float32) {
+
+	// prevent negative height
+	if t.mean[3]+t.mean[7]*dt <= 0 {
+		t.mean[7] = 0
+	}
+
+	t.kalmanFilter.PredictDt(t.mean, &t.covariance, dt)
+	t.updateRect()
+
+	if t.frozen {
+		t.gapSteps = append(t.gapSteps, dt)
+	}
+
+	t.age++
+
+	if t.timeSinceUpdate > 0 {
+		t.hitStreak = 0
+	}
+
+	t.timeSinceUpdate++
+}
+
+// update updates the track with the matched detection
+func (t *ocTrack) update(det *STrack, frameID int) error {
+
+	rect := *det.GetRect()
+
+	// update velocity direction from the observation deltaT frames ago
+	prev, ok := t.previousObservation(t.deltaT)
+
+	if !ok {
+		prev = t.lastObservation
+	}
+
+	t.velocity = speedDirection(prev, rect)
+
+	if t.frozen {
+		if err := t.reUpdate(rect); err != nil {
+			return err
+		}
+	}
+
+	// tracks are activated once they are output
+	activated := t.isActivated
+
+	if err := t.STrack.Update(det, frameID); err != nil {
+		return err
+	}
+
+	t.isActivated = activated
+
+	// output the observed box rather than the filtered state
+	t.rect = NewRect(rect.X(), rect.Y(), rect.Width(), rect.Height())
+
+	t.lastObservation = rect
+	t.lastObservationAngle = det.GetAngle()
+	t.lastObservationAge = t.age
+	t.observations[t.age] = rect
+	t.timeSinceUpdate = 0
+	t.hitStreak++
+
+	return nil
+}
+
+// markMissed marks the track as unmatched in this frame saving the Kalman
+// state at the start of the gap in observations for ORU
+func (t *ocTrack) markMissed() {
+
+	if !t.frozen {
+		t.frozenMean = make(StateMean, len(t.mean))
+		copy(t.frozenMean, t.mean)
+		t.frozenCov = StateCov{mat.DenseCopyOf(t.covariance)}
+		t.frozen = true
+	}
+
+	t.MarkAsLost()
+}
+
+// reUpdate performs the observation-centric re-update, restoring the Kalman
+// state saved when the track was lost and updating it along a virtual
+// trajectory linearly interpolated between the last observation and the new
+// observation to remove the error accumulated during the gap
+func (t *ocTrack) reUpdate(rect Rect) error {
+
+	copy(t.mean, t.frozenMean)
+	t.covariance.Copy(t.frozenCov)
+	t.frozen = false
+
+	steps := t.gapSteps
+	t.gapSteps = nil
+
+	gap := t.age - t.lastObservationAge
+	from := t.lastObservation.GetXyah()
+	to := rect.GetXyah()
+
+	// interpolate center and size
+	w1, w2 := from[2]*from[3], to[2]*to[3]
+
+	for i := 1; i < gap; i++ {
+
+		f := float32(i) / float32(gap)
+		w := w1 + (w2-w1)*f
+		h := from[3] + (to[3]-from[3])*f
+
+		box := DetectBox{
+			from[0] + (to[0]-from[0])*f,
+			from[1] + (to[1]-from[1])*f,
+			w / h,
+			h,
+		}
+
+		if err := t.kalmanFilter.Update(t.mean, &t.covariance, box); err != nil {
+			return fmt.Errorf("error re-updating: %w", err)
+		}
+
+		dt := float32(1)
+
+		if i-1 < len(steps) {
+			dt = steps[i-1]
+		}
+
+		t.kalmanFilter.PredictDt(t.mean, &t.covariance, dt)
+	}
+
+	return nil
+}
+
+// previousObservation returns the observation made deltaT frames ago, or the
+// closest to it within deltaT frames.  Returns false if no observation was
+// made in that period.
+func (t *ocTrack) previousObservation(deltaT int) (Rect, bool) {
+
+	for dt := deltaT; dt > 0; dt-- {
+		if obs, ok := t.observations[t.age-dt]; ok {
+			return obs, true
+		}
+	}
+
+	return Rect{}, false
+}
+
+// speedDirection returns the normalised (dy, dx) direction between the
+// centers of the two boxes
+func speedDirection(from, to Rect) [2]float32 {
+
+	fx, fy := from.X()+from.Width()/2, from.Y()+from.Height()/2
+	tx, ty := to.X()+to.Width()/2, to.Y()+to.Height()/2
+
+	dx, dy := tx-fx, ty-fy
+	norm := float32(math.Sqrt(float64(dx*dx+dy*dy))) + 1e-6
+
+	return [2]float32{dy / norm, dx / norm}
+}
+
+// filterMatches removes the assigned pairs with an IoU below the threshold
+// and returns the matched pairs and unmatched row and column indexes
+func filterMatches(pairs [][2]int, ious [][]float32, thresh float32,
+	rows, cols int) (matches [][2]int, unmatchRows, unmatchCols []int) {
+
+	rowMatched := make([]bool, rows)
+	colMatched := make([]bool, cols)
+
+	for _, p := range pairs {
+		if ious[p[0]][p[1]] < thresh {
+			continue
+		}
+
+		matches = append(matches, p)
+		rowMatched[p[0]] = true
+		colMatched[p[1]] = true
+	}
+
+	for i, ok := range rowMatched {
+		if !ok {
+			unmatchRows = append(unmatchRows, i)
+		}
+	}
+
+	for j, ok := range colMatched {
+		if !ok {
+			unmatchCols = append(unmatchCols, j)
+		}
+	}
+
+	return matches, unmatchRows, unmatchCols
+}
+
+// selectIdx returns the values at the given positions
+func selectIdx(values, positions []int) []int {
+
+	res := make([]int, len(positions))
+
+	for i, p := range positions {
+		res[i] = values[p]
+	}
+
+	return res
+}
+
+// seq returns the indexes 0 to n-1
+func seq(n int) []int {
+
+	res := make([]int, n)
+
+	for i := range res {
+		res[i] = i
+	}
+
+	return res
+}
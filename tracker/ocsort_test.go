@@ -0,0 +1,202 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+// movingObjects returns the detections of two objects moving in opposite
+// directions for the frame
+func movingObjects(frame int) []Object {
+	f := float32(frame)
+
+	return []Object{
+		NewObject(NewRect(100+f*4, 200, 50, 120), 0, 0.9, int64(frame*10+1)),
+		NewObject(NewRect(400-f*4, 220, 60, 130), 0, 0.85, int64(frame*10+2)),
+	}
+}
+
+// trackIDs returns the track IDs of the tracks
+func trackIDs(tracks []Track) []int {
+
+	ids := make([]int, len(tracks))
+
+	for i, t := range tracks {
+		ids[i] = t.GetTrackID()
+	}
+
+	return ids
+}
+
+func TestOCSORTTracking(t *testing.T) {
+
+	oc := NewOCSORT(30, 30, 0.6, 0.3)
+
+	for frame := 0; frame < 10; frame++ {
+
+		tracks, err := oc.Update(movingObjects(frame))
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		ids := trackIDs(tracks)
+
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Fatalf("frame %d: expected track IDs [1 2], got %v", frame, ids)
+		}
+
+		// tracks output the observed detection box
+		if tracks[0].GetRect().X() != 100+float32(frame)*4 ||
+			tracks[0].GetDetectionID() != int64(frame*10+1) {
+			t.Errorf("frame %d: unexpected track %v", frame, tracks[0].GetRect())
+		}
+	}
+}
+
+func TestOCSORTRecovery(t *testing.T) {
+
+	oc := NewOCSORT(30, 30, 0.6, 0.3)
+
+	for frame := 0; frame < 14; frame++ {
+
+		objects := movingObjects(frame)[:1]
+
+		// object is occluded for 3 frames
+		if frame >= 5 && frame <= 7 {
+			objects = nil
+		}
+
+		tracks, err := oc.Update(objects)
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		switch {
+		case frame >= 5 && frame <= 9:
+			// not output while occluded and until the hit streak is rebuilt
+			if len(tracks) != 0 {
+				t.Errorf("frame %d: expected no tracks, got %v", frame, trackIDs(tracks))
+			}
+
+		default:
+			if len(tracks) != 1 || tracks[0].GetTrackID() != 1 {
+				t.Errorf("frame %d: expected track 1, got %v", frame, trackIDs(tracks))
+			}
+		}
+	}
+
+	if oc.trackIDCount != 1 {
+		t.Errorf("expected object to keep its track, got %d tracks", oc.trackIDCount)
+	}
+
+	// observation-centric re-update leaves the state close to the observation
+	tr := oc.tracks[0]
+
+	if !almostEqual(tr.mean[0], 100+13*4+25, 1) || tr.frozen {
+		t.Errorf("unexpected state after re-update %v", tr.mean)
+	}
+}
+
+func TestOCSORTRemoval(t *testing.T) {
+
+	oc := NewOCSORT(30, 2, 0.6, 0.3)
+
+	if _, err := oc.Update(movingObjects(0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := oc.Update(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(oc.tracks) != 0 {
+		t.Errorf("expected tracks to be removed, got %d", len(oc.tracks))
+	}
+
+	oc.Reset()
+
+	tracks, _ := oc.Update(movingObjects(0))
+
+	if ids := trackIDs(tracks); len(ids) != 2 || ids[0] != 1 {
+		t.Errorf("expected track IDs to restart after reset, got %v", ids)
+	}
+}
+
+func TestOCSORTAngleCost(t *testing.T) {
+
+	oc := NewOCSORT(30, 30, 0.6, 0.3)
+
+	det := NewSTrack(NewRect(100, 100, 50, 50), 0.6, 0, 0)
+	det.Activate(1, 1)
+
+	tr := newOCTrack(det, 1, 1, oc.deltaT)
+	tr.predict(1)
+
+	// moving right
+	tr.velocity = [2]float32{0, 1}
+
+	right := NewSTrack(NewRect(120, 100, 50, 50), 0.5, 0, 0)
+	left := NewSTrack(NewRect(80, 100, 50, 50), 0.5, 0, 0)
+	down := NewSTrack(NewRect(100, 120, 50, 50), 0.5, 0, 0)
+
+	if c := oc.angleCost(tr, right); !almostEqual(c, 0.2*0.5*0.5, 1e-4) {
+		t.Errorf("expected consistent direction cost of 0.05, got %f", c)
+	}
+
+	if c := oc.angleCost(tr, left); !almostEqual(c, -0.05, 1e-4) {
+		t.Errorf("expected opposite direction cost of -0.05, got %f", c)
+	}
+
+	if c := oc.angleCost(tr, down); !almostEqual(c, 0, 1e-4) {
+		t.Errorf("expected perpendicular direction cost of 0, got %f", c)
+	}
+}
+
+func TestOCSORTUpdateAt(t *testing.T) {
+
+	oc := NewOCSORT(30, 30, 0.6, 0.3)
+	oc.SetLostTimeout(300 * time.Millisecond)
+	start := time.Unix(1000, 0)
+	interval := time.Second / 30
+
+	// objects are detected every 3rd frame
+	for frame := 0; frame < 30; frame += 3 {
+
+		tracks, err := oc.UpdateAt(movingObjects(frame), start.Add(time.Duration(frame)*interval))
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		if ids := trackIDs(tracks); len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Fatalf("frame %d: expected track IDs [1 2], got %v", frame, ids)
+		}
+	}
+
+	// tracks are removed once unmatched for longer than the timeout
+	last := start.Add(27 * interval)
+
+	if _, err := oc.UpdateAt(nil, last.Add(300*time.Millisecond)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(oc.tracks) != 2 {
+		t.Errorf("expected 2 tracks kept at the timeout, got %d", len(oc.tracks))
+	}
+
+	if _, err := oc.UpdateAt(nil, last.Add(301*time.Millisecond)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(oc.tracks) != 0 {
+		t.Errorf("expected tracks removed after the timeout, got %d", len(oc.tracks))
+	}
+
+	if _, err := oc.UpdateAt(nil, start); err == nil {
+		t.Errorf("expected error for timestamp before previous update")
+	}
+}
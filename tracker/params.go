@@ -0,0 +1,137 @@
+package tracker
+
+import (
+	"time"
+)
+
+// ClassMatching defines how the object label/class is used when associating
+// detections with tracks
+type ClassMatching int
+
+const (
+	// MatchAnyClass associates detections with tracks regardless of their
+	// label
+	MatchAnyClass ClassMatching = 0
+	// MatchSameClass only associates detections with tracks of the same
+	// label, tracking each class independently
+	MatchSameClass ClassMatching = 1
+	// PenaliseClassMismatch adds the LabelMismatchCost to the association
+	// cost of detections and tracks with different labels, so a different
+	// class is only matched when there is no better candidate
+	PenaliseClassMismatch ClassMatching = 2
+)
+
+// classMismatchCost is the association cost of detections and tracks of a
+// different label with MatchSameClass, being above any match threshold
+const classMismatchCost = 1e5
+
+// ClassParams defines thresholds for an object label/class that override
+// those of the BYTETrackerParams.  Fields left as zero use the value from
+// BYTETrackerParams.
+type ClassParams struct {
+	// TrackThresh is the detection score threshold for the first high score
+	// association
+	TrackThresh float32
+	// HighThresh is the detection score threshold for creating a new track
+	HighThresh float32
+	// LostTimeout is the time a track can be lost before being removed
+	LostTimeout time.Duration
+}
+
+// BYTETrackerParams defines the struct containing the parameters for the
+// BYTETracker
+type BYTETrackerParams struct {
+	// FrameRate is the frame rate of the video
+	FrameRate int
+	// TrackBuffer is the number of frames at 30 FPS a track can be lost before
+	// being removed
+	TrackBuffer int
+	// TrackThresh is the detection score threshold for the first high score
+	// association, detections below it are used in the second association
+	TrackThresh float32
+	// HighThresh is the detection score threshold for creating a new track
+	HighThresh float32
+	// MatchThresh is the maximum cost of the first association
+	MatchThresh float32
+	// LostTimeout is the time a track can be lost before being removed.  If
+	// zero it is derived from the FrameRate and TrackBuffer
+	LostTimeout time.Duration
+	// ClassMatching defines how labels are used in the association
+	ClassMatching ClassMatching
+	// LabelMismatchCost is the cost added for detections and tracks with
+	// different labels when using PenaliseClassMismatch
+	LabelMismatchCost float32
+	// Classes are the per class thresholds keyed by label
+	Classes map[int]ClassParams
+}
+
+// DefaultBYTETrackerParams returns an instance of BYTETrackerParams with the
+// ByteTrack default thresholds for a 30 FPS video
+func DefaultBYTETrackerParams() BYTETrackerParams {
+	return BYTETrackerParams{
+		FrameRate:         30,
+		TrackBuffer:       30,
+		TrackThresh:       0.5,
+		HighThresh:        0.6,
+		MatchThresh:       0.8,
+		ClassMatching:     MatchAnyClass,
+		LabelMismatchCost: 0.5,
+	}
+}
+
+// trackThreshFor returns the track threshold for the label
+func (bt *BYTETracker) trackThreshFor(label int) float32 {
+
+	if cp, ok := bt.classes[label]; ok && cp.TrackThresh > 0 {
+		return cp.TrackThresh
+	}
+
+	return bt.trackThresh
+}
+
+// highThreshFor returns the new track threshold for the label
+func (bt *BYTETracker) highThreshFor(label int) float32 {
+
+	if cp, ok := bt.classes[label]; ok && cp.HighThresh > 0 {
+		return cp.HighThresh
+	}
+
+	return bt.highThresh
+}
+
+// lostTimeoutFor returns the lost track timeout for the label
+func (bt *BYTETracker) lostTimeoutFor(label int) time.Duration {
+
+	if cp, ok := bt.classes[label]; ok && cp.LostTimeout > 0 {
+		return cp.LostTimeout
+	}
+
+	return bt.maxTimeLost
+}
+
+// applyClassCost adjusts the association cost matrix of the tracks and
+// detections for differing labels according to the class matching mode
+func (bt *BYTETracker) applyClassCost(cost [][]float32, tracks,
+	dets []*STrack) [][]float32 {
+
+	if bt.classMatching == MatchAnyClass {
+		return cost
+	}
+
+	for i, row := range cost {
+		for j := range row {
+
+			if tracks[i].GetLabel() == dets[j].GetLabel() {
+				continue
+			}
+
+			if bt.classMatching == MatchSameClass {
+				row[j] = classMismatchCost
+			} else {
+				row[j] += bt.labelMismatchCost
+			}
+		}
+	}
+
+	return cost
+}
@@ -0,0 +1,133 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+// carThenBus returns the track IDs of a car tracked for 5 frames that is then
+// replaced by a bus detected at an overlapping position
+func carThenBus(t *testing.T, p BYTETrackerParams) []int {
+
+	bt := NewBYTETrackerWithParams(p)
+	var ids []int
+
+	for frame := 0; frame < 8; frame++ {
+
+		obj := NewObject(NewRect(100+float32(frame)*2, 100, 80, 60), 2, 0.9, int64(frame))
+
+		if frame >= 5 {
+			obj = NewObject(NewRect(104+float32(frame)*2, 96, 84, 64), 5, 0.9, int64(frame))
+		}
+
+		tracks, err := bt.Update([]Object{obj})
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		for _, tr := range tracks {
+			if tr.GetDetectionID() == int64(frame) {
+				ids = append(ids, tr.GetTrackID())
+			}
+		}
+	}
+
+	return ids
+}
+
+func TestBYTETrackerClassMatching(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		matching ClassMatching
+		cost     float32
+		want     []int
+	}{
+		// the bus takes over the car's track
+		{"any", MatchAnyClass, 0, []int{1, 1, 1, 1, 1, 1, 1, 1}},
+		// the bus gets a new track which is output from its second frame
+		{"same", MatchSameClass, 0, []int{1, 1, 1, 1, 1, 2, 2}},
+		// a small penalty still matches when there is no other candidate
+		{"penalty", PenaliseClassMismatch, 0.5, []int{1, 1, 1, 1, 1, 1, 1, 1}},
+		// a large penalty prevents the match
+		{"large penalty", PenaliseClassMismatch, 0.8, []int{1, 1, 1, 1, 1, 2, 2}},
+	}
+
+	for _, tc := range tests {
+
+		p := DefaultBYTETrackerParams()
+		p.ClassMatching = tc.matching
+		p.LabelMismatchCost = tc.cost
+
+		got := carThenBus(t, p)
+
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected track IDs %v, got %v", tc.name, tc.want, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: expected track IDs %v, got %v", tc.name, tc.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestBYTETrackerClassParams(t *testing.T) {
+
+	p := DefaultBYTETrackerParams()
+	p.Classes = map[int]ClassParams{
+		1: {TrackThresh: 0.3, HighThresh: 0.35},
+		2: {LostTimeout: 100 * time.Millisecond},
+	}
+
+	bt := NewBYTETrackerWithParams(p)
+	var removed []int
+
+	bt.SetEventHandler(EventFuncs{
+		Removed: func(e Event) {
+			removed = append(removed, e.Label)
+		},
+	})
+
+	start := time.Unix(0, 0)
+
+	for frame := 0; frame < 10; frame++ {
+
+		// low score detections of class 0 and 1 and class 2 and 3 which
+		// disappear after the first frame
+		objects := []Object{
+			NewObject(NewRect(100, 100, 50, 50), 0, 0.4, 1),
+			NewObject(NewRect(300, 100, 50, 50), 1, 0.4, 2),
+		}
+
+		if frame == 0 {
+			objects = append(objects,
+				NewObject(NewRect(100, 300, 50, 50), 2, 0.9, 3),
+				NewObject(NewRect(300, 300, 50, 50), 3, 0.9, 4),
+			)
+		}
+
+		tracks, err := bt.UpdateAt(objects, start.Add(time.Duration(frame)*50*time.Millisecond))
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		// only the class 1 detection is above its thresholds
+		if frame > 0 {
+			if len(tracks) != 1 || tracks[0].GetLabel() != 1 {
+				t.Errorf("frame %d: expected only a track of class 1, got %d tracks",
+					frame, len(tracks))
+			}
+		}
+	}
+
+	// class 2 is removed after its shorter timeout
+	if len(removed) != 1 || removed[0] != 2 {
+		t.Errorf("expected removal of class 2, got %v", removed)
+	}
+}
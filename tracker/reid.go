@@ -7,14 +7,16 @@ import (
 	"gocv.io/x/gocv"
 	"image"
 	"sync"
+	"time"
 )
 
-// DistanceMethod defines ReID distance calculation methods
-type DistanceMethod int
+// DistanceMethod defines ReID distance calculation methods, being the same
+// methods as the reid package Gallery
+type DistanceMethod = reid.DistanceMethod
 
 const (
-	Euclidean DistanceMethod = 1
-	Cosine    DistanceMethod = 2
+	Euclidean = reid.Euclidean
+	Cosine    = reid.Cosine
 )
 
 // reID struct holds all ReIdentification processing features
@@ -66,28 +68,57 @@ func (bt *BYTETracker) UseReID(pool *rknnlite.Pool, dist DistanceMethod,
 }
 
 // UpdateWithFrame updates the tracker with new detections and passes the
-// image frame so ReID inference can be conducted
-func (bt *BYTETracker) UpdateWithFrame(objects []Object, frame gocv.Mat) ([]*STrack, error) {
+// image frame so ReID inference and camera motion compensation can be
+// conducted
+func (bt *BYTETracker) UpdateWithFrame(objects []Object, frame gocv.Mat) ([]Track, error) {
+	return bt.UpdateWithFrameAt(objects, frame, bt.timestamp.Add(bt.frameInterval))
+}
 
-	// check if ReID is enabled and get embedding features for all objects
-	if bt.useReid {
+// UpdateWithFrameAt is UpdateWithFrame for the frame captured at the timestamp
+func (bt *BYTETracker) UpdateWithFrameAt(objects []Object, frame gocv.Mat,
+	timestamp time.Time) ([]Track, error) {
 
-		bufFrame := frame.Clone()
-		defer bufFrame.Close()
+	// estimate camera motion since the last frame
+	if bt.gmc != nil {
 
-		features, err := bt.reid.processObjects(objects, bufFrame)
+		h, err := bt.gmc.apply(frame)
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to process objects: %w", err)
+			return nil, fmt.Errorf("failed to estimate camera motion: %w", err)
+		}
+
+		bt.cameraMotion = h
+	}
+
+	// check if ReID is enabled and get embedding features for the objects
+	// selected by the ReID schedule
+	if bt.useReid && bt.reid != nil {
+
+		// the frame is copied as extraction can run after returning
+		bufFrame := frame.Clone()
+
+		extract := func(objs []Object) ([][]float32, error) {
+			return bt.reid.processObjects(objs, bufFrame)
 		}
 
-		for i := range objects {
-			objects[i].Feature = features[i]
+		tracks, err := bt.UpdateWithExtractor(objects, timestamp, extract)
+
+		// release the frame once asynchronous extraction has finished
+		if bt.pending != nil {
+			bt.pending.release = func() { bufFrame.Close() }
+		} else {
+			bufFrame.Close()
 		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error updating objects: %w", err)
+		}
+
+		return tracks, nil
 	}
 
 	// run track update
-	tracks, err := bt.Update(objects)
+	tracks, err := bt.UpdateAt(objects, timestamp)
 
 	if err != nil {
 		return nil, fmt.Errorf("error updating objects: %w", err)
@@ -0,0 +1,281 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// featureAlpha is the EMA smoothing weight of track ReID features
+	featureAlpha = 0.9
+	// featureQueueSize is the number of past ReID features kept per track
+	featureQueueSize = 30
+	// scheduleMatchIoU is the minimum IoU of a detection with a track for the
+	// detection to be taken as the track's next observation when scheduling
+	// feature extraction
+	scheduleMatchIoU = 0.5
+)
+
+// ReIDSchedule defines when ReID features are extracted for detections.  The
+// zero value extracts features for every detection on every frame.
+// Detections that do not overlap an existing track are always extracted as
+// ReID is needed to identify new and reappearing objects.
+type ReIDSchedule struct {
+	// Interval is the number of frames between feature extraction for each
+	// track, 0 or 1 extracts every frame
+	Interval int
+	// SkipSeparated skips extraction for detections of a track that do not
+	// overlap any other detection or track by more than the SeparationIoU,
+	// as their IoU association is unambiguous
+	SkipSeparated bool
+	// SeparationIoU is the maximum IoU with other detections and tracks for a
+	// detection to be considered well separated
+	SeparationIoU float32
+	// Async extracts features of detections of existing tracks in the
+	// background so the update does not wait on ReID inference.  Features are
+	// applied to the tracks of the detections at the start of the next
+	// update, so Object IDs must be unique within a frame.  New and
+	// reappearing objects are still extracted before the update as ReID is
+	// needed to identify them on the frame they appear.
+	Async bool
+}
+
+// FeatureExtractor computes the ReID embeddings of the objects
+type FeatureExtractor func(objects []Object) ([][]float32, error)
+
+// pendingFeatures are the features of a frame being extracted asynchronously
+type pendingFeatures struct {
+	// frameID is the frame the detections are from
+	frameID int
+	// ids are the Object IDs of the detections
+	ids []int64
+	// features are the extracted features of the detections
+	features [][]float32
+	// err is the extraction error
+	err error
+	// done is closed once extraction has finished
+	done chan struct{}
+	// release frees resources used by the extractor once extraction has
+	// finished, nil if not set
+	release func()
+}
+
+// SetReIDSchedule sets when ReID features are extracted by UpdateWithFrame()
+// and UpdateWithExtractor()
+func (bt *BYTETracker) SetReIDSchedule(s ReIDSchedule) {
+	bt.schedule = s
+}
+
+// UpdateWithExtractor updates the tracker with new detections, using the
+// extractor to compute the ReID features of the detections selected by the
+// ReIDSchedule.  This enables ReID tracking with a feature extractor other
+// than that set by UseReID().  ReID matching is only used for this update
+// unless enabled by UseReID(), so Update() can still be called for frames
+// without features.  If the schedule is Async the extractor is also called
+// from another goroutine, which has finished by the start of the next update.
+func (bt *BYTETracker) UpdateWithExtractor(objects []Object, timestamp time.Time,
+	extract FeatureExtractor) ([]Track, error) {
+
+	if !bt.useReid {
+		bt.useReid = true
+		defer func() { bt.useReid = false }()
+	}
+
+	// apply features extracted asynchronously on the last update
+	if err := bt.applyPending(); err != nil {
+		return nil, err
+	}
+
+	now, later := bt.scheduleReID(objects)
+
+	if len(now) > 0 {
+
+		features, err := extract(selectObjects(objects, now))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract features: %w", err)
+		}
+
+		if len(features) != len(now) {
+			return nil, fmt.Errorf("extracted %d features for %d objects",
+				len(features), len(now))
+		}
+
+		for i, idx := range now {
+			objects[idx].Feature = features[i]
+		}
+	}
+
+	if len(later) > 0 {
+
+		selected := selectObjects(objects, later)
+
+		p := &pendingFeatures{
+			frameID: bt.frameID + 1,
+			ids:     make([]int64, len(selected)),
+			done:    make(chan struct{}),
+		}
+
+		for i, obj := range selected {
+			p.ids[i] = obj.ID
+		}
+
+		go func() {
+			defer close(p.done)
+			p.features, p.err = extract(selected)
+		}()
+
+		bt.pending = p
+	}
+
+	return bt.UpdateAt(objects, timestamp)
+}
+
+// selectObjects returns the objects at the indexes
+func selectObjects(objects []Object, indexes []int) []Object {
+
+	res := make([]Object, len(indexes))
+
+	for i, idx := range indexes {
+		res[i] = objects[idx]
+	}
+
+	return res
+}
+
+// applyPending waits for the asynchronous feature extraction of the last
+// update and adds the features to the tracks of the detections
+func (bt *BYTETracker) applyPending() error {
+
+	p := bt.pending
+
+	if p == nil {
+		return nil
+	}
+
+	bt.pending = nil
+	<-p.done
+
+	if p.release != nil {
+		p.release()
+	}
+
+	if p.err != nil {
+		return fmt.Errorf("failed to extract features: %w", p.err)
+	}
+
+	if len(p.features) != len(p.ids) {
+		return fmt.Errorf("extracted %d features for %d objects",
+			len(p.features), len(p.ids))
+	}
+
+	features := make(map[int64][]float32, len(p.ids))
+
+	for i, id := range p.ids {
+		features[id] = p.features[i]
+	}
+
+	for _, track := range bt.jointStracks(bt.trackedStracks, bt.lostStracks) {
+
+		// only tracks updated with a detection of the frame
+		if track.frameID != p.frameID {
+			continue
+		}
+
+		if f, ok := features[track.detectionID]; ok && f != nil {
+			track.addFeature(f, p.frameID)
+		}
+	}
+
+	return nil
+}
+
+// discardPending waits for any asynchronous feature extraction to finish
+// without applying the features
+func (bt *BYTETracker) discardPending() {
+
+	if bt.pending == nil {
+		return
+	}
+
+	<-bt.pending.done
+
+	if bt.pending.release != nil {
+		bt.pending.release()
+	}
+
+	bt.pending = nil
+}
+
+// scheduleReID returns the indexes of the objects to extract ReID features
+// for on the next frame before the update, and those to extract
+// asynchronously
+func (bt *BYTETracker) scheduleReID(objects []Object) (now, later []int) {
+
+	frameID := bt.frameID + 1
+
+	for i, obj := range objects {
+
+		track, iou := bt.closestTrack(obj.Rect)
+
+		// new or reappearing object
+		if track == nil || iou < scheduleMatchIoU {
+			now = append(now, i)
+			continue
+		}
+
+		if bt.schedule.SkipSeparated && bt.isSeparated(i, objects, track) {
+			continue
+		}
+
+		if bt.schedule.Interval > 1 && track.hasFeature &&
+			frameID-track.featureFrame < bt.schedule.Interval {
+			continue
+		}
+
+		if bt.schedule.Async {
+			later = append(later, i)
+		} else {
+			now = append(now, i)
+		}
+	}
+
+	return now, later
+}
+
+// closestTrack returns the tracked track with the highest IoU with the rect
+func (bt *BYTETracker) closestTrack(rect Rect) (*STrack, float32) {
+
+	var best *STrack
+	var bestIoU float32
+
+	for _, track := range bt.trackedStracks {
+		if iou := track.GetRect().CalcIoU(rect); iou > bestIoU {
+			best = track
+			bestIoU = iou
+		}
+	}
+
+	return best, bestIoU
+}
+
+// isSeparated returns true if the object does not overlap any other object
+// or track other than its own by more than the separation IoU
+func (bt *BYTETracker) isSeparated(idx int, objects []Object, own *STrack) bool {
+
+	rect := objects[idx].Rect
+
+	for i, obj := range objects {
+		if i != idx && obj.Rect.CalcIoU(rect) > bt.schedule.SeparationIoU {
+			return false
+		}
+	}
+
+	for _, track := range bt.trackedStracks {
+		if track != own && track.GetRect().CalcIoU(rect) > bt.schedule.SeparationIoU {
+			return false
+		}
+	}
+
+	return true
+}
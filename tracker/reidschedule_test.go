@@ -0,0 +1,296 @@
+package tracker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// idFeature returns a ReID feature unique to the object ID
+func idFeature(id int64) []float32 {
+
+	f := make([]float32, 8)
+	f[id%8] = 1
+
+	return f
+}
+
+// extractLog is a FeatureExtractor counting the objects extracted
+type extractLog struct {
+	total int
+	err   error
+	mu    sync.Mutex
+}
+
+// extract returns the features of the objects by ID
+func (l *extractLog) extract(objects []Object) ([][]float32, error) {
+
+	features := make([][]float32, len(objects))
+
+	for i, obj := range objects {
+		features[i] = idFeature(obj.ID)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total += len(objects)
+
+	return features, l.err
+}
+
+// count returns the total number of objects extracted
+func (l *extractLog) count() int {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.total
+}
+
+// updateCounted updates the tracker with the objects on the frame returning
+// the number of objects extracted
+func (l *extractLog) updateCounted(t *testing.T, bt *BYTETracker, objects []Object,
+	frame int) int {
+
+	before := l.count()
+	ts := time.Unix(1000, 0).Add(time.Duration(frame) * bt.frameInterval)
+
+	if _, err := bt.UpdateWithExtractor(objects, ts, l.extract); err != nil {
+		t.Fatalf("frame %d: unexpected error: %v", frame, err)
+	}
+
+	return l.count() - before
+}
+
+// twoObjects returns two objects side by side which overlap when close is set
+func twoObjects(close bool) []Object {
+
+	x := float32(400)
+
+	if close {
+		x = 130
+	}
+
+	return []Object{
+		NewObject(NewRect(100, 100, 50, 100), 0, 0.9, 1),
+		NewObject(NewRect(x, 100, 50, 100), 0, 0.9, 2),
+	}
+}
+
+func equalInts(a, b []int) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestReIDScheduleEveryFrame(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	log := &extractLog{}
+
+	var counts []int
+
+	for f := 0; f < 3; f++ {
+		counts = append(counts, log.updateCounted(t, bt, twoObjects(false), f))
+	}
+
+	if !equalInts(counts, []int{2, 2, 2}) {
+		t.Errorf("expected all objects extracted every frame, got %v", counts)
+	}
+
+	if ids := trackIDs(toTracks(bt.trackedStracks)); len(ids) != 2 {
+		t.Fatalf("expected 2 tracks, got %v", ids)
+	}
+
+	track := bt.trackedStracks[0]
+
+	if !track.hasFeature || track.featureFrame != 3 || len(track.featureQueue) != 3 {
+		t.Errorf("expected features added each frame, got frame %d queue %d",
+			track.featureFrame, len(track.featureQueue))
+	}
+}
+
+func TestReIDScheduleInterval(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.SetReIDSchedule(ReIDSchedule{Interval: 3})
+	log := &extractLog{}
+
+	var counts []int
+
+	for f := 0; f < 7; f++ {
+
+		objects := twoObjects(false)[:1]
+
+		// second object appears on frame 3
+		if f >= 2 {
+			objects = twoObjects(false)
+		}
+
+		counts = append(counts, log.updateCounted(t, bt, objects, f))
+	}
+
+	// each track is extracted every third frame from when it first appeared
+	if !equalInts(counts, []int{1, 0, 1, 1, 0, 1, 1}) {
+		t.Errorf("unexpected extraction counts %v", counts)
+	}
+
+	if ids := trackIDs(toTracks(bt.trackedStracks)); len(ids) != 2 {
+		t.Errorf("expected 2 tracks, got %v", ids)
+	}
+}
+
+func TestReIDScheduleSkipSeparated(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.SetReIDSchedule(ReIDSchedule{SkipSeparated: true, SeparationIoU: 0.1})
+	log := &extractLog{}
+
+	var counts []int
+
+	for f := 0; f < 4; f++ {
+		counts = append(counts, log.updateCounted(t, bt, twoObjects(f == 3), f))
+	}
+
+	// new objects are extracted, then skipped until they overlap on frame 4
+	if !equalInts(counts, []int{2, 0, 0, 2}) {
+		t.Errorf("unexpected extraction counts %v", counts)
+	}
+
+	track := bt.trackedStracks[0]
+
+	if track.featureFrame != 4 || len(track.featureQueue) != 2 {
+		t.Errorf("expected features from frames 1 and 4, got frame %d queue %d",
+			track.featureFrame, len(track.featureQueue))
+	}
+}
+
+func TestReIDScheduleAsync(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.SetReIDSchedule(ReIDSchedule{Async: true})
+	log := &extractLog{}
+
+	// new objects are extracted before the update
+	if n := log.updateCounted(t, bt, twoObjects(false), 0); n != 2 || bt.pending != nil {
+		t.Fatalf("expected 2 objects extracted synchronously, got %d", n)
+	}
+
+	// objects of existing tracks are extracted asynchronously and their
+	// features added on the next update
+	for f := 1; f < 3; f++ {
+
+		log.updateCounted(t, bt, twoObjects(false), f)
+
+		if bt.pending == nil || len(bt.pending.ids) != 2 {
+			t.Fatalf("frame %d: expected asynchronous extraction of 2 objects", f)
+		}
+
+		for _, track := range bt.trackedStracks {
+
+			want := idFeature(int64(track.trackID))
+
+			if !track.hasFeature || track.featureFrame != f ||
+				track.smoothFeature[track.trackID%8] != want[track.trackID%8] {
+				t.Errorf("frame %d: expected track %d to have feature of frame %d, got frame %d %v",
+					f, track.trackID, f, track.featureFrame, track.smoothFeature)
+			}
+		}
+	}
+
+	// extraction errors are returned on the next update
+	<-bt.pending.done
+	log.mu.Lock()
+	log.err = errors.New("inference failed")
+	log.mu.Unlock()
+	log.updateCounted(t, bt, twoObjects(false), 3)
+
+	ts := time.Unix(1000, 0).Add(4 * bt.frameInterval)
+
+	if _, err := bt.UpdateWithExtractor(twoObjects(false), ts, log.extract); err == nil {
+		t.Errorf("expected extraction error")
+	}
+
+	bt.Reset()
+
+	if bt.pending != nil {
+		t.Errorf("expected pending extraction discarded on reset")
+	}
+}
+
+func TestUpdateWithExtractorFeatureCount(t *testing.T) {
+
+	// extractor returning one feature less than the objects
+	short := func(objects []Object) ([][]float32, error) {
+		return make([][]float32, len(objects)-1), nil
+	}
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	ts := time.Unix(1000, 0)
+
+	if _, err := bt.UpdateWithExtractor(twoObjects(false), ts, short); err == nil {
+		t.Errorf("expected feature count error")
+	}
+
+	// asynchronous extraction returns the error on the next update
+	bt = NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	bt.SetReIDSchedule(ReIDSchedule{Async: true})
+	log := &extractLog{}
+
+	log.updateCounted(t, bt, twoObjects(false), 0)
+
+	ts = ts.Add(bt.frameInterval)
+
+	if _, err := bt.UpdateWithExtractor(twoObjects(false), ts, short); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts = ts.Add(bt.frameInterval)
+
+	if _, err := bt.UpdateWithExtractor(twoObjects(false), ts, log.extract); err == nil {
+		t.Errorf("expected asynchronous feature count error")
+	}
+}
+
+func TestUpdateWithExtractorScopesReID(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	log := &extractLog{}
+
+	log.updateCounted(t, bt, twoObjects(false), 1)
+
+	if bt.useReid {
+		t.Fatalf("expected ReID to only be used for the extractor update")
+	}
+
+	// objects without features continue their tracks by IoU
+	tracks, err := bt.UpdateAt(twoObjects(false),
+		time.Unix(1000, 0).Add(2*bt.frameInterval))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tracks) != 2 || tracks[0].GetTrackID() != 1 || tracks[1].GetTrackID() != 2 {
+		t.Errorf("expected tracks 1 and 2 to continue, got %+v", tracks)
+	}
+
+	// ReID enabled by the caller is left enabled
+	bt.useReid = true
+	log.updateCounted(t, bt, twoObjects(false), 3)
+
+	if !bt.useReid {
+		t.Errorf("expected ReID to remain enabled")
+	}
+}
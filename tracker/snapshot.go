@@ -0,0 +1,251 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"gonum.org/v1/gonum/mat"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format
+const snapshotVersion = 1
+
+// trackerSnapshot is the serialised state of a BYTETracker
+type trackerSnapshot struct {
+	Version      int              `json:"version"`
+	FrameID      int              `json:"frame_id"`
+	TrackIDCount int              `json:"track_id_count"`
+	Timestamp    time.Time        `json:"timestamp"`
+	Tracked      []strackSnapshot `json:"tracked"`
+	Lost         []strackSnapshot `json:"lost"`
+	Removed      []strackSnapshot `json:"removed"`
+}
+
+// strackSnapshot is the serialised state of an STrack
+type strackSnapshot struct {
+	Mean          []float32   `json:"mean"`
+	Covariance    []float64   `json:"covariance"`
+	Rect          [4]float32  `json:"rect"`
+	State         STrackState `json:"state"`
+	IsActivated   bool        `json:"is_activated"`
+	Score         float32     `json:"score"`
+	TrackID       int         `json:"track_id"`
+	FrameID       int         `json:"frame_id"`
+	StartFrameID  int         `json:"start_frame_id"`
+	TrackletLen   int         `json:"tracklet_len"`
+	DetectionID   int64       `json:"detection_id"`
+	Label         int         `json:"label"`
+	FirstSeen     time.Time   `json:"first_seen"`
+	LastSeen      time.Time   `json:"last_seen"`
+	HasFeature    bool        `json:"has_feature,omitempty"`
+	Feature       []float32   `json:"feature,omitempty"`
+	SmoothFeature []float32   `json:"smooth_feature,omitempty"`
+	FeatureQueue  [][]float32 `json:"feature_queue,omitempty"`
+	MaxQueueSize  int         `json:"max_queue_size,omitempty"`
+	Alpha         float32     `json:"alpha,omitempty"`
+	FeatureFrame  int         `json:"feature_frame,omitempty"`
+	Oriented      bool        `json:"oriented,omitempty"`
+	Angle         float64     `json:"angle,omitempty"`
+	AngleVelocity float64     `json:"angle_velocity,omitempty"`
+	AngleCov      [4]float64  `json:"angle_covariance,omitempty"`
+}
+
+// Snapshot serialises the tracked, lost and removed tracks, including their
+// Kalman state and ReID features, along with the frame and track ID counters
+// to JSON.  Pass the result to Restore() on a BYTETracker created with the
+// same parameters to resume tracking, such as after a service restart, without
+// track IDs starting from 1 again.  The camera motion compensation state is
+// not included and restarts on the next frame.
+func (bt *BYTETracker) Snapshot() ([]byte, error) {
+
+	snap := trackerSnapshot{
+		Version:      snapshotVersion,
+		FrameID:      bt.frameID,
+		TrackIDCount: bt.trackIDCount,
+		Timestamp:    bt.timestamp,
+		Tracked:      snapshotStracks(bt.trackedStracks),
+		Lost:         snapshotStracks(bt.lostStracks),
+		Removed:      snapshotStracks(bt.removedStracks),
+	}
+
+	data, err := json.Marshal(snap)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// Restore replaces the tracker state with the snapshot created by Snapshot()
+func (bt *BYTETracker) Restore(data []byte) error {
+
+	var snap trackerSnapshot
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	// the same track can be in both the lost and removed lists so restore
+	// them as a single STrack
+	restored := make(map[int]*STrack)
+
+	tracked, err := restoreStracks(snap.Tracked, restored)
+
+	if err != nil {
+		return fmt.Errorf("failed to restore tracked stracks: %w", err)
+	}
+
+	lost, err := restoreStracks(snap.Lost, restored)
+
+	if err != nil {
+		return fmt.Errorf("failed to restore lost stracks: %w", err)
+	}
+
+	removed, err := restoreStracks(snap.Removed, restored)
+
+	if err != nil {
+		return fmt.Errorf("failed to restore removed stracks: %w", err)
+	}
+
+	bt.Reset()
+
+	bt.frameID = snap.FrameID
+	bt.trackIDCount = snap.TrackIDCount
+	bt.timestamp = snap.Timestamp
+	bt.trackedStracks = tracked
+	bt.lostStracks = lost
+	bt.removedStracks = removed
+
+	return nil
+}
+
+// snapshotStracks returns the serialised state of the STracks
+func snapshotStracks(stracks []*STrack) []strackSnapshot {
+
+	res := make([]strackSnapshot, len(stracks))
+
+	for i, s := range stracks {
+		res[i] = s.snapshot()
+	}
+
+	return res
+}
+
+// restoreStracks returns the STracks of the serialised states, reusing those
+// already restored with the same track ID
+func restoreStracks(snaps []strackSnapshot,
+	restored map[int]*STrack) ([]*STrack, error) {
+
+	res := make([]*STrack, 0, len(snaps))
+
+	for _, snap := range snaps {
+
+		if s, ok := restored[snap.TrackID]; ok {
+			res = append(res, s)
+			continue
+		}
+
+		s, err := restoreSTrack(snap)
+
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", snap.TrackID, err)
+		}
+
+		restored[snap.TrackID] = s
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// snapshot returns the serialised state of the STrack
+func (s *STrack) snapshot() strackSnapshot {
+
+	rows, cols := s.covariance.Dims()
+	cov := make([]float64, 0, rows*cols)
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			cov = append(cov, s.covariance.At(i, j))
+		}
+	}
+
+	return strackSnapshot{
+		Mean:          append([]float32(nil), s.mean...),
+		Covariance:    cov,
+		Rect:          [4]float32{s.rect.X(), s.rect.Y(), s.rect.Width(), s.rect.Height()},
+		State:         s.state,
+		IsActivated:   s.isActivated,
+		Score:         s.score,
+		TrackID:       s.trackID,
+		FrameID:       s.frameID,
+		StartFrameID:  s.startFrameID,
+		TrackletLen:   s.trackletLen,
+		DetectionID:   s.detectionID,
+		Label:         s.label,
+		FirstSeen:     s.firstSeen,
+		LastSeen:      s.lastSeen,
+		HasFeature:    s.hasFeature,
+		Feature:       s.feature,
+		SmoothFeature: s.smoothFeature,
+		FeatureQueue:  s.featureQueue,
+		MaxQueueSize:  s.maxQueueSize,
+		Alpha:         s.alpha,
+		FeatureFrame:  s.featureFrame,
+		Oriented:      s.oriented,
+		Angle:         s.angleKF.angle,
+		AngleVelocity: s.angleKF.velocity,
+		AngleCov: [4]float64{s.angleKF.cov[0][0], s.angleKF.cov[0][1],
+			s.angleKF.cov[1][0], s.angleKF.cov[1][1]},
+	}
+}
+
+// restoreSTrack returns the STrack of the serialised state
+func restoreSTrack(snap strackSnapshot) (*STrack, error) {
+
+	if len(snap.Mean) != 8 {
+		return nil, fmt.Errorf("expected mean of length 8, got %d", len(snap.Mean))
+	}
+
+	if len(snap.Covariance) != 64 {
+		return nil, fmt.Errorf("expected covariance of length 64, got %d",
+			len(snap.Covariance))
+	}
+
+	s := NewSTrack(NewRect(snap.Rect[0], snap.Rect[1], snap.Rect[2], snap.Rect[3]),
+		snap.Score, snap.DetectionID, snap.Label)
+
+	copy(s.mean, snap.Mean)
+	s.covariance = StateCov{mat.NewDense(8, 8, append([]float64(nil), snap.Covariance...))}
+	s.state = snap.State
+	s.isActivated = snap.IsActivated
+	s.trackID = snap.TrackID
+	s.frameID = snap.FrameID
+	s.startFrameID = snap.StartFrameID
+	s.trackletLen = snap.TrackletLen
+	s.firstSeen = snap.FirstSeen
+	s.lastSeen = snap.LastSeen
+	s.hasFeature = snap.HasFeature
+	s.feature = snap.Feature
+	s.smoothFeature = snap.SmoothFeature
+	s.featureQueue = snap.FeatureQueue
+	s.maxQueueSize = snap.MaxQueueSize
+	s.alpha = snap.Alpha
+	s.featureFrame = snap.FeatureFrame
+	s.oriented = snap.Oriented
+	s.angleKF = angleFilter{
+		angle:    snap.Angle,
+		velocity: snap.AngleVelocity,
+		cov: [2][2]float64{
+			{snap.AngleCov[0], snap.AngleCov[1]},
+			{snap.AngleCov[2], snap.AngleCov[3]},
+		},
+	}
+
+	return s, nil
+}
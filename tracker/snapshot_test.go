@@ -0,0 +1,156 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// snapshotObjects returns the detections for the frame of an object that
+// leaves after frame 5 and two objects moving in opposite directions
+func snapshotObjects(frame int) []Object {
+
+	objects := movingObjects(frame)
+
+	if frame <= 5 {
+		objects = append(objects, NewObject(NewRect(600, 50, 40, 80), 1, 0.9, int64(frame*10+3)))
+	}
+
+	return objects
+}
+
+func TestBYTETrackerSnapshotRestore(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+	start := time.Unix(1000, 0)
+	interval := time.Second / 30
+
+	for frame := 0; frame < 10; frame++ {
+		if _, err := bt.UpdateAt(snapshotObjects(frame), start.Add(time.Duration(frame)*interval)); err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+	}
+
+	if len(bt.lostStracks) != 1 {
+		t.Fatalf("expected 1 lost track, got %d", len(bt.lostStracks))
+	}
+
+	data, err := bt.Snapshot()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+
+	if err := restored.Restore(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if restored.frameID != bt.frameID || restored.trackIDCount != bt.trackIDCount ||
+		!restored.timestamp.Equal(bt.timestamp) {
+		t.Errorf("expected frame %d, track count %d and timestamp %v, got %d, %d and %v",
+			bt.frameID, bt.trackIDCount, bt.timestamp,
+			restored.frameID, restored.trackIDCount, restored.timestamp)
+	}
+
+	// both trackers continue with identical results
+	for frame := 10; frame < 20; frame++ {
+
+		now := start.Add(time.Duration(frame) * interval)
+		want, err := bt.UpdateAt(snapshotObjects(frame), now)
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		got, err := restored.UpdateAt(snapshotObjects(frame), now)
+
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", frame, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("frame %d: expected %d tracks, got %d", frame, len(want), len(got))
+		}
+
+		for i := range want {
+			if got[i].GetTrackID() != want[i].GetTrackID() ||
+				!reflect.DeepEqual(got[i].GetRect().Tlwh, want[i].GetRect().Tlwh) {
+				t.Errorf("frame %d: expected track %d %v, got %d %v", frame,
+					want[i].GetTrackID(), want[i].GetRect(),
+					got[i].GetTrackID(), got[i].GetRect())
+			}
+		}
+	}
+
+	// new tracks continue the track ID count
+	var tracks []Track
+
+	for n := 0; n < 2; n++ {
+
+		tracks, err = restored.Update([]Object{NewObject(NewRect(50, 400, 40, 80), 0, 0.9, 99)})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(tracks) != 1 || tracks[0].GetTrackID() != 4 {
+		t.Errorf("expected new track ID 4, got %v", trackIDs(tracks))
+	}
+}
+
+func TestSTrackSnapshotFeatures(t *testing.T) {
+
+	s := NewSTrack(NewRect(10, 20, 30, 40), 0.8, 7, 2)
+	s.WithFeature([]float32{1, 0, 0}, 0.9, 2)
+	s.Activate(1, 5)
+	s.UpdateFeatures([]float32{0, 1, 0})
+	s.UpdateFeatures([]float32{0, 0, 1})
+	s.Predict()
+	s.MarkAsLost()
+	s.firstSeen = time.Unix(990, 0)
+	s.lastSeen = time.Unix(1000, 0)
+
+	got, err := restoreSTrack(s.snapshot())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.mean, s.mean) ||
+		!matricesEqual(got.covariance, s.covariance, 0) {
+		t.Errorf("expected Kalman state %v, got %v", s.mean, got.mean)
+	}
+
+	if !reflect.DeepEqual(got.featureQueue, s.featureQueue) ||
+		!reflect.DeepEqual(got.smoothFeature, s.smoothFeature) ||
+		!reflect.DeepEqual(got.feature, s.feature) ||
+		got.maxQueueSize != 2 || got.alpha != 0.9 || !got.hasFeature {
+		t.Errorf("expected features %v, got %v", s.featureQueue, got.featureQueue)
+	}
+
+	if got.trackID != 5 || got.state != Lost || !got.isActivated ||
+		!reflect.DeepEqual(got.rect.Tlwh, s.rect.Tlwh) || got.label != 2 || got.detectionID != 7 ||
+		!got.firstSeen.Equal(s.firstSeen) || !got.lastSeen.Equal(s.lastSeen) {
+		t.Errorf("expected track %+v, got %+v", s, got)
+	}
+}
+
+func TestBYTETrackerRestoreErrors(t *testing.T) {
+
+	bt := NewBYTETracker(30, 30, 0.5, 0.6, 0.8)
+
+	tests := []string{
+		`not json`,
+		`{"version": 99}`,
+		`{"version": 1, "tracked": [{"track_id": 1, "mean": [1, 2]}]}`,
+	}
+
+	for _, data := range tests {
+		if err := bt.Restore([]byte(data)); err == nil {
+			t.Errorf("expected error restoring %s", data)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/swdee/go-rknnlite/postprocess/reid"
 	"gonum.org/v1/gonum/mat"
+	"time"
 )
 
 // STrackState represents the state of a tracked object
@@ -60,6 +61,17 @@ type STrack struct {
 	alpha float32
 	// hasFeature is a flag to indicate if WithFeature() has been set
 	hasFeature bool
+	// featureFrame is the frame ID the last feature was added on
+	featureFrame int
+	// firstSeen is the timestamp of the frame the track was started on
+	firstSeen time.Time
+	// lastSeen is the timestamp of the frame the track was last matched to a
+	// detection
+	lastSeen time.Time
+	// oriented is a flag to indicate the track is of an oriented bounding box
+	oriented bool
+	// angleKF filters the rotation angle of an oriented bounding box
+	angleKF angleFilter
 }
 
 // NewSTrack creates a new STrack
@@ -157,6 +169,10 @@ func (s *STrack) Activate(frameID, trackID int) {
 	s.frameID = frameID
 	s.startFrameID = frameID
 	s.trackletLen = 0
+
+	if s.hasFeature {
+		s.featureFrame = frameID
+	}
 }
 
 // ReActivate reinitializes the track with a new detection
@@ -170,6 +186,7 @@ func (s *STrack) ReActivate(newTrack *STrack, frameID, newTrackID int) {
 	s.isActivated = true
 	s.score = newTrack.GetScore()
 	s.detectionID = newTrack.GetDetectionID()
+	s.updateAngle(newTrack)
 
 	if newTrackID >= 0 {
 		s.trackID = newTrackID
@@ -178,16 +195,25 @@ func (s *STrack) ReActivate(newTrack *STrack, frameID, newTrackID int) {
 	s.frameID = frameID
 	s.trackletLen = 0
 
-	s.UpdateFeatures(newTrack.feature)
+	s.updateFeature(newTrack, frameID)
 }
 
 // Predict predicts the next state of the track
 func (s *STrack) Predict() {
+	s.PredictDt(1)
+}
+
+// PredictDt predicts the state of the track dt frame intervals ahead
+func (s *STrack) PredictDt(dt float32) {
 	if s.state != Tracked {
 		s.mean[7] = 0
 	}
 
-	s.kalmanFilter.Predict(s.mean, &s.covariance)
+	s.kalmanFilter.PredictDt(s.mean, &s.covariance, dt)
+
+	if s.oriented {
+		s.angleKF.predict(float64(dt))
+	}
 }
 
 // Update updates the track with a new detection
@@ -208,12 +234,21 @@ func (s *STrack) Update(newTrack *STrack, frameID int) error {
 	s.detectionID = newTrack.GetDetectionID()
 	s.frameID = frameID
 	s.trackletLen++
+	s.updateAngle(newTrack)
 
-	s.UpdateFeatures(newTrack.feature)
+	s.updateFeature(newTrack, frameID)
 
 	return nil
 }
 
+// updateAngle updates the angle filter with the angle of the oriented
+// bounding box detection
+func (s *STrack) updateAngle(newTrack *STrack) {
+	if s.oriented && newTrack.oriented {
+		s.angleKF.update(newTrack.GetAngle())
+	}
+}
+
 // MarkAsLost marks the track as lost
 func (s *STrack) MarkAsLost() {
 	s.state = Lost
@@ -261,6 +296,27 @@ func (s *STrack) UpdateFeatures(feat []float32) {
 	}
 }
 
+// updateFeature adds the feature of the detection, if it has one, as ReID
+// features are not extracted for every detection when scheduled
+func (s *STrack) updateFeature(newTrack *STrack, frameID int) {
+	if newTrack.hasFeature && newTrack.feature != nil {
+		s.addFeature(newTrack.feature, frameID)
+	}
+}
+
+// addFeature adds the ReID feature of the track's detection on the frame,
+// enabling features on a track created from a detection without one
+func (s *STrack) addFeature(feature []float32, frameID int) {
+
+	if s.hasFeature {
+		s.UpdateFeatures(feature)
+	} else {
+		s.WithFeature(feature, featureAlpha, featureQueueSize)
+	}
+
+	s.featureFrame = frameID
+}
+
 // BestMatchDistance compares a new detection against all stored past features
 func (s *STrack) BestMatchDistance(detFeat []float32) float32 {
 
@@ -0,0 +1,94 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Track defines the interface of a tracked object returned by a Tracker
+type Track interface {
+	// GetRect returns the bounding box of the tracked object
+	GetRect() *Rect
+	// GetSTrackState returns the current state of the track
+	GetSTrackState() STrackState
+	// IsActivated returns whether the track is activated
+	IsActivated() bool
+	// GetScore returns the detection score
+	GetScore() float32
+	// GetTrackID returns the unique ID for the track
+	GetTrackID() int
+	// GetFrameID returns the frame ID the track was last updated on
+	GetFrameID() int
+	// GetDetectionID returns the ID of the detection matched to the track
+	GetDetectionID() int64
+	// GetLabel returns the object label/class
+	GetLabel() int
+	// GetStartFrameID returns the frame ID when the track started
+	GetStartFrameID() int
+	// GetTrackletLength returns the length of the tracklet
+	GetTrackletLength() int
+}
+
+// Tracker defines the interface for multi object trackers
+type Tracker interface {
+	// Update associates the detected objects of the next frame with the
+	// existing tracks and returns the active tracks
+	Update(objects []Object) ([]Track, error)
+	// UpdateAt associates the detected objects of the frame captured at the
+	// timestamp with the existing tracks and returns the active tracks
+	UpdateAt(objects []Object, timestamp time.Time) ([]Track, error)
+	// Reset clears the tracked data and resets everything
+	Reset()
+}
+
+var (
+	_ Tracker = (*BYTETracker)(nil)
+	_ Tracker = (*OCSORT)(nil)
+	_ Tracker = (*BoTSORT)(nil)
+	_ Track   = (*STrack)(nil)
+)
+
+// toTracks converts the STracks to the Track interface
+func toTracks(stracks []*STrack) []Track {
+
+	tracks := make([]Track, len(stracks))
+
+	for i, s := range stracks {
+		tracks[i] = s
+	}
+
+	return tracks
+}
+
+// frameInterval returns the nominal time between frames of the frame rate
+func frameInterval(frameRate int) time.Duration {
+
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+
+	return time.Second / time.Duration(frameRate)
+}
+
+// lostTimeout returns the time a track can be lost for given the number of
+// frames buffered at 30 FPS
+func lostTimeout(frameRate, trackBuffer int) time.Duration {
+	frames := int(float32(frameRate) / 30.0 * float32(trackBuffer))
+	return time.Duration(frames) * frameInterval(frameRate)
+}
+
+// frameDelta returns the number of frame intervals elapsed between the
+// timestamps of the previous and current update.  The first update, with a
+// zero previous timestamp, is one frame interval.
+func frameDelta(prev, now time.Time, interval time.Duration) (float32, error) {
+
+	if prev.IsZero() {
+		return 1, nil
+	}
+
+	if now.Before(prev) {
+		return 0, fmt.Errorf("timestamp %v is before the previous update %v", now, prev)
+	}
+
+	return float32(now.Sub(prev)) / float32(interval), nil
+}
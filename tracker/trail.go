@@ -8,8 +8,8 @@ type Point struct {
 	X, Y int
 }
 
-// Track represents a track history
-type Track struct {
+// trackHistory represents the point history of a track
+type trackHistory struct {
 	points []Point
 }
 
@@ -19,7 +19,7 @@ type Trail struct {
 	// size is the maximum number of most recent points to keep in history
 	size int
 	// history of tracked points
-	history map[int]*Track
+	history map[int]*trackHistory
 	sync.Mutex
 }
 
@@ -29,7 +29,7 @@ type Trail struct {
 func NewTrail(size int) *Trail {
 	return &Trail{
 		size:    size,
-		history: make(map[int]*Track),
+		history: make(map[int]*trackHistory),
 	}
 }
 
@@ -38,17 +38,17 @@ func (t *Trail) Reset() {
 	t.Lock()
 	defer t.Unlock()
 
-	t.history = make(map[int]*Track)
+	t.history = make(map[int]*trackHistory)
 }
 
 // Add a track to the history
-func (t *Trail) Add(strack *STrack) {
+func (t *Trail) Add(strack Track) {
 	t.Lock()
 	defer t.Unlock()
 
 	// init map if no history exists yet for track id
 	if _, exists := t.history[strack.GetTrackID()]; !exists {
-		t.history[strack.GetTrackID()] = &Track{}
+		t.history[strack.GetTrackID()] = &trackHistory{}
 	}
 
 	// add bounding box/rect's center point to track history